@@ -12,15 +12,29 @@ import (
 
 var DB *sql.DB
 
+// InitDB connects to the database and brings its schema up to date, unless
+// AUTO_MIGRATE is off
 func InitDB() error {
-	cfg, err := config.Loadconfig()
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+	if err := Connect(); err != nil {
+		return err
 	}
+	if !config.Get().Migration.AutoMigrate {
+		log.Println("AUTO_MIGRATE is off; not applying migrations")
+		return nil
+	}
+	if err := RunMigration(DB); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	return nil
+}
 
-	connStr := cfg.GetDBConnectionString()
-
-	DB, err = sql.Open("postgres", connStr)
+// Connect opens and checks the database connection without migrating it
+func Connect() error {
+	dialect, err := DialectFor(config.Get().Database.Driver)
+	if err != nil {
+		return err
+	}
+	DB, err = Open(dialect, config.Get().DatabaseURL())
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
@@ -30,13 +44,19 @@ func InitDB() error {
 		return fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Println("Successfully connected to PostgreSQL database")
-
-	RunMigration(DB)
-
+	log.Printf("Successfully connected to the %s database", dialect.Name)
 	return nil
 }
 
+// Open opens the dialect's database at source with the connection settings
+// the repositories rely on
+func Open(dialect *Dialect, source string) (*sql.DB, error) {
+	if dialect == SQLite {
+		source = sqliteSource(source)
+	}
+	return sql.Open(dialect.Driver, source)
+}
+
 func CloseDB() error {
 	if DB != nil {
 		return DB.Close()
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"modernc.org/sqlite"
+)
+
+// Dialect is how a database engine differs from the Postgres SQL the
+// repositories and migrations are written in. DB_DRIVER picks one.
+type Dialect struct {
+	Name   string // the DB_DRIVER value
+	Driver string // the database/sql driver it needs
+	// Migrations is the directory of migrationFiles with its schema
+	Migrations string
+
+	numberedParams bool   // placeholders are $1, $2, … rather than ?1, ?2, …
+	rowLocks       bool   // SELECT … FOR UPDATE [SKIP LOCKED]
+	advisoryLocks  bool   // pg_advisory_lock and friends
+	now            string // what CURRENT_TIMESTAMP is written as
+	bitDistance    string // how many bits two BIGINTs differ in, given them as %[1]s and %[2]s
+}
+
+var (
+	Postgres = &Dialect{
+		Name:           "postgres",
+		Driver:         "postgres",
+		Migrations:     "migrations",
+		numberedParams: true,
+		rowLocks:       true,
+		advisoryLocks:  true,
+		now:            "CURRENT_TIMESTAMP",
+		// bit_count() needs PostgreSQL 14, so count the set bits of the XOR by hand
+		bitDistance: `length(replace(((%[1]s # %[2]s::bigint)::bit(64))::text, '0', ''))`,
+	}
+	// SQLite writers take the whole database, so it needs no row or
+	// advisory locks. Its CURRENT_TIMESTAMP is to the second, it has no XOR
+	// operator, and utc_now and bit_count are registered in sqlite.go.
+	SQLite = &Dialect{
+		Name:        "sqlite",
+		Driver:      "sqlite",
+		Migrations:  "migrations/sqlite",
+		now:         "utc_now()",
+		bitDistance: `bit_count((%[1]s | %[2]s) & ~(%[1]s & %[2]s))`,
+	}
+)
+
+// Dialects are the dialects by DB_DRIVER value
+var Dialects = map[string]*Dialect{
+	Postgres.Name: Postgres,
+	SQLite.Name:   SQLite,
+}
+
+var (
+	placeholder      = regexp.MustCompile(`\$(\d+)`)
+	currentTimestamp = regexp.MustCompile(`(?i)\bCURRENT_TIMESTAMP\b`)
+	rowLock          = regexp.MustCompile(`(?i)\s*\bFOR\s+UPDATE(\s+OF\s+\w+(\s*,\s*\w+)*)?(\s+SKIP\s+LOCKED|\s+NOWAIT)?\b`)
+)
+
+// Rewrite turns a query written for Postgres into the dialect's: the $1,
+// $2, … placeholders and CURRENT_TIMESTAMP become the dialect's, and row
+// locks are dropped where the database doesn't take them. Quoted strings
+// are left alone.
+func (d *Dialect) Rewrite(query string) string {
+	if d == Postgres {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query))
+	rewrite := func(sql string) {
+		if !d.numberedParams {
+			sql = placeholder.ReplaceAllString(sql, "?$1")
+		}
+		if !d.rowLocks {
+			sql = rowLock.ReplaceAllString(sql, "")
+		}
+		sql = currentTimestamp.ReplaceAllLiteralString(sql, d.now)
+		b.WriteString(sql)
+	}
+	start := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+				b.WriteString(query[start : i+1])
+				start = i + 1
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			rewrite(query[start:i])
+			start = i
+		}
+	}
+	if quote != 0 {
+		b.WriteString(query[start:])
+	} else {
+		rewrite(query[start:])
+	}
+	return b.String()
+}
+
+// BitDistance returns the expression for how many bits the BIGINTs a and b
+// differ in
+func (d *Dialect) BitDistance(a, b string) string {
+	return fmt.Sprintf(d.bitDistance, a, b)
+}
+
+// LockTx takes the lock named key until tx ends, so transactions doing the
+// same work run one at a time
+func (d *Dialect) LockTx(ctx context.Context, tx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}, key int64) error {
+	if !d.advisoryLocks {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, key)
+	return err
+}
+
+// lockSession takes the lock named key on conn, across transactions, and
+// returns how to release it
+func (d *Dialect) lockSession(ctx context.Context, conn *sql.Conn, key int64) (func(), error) {
+	if !d.advisoryLocks {
+		return func() {}, nil
+	}
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, key); err != nil {
+		return nil, err
+	}
+	return func() { conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, key) }, nil
+}
+
+// DialectOf returns the dialect of the database db is open on
+func DialectOf(db *sql.DB) *Dialect {
+	if _, ok := db.Driver().(*sqlite.Driver); ok {
+		return SQLite
+	}
+	return Postgres
+}
+
+// DialectFor returns the dialect DB_DRIVER names
+func DialectFor(driver string) (*Dialect, error) {
+	d, ok := Dialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("unknown DB_DRIVER %q", driver)
+	}
+	return d, nil
+}
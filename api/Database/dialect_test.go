@@ -0,0 +1,29 @@
+package database
+
+import "testing"
+
+func TestRewrite(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		want  string
+	}{
+		{`SELECT id FROM items WHERE id = $1`, `SELECT id FROM items WHERE id = ?1`},
+		{`UPDATE items SET quantity = $1, is_sold = ($1 <= 0) WHERE id = $12`, `UPDATE items SET quantity = ?1, is_sold = (?1 <= 0) WHERE id = ?12`},
+		{`SELECT '$1', "a$2" FROM t WHERE x = $3`, `SELECT '$1', "a$2" FROM t WHERE x = ?3`},
+		{`SELECT 'it''s $1' WHERE y = $2`, `SELECT 'it''s $1' WHERE y = ?2`},
+		{`SELECT '$' || $1`, `SELECT '$' || ?1`},
+		{`SELECT status FROM orders WHERE id = $1 FOR UPDATE`, `SELECT status FROM orders WHERE id = ?1`},
+		{"SELECT id FROM orders\n\t\tORDER BY expires_at\n\t\tFOR UPDATE SKIP LOCKED", "SELECT id FROM orders\n\t\tORDER BY expires_at"},
+		{"SELECT i.id FROM items i JOIN t ON true\n\t\tFOR UPDATE OF items", "SELECT i.id FROM items i JOIN t ON true"},
+		{`SELECT id FROM t LIMIT 1 FOR UPDATE) x WHERE 'FOR UPDATE' <> $1`, `SELECT id FROM t LIMIT 1) x WHERE 'FOR UPDATE' <> ?1`},
+		{`SELECT before_update FROM t`, `SELECT before_update FROM t`},
+		{`UPDATE t SET updated_at = CURRENT_TIMESTAMP WHERE note <> 'CURRENT_TIMESTAMP'`, `UPDATE t SET updated_at = utc_now() WHERE note <> 'CURRENT_TIMESTAMP'`},
+	} {
+		if got := SQLite.Rewrite(tc.query); got != tc.want {
+			t.Errorf("SQLite.Rewrite(%q) = %q, want %q", tc.query, got, tc.want)
+		}
+		if got := Postgres.Rewrite(tc.query); got != tc.query {
+			t.Errorf("Postgres.Rewrite(%q) = %q, want it unchanged", tc.query, got)
+		}
+	}
+}
@@ -1,65 +1,271 @@
 package database
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"embed"
+	"encoding/hex"
 	"fmt"
+	"io/fs"
 	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
-func RunMigration(db *sql.DB) {
-	migrations := []string{
-		createUsersTable,
-		createItemsTable,
-		createItemsUserIndex,
-		createItemImagesTable,
+// migrationFiles holds the schema as numbered pairs of files,
+// NNNN_name.up.sql and NNNN_name.down.sql, in a directory per dialect. A
+// migration must never be edited once released; add a new one instead, for
+// every dialect, under the same version.
+//
+//go:embed migrations/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey is the lock held while migrating, so instances starting
+// together apply each migration once
+const migrationLockKey = 4_711_203_339
+
+const createSchemaMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	checksum CHAR(64) NOT NULL,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);`
+
+// Migration is one numbered schema change and how to undo it
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // of Up, to spot migrations edited after release
+}
+
+// MigrationStatus is a migration and when it was applied, if it has been
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// LoadMigrations reads the migrations in the directory dir of fsys in
+// version order. Every migration needs both an up and a down file, and
+// versions must be unique.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	paths, err := fs.Glob(fsys, path.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no migrations in %s", dir)
 	}
-	for _, migration := range migrations {
-		_, err := db.Exec(migration)
+	byVersion := map[int]*Migration{}
+	for _, p := range paths {
+		base := path.Base(p)
+		stem, direction, ok := strings.Cut(strings.TrimSuffix(base, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s: name must end in .up.sql or .down.sql", base)
+		}
+		number, name, ok := strings.Cut(stem, "_")
+		version, err := strconv.Atoi(number)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a version number", base)
+		}
+		body, err := fs.ReadFile(fsys, p)
 		if err != nil {
-			log.Fatalf("Error running migration: %v", err)
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %s: version %d is already used by %s", base, version, m.Name)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+			sum := sha256.Sum256(body)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(body)
 		}
 	}
-	fmt.Println("Migrations completed successfully")
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s: needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
 }
 
-const createUsersTable = `
-CREATE TABLE IF NOT EXISTS users (
-	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-	username VARCHAR(50) UNIQUE NOT NULL,
-	email VARCHAR(100) UNIQUE NOT NULL,
-	password VARCHAR(255) NOT NULL,
-	is_admin BOOLEAN DEFAULT FALSE,
-	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);`
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
 
-const createItemsTable = `
-CREATE TABLE IF NOT EXISTS items (
-	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-	user_id UUID NOT NULL,
-	name VARCHAR(255) NOT NULL,
-	description TEXT,
-	price DECIMAL(10, 2) NOT NULL,
-	selling_price DECIMAL(10, 2) NOT NULL,
-	image VARCHAR(500),
-	quantity INTEGER DEFAULT 0,
-	is_sold BOOLEAN DEFAULT FALSE,
-	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-);`
-const createItemsUserIndex = `
-CREATE INDEX IF NOT EXISTS idx_items_user_id ON items(user_id);`
-
-const createItemImagesTable = `
-CREATE TABLE IF NOT EXISTS item_images (
-	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-	item_id UUID NOT NULL,
-	image_path VARCHAR(500) NOT NULL,
-	display_order INTEGER DEFAULT 0,
-	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-	CONSTRAINT fk_item FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
-);
-
-CREATE INDEX IF NOT EXISTS idx_item_images_item_id ON item_images(item_id);
-`
+// RunMigration brings the schema up to date. Migrations already applied
+// are checked against their files, and each pending one runs in its own
+// transaction.
+func RunMigration(db *sql.DB) error {
+	dialect := DialectOf(db)
+	migrations, err := LoadMigrations(migrationFiles, dialect.Migrations)
+	if err != nil {
+		return err
+	}
+	return withMigrationLock(db, dialect, migrations, func(conn *sql.Conn, applied map[int]appliedMigration) error {
+		count := 0
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			err := inMigrationTx(conn, m.Up, dialect.Rewrite(`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`),
+				m.Version, m.Name, m.Checksum)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+			}
+			log.Printf("Applied migration %04d_%s", m.Version, m.Name)
+			count++
+		}
+		log.Printf("Migrations completed successfully (%d applied)", count)
+		return nil
+	})
+}
+
+// RollbackMigrations undoes the most recent steps applied migrations, the
+// newest first
+func RollbackMigrations(db *sql.DB, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("steps must be at least 1")
+	}
+	dialect := DialectOf(db)
+	migrations, err := LoadMigrations(migrationFiles, dialect.Migrations)
+	if err != nil {
+		return err
+	}
+	return withMigrationLock(db, dialect, migrations, func(conn *sql.Conn, applied map[int]appliedMigration) error {
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if err := inMigrationTx(conn, m.Down, dialect.Rewrite(`DELETE FROM schema_migrations WHERE version = $1`), m.Version); err != nil {
+				return fmt.Errorf("rolling back migration %04d_%s: %w", m.Version, m.Name, err)
+			}
+			log.Printf("Rolled back migration %04d_%s", m.Version, m.Name)
+			steps--
+		}
+		return nil
+	})
+}
+
+// GetMigrationStatus lists every migration and when it was applied
+func GetMigrationStatus(db *sql.DB) ([]MigrationStatus, error) {
+	dialect := DialectOf(db)
+	migrations, err := LoadMigrations(migrationFiles, dialect.Migrations)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []MigrationStatus
+	err = withMigrationLock(db, dialect, migrations, func(conn *sql.Conn, applied map[int]appliedMigration) error {
+		for _, m := range migrations {
+			status := MigrationStatus{Version: m.Version, Name: m.Name}
+			if a, ok := applied[m.Version]; ok {
+				status.AppliedAt = &a.appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// withMigrationLock runs fn on one connection holding the migration lock,
+// with the migrations applied so far. It refuses to go on if an applied
+// migration has been edited or is no longer known, since the schema would
+// then not be what the files describe.
+func withMigrationLock(db *sql.DB, dialect *Dialect, migrations []Migration, fn func(conn *sql.Conn, applied map[int]appliedMigration) error) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Session-level lock, so it is held across the per-migration transactions
+	unlock, err := dialect.lockSession(ctx, conn, migrationLockKey)
+	if err != nil {
+		return fmt.Errorf("failed to take the migration lock: %w", err)
+	}
+	defer unlock()
+
+	if _, err := conn.ExecContext(ctx, createSchemaMigrationsTable); err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+		if a, ok := applied[m.Version]; ok && a.checksum != m.Checksum {
+			return fmt.Errorf("migration %04d_%s has been edited since it was applied", m.Version, m.Name)
+		}
+	}
+	for version, a := range applied {
+		if !known[version] {
+			return fmt.Errorf("migration %04d_%s was applied but its files are missing", version, a.name)
+		}
+	}
+	return fn(conn, applied)
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]appliedMigration{}
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = a
+	}
+	return applied, rows.Err()
+}
+
+// inMigrationTx runs a migration's SQL and records it in schema_migrations
+// in one transaction
+func inMigrationTx(conn *sql.Conn, script, record string, args ...any) error {
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDialectMigrationsMatch(t *testing.T) {
+	postgres, err := LoadMigrations(migrationFiles, Postgres.Migrations)
+	if err != nil {
+		t.Fatalf("loading the Postgres migrations: %v", err)
+	}
+	sqlite, err := LoadMigrations(migrationFiles, SQLite.Migrations)
+	if err != nil {
+		t.Fatalf("loading the SQLite migrations: %v", err)
+	}
+	if len(sqlite) != len(postgres) {
+		t.Fatalf("there are %d SQLite migrations and %d Postgres ones", len(sqlite), len(postgres))
+	}
+	for i := range postgres {
+		if sqlite[i].Version != postgres[i].Version || sqlite[i].Name != postgres[i].Name {
+			t.Errorf("SQLite migration %04d_%s doesn't match Postgres migration %04d_%s",
+				sqlite[i].Version, sqlite[i].Name, postgres[i].Version, postgres[i].Name)
+		}
+	}
+}
+
+func TestSQLiteMigrationsRoundTrip(t *testing.T) {
+	db, err := Open(SQLite, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrations, err := LoadMigrations(migrationFiles, SQLite.Migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RunMigration(db); err != nil {
+		t.Fatalf("migrating up: %v", err)
+	}
+	if err := RollbackMigrations(db, len(migrations)); err != nil {
+		t.Fatalf("rolling every migration back: %v", err)
+	}
+	var tables int
+	if err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name <> 'schema_migrations'`).Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Errorf("%d tables are left after rolling every migration back", tables)
+	}
+	if err := RunMigration(db); err != nil {
+		t.Fatalf("migrating up again: %v", err)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(body string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(body)} }
+	fsys := fstest.MapFS{
+		"m/0010_add_index.up.sql":      file("CREATE INDEX i ON t (c);"),
+		"m/0010_add_index.down.sql":    file("DROP INDEX i;"),
+		"m/0002_create_table.up.sql":   file("CREATE TABLE t (c INT);"),
+		"m/0002_create_table.down.sql": file("DROP TABLE t;"),
+		"m/README.md":                  file("not a migration"),
+	}
+	migrations, err := LoadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 2 || migrations[1].Version != 10 {
+		t.Fatalf("LoadMigrations = %+v, want versions 2 and 10 in order", migrations)
+	}
+	m := migrations[0]
+	if m.Name != "create_table" || m.Up != "CREATE TABLE t (c INT);" || m.Down != "DROP TABLE t;" || len(m.Checksum) != 64 {
+		t.Errorf("migration 2 = %+v", m)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{"empty", fstest.MapFS{"m/.keep": file("")}, "no migrations"},
+		{"duplicate version", fstest.MapFS{
+			"m/0001_one.up.sql": file("up"), "m/0001_one.down.sql": file("down"),
+			"m/0001_two.up.sql": file("up"), "m/0001_two.down.sql": file("down"),
+		}, "version 1 is already used"},
+		{"no direction", fstest.MapFS{"m/0001_one.sql": file("up")}, "must end in .up.sql or .down.sql"},
+		{"unknown direction", fstest.MapFS{"m/0001_one.sideways.sql": file("up")}, "must end in .up.sql or .down.sql"},
+		{"no version", fstest.MapFS{"m/one.up.sql": file("up"), "m/one.down.sql": file("down")}, "must start with a version number"},
+		{"version zero", fstest.MapFS{"m/0000_one.up.sql": file("up"), "m/0000_one.down.sql": file("down")}, "must start with a version number"},
+		{"no name", fstest.MapFS{"m/0001.up.sql": file("up"), "m/0001.down.sql": file("down")}, "must start with a version number"},
+		{"no down", fstest.MapFS{"m/0001_one.up.sql": file("up")}, "needs both an up and a down file"},
+		{"no up", fstest.MapFS{"m/0001_one.down.sql": file("down")}, "needs both an up and a down file"},
+	} {
+		if _, err := LoadMigrations(tc.files, "m"); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: LoadMigrations error = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestMigrationChecksum(t *testing.T) {
+	load := func(up, down string) string {
+		t.Helper()
+		migrations, err := LoadMigrations(fstest.MapFS{
+			"m/0001_one.up.sql":   {Data: []byte(up)},
+			"m/0001_one.down.sql": {Data: []byte(down)},
+		}, "m")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return migrations[0].Checksum
+	}
+	checksum := load("CREATE TABLE t (c INT);", "DROP TABLE t;")
+	if load("CREATE TABLE t (c INT);", "DROP TABLE IF EXISTS t;") != checksum {
+		t.Error("editing the down file changed the checksum")
+	}
+	if load("CREATE TABLE t (c BIGINT);", "DROP TABLE t;") == checksum {
+		t.Error("editing the up file didn't change the checksum")
+	}
+}
+
+func TestRunMigrationRefusesDrift(t *testing.T) {
+	db, err := Open(SQLite, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := RunMigration(db); err != nil {
+		t.Fatal(err)
+	}
+
+	// A migration edited after it was applied
+	if _, err := db.Exec(`UPDATE schema_migrations SET checksum = ? WHERE version = 1`, strings.Repeat("0", 64)); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunMigration(db); err == nil || !strings.Contains(err.Error(), "has been edited") {
+		t.Errorf("RunMigration after an edit: error = %v", err)
+	}
+	if _, err := db.Exec(`DELETE FROM schema_migrations WHERE version = 1`); err != nil {
+		t.Fatal(err)
+	}
+
+	// A migration applied from a file that is gone
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name, checksum) VALUES (9999, 'removed', ?)`, strings.Repeat("0", 64)); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunMigration(db); err == nil || !strings.Contains(err.Error(), "files are missing") {
+		t.Errorf("RunMigration with an unknown migration: error = %v", err)
+	}
+}
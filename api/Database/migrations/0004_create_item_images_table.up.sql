@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS item_images (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	item_id UUID NOT NULL,
+	image_path VARCHAR(500) NOT NULL,
+	display_order INTEGER DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_item FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_item_images_item_id ON item_images(item_id);
@@ -0,0 +1 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS visibility VARCHAR(20) NOT NULL DEFAULT 'public';
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS item_invites (
+	item_id UUID NOT NULL,
+	user_id UUID NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (item_id, user_id),
+	CONSTRAINT fk_invite_item FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE,
+	CONSTRAINT fk_invite_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
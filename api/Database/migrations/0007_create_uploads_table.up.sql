@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS uploads (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id UUID NOT NULL,
+	filename VARCHAR(255) NOT NULL DEFAULT '',
+	size BIGINT NOT NULL,
+	upload_offset BIGINT NOT NULL DEFAULT 0,
+	status VARCHAR(20) NOT NULL DEFAULT 'in_progress',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP NOT NULL,
+	CONSTRAINT fk_upload_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_uploads_user_id ON uploads(user_id);
+CREATE INDEX IF NOT EXISTS idx_uploads_expires_at ON uploads(expires_at);
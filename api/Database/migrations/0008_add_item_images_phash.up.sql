@@ -0,0 +1,3 @@
+ALTER TABLE item_images ADD COLUMN IF NOT EXISTS phash BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_item_images_phash ON item_images(phash) WHERE phash IS NOT NULL;
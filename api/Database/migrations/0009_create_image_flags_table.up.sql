@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS image_flags (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	item_id UUID NOT NULL,
+	image_id UUID NOT NULL,
+	matched_item_id UUID NOT NULL,
+	matched_image_id UUID NOT NULL,
+	distance INTEGER NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	reviewed_by UUID,
+	reviewed_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_flag_item FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE,
+	CONSTRAINT fk_flag_image FOREIGN KEY (image_id) REFERENCES item_images(id) ON DELETE CASCADE,
+	CONSTRAINT fk_flag_matched_item FOREIGN KEY (matched_item_id) REFERENCES items(id) ON DELETE CASCADE,
+	CONSTRAINT fk_flag_matched_image FOREIGN KEY (matched_image_id) REFERENCES item_images(id) ON DELETE CASCADE,
+	CONSTRAINT uq_flag_pair UNIQUE (image_id, matched_image_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_image_flags_status ON image_flags(status);
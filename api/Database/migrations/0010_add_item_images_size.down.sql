@@ -0,0 +1 @@
+ALTER TABLE item_images DROP COLUMN IF EXISTS size_bytes;
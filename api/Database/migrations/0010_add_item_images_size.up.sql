@@ -0,0 +1 @@
+ALTER TABLE item_images ADD COLUMN IF NOT EXISTS size_bytes BIGINT NOT NULL DEFAULT 0;
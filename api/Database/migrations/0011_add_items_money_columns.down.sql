@@ -0,0 +1,19 @@
+-- Prices go back to major units, so each is divided by its currency's
+-- minor unit: the currencies listed in models.currencyExponents, and
+-- hundredths for the rest. The old columns keep two decimal places, so
+-- amounts in three-decimal currencies are rounded.
+UPDATE items SET price = items.price_minor / m.scale, selling_price = items.selling_price_minor / m.scale
+FROM (
+	SELECT id, CASE
+		WHEN currency IN ('JPY', 'KRW', 'VND', 'CLP', 'ISK') THEN 1.0
+		WHEN currency IN ('BHD', 'KWD', 'JOD', 'OMR', 'TND') THEN 1000.0
+		ELSE 100.0
+	END AS scale
+	FROM items
+) m
+WHERE m.id = items.id;
+ALTER TABLE items ALTER COLUMN price SET NOT NULL;
+ALTER TABLE items ALTER COLUMN selling_price SET NOT NULL;
+ALTER TABLE items DROP COLUMN IF EXISTS price_minor;
+ALTER TABLE items DROP COLUMN IF EXISTS selling_price_minor;
+ALTER TABLE items DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,11 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS price_minor BIGINT;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS selling_price_minor BIGINT;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+UPDATE items SET price_minor = ROUND(price * 100) WHERE price_minor IS NULL;
+UPDATE items SET selling_price_minor = ROUND(selling_price * 100) WHERE selling_price_minor IS NULL;
+
+ALTER TABLE items ALTER COLUMN price_minor SET NOT NULL;
+ALTER TABLE items ALTER COLUMN selling_price_minor SET NOT NULL;
+ALTER TABLE items ALTER COLUMN price DROP NOT NULL;
+ALTER TABLE items ALTER COLUMN selling_price DROP NOT NULL;
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS orders (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	buyer_id UUID NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	total_minor BIGINT NOT NULL,
+	currency CHAR(3) NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	paid_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_order_buyer FOREIGN KEY (buyer_id) REFERENCES users(id) ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_buyer_id ON orders(buyer_id);
+CREATE INDEX IF NOT EXISTS idx_orders_pending_expiry ON orders(expires_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS order_lines (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	order_id UUID NOT NULL,
+	item_id UUID,
+	seller_id UUID NOT NULL,
+	item_name VARCHAR(255) NOT NULL,
+	quantity INTEGER NOT NULL CHECK (quantity > 0),
+	unit_price_minor BIGINT NOT NULL,
+	currency CHAR(3) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_line_order FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+	CONSTRAINT fk_line_item FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_lines_order_id ON order_lines(order_id);
+CREATE INDEX IF NOT EXISTS idx_order_lines_item_id ON order_lines(item_id);
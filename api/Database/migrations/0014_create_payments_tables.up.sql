@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS payments (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	order_id UUID NOT NULL,
+	provider VARCHAR(50) NOT NULL,
+	provider_ref VARCHAR(255),
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	amount_minor BIGINT NOT NULL,
+	refunded_minor BIGINT NOT NULL DEFAULT 0,
+	currency CHAR(3) NOT NULL,
+	client_secret VARCHAR(255) NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_payment_order FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE RESTRICT,
+	CONSTRAINT uq_payment_provider_ref UNIQUE (provider, provider_ref)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments(order_id);
+
+CREATE TABLE IF NOT EXISTS payment_events (
+	provider VARCHAR(50) NOT NULL,
+	event_id VARCHAR(255) NOT NULL,
+	event_type VARCHAR(100) NOT NULL,
+	payment_id UUID,
+	from_status VARCHAR(20) NOT NULL DEFAULT '',
+	to_status VARCHAR(20) NOT NULL DEFAULT '',
+	received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (provider, event_id),
+	CONSTRAINT fk_event_payment FOREIGN KEY (payment_id) REFERENCES payments(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_events_payment_id ON payment_events(payment_id);
@@ -0,0 +1,76 @@
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	code VARCHAR(255) UNIQUE NOT NULL,
+	type VARCHAR(20) NOT NULL,
+	owner_id UUID,
+	currency CHAR(3) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_accounts_owner_id ON ledger_accounts(owner_id);
+
+CREATE TABLE IF NOT EXISTS ledger_entries (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	seq BIGSERIAL UNIQUE,
+	kind VARCHAR(20) NOT NULL,
+	reference_id VARCHAR(255) NOT NULL DEFAULT '',
+	idempotency_key VARCHAR(255) UNIQUE NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	prev_hash CHAR(64) NOT NULL,
+	hash CHAR(64) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_reference ON ledger_entries(kind, reference_id);
+
+CREATE TABLE IF NOT EXISTS ledger_postings (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	entry_id UUID NOT NULL,
+	account_id UUID NOT NULL,
+	memo VARCHAR(100) NOT NULL DEFAULT '',
+	amount_minor BIGINT NOT NULL CHECK (amount_minor <> 0),
+	currency CHAR(3) NOT NULL,
+	CONSTRAINT fk_posting_entry FOREIGN KEY (entry_id) REFERENCES ledger_entries(id),
+	CONSTRAINT fk_posting_account FOREIGN KEY (account_id) REFERENCES ledger_accounts(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_entry_id ON ledger_postings(entry_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_account_id ON ledger_postings(account_id);
+
+CREATE OR REPLACE FUNCTION ledger_reject_change() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'the ledger is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_entries_append_only ON ledger_entries;
+CREATE TRIGGER ledger_entries_append_only BEFORE UPDATE OR DELETE ON ledger_entries
+	FOR EACH ROW EXECUTE FUNCTION ledger_reject_change();
+DROP TRIGGER IF EXISTS ledger_entries_no_truncate ON ledger_entries;
+CREATE TRIGGER ledger_entries_no_truncate BEFORE TRUNCATE ON ledger_entries
+	FOR EACH STATEMENT EXECUTE FUNCTION ledger_reject_change();
+DROP TRIGGER IF EXISTS ledger_postings_append_only ON ledger_postings;
+CREATE TRIGGER ledger_postings_append_only BEFORE UPDATE OR DELETE ON ledger_postings
+	FOR EACH ROW EXECUTE FUNCTION ledger_reject_change();
+DROP TRIGGER IF EXISTS ledger_postings_no_truncate ON ledger_postings;
+CREATE TRIGGER ledger_postings_no_truncate BEFORE TRUNCATE ON ledger_postings
+	FOR EACH STATEMENT EXECUTE FUNCTION ledger_reject_change();
+
+CREATE OR REPLACE FUNCTION ledger_check_balanced() RETURNS trigger AS $$
+BEGIN
+	IF EXISTS (
+		SELECT 1 FROM ledger_postings
+		WHERE entry_id = NEW.entry_id
+		GROUP BY currency
+		HAVING SUM(amount_minor) <> 0
+	) THEN
+		RAISE EXCEPTION 'ledger entry % does not balance', NEW.entry_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_postings_balanced ON ledger_postings;
+CREATE CONSTRAINT TRIGGER ledger_postings_balanced AFTER INSERT ON ledger_postings
+	DEFERRABLE INITIALLY DEFERRED
+	FOR EACH ROW EXECUTE FUNCTION ledger_check_balanced();
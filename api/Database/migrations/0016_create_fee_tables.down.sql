@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_order_lines_seller_id;
+ALTER TABLE order_lines DROP COLUMN IF EXISTS fee;
+ALTER TABLE order_lines DROP COLUMN IF EXISTS fee_minor;
+ALTER TABLE order_lines DROP COLUMN IF EXISTS category;
+ALTER TABLE orders DROP COLUMN IF EXISTS fee_schedule_version;
+ALTER TABLE users DROP COLUMN IF EXISTS seller_tier;
+ALTER TABLE items DROP COLUMN IF EXISTS category;
+DROP TABLE IF EXISTS fee_schedules;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS fee_schedules (
+	version SERIAL PRIMARY KEY,
+	rules JSONB NOT NULL,
+	note TEXT NOT NULL DEFAULT '',
+	created_by UUID,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_fee_schedule_creator FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+INSERT INTO fee_schedules (rules, note)
+SELECT '[{"name": "Sale commission", "event": "sale", "type": "percentage", "bps": 1000}]', 'Initial schedule'
+WHERE NOT EXISTS (SELECT 1 FROM fee_schedules);
+
+ALTER TABLE items ADD COLUMN IF NOT EXISTS category VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS seller_tier VARCHAR(30) NOT NULL DEFAULT 'standard';
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS fee_schedule_version INTEGER REFERENCES fee_schedules(version);
+ALTER TABLE order_lines ADD COLUMN IF NOT EXISTS category VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE order_lines ADD COLUMN IF NOT EXISTS fee_minor BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE order_lines ADD COLUMN IF NOT EXISTS fee JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_order_lines_seller_id ON order_lines(seller_id);
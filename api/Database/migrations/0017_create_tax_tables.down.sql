@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS order_tax_lines;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_minor;
+ALTER TABLE orders DROP COLUMN IF EXISTS subtotal_minor;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_region;
+ALTER TABLE users DROP COLUMN IF EXISTS prices_include_tax;
+ALTER TABLE users DROP COLUMN IF EXISTS tax_region;
+DROP TABLE IF EXISTS tax_rates;
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS tax_rates (
+	region VARCHAR(10) NOT NULL,
+	category VARCHAR(50) NOT NULL DEFAULT '',
+	name VARCHAR(50) NOT NULL,
+	rate_bps INTEGER NOT NULL CHECK (rate_bps >= 0 AND rate_bps <= 10000),
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (region, category)
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tax_region VARCHAR(10);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS prices_include_tax BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_region VARCHAR(10);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS subtotal_minor BIGINT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_minor BIGINT NOT NULL DEFAULT 0;
+UPDATE orders SET subtotal_minor = total_minor WHERE subtotal_minor IS NULL;
+ALTER TABLE orders ALTER COLUMN subtotal_minor SET NOT NULL;
+
+CREATE TABLE IF NOT EXISTS order_tax_lines (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	order_id UUID NOT NULL,
+	order_line_id UUID NOT NULL,
+	region VARCHAR(10) NOT NULL,
+	name VARCHAR(50) NOT NULL,
+	rate_bps INTEGER NOT NULL,
+	inclusive BOOLEAN NOT NULL,
+	taxable_minor BIGINT NOT NULL,
+	tax_minor BIGINT NOT NULL,
+	currency CHAR(3) NOT NULL,
+	CONSTRAINT fk_tax_line_order FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+	CONSTRAINT fk_tax_line_order_line FOREIGN KEY (order_line_id) REFERENCES order_lines(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_tax_lines_order_id ON order_tax_lines(order_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS invoices;
+DROP TABLE IF EXISTS invoice_counters;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS invoice_counters (
+	seller_id UUID PRIMARY KEY,
+	last_sequence BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS invoices (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	seller_id UUID NOT NULL,
+	sequence BIGINT NOT NULL,
+	number VARCHAR(40) NOT NULL,
+	order_id UUID NOT NULL,
+	buyer_id UUID NOT NULL,
+	subtotal_minor BIGINT NOT NULL,
+	tax_minor BIGINT NOT NULL,
+	total_minor BIGINT NOT NULL,
+	currency CHAR(3) NOT NULL,
+	file_path VARCHAR(500) NOT NULL,
+	issued_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT uq_invoice_sequence UNIQUE (seller_id, sequence),
+	CONSTRAINT uq_invoice_order_seller UNIQUE (order_id, seller_id),
+	CONSTRAINT fk_invoice_order FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE RESTRICT
+);
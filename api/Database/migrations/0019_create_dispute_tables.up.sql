@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS disputes (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	order_id UUID NOT NULL,
+	buyer_id UUID NOT NULL,
+	seller_id UUID NOT NULL,
+	reason VARCHAR(30) NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'open',
+	resolution VARCHAR(20),
+	refund_minor BIGINT NOT NULL DEFAULT 0,
+	currency CHAR(3) NOT NULL,
+	restocked BOOLEAN NOT NULL DEFAULT FALSE,
+	resolved_by UUID,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	resolved_at TIMESTAMP,
+	CONSTRAINT fk_dispute_order FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE RESTRICT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_disputes_active ON disputes(order_id, seller_id) WHERE status <> 'resolved';
+CREATE INDEX IF NOT EXISTS idx_disputes_buyer_id ON disputes(buyer_id);
+CREATE INDEX IF NOT EXISTS idx_disputes_seller_id ON disputes(seller_id);
+CREATE INDEX IF NOT EXISTS idx_disputes_status ON disputes(status);
+
+CREATE TABLE IF NOT EXISTS dispute_messages (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	dispute_id UUID NOT NULL,
+	author_id UUID NOT NULL,
+	role VARCHAR(10) NOT NULL,
+	body TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_message_dispute FOREIGN KEY (dispute_id) REFERENCES disputes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_dispute_messages_dispute_id ON dispute_messages(dispute_id, created_at);
@@ -0,0 +1,12 @@
+ALTER TABLE invoices DROP COLUMN IF EXISTS shipping_minor;
+ALTER TABLE order_lines DROP COLUMN IF EXISTS shipment_id;
+DROP TABLE IF EXISTS shipment_events;
+DROP TABLE IF EXISTS shipments;
+ALTER TABLE orders DROP COLUMN IF EXISTS ship_to_region;
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_minor;
+ALTER TABLE items DROP COLUMN IF EXISTS shipping_profile_id;
+ALTER TABLE items DROP COLUMN IF EXISTS height_cm;
+ALTER TABLE items DROP COLUMN IF EXISTS width_cm;
+ALTER TABLE items DROP COLUMN IF EXISTS length_cm;
+ALTER TABLE items DROP COLUMN IF EXISTS weight_grams;
+DROP TABLE IF EXISTS shipping_profiles;
@@ -0,0 +1,63 @@
+CREATE TABLE IF NOT EXISTS shipping_profiles (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	seller_id UUID NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	type VARCHAR(20) NOT NULL,
+	currency CHAR(3) NOT NULL,
+	flat_minor BIGINT,
+	rates JSONB NOT NULL DEFAULT '[]',
+	free_over_minor BIGINT,
+	excluded_regions JSONB NOT NULL DEFAULT '[]',
+	pickup_location VARCHAR(255) NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_shipping_profile_seller FOREIGN KEY (seller_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipping_profiles_seller_id ON shipping_profiles(seller_id);
+
+ALTER TABLE items ADD COLUMN IF NOT EXISTS weight_grams INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS length_cm INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS width_cm INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS height_cm INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS shipping_profile_id UUID REFERENCES shipping_profiles(id) ON DELETE RESTRICT;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_minor BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS ship_to_region VARCHAR(10);
+
+CREATE TABLE IF NOT EXISTS shipments (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	order_id UUID NOT NULL,
+	seller_id UUID NOT NULL,
+	profile_id UUID,
+	method VARCHAR(20) NOT NULL DEFAULT '',
+	weight_grams BIGINT NOT NULL DEFAULT 0,
+	cost_minor BIGINT NOT NULL,
+	currency CHAR(3) NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	carrier VARCHAR(100) NOT NULL DEFAULT '',
+	tracking_number VARCHAR(100) NOT NULL DEFAULT '',
+	pickup_location VARCHAR(255) NOT NULL DEFAULT '',
+	shipped_at TIMESTAMP,
+	delivered_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_shipment_order FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipments_order_id ON shipments(order_id);
+
+CREATE TABLE IF NOT EXISTS shipment_events (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	shipment_id UUID NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	note VARCHAR(500) NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_event_shipment FOREIGN KEY (shipment_id) REFERENCES shipments(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipment_events_shipment_id ON shipment_events(shipment_id, created_at);
+
+ALTER TABLE order_lines ADD COLUMN IF NOT EXISTS shipment_id UUID REFERENCES shipments(id) ON DELETE SET NULL;
+
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS shipping_minor BIGINT NOT NULL DEFAULT 0;
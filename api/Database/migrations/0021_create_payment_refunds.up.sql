@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS payment_refunds (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	payment_id UUID NOT NULL,
+	idempotency_key VARCHAR(255) UNIQUE NOT NULL,
+	amount_minor BIGINT NOT NULL,
+	currency CHAR(3) NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	provider_ref VARCHAR(255),
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_refund_payment FOREIGN KEY (payment_id) REFERENCES payments(id) ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_refunds_payment_id ON payment_refunds(payment_id);
@@ -0,0 +1 @@
+ALTER TABLE payment_refunds ADD COLUMN IF NOT EXISTS seller_id UUID;
@@ -0,0 +1,12 @@
+-- The SQLite schema follows the Postgres one in ../, migration for
+-- migration. Ids are random UUIDs in text, and timestamps default to
+-- utc_now(), which the API registers with the driver.
+CREATE TABLE IF NOT EXISTS users (
+	id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+	username VARCHAR(50) UNIQUE NOT NULL,
+	email VARCHAR(100) UNIQUE NOT NULL,
+	password VARCHAR(255) NOT NULL,
+	is_admin BOOLEAN DEFAULT FALSE,
+	created_at TIMESTAMP DEFAULT (utc_now()),
+	updated_at TIMESTAMP DEFAULT (utc_now())
+);
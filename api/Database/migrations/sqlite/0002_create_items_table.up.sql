@@ -0,0 +1,16 @@
+-- SQLite can't make a column optional later, and 0011 makes the prices
+-- so, so they are optional from the start
+CREATE TABLE IF NOT EXISTS items (
+	id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+	user_id TEXT NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	description TEXT,
+	price DECIMAL(10, 2),
+	selling_price DECIMAL(10, 2),
+	image VARCHAR(500),
+	quantity INTEGER DEFAULT 0,
+	is_sold BOOLEAN DEFAULT FALSE,
+	created_at TIMESTAMP DEFAULT (utc_now()),
+	updated_at TIMESTAMP DEFAULT (utc_now()),
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_items_user_id ON items(user_id);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS item_images (
+	id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+	item_id TEXT NOT NULL,
+	image_path VARCHAR(500) NOT NULL,
+	display_order INTEGER DEFAULT 0,
+	created_at TIMESTAMP DEFAULT (utc_now()),
+	CONSTRAINT fk_item FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_item_images_item_id ON item_images(item_id);
@@ -0,0 +1 @@
+ALTER TABLE items ADD COLUMN visibility VARCHAR(20) NOT NULL DEFAULT 'public';
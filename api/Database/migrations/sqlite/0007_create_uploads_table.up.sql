@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS uploads (
+	id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+	user_id TEXT NOT NULL,
+	filename VARCHAR(255) NOT NULL DEFAULT '',
+	size BIGINT NOT NULL,
+	upload_offset BIGINT NOT NULL DEFAULT 0,
+	status VARCHAR(20) NOT NULL DEFAULT 'in_progress',
+	created_at TIMESTAMP DEFAULT (utc_now()),
+	updated_at TIMESTAMP DEFAULT (utc_now()),
+	expires_at TIMESTAMP NOT NULL,
+	CONSTRAINT fk_upload_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_uploads_user_id ON uploads(user_id);
+CREATE INDEX IF NOT EXISTS idx_uploads_expires_at ON uploads(expires_at);
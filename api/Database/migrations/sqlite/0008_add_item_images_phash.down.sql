@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_item_images_phash;
+ALTER TABLE item_images DROP COLUMN phash;
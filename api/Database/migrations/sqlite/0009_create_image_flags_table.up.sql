@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS image_flags (
+	id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+	item_id TEXT NOT NULL,
+	image_id TEXT NOT NULL,
+	matched_item_id TEXT NOT NULL,
+	matched_image_id TEXT NOT NULL,
+	distance INTEGER NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	reviewed_by TEXT,
+	reviewed_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT (utc_now()),
+	CONSTRAINT fk_flag_item FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE,
+	CONSTRAINT fk_flag_image FOREIGN KEY (image_id) REFERENCES item_images(id) ON DELETE CASCADE,
+	CONSTRAINT fk_flag_matched_item FOREIGN KEY (matched_item_id) REFERENCES items(id) ON DELETE CASCADE,
+	CONSTRAINT fk_flag_matched_image FOREIGN KEY (matched_image_id) REFERENCES item_images(id) ON DELETE CASCADE,
+	CONSTRAINT uq_flag_pair UNIQUE (image_id, matched_image_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_image_flags_status ON image_flags(status);
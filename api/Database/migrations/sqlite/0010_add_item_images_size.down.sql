@@ -0,0 +1 @@
+ALTER TABLE item_images DROP COLUMN size_bytes;
@@ -0,0 +1 @@
+ALTER TABLE item_images ADD COLUMN size_bytes BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,16 @@
+-- Prices go back to major units as in the Postgres migration: divided by
+-- each currency's minor unit, the currencies listed in
+-- models.currencyExponents and hundredths for the rest
+UPDATE items SET price = items.price_minor / m.scale, selling_price = items.selling_price_minor / m.scale
+FROM (
+	SELECT id, CASE
+		WHEN currency IN ('JPY', 'KRW', 'VND', 'CLP', 'ISK') THEN 1.0
+		WHEN currency IN ('BHD', 'KWD', 'JOD', 'OMR', 'TND') THEN 1000.0
+		ELSE 100.0
+	END AS scale
+	FROM items
+) m
+WHERE m.id = items.id;
+ALTER TABLE items DROP COLUMN price_minor;
+ALTER TABLE items DROP COLUMN selling_price_minor;
+ALTER TABLE items DROP COLUMN currency;
@@ -0,0 +1,7 @@
+-- SQLite only adds a required column with a default; every item written
+-- from here on sets both amounts
+ALTER TABLE items ADD COLUMN price_minor BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN selling_price_minor BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+UPDATE items SET price_minor = ROUND(price * 100), selling_price_minor = ROUND(selling_price * 100);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN preferred_currency VARCHAR(3) NOT NULL DEFAULT '';
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS orders (
+	id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+	buyer_id TEXT NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	total_minor BIGINT NOT NULL,
+	currency CHAR(3) NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	paid_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT (utc_now()),
+	updated_at TIMESTAMP DEFAULT (utc_now()),
+	CONSTRAINT fk_order_buyer FOREIGN KEY (buyer_id) REFERENCES users(id) ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_buyer_id ON orders(buyer_id);
+CREATE INDEX IF NOT EXISTS idx_orders_pending_expiry ON orders(expires_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS order_lines (
+	id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+	order_id TEXT NOT NULL,
+	item_id TEXT,
+	seller_id TEXT NOT NULL,
+	item_name VARCHAR(255) NOT NULL,
+	quantity INTEGER NOT NULL CHECK (quantity > 0),
+	unit_price_minor BIGINT NOT NULL,
+	currency CHAR(3) NOT NULL,
+	created_at TIMESTAMP DEFAULT (utc_now()),
+	CONSTRAINT fk_line_order FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+	CONSTRAINT fk_line_item FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_lines_order_id ON order_lines(order_id);
+CREATE INDEX IF NOT EXISTS idx_order_lines_item_id ON order_lines(item_id);
@@ -0,0 +1,60 @@
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+	id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+	code VARCHAR(255) UNIQUE NOT NULL,
+	type VARCHAR(20) NOT NULL,
+	owner_id TEXT,
+	currency CHAR(3) NOT NULL,
+	created_at TIMESTAMP DEFAULT (utc_now())
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_accounts_owner_id ON ledger_accounts(owner_id);
+
+-- Only an INTEGER PRIMARY KEY numbers itself in SQLite, so seq is the key
+-- and id is kept unique beside it
+CREATE TABLE IF NOT EXISTS ledger_entries (
+	seq INTEGER PRIMARY KEY,
+	id TEXT UNIQUE NOT NULL DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+	kind VARCHAR(20) NOT NULL,
+	reference_id VARCHAR(255) NOT NULL DEFAULT '',
+	idempotency_key VARCHAR(255) UNIQUE NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	prev_hash CHAR(64) NOT NULL,
+	hash CHAR(64) NOT NULL,
+	created_at TIMESTAMP DEFAULT (utc_now())
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_reference ON ledger_entries(kind, reference_id);
+
+CREATE TABLE IF NOT EXISTS ledger_postings (
+	id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+	entry_id TEXT NOT NULL,
+	account_id TEXT NOT NULL,
+	memo VARCHAR(100) NOT NULL DEFAULT '',
+	amount_minor BIGINT NOT NULL CHECK (amount_minor <> 0),
+	currency CHAR(3) NOT NULL,
+	CONSTRAINT fk_posting_entry FOREIGN KEY (entry_id) REFERENCES ledger_entries(id),
+	CONSTRAINT fk_posting_account FOREIGN KEY (account_id) REFERENCES ledger_accounts(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_entry_id ON ledger_postings(entry_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_account_id ON ledger_postings(account_id);
+
+CREATE TRIGGER IF NOT EXISTS ledger_entries_no_update BEFORE UPDATE ON ledger_entries
+BEGIN
+	SELECT RAISE(ABORT, 'the ledger is append-only');
+END;
+CREATE TRIGGER IF NOT EXISTS ledger_entries_no_delete BEFORE DELETE ON ledger_entries
+BEGIN
+	SELECT RAISE(ABORT, 'the ledger is append-only');
+END;
+CREATE TRIGGER IF NOT EXISTS ledger_postings_no_update BEFORE UPDATE ON ledger_postings
+BEGIN
+	SELECT RAISE(ABORT, 'the ledger is append-only');
+END;
+CREATE TRIGGER IF NOT EXISTS ledger_postings_no_delete BEFORE DELETE ON ledger_postings
+BEGIN
+	SELECT RAISE(ABORT, 'the ledger is append-only');
+END;
+
+-- SQLite triggers can't be deferred to commit, so unlike Postgres it
+-- leaves checking that entries balance to LedgerRepository.PostEntry
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_order_lines_seller_id;
+ALTER TABLE order_lines DROP COLUMN fee;
+ALTER TABLE order_lines DROP COLUMN fee_minor;
+ALTER TABLE order_lines DROP COLUMN category;
+ALTER TABLE orders DROP COLUMN fee_schedule_version;
+ALTER TABLE users DROP COLUMN seller_tier;
+ALTER TABLE items DROP COLUMN category;
+DROP TABLE IF EXISTS fee_schedules;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS order_tax_lines;
+ALTER TABLE orders DROP COLUMN tax_minor;
+ALTER TABLE orders DROP COLUMN subtotal_minor;
+ALTER TABLE orders DROP COLUMN tax_region;
+ALTER TABLE users DROP COLUMN prices_include_tax;
+ALTER TABLE users DROP COLUMN tax_region;
+DROP TABLE IF EXISTS tax_rates;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS invoice_counters (
+	seller_id TEXT PRIMARY KEY,
+	last_sequence BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS invoices (
+	id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+	seller_id TEXT NOT NULL,
+	sequence BIGINT NOT NULL,
+	number VARCHAR(40) NOT NULL,
+	order_id TEXT NOT NULL,
+	buyer_id TEXT NOT NULL,
+	subtotal_minor BIGINT NOT NULL,
+	tax_minor BIGINT NOT NULL,
+	total_minor BIGINT NOT NULL,
+	currency CHAR(3) NOT NULL,
+	file_path VARCHAR(500) NOT NULL,
+	issued_at TIMESTAMP DEFAULT (utc_now()),
+	CONSTRAINT uq_invoice_sequence UNIQUE (seller_id, sequence),
+	CONSTRAINT uq_invoice_order_seller UNIQUE (order_id, seller_id),
+	CONSTRAINT fk_invoice_order FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE RESTRICT
+);
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS disputes (
+	id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+	order_id TEXT NOT NULL,
+	buyer_id TEXT NOT NULL,
+	seller_id TEXT NOT NULL,
+	reason VARCHAR(30) NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'open',
+	resolution VARCHAR(20),
+	refund_minor BIGINT NOT NULL DEFAULT 0,
+	currency CHAR(3) NOT NULL,
+	restocked BOOLEAN NOT NULL DEFAULT FALSE,
+	resolved_by TEXT,
+	created_at TIMESTAMP DEFAULT (utc_now()),
+	updated_at TIMESTAMP DEFAULT (utc_now()),
+	resolved_at TIMESTAMP,
+	CONSTRAINT fk_dispute_order FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE RESTRICT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_disputes_active ON disputes(order_id, seller_id) WHERE status <> 'resolved';
+CREATE INDEX IF NOT EXISTS idx_disputes_buyer_id ON disputes(buyer_id);
+CREATE INDEX IF NOT EXISTS idx_disputes_seller_id ON disputes(seller_id);
+CREATE INDEX IF NOT EXISTS idx_disputes_status ON disputes(status);
+
+CREATE TABLE IF NOT EXISTS dispute_messages (
+	id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+	dispute_id TEXT NOT NULL,
+	author_id TEXT NOT NULL,
+	role VARCHAR(10) NOT NULL,
+	body TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT (utc_now()),
+	CONSTRAINT fk_message_dispute FOREIGN KEY (dispute_id) REFERENCES disputes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_dispute_messages_dispute_id ON dispute_messages(dispute_id, created_at);
@@ -0,0 +1,12 @@
+ALTER TABLE invoices DROP COLUMN shipping_minor;
+ALTER TABLE order_lines DROP COLUMN shipment_id;
+DROP TABLE IF EXISTS shipment_events;
+DROP TABLE IF EXISTS shipments;
+ALTER TABLE orders DROP COLUMN ship_to_region;
+ALTER TABLE orders DROP COLUMN shipping_minor;
+ALTER TABLE items DROP COLUMN shipping_profile_id;
+ALTER TABLE items DROP COLUMN height_cm;
+ALTER TABLE items DROP COLUMN width_cm;
+ALTER TABLE items DROP COLUMN length_cm;
+ALTER TABLE items DROP COLUMN weight_grams;
+DROP TABLE IF EXISTS shipping_profiles;
@@ -0,0 +1,63 @@
+CREATE TABLE IF NOT EXISTS shipping_profiles (
+	id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+	seller_id TEXT NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	type VARCHAR(20) NOT NULL,
+	currency CHAR(3) NOT NULL,
+	flat_minor BIGINT,
+	rates TEXT NOT NULL DEFAULT '[]',
+	free_over_minor BIGINT,
+	excluded_regions TEXT NOT NULL DEFAULT '[]',
+	pickup_location VARCHAR(255) NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT (utc_now()),
+	updated_at TIMESTAMP DEFAULT (utc_now()),
+	CONSTRAINT fk_shipping_profile_seller FOREIGN KEY (seller_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipping_profiles_seller_id ON shipping_profiles(seller_id);
+
+ALTER TABLE items ADD COLUMN weight_grams INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN length_cm INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN width_cm INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN height_cm INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN shipping_profile_id TEXT REFERENCES shipping_profiles(id) ON DELETE RESTRICT;
+
+ALTER TABLE orders ADD COLUMN shipping_minor BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN ship_to_region VARCHAR(10);
+
+CREATE TABLE IF NOT EXISTS shipments (
+	id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+	order_id TEXT NOT NULL,
+	seller_id TEXT NOT NULL,
+	profile_id TEXT,
+	method VARCHAR(20) NOT NULL DEFAULT '',
+	weight_grams BIGINT NOT NULL DEFAULT 0,
+	cost_minor BIGINT NOT NULL,
+	currency CHAR(3) NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	carrier VARCHAR(100) NOT NULL DEFAULT '',
+	tracking_number VARCHAR(100) NOT NULL DEFAULT '',
+	pickup_location VARCHAR(255) NOT NULL DEFAULT '',
+	shipped_at TIMESTAMP,
+	delivered_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT (utc_now()),
+	updated_at TIMESTAMP DEFAULT (utc_now()),
+	CONSTRAINT fk_shipment_order FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipments_order_id ON shipments(order_id);
+
+CREATE TABLE IF NOT EXISTS shipment_events (
+	id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+	shipment_id TEXT NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	note VARCHAR(500) NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT (utc_now()),
+	CONSTRAINT fk_event_shipment FOREIGN KEY (shipment_id) REFERENCES shipments(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipment_events_shipment_id ON shipment_events(shipment_id, created_at);
+
+ALTER TABLE order_lines ADD COLUMN shipment_id TEXT REFERENCES shipments(id) ON DELETE SET NULL;
+
+ALTER TABLE invoices ADD COLUMN shipping_minor BIGINT NOT NULL DEFAULT 0;
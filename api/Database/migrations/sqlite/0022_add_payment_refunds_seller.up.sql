@@ -0,0 +1 @@
+ALTER TABLE payment_refunds ADD COLUMN seller_id TEXT;
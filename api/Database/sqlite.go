@@ -0,0 +1,45 @@
+package database
+
+import (
+	"database/sql/driver"
+	"math/bits"
+	"net/url"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// sqliteTimestamp is how utc_now() writes the time, which the driver reads
+// back into a time.Time for TIMESTAMP columns
+const sqliteTimestamp = "2006-01-02 15:04:05.000000"
+
+func init() {
+	// SQLite's own clock stops at milliseconds, too coarse to order rows
+	// created together as Postgres does, so the SQLite migrations default
+	// timestamps to utc_now() instead
+	sqlite.MustRegisterScalarFunction("utc_now", 0, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+		return time.Now().UTC().Format(sqliteTimestamp), nil
+	})
+	sqlite.MustRegisterDeterministicScalarFunction("bit_count", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		n, ok := args[0].(int64)
+		if !ok {
+			return nil, nil
+		}
+		return int64(bits.OnesCount64(uint64(n))), nil
+	})
+}
+
+// sqliteSource returns the data source for the SQLite database file path.
+// Foreign keys are off in SQLite unless asked for. Transactions take the
+// write lock when they begin, since one that upgrades to it later fails
+// at once if another writer got there first, and a busy database is waited
+// for rather than reported. Times are written in a format the driver can
+// read back.
+func sqliteSource(path string) string {
+	query := url.Values{
+		"_pragma":      {"foreign_keys(1)", "busy_timeout(5000)", "journal_mode(WAL)"},
+		"_txlock":      {"immediate"},
+		"_time_format": {"sqlite"},
+	}
+	return "file:" + path + "?" + query.Encode()
+}
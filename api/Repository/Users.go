@@ -1,50 +1,68 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"primeauction/api/models"
 )
 
 type UserRepository struct {
-	db *sql.DB
+	db conn
 }
 
 func NewUserRepository(db *sql.DB) *UserRepository {
-	return &UserRepository{db: db}
+	return &UserRepository{db: conn{db}}
 }
-func (r *UserRepository) CreateUser(user *models.User) error {
-	query := `INSERT INTO users (username, email, password) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`
-	err := r.db.QueryRow(query, user.Username, user.Email, user.Password).Scan(&user.Id, &user.CreatedAt, &user.UpdatedAt)
+func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO users (username, email, password, is_admin) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`
+	err := r.db.QueryRowContext(ctx, query, user.Username, user.Email, user.Password, user.IsAdmin).Scan(&user.Id, &user.CreatedAt, &user.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrUserExists
+	}
 	return err
 }
-func (r *UserRepository) GetUserByID(id string) (*models.User, error) {
-	query := `SELECT id, username, email, password FROM users WHERE id = $1`
-	row := r.db.QueryRow(query, id)
+func (r *UserRepository) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, username, email, password, is_admin, preferred_currency, created_at, updated_at FROM users WHERE id = $1`
+	row := r.db.QueryRowContext(ctx, query, id)
 	var user models.User
-	err := row.Scan(&user.Id, &user.Username, &user.Email, &user.Password)
+	err := row.Scan(&user.Id, &user.Username, &user.Email, &user.Password, &user.IsAdmin, &user.PreferredCurrency, &user.CreatedAt, &user.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
 	if err != nil {
-		return nil, errors.New("user not found")
-
+		return nil, err
 	}
 	return &user, nil
 }
-func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
-	query := `SELECT id, username, email, password, is_admin, created_at, updated_at FROM users WHERE email = $1`
-	row := r.db.QueryRow(query, email)
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, username, email, password, is_admin, preferred_currency, created_at, updated_at FROM users WHERE email = $1`
+	row := r.db.QueryRowContext(ctx, query, email)
 	var user models.User
-	err := row.Scan(&user.Id, &user.Username, &user.Email, &user.Password, &user.IsAdmin, &user.CreatedAt, &user.UpdatedAt)
+	err := row.Scan(&user.Id, &user.Username, &user.Email, &user.Password, &user.IsAdmin, &user.PreferredCurrency, &user.CreatedAt, &user.UpdatedAt)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
-func (r *UserRepository) GetAllUsers() ([]models.User, error) {
+func (r *UserRepository) GetAllUsers(ctx context.Context) ([]models.User, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
 	query := `SELECT id, username, email, password FROM users`
-	rows, err := r.db.Query(query)
+	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -60,17 +78,68 @@ func (r *UserRepository) GetAllUsers() ([]models.User, error) {
 	}
 	return users, nil
 }
-func (r *UserRepository) UpdateUser(id string, user *models.User) error {
-	query := `UPDATE users SET username = $1, email = $2, password = $3 WHERE id = $4`
-	_, err := r.db.Exec(query, user.Username, user.Email, user.Password, id)
+func (r *UserRepository) UpdateUser(ctx context.Context, id string, user *models.User) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE users SET username = $1, email = $2, password = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4`
+	err := r.updateOne(ctx, query, user.Username, user.Email, user.Password, id)
+	if isUniqueViolation(err) {
+		return ErrUserExists
+	}
 	return err
 }
-func (r *UserRepository) DeleteUser(id string) error {
-	query := `Delete users where id=$1`
-	_, err := r.db.Exec(query)
+// UpdatePreferredCurrency sets the currency prices are shown in for a user
+func (r *UserRepository) UpdatePreferredCurrency(ctx context.Context, id, currency string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE users SET preferred_currency = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	result, err := r.db.ExecContext(ctx, query, currency, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return errors.New("failed to delete user")
+		return errors.New("failed to get rows affected")
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
 	}
 	return nil
+}
+// SetAdmin grants or revokes a user's admin rights
+func (r *UserRepository) SetAdmin(ctx context.Context, id string, isAdmin bool) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	return r.updateOne(ctx, `UPDATE users SET is_admin = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, isAdmin, id)
+}
+// UpdatePassword replaces a user's password hash
+func (r *UserRepository) UpdatePassword(ctx context.Context, id, hash string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	return r.updateOne(ctx, `UPDATE users SET password = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, hash, id)
+}
+// updateOne runs a statement that must change exactly the one user it names
+func (r *UserRepository) updateOne(ctx context.Context, query string, args ...any) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.New("failed to get rows affected")
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+func (r *UserRepository) DeleteUser(ctx context.Context, id string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
 
+	return r.updateOne(ctx, `DELETE FROM users WHERE id = $1`, id)
 }
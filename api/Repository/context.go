@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"primeauction/api/config"
+)
+
+// sqlStateQueryCanceled is the SQLSTATE Postgres reports for a statement
+// cancelled because its context ended
+const sqlStateQueryCanceled = "57014"
+
+// withTimeout bounds a repository operation by DB_QUERY_TIMEOUT, so a slow
+// query is abandoned even when the caller set no deadline. A sooner
+// deadline on ctx still applies.
+func withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, config.Get().Database.QueryTimeout)
+}
+
+// IsCanceled reports whether err means an operation was abandoned because
+// its context was cancelled or ran out of time, whether that happened
+// before the query was sent or while the database was running it
+func IsCanceled(err error) bool {
+	var sqlErr interface{ SQLState() string }
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.As(err, &sqlErr) && sqlErr.SQLState() == sqlStateQueryCanceled
+}
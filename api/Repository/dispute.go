@@ -0,0 +1,367 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"primeauction/api/models"
+)
+
+// Errors raised inside dispute transactions
+var (
+	ErrDisputeNotFound = errors.New("dispute not found")
+	// A seller's part of an order already has a dispute that isn't resolved
+	ErrDisputeInProgress = errors.New("a dispute is already in progress for this seller's items")
+)
+
+type DisputeRepository struct {
+	db conn
+}
+
+func NewDisputeRepository(db *sql.DB) *DisputeRepository {
+	return &DisputeRepository{db: conn{db}}
+}
+
+const disputeColumns = `id, order_id, buyer_id, seller_id, reason, status, resolution, refund_minor, currency, restocked,
+	resolved_by, created_at, updated_at, resolved_at`
+
+func scanDispute(row interface{ Scan(...any) error }) (*models.Dispute, error) {
+	var dispute models.Dispute
+	var resolution sql.NullString
+	err := row.Scan(&dispute.Id, &dispute.OrderId, &dispute.BuyerId, &dispute.SellerId, &dispute.Reason, &dispute.Status,
+		&resolution, &dispute.Refund.Amount, &dispute.Refund.Currency, &dispute.Restocked,
+		&dispute.ResolvedBy, &dispute.CreatedAt, &dispute.UpdatedAt, &dispute.ResolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	dispute.Resolution = resolution.String
+	return &dispute, nil
+}
+
+// CreateDispute opens a dispute with the buyer's first message. Disputes
+// for an order are created one at a time, under the order's row lock.
+func (r *DisputeRepository) CreateDispute(ctx context.Context, dispute *models.Dispute, message *models.DisputeMessage) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	ctx, tx, err := r.db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, dispute.OrderId).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrOrderNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var inProgress bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM disputes WHERE order_id = $1 AND seller_id = $2 AND status <> $3)`,
+		dispute.OrderId, dispute.SellerId, models.DisputeResolved).Scan(&inProgress)
+	if err != nil {
+		return err
+	}
+	if inProgress {
+		return ErrDisputeInProgress
+	}
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO disputes (order_id, buyer_id, seller_id, reason, status, currency)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at`,
+		dispute.OrderId, dispute.BuyerId, dispute.SellerId, dispute.Reason, dispute.Status, dispute.Refund.Currency,
+	).Scan(&dispute.Id, &dispute.CreatedAt, &dispute.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	message.DisputeId = dispute.Id
+	if err := insertDisputeMessage(ctx, tx, message); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	dispute.Messages = []models.DisputeMessage{*message}
+	return nil
+}
+
+// GetDispute retrieves a dispute with its messages
+func (r *DisputeRepository) GetDispute(ctx context.Context, id string) (*models.Dispute, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + disputeColumns + ` FROM disputes WHERE id = $1`
+	dispute, err := scanDispute(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrDisputeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dispute.Messages, err = r.getMessages(ctx, id); err != nil {
+		return nil, err
+	}
+	return dispute, nil
+}
+
+// GetDisputesByUser lists the disputes a user is the buyer or seller in,
+// newest first, without their messages
+func (r *DisputeRepository) GetDisputesByUser(ctx context.Context, userID string) ([]models.Dispute, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	return r.getDisputes(ctx, `SELECT `+disputeColumns+` FROM disputes
+		WHERE buyer_id = $1 OR seller_id = $1
+		ORDER BY created_at DESC`, userID)
+}
+
+// GetDisputesByStatus lists disputes in a status, or all of them when
+// status is empty, oldest first so the longest-waiting come first
+func (r *DisputeRepository) GetDisputesByStatus(ctx context.Context, status string) ([]models.Dispute, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	if status == "" {
+		return r.getDisputes(ctx, `SELECT `+disputeColumns+` FROM disputes ORDER BY created_at`)
+	}
+	return r.getDisputes(ctx, `SELECT `+disputeColumns+` FROM disputes WHERE status = $1 ORDER BY created_at`, status)
+}
+
+func (r *DisputeRepository) getDisputes(ctx context.Context, query string, args ...any) ([]models.Dispute, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	disputes := []models.Dispute{}
+	for rows.Next() {
+		dispute, err := scanDispute(rows)
+		if err != nil {
+			return nil, err
+		}
+		disputes = append(disputes, *dispute)
+	}
+	return disputes, rows.Err()
+}
+
+// UpdateDispute locks a dispute and lets apply change its status and
+// return a message to add to the thread (or nil), then stores both
+func (r *DisputeRepository) UpdateDispute(ctx context.Context, id string, apply func(dispute *models.Dispute) (*models.DisputeMessage, error)) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	ctx, tx, err := r.db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	dispute, err := lockDispute(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	message, err := apply(dispute)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE disputes SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, dispute.Status, id)
+	if err != nil {
+		return err
+	}
+	if message != nil {
+		message.DisputeId = id
+		if err := insertDisputeMessage(ctx, tx, message); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetResolution returns what ResolveDispute would pass to resolve, without
+// locking anything, so a resolution and its refund can be worked out
+// before the rows are locked
+func (r *DisputeRepository) GetResolution(ctx context.Context, id string) (*models.Dispute, *models.Payment, int64, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	ctx, tx, err := r.db.begin(ctx)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer tx.Rollback()
+
+	dispute, err := scanDispute(tx.QueryRowContext(ctx, `SELECT `+disputeColumns+` FROM disputes WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil, 0, ErrDisputeNotFound
+	}
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	payment, refunded, err := resolutionState(ctx, tx, dispute, "")
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return dispute, payment, refunded, nil
+}
+
+// resolutionState returns the order's captured payment, or nil if there is
+// none, and how much earlier disputes refunded for the dispute's seller.
+// lock is appended to the payment query.
+func resolutionState(ctx context.Context, tx *Tx, dispute *models.Dispute, lock string) (*models.Payment, int64, error) {
+	var refunded int64
+	err := tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(refund_minor), 0) FROM disputes
+		WHERE order_id = $1 AND seller_id = $2 AND status = $3`,
+		dispute.OrderId, dispute.SellerId, models.DisputeResolved).Scan(&refunded)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	payment, err := scanPayment(tx.QueryRowContext(ctx, `SELECT `+paymentColumns+` FROM payments
+		WHERE order_id = $1 AND status IN ($2, $3, $4)
+		ORDER BY created_at DESC
+		LIMIT 1 `+lock, dispute.OrderId, models.PaymentCaptured, models.PaymentPartiallyRefunded, models.PaymentRefunded))
+	if err == sql.ErrNoRows {
+		return nil, refunded, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return payment, refunded, nil
+}
+
+// ResolveDispute settles a dispute in one transaction. resolve gets the
+// locked dispute, the order's captured payment (locked, or nil if there is
+// none) and how much earlier disputes refunded for the same seller on the
+// order. It sets the dispute's resolution and may move the payment's
+// refunded total on, for refund, which the provider has made and which is
+// recorded as done with it; if it fails nothing is stored. The payment is
+// returned so the caller can post the refund to the ledger.
+func (r *DisputeRepository) ResolveDispute(ctx context.Context, id string, refund *models.PaymentRefund, resolve func(dispute *models.Dispute, payment *models.Payment, refunded int64) (*models.DisputeMessage, error)) (*models.Payment, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	ctx, tx, err := r.db.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	dispute, err := lockDispute(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	payment, refunded, err := resolutionState(ctx, tx, dispute, "FOR UPDATE")
+	if err != nil {
+		return nil, err
+	}
+
+	var before int64
+	if payment != nil {
+		before = payment.Refunded.Amount
+	}
+	message, err := resolve(dispute, payment, refunded)
+	if err != nil {
+		return nil, err
+	}
+
+	if payment != nil && payment.Refunded.Amount != before {
+		_, err = tx.ExecContext(ctx, `UPDATE payments SET refunded_minor = $1, status = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`,
+			payment.Refunded.Amount, payment.Status, payment.Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if refund != nil {
+		_, err = tx.ExecContext(ctx, `UPDATE payment_refunds SET status = $1, provider_ref = $2, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $3`, models.RefundSucceeded, refund.ProviderRef, refund.Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE disputes
+		SET status = $1, resolution = $2, refund_minor = $3, restocked = $4, resolved_by = $5,
+			resolved_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6`,
+		dispute.Status, dispute.Resolution, dispute.Refund.Amount, dispute.Restocked, dispute.ResolvedBy, id)
+	if err != nil {
+		return nil, err
+	}
+	if dispute.Restocked {
+		if err := restockSellerLines(ctx, tx, dispute.OrderId, dispute.SellerId); err != nil {
+			return nil, err
+		}
+	}
+	if message != nil {
+		message.DisputeId = id
+		if err := insertDisputeMessage(ctx, tx, message); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+func lockDispute(ctx context.Context, tx *Tx, id string) (*models.Dispute, error) {
+	dispute, err := scanDispute(tx.QueryRowContext(ctx, `SELECT `+disputeColumns+` FROM disputes WHERE id = $1 FOR UPDATE`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrDisputeNotFound
+	}
+	return dispute, err
+}
+
+func insertDisputeMessage(ctx context.Context, tx *Tx, message *models.DisputeMessage) error {
+	return tx.QueryRowContext(ctx, `INSERT INTO dispute_messages (dispute_id, author_id, role, body)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`, message.DisputeId, message.AuthorId, message.Role, message.Body,
+	).Scan(&message.Id, &message.CreatedAt)
+}
+
+func (r *DisputeRepository) getMessages(ctx context.Context, disputeID string) ([]models.DisputeMessage, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, dispute_id, author_id, role, body, created_at
+		FROM dispute_messages
+		WHERE dispute_id = $1
+		ORDER BY created_at, id`, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.DisputeMessage{}
+	for rows.Next() {
+		var message models.DisputeMessage
+		err := rows.Scan(&message.Id, &message.DisputeId, &message.AuthorId, &message.Role, &message.Body, &message.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+// restockSellerLines puts the quantities a seller sold on an order back on
+// their items, like releaseOrder does for a whole order. Deleted items are
+// skipped.
+func restockSellerLines(ctx context.Context, tx *Tx, orderID, sellerID string) error {
+	// Lock the items in ID order, the same order checkout locks them in
+	_, err := tx.ExecContext(ctx, `SELECT id FROM items
+		WHERE id IN (SELECT item_id FROM order_lines WHERE order_id = $1 AND seller_id = $2)
+		ORDER BY id
+		FOR UPDATE`, orderID, sellerID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE items
+		SET quantity = items.quantity + l.quantity,
+			is_sold = CASE WHEN items.quantity <= 0 THEN FALSE ELSE items.is_sold END,
+			updated_at = CURRENT_TIMESTAMP
+		FROM order_lines l
+		WHERE l.order_id = $1 AND l.seller_id = $2 AND l.item_id = items.id`, orderID, sellerID)
+	return err
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"primeauction/api/models"
+)
+
+// ErrFeeScheduleNotFound is returned for an unknown fee schedule version
+var ErrFeeScheduleNotFound = errors.New("fee schedule not found")
+
+type FeeRepository struct {
+	db conn
+}
+
+func NewFeeRepository(db *sql.DB) *FeeRepository {
+	return &FeeRepository{db: conn{db}}
+}
+
+const feeScheduleColumns = `version, rules, note, created_by, created_at`
+
+func scanFeeSchedule(row interface{ Scan(...any) error }) (*models.FeeSchedule, error) {
+	var schedule models.FeeSchedule
+	var rules []byte
+	var createdBy sql.NullString
+	if err := row.Scan(&schedule.Version, &rules, &schedule.Note, &createdBy, &schedule.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(rules, &schedule.Rules); err != nil {
+		return nil, err
+	}
+	if createdBy.Valid {
+		schedule.CreatedBy = &createdBy.String
+	}
+	return &schedule, nil
+}
+
+// CreateSchedule publishes a new version of the fee rules
+func (r *FeeRepository) CreateSchedule(ctx context.Context, schedule *models.FeeSchedule) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	rules, err := json.Marshal(schedule.Rules)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO fee_schedules (rules, note, created_by)
+		VALUES ($1, $2, $3)
+		RETURNING version, created_at`
+	return r.db.QueryRowContext(ctx, query, rules, schedule.Note, schedule.CreatedBy).Scan(&schedule.Version, &schedule.CreatedAt)
+}
+
+// GetCurrentSchedule returns the fee rules in effect, the latest version
+func (r *FeeRepository) GetCurrentSchedule(ctx context.Context) (*models.FeeSchedule, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + feeScheduleColumns + ` FROM fee_schedules ORDER BY version DESC LIMIT 1`
+	schedule, err := scanFeeSchedule(r.db.QueryRowContext(ctx, query))
+	if err == sql.ErrNoRows {
+		return nil, ErrFeeScheduleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// GetSchedule retrieves one version of the fee rules
+func (r *FeeRepository) GetSchedule(ctx context.Context, version int) (*models.FeeSchedule, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + feeScheduleColumns + ` FROM fee_schedules WHERE version = $1`
+	schedule, err := scanFeeSchedule(r.db.QueryRowContext(ctx, query, version))
+	if err == sql.ErrNoRows {
+		return nil, ErrFeeScheduleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// GetSchedules lists every version of the fee rules, newest first
+func (r *FeeRepository) GetSchedules(ctx context.Context) ([]models.FeeSchedule, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + feeScheduleColumns + ` FROM fee_schedules ORDER BY version DESC`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []models.FeeSchedule{}
+	for rows.Next() {
+		schedule, err := scanFeeSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, *schedule)
+	}
+	return schedules, rows.Err()
+}
+
+// GetSellerTier returns the fee tier of a seller
+func (r *FeeRepository) GetSellerTier(ctx context.Context, userID string) (string, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	var tier string
+	err := r.db.QueryRowContext(ctx, `SELECT seller_tier FROM users WHERE id = $1`, userID).Scan(&tier)
+	if err == sql.ErrNoRows {
+		return "", ErrUserNotFound
+	}
+	return tier, err
+}
+
+// SetSellerTier moves a seller to another fee tier
+func (r *FeeRepository) SetSellerTier(ctx context.Context, userID, tier string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `UPDATE users SET seller_tier = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, tier, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.New("failed to get rows affected")
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
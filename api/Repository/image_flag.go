@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"primeauction/api/models"
+)
+
+// ErrFlagNotFound is returned for an unknown image flag
+var ErrFlagNotFound = errors.New("flag not found")
+
+type ImageFlagRepository struct {
+	db conn
+}
+
+func NewImageFlagRepository(db *sql.DB) *ImageFlagRepository {
+	return &ImageFlagRepository{db: conn{db}}
+}
+
+const imageFlagColumns = `id, item_id, image_id, matched_item_id, matched_image_id, distance, status, reviewed_by, reviewed_at, created_at`
+
+func scanImageFlag(row interface{ Scan(...any) error }) (*models.ImageFlag, error) {
+	var flag models.ImageFlag
+	err := row.Scan(&flag.Id, &flag.ItemId, &flag.ImageId, &flag.MatchedItemId, &flag.MatchedImageId,
+		&flag.Distance, &flag.Status, &flag.ReviewedBy, &flag.ReviewedAt, &flag.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// CreateFlag records a suspected duplicate. Re-flagging the same pair of
+// images is a no-op.
+func (r *ImageFlagRepository) CreateFlag(ctx context.Context, flag *models.ImageFlag) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO image_flags (item_id, image_id, matched_item_id, matched_image_id, distance, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (image_id, matched_image_id) DO NOTHING`
+	_, err := r.db.ExecContext(ctx, query, flag.ItemId, flag.ImageId, flag.MatchedItemId, flag.MatchedImageId, flag.Distance, flag.Status)
+	return err
+}
+
+// GetFlags lists flags, optionally filtered by status, newest first
+func (r *ImageFlagRepository) GetFlags(ctx context.Context, status string) ([]models.ImageFlag, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + imageFlagColumns + ` FROM image_flags
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := []models.ImageFlag{}
+	for rows.Next() {
+		flag, err := scanImageFlag(rows)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, *flag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return flags, nil
+}
+
+// ReviewFlag records an admin's decision on a flag
+func (r *ImageFlagRepository) ReviewFlag(ctx context.Context, id, status, reviewerID string) (*models.ImageFlag, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE image_flags SET status = $1, reviewed_by = $2, reviewed_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+		RETURNING ` + imageFlagColumns
+
+	flag, err := scanImageFlag(r.db.QueryRowContext(ctx, query, status, reviewerID, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrFlagNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return flag, nil
+}
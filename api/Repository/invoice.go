@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"primeauction/api/models"
+)
+
+// ErrInvoiceNotFound is returned for an unknown invoice
+var ErrInvoiceNotFound = errors.New("invoice not found")
+
+type InvoiceRepository struct {
+	db conn
+}
+
+func NewInvoiceRepository(db *sql.DB) *InvoiceRepository {
+	return &InvoiceRepository{db: conn{db}}
+}
+
+const invoiceColumns = `id, number, sequence, order_id, seller_id, buyer_id, subtotal_minor, tax_minor, shipping_minor, total_minor, currency,
+	file_path, issued_at`
+
+func scanInvoice(row interface{ Scan(...any) error }) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := row.Scan(&invoice.Id, &invoice.Number, &invoice.Sequence, &invoice.OrderId, &invoice.SellerId, &invoice.BuyerId,
+		&invoice.Subtotal.Amount, &invoice.Tax.Amount, &invoice.Shipping.Amount, &invoice.Total.Amount, &invoice.Total.Currency,
+		&invoice.FilePath, &invoice.IssuedAt)
+	if err != nil {
+		return nil, err
+	}
+	invoice.Subtotal.Currency = invoice.Total.Currency
+	invoice.Shipping.Currency = invoice.Total.Currency
+	invoice.Tax.Currency = invoice.Total.Currency
+	return &invoice, nil
+}
+
+// CreateInvoice numbers and stores an invoice. The seller's counter row is
+// incremented first, which also serializes invoicing per seller; if the
+// order already has an invoice from this seller, or render or the insert
+// fails, the transaction rolls back and the number is not used. render
+// gets the numbered invoice and returns where it stored the document. It
+// returns false when the invoice already existed.
+func (r *InvoiceRepository) CreateInvoice(ctx context.Context, invoice *models.Invoice, render func(invoice *models.Invoice) (string, error)) (bool, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	ctx, tx, err := r.db.begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO invoice_counters (seller_id, last_sequence)
+		VALUES ($1, 1)
+		ON CONFLICT (seller_id) DO UPDATE SET last_sequence = invoice_counters.last_sequence + 1
+		RETURNING last_sequence`, invoice.SellerId).Scan(&invoice.Sequence)
+	if err != nil {
+		return false, err
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM invoices WHERE order_id = $1 AND seller_id = $2)`,
+		invoice.OrderId, invoice.SellerId).Scan(&exists)
+	if err != nil || exists {
+		return false, err
+	}
+
+	invoice.Number = models.InvoiceNumber(invoice.SellerId, invoice.Sequence)
+	if invoice.FilePath, err = render(invoice); err != nil {
+		return false, err
+	}
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO invoices (seller_id, sequence, number, order_id, buyer_id, subtotal_minor, tax_minor,
+			shipping_minor, total_minor, currency, file_path)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, issued_at`,
+		invoice.SellerId, invoice.Sequence, invoice.Number, invoice.OrderId, invoice.BuyerId, invoice.Subtotal.Amount,
+		invoice.Tax.Amount, invoice.Shipping.Amount, invoice.Total.Amount, invoice.Total.Currency, invoice.FilePath,
+	).Scan(&invoice.Id, &invoice.IssuedAt)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// GetInvoicesByOrder lists the invoices issued for an order
+func (r *InvoiceRepository) GetInvoicesByOrder(ctx context.Context, orderID string) ([]models.Invoice, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+invoiceColumns+` FROM invoices WHERE order_id = $1 ORDER BY issued_at, number`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []models.Invoice{}
+	for rows.Next() {
+		invoice, err := scanInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, *invoice)
+	}
+	return invoices, rows.Err()
+}
+
+// GetInvoice retrieves the invoice a seller issued for an order
+func (r *InvoiceRepository) GetInvoice(ctx context.Context, orderID, sellerID string) (*models.Invoice, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + invoiceColumns + ` FROM invoices WHERE order_id = $1 AND seller_id = $2`
+	invoice, err := scanInvoice(r.db.QueryRowContext(ctx, query, orderID, sellerID))
+	if err == sql.ErrNoRows {
+		return nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
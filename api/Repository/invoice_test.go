@@ -0,0 +1,114 @@
+package repository_test
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+)
+
+// newTestUser stores a user with a name no other test or earlier run uses
+func newTestUser(t *testing.T, users *repository.UserRepository) *models.User {
+	t.Helper()
+	b := make([]byte, 6)
+	rand.Read(b)
+	name := fmt.Sprintf("user-%x", b)
+	user := &models.User{Username: name, Email: name + "@example.com", Password: "hash"}
+	if err := users.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return user
+}
+
+// Invoices for one seller are numbered without gaps, even when orders are
+// invoiced at the same time and some of them fail or were already invoiced
+func TestInvoiceNumbering(t *testing.T) {
+	for name, open := range databases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			db := open(t)
+			users, invoices := repository.NewUserRepository(db), repository.NewInvoiceRepository(db)
+			seller, buyer := newTestUser(t, users), newTestUser(t, users)
+
+			const orders = 20
+			orderIDs := make([]string, orders)
+			for i := range orderIDs {
+				err := db.QueryRow(`INSERT INTO orders (buyer_id, total_minor, currency, expires_at)
+					VALUES ($1, 1000, 'USD', CURRENT_TIMESTAMP) RETURNING id`, buyer.Id).Scan(&orderIDs[i])
+				if err != nil {
+					t.Fatalf("creating an order: %v", err)
+				}
+			}
+
+			invoice := func(orderID string, render func(*models.Invoice) (string, error)) (*models.Invoice, bool, error) {
+				inv := &models.Invoice{
+					OrderId:  orderID,
+					SellerId: seller.Id,
+					BuyerId:  buyer.Id,
+					Subtotal: models.NewMoney(1000, "USD"),
+					Tax:      models.NewMoney(0, "USD"),
+					Shipping: models.NewMoney(0, "USD"),
+					Total:    models.NewMoney(1000, "USD"),
+				}
+				created, err := invoices.CreateInvoice(ctx, inv, render)
+				return inv, created, err
+			}
+			stored := func(inv *models.Invoice) (string, error) { return "invoices/" + inv.Number + ".pdf", nil }
+			errRender := errors.New("render failed")
+			failing := func(*models.Invoice) (string, error) { return "", errRender }
+
+			// Every order is invoiced twice at once, and for every fourth
+			// order one of the two attempts fails to render
+			var wg sync.WaitGroup
+			errs := make(chan error, 2*orders)
+			for i, orderID := range orderIDs {
+				for attempt := range 2 {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						render := stored
+						if i%4 == 0 && attempt == 0 {
+							render = failing
+						}
+						if _, _, err := invoice(orderID, render); err != nil && !errors.Is(err, errRender) {
+							errs <- err
+						}
+					}()
+				}
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Fatalf("CreateInvoice: %v", err)
+			}
+
+			var sequences []int64
+			for _, orderID := range orderIDs {
+				got, err := invoices.GetInvoice(ctx, orderID, seller.Id)
+				if err != nil {
+					t.Fatalf("GetInvoice(%s): %v", orderID, err)
+				}
+				if got.Number != models.InvoiceNumber(seller.Id, got.Sequence) {
+					t.Errorf("invoice %d is numbered %s", got.Sequence, got.Number)
+				}
+				sequences = append(sequences, got.Sequence)
+			}
+
+			sort.Slice(sequences, func(a, b int) bool { return sequences[a] < sequences[b] })
+			for i, sequence := range sequences {
+				if sequence != int64(i+1) {
+					t.Fatalf("invoice sequences are %v, want 1 to %d", sequences, orders)
+				}
+			}
+			if _, created, err := invoice(orderIDs[0], stored); err != nil || created {
+				t.Errorf("invoicing an invoiced order: created %v, %v", created, err)
+			}
+		})
+	}
+}
@@ -1,38 +1,51 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"primeauction/api/models"
 )
 
 type ItemRepository struct {
-	db *sql.DB
+	db conn
 }
 
 func NewItemRepository(db *sql.DB) *ItemRepository {
-	return &ItemRepository{db: db}
+	return &ItemRepository{db: conn{db}}
 }
 
 // GetDB returns the database connection (for creating other repositories)
 func (r *ItemRepository) GetDB() *sql.DB {
-	return r.db
+	return r.db.DB
 }
-func (r *ItemRepository) CreateItem(item *models.Item) error {
-	query := `INSERT INTO items (user_id, name, description, price, selling_price, image, quantity, is_sold)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+func (r *ItemRepository) CreateItem(ctx context.Context, item *models.Item) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO items (user_id, name, description, price_minor, selling_price_minor, currency, image, quantity, is_sold, visibility, category,
+			weight_grams, length_cm, width_cm, height_cm, shipping_profile_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 		RETURNING id, created_at, updated_at`
 
-	err := r.db.QueryRow(
+	err := r.db.QueryRowContext(ctx,
 		query,
 		item.UserId,
 		item.Name,
 		item.Description,
-		item.Price,
-		item.SellingPrice,
+		item.Price.Amount,
+		item.SellingPrice.Amount,
+		item.Price.Currency,
 		item.Image,
 		item.Quantity,
 		item.IsSold,
+		item.Visibility,
+		item.Category,
+		item.WeightGrams,
+		item.LengthCm,
+		item.WidthCm,
+		item.HeightCm,
+		item.ShippingProfileId,
 	).Scan(&item.Id, &item.CreatedAt, &item.UpdatedAt)
 
 	if err != nil {
@@ -41,24 +54,28 @@ func (r *ItemRepository) CreateItem(item *models.Item) error {
 	return nil
 }
 
-func (r *ItemRepository) GetItemById(id string) (*models.Item, error) {
-	query := `SELECT id, user_id, name, description, price, selling_price, image, quantity, is_sold, created_at, updated_at 
+func (r *ItemRepository) GetItemById(ctx context.Context, id string) (*models.Item, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, user_id, name, description, price_minor, selling_price_minor, currency, image, quantity, is_sold, visibility, category, weight_grams, length_cm, width_cm, height_cm, shipping_profile_id, created_at, updated_at 
 	FROM items 
 	WHERE id = $1`
 	item := &models.Item{}
 
-	err := r.db.QueryRow(query, id).Scan(&item.Id, &item.UserId, &item.Name, &item.Description, &item.Price, &item.SellingPrice, &item.Image, &item.Quantity, &item.IsSold, &item.CreatedAt, &item.UpdatedAt)
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&item.Id, &item.UserId, &item.Name, &item.Description, &item.Price.Amount, &item.SellingPrice.Amount, &item.Price.Currency, &item.Image, &item.Quantity, &item.IsSold, &item.Visibility, &item.Category, &item.WeightGrams, &item.LengthCm, &item.WidthCm, &item.HeightCm, &item.ShippingProfileId, &item.CreatedAt, &item.UpdatedAt)
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("item not found")
+		return nil, ErrItemNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
+	item.SellingPrice.Currency = item.Price.Currency // both prices share the item's currency
 
 	// Load images for this item
-	imageRepo := NewItemImageRepository(r.db)
-	images, err := imageRepo.GetImagesByItemID(item.Id)
+	imageRepo := NewItemImageRepository(r.db.DB)
+	images, err := imageRepo.GetImagesByItemID(ctx, item.Id)
 	if err == nil {
 		item.Images = images
 		// Set primary image if not set and we have images
@@ -69,35 +86,68 @@ func (r *ItemRepository) GetItemById(id string) (*models.Item, error) {
 
 	return item, nil
 }
-func (r *ItemRepository) UpdateItem(item *models.Item) error {
+func (r *ItemRepository) UpdateItem(ctx context.Context, item *models.Item) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	// Stock is left alone: checkout changes it under the seller's edit
 	query := `UPDATE items 
-		SET name=$1, description=$2, price=$3, selling_price=$4, image=$5, quantity=$6, is_sold=$7, updated_at=CURRENT_TIMESTAMP
-		WHERE id=$8
-		RETURNING updated_at`
+		SET name=$1, description=$2, price_minor=$3, selling_price_minor=$4, currency=$5, image=$6, visibility=$7, category=$8,
+			weight_grams=$9, length_cm=$10, width_cm=$11, height_cm=$12, shipping_profile_id=$13, updated_at=CURRENT_TIMESTAMP
+		WHERE id=$14
+		RETURNING quantity, is_sold, updated_at`
 
-	err := r.db.QueryRow(
+	err := r.db.QueryRowContext(ctx,
 		query,
 		item.Name,
 		item.Description,
-		item.Price,
-		item.SellingPrice,
+		item.Price.Amount,
+		item.SellingPrice.Amount,
+		item.Price.Currency,
 		item.Image,
-		item.Quantity,
-		item.IsSold,
+		item.Visibility,
+		item.Category,
+		item.WeightGrams,
+		item.LengthCm,
+		item.WidthCm,
+		item.HeightCm,
+		item.ShippingProfileId,
 		item.Id,
-	).Scan(&item.UpdatedAt)
+	).Scan(&item.Quantity, &item.IsSold, &item.UpdatedAt)
 
 	if err == sql.ErrNoRows {
-		return errors.New("item not found")
+		return ErrItemNotFound
 	}
 	if err != nil {
 		return err
 	}
 	return nil
 }
-func (r *ItemRepository) DeleteItem(id string) error {
+
+// SetQuantity sets the stock of an item, which is sold out when none is left
+func (r *ItemRepository) SetQuantity(ctx context.Context, id string, quantity int) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `UPDATE items
+		SET quantity = $1, is_sold = ($1 <= 0), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`, quantity, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrItemNotFound
+	}
+	return nil
+}
+func (r *ItemRepository) DeleteItem(ctx context.Context, id string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
 	query := "DELETE FROM items WHERE id=$1"
-	result, err := r.db.Exec(query, id)
+	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -106,17 +156,19 @@ func (r *ItemRepository) DeleteItem(id string) error {
 		return errors.New("failed to get rows affected")
 	}
 	if rowsAffected == 0 {
-		return errors.New("the item does not exist")
-
+		return ErrItemNotFound
 	}
 	return nil
 }
-func (r *ItemRepository) GetAllItems() ([]*models.Item, error) {
-	query := `SELECT id, user_id, name, description, price, selling_price, image, quantity, is_sold, created_at, updated_at 
+func (r *ItemRepository) GetAllItems(ctx context.Context) ([]*models.Item, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, user_id, name, description, price_minor, selling_price_minor, currency, image, quantity, is_sold, visibility, category, weight_grams, length_cm, width_cm, height_cm, shipping_profile_id, created_at, updated_at 
 		FROM items 
 		ORDER BY created_at DESC`
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -130,37 +182,49 @@ func (r *ItemRepository) GetAllItems() ([]*models.Item, error) {
 			&item.UserId,
 			&item.Name,
 			&item.Description,
-			&item.Price,
-			&item.SellingPrice,
+			&item.Price.Amount,
+			&item.SellingPrice.Amount,
+			&item.Price.Currency,
 			&item.Image,
 			&item.Quantity,
 			&item.IsSold,
+			&item.Visibility,
+			&item.Category,
+			&item.WeightGrams,
+			&item.LengthCm,
+			&item.WidthCm,
+			&item.HeightCm,
+			&item.ShippingProfileId,
 			&item.CreatedAt,
 			&item.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
+		item.SellingPrice.Currency = item.Price.Currency
 		items = append(items, item)
 	}
 	return items, nil
 }
 
 // GetItemsByUserID retrieves all items for a specific user
-func (r *ItemRepository) GetItemsByUserID(userID string) ([]*models.Item, error) {
-	query := `SELECT id, user_id, name, description, price, selling_price, image, quantity, is_sold, created_at, updated_at 
+func (r *ItemRepository) GetItemsByUserID(ctx context.Context, userID string) ([]*models.Item, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, user_id, name, description, price_minor, selling_price_minor, currency, image, quantity, is_sold, visibility, category, weight_grams, length_cm, width_cm, height_cm, shipping_profile_id, created_at, updated_at 
 		FROM items 
 		WHERE user_id = $1 
 		ORDER BY created_at DESC`
 
-	rows, err := r.db.Query(query, userID)
+	rows, err := r.db.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	var items []*models.Item
-	imageRepo := NewItemImageRepository(r.db)
+	imageRepo := NewItemImageRepository(r.db.DB)
 
 	for rows.Next() {
 		item := &models.Item{}
@@ -169,20 +233,29 @@ func (r *ItemRepository) GetItemsByUserID(userID string) ([]*models.Item, error)
 			&item.UserId,
 			&item.Name,
 			&item.Description,
-			&item.Price,
-			&item.SellingPrice,
+			&item.Price.Amount,
+			&item.SellingPrice.Amount,
+			&item.Price.Currency,
 			&item.Image,
 			&item.Quantity,
 			&item.IsSold,
+			&item.Visibility,
+			&item.Category,
+			&item.WeightGrams,
+			&item.LengthCm,
+			&item.WidthCm,
+			&item.HeightCm,
+			&item.ShippingProfileId,
 			&item.CreatedAt,
 			&item.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
+		item.SellingPrice.Currency = item.Price.Currency
 
 		// Load images for each item
-		images, err := imageRepo.GetImagesByItemID(item.Id)
+		images, err := imageRepo.GetImagesByItemID(ctx, item.Id)
 		if err == nil {
 			item.Images = images
 			// Set primary image if not set and we have images
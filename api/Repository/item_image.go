@@ -1,38 +1,51 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"primeauction/api/models"
 )
 
 type ItemImageRepository struct {
-	db *sql.DB
+	db conn
 }
 
 func NewItemImageRepository(db *sql.DB) *ItemImageRepository {
-	return &ItemImageRepository{db: db}
+	return &ItemImageRepository{db: conn{db}}
 }
 
-// CreateImages creates multiple image records for an item
-func (r *ItemImageRepository) CreateImages(itemID string, imagePaths []string) error {
-	query := `INSERT INTO item_images (item_id, image_path, display_order) 
-		VALUES ($1, $2, $3)`
+// CreateImages creates multiple image records for an item, filling in the
+// generated IDs. Display order follows the slice order.
+func (r *ItemImageRepository) CreateImages(ctx context.Context, itemID string, images []models.ItemImage) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
 
-	for i, path := range imagePaths {
-		_, err := r.db.Exec(query, itemID, path, i)
-		if err != nil {
-			return err
+	query := `INSERT INTO item_images (item_id, image_path, display_order, phash, size_bytes) 
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	return WithTx(ctx, r.db.DB, func(ctx context.Context) error {
+		for i := range images {
+			images[i].ItemId = itemID
+			images[i].DisplayOrder = i
+			err := r.db.QueryRowContext(ctx, query, itemID, images[i].ImagePath, i, images[i].PHash, images[i].SizeBytes).Scan(&images[i].Id, &images[i].CreatedAt)
+			if err != nil {
+				return err
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 // GetImagesByItemID retrieves all images for an item
-func (r *ItemImageRepository) GetImagesByItemID(itemID string) ([]models.ItemImage, error) {
-	query := `SELECT id, item_id, image_path, display_order, created_at 
+func (r *ItemImageRepository) GetImagesByItemID(ctx context.Context, itemID string) ([]models.ItemImage, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, item_id, image_path, display_order, phash, size_bytes, created_at 
 		FROM item_images WHERE item_id = $1 ORDER BY display_order`
 
-	rows, err := r.db.Query(query, itemID)
+	rows, err := r.db.QueryContext(ctx, query, itemID)
 	if err != nil {
 		return nil, err
 	}
@@ -41,7 +54,7 @@ func (r *ItemImageRepository) GetImagesByItemID(itemID string) ([]models.ItemIma
 	var images []models.ItemImage
 	for rows.Next() {
 		var img models.ItemImage
-		err := rows.Scan(&img.Id, &img.ItemId, &img.ImagePath, &img.DisplayOrder, &img.CreatedAt)
+		err := rows.Scan(&img.Id, &img.ItemId, &img.ImagePath, &img.DisplayOrder, &img.PHash, &img.SizeBytes, &img.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -56,30 +69,41 @@ func (r *ItemImageRepository) GetImagesByItemID(itemID string) ([]models.ItemIma
 }
 
 // DeleteImagesByItemID deletes all images for an item
-func (r *ItemImageRepository) DeleteImagesByItemID(itemID string) error {
+func (r *ItemImageRepository) DeleteImagesByItemID(ctx context.Context, itemID string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
 	query := `DELETE FROM item_images WHERE item_id = $1`
-	_, err := r.db.Exec(query, itemID)
+	_, err := r.db.ExecContext(ctx, query, itemID)
 	return err
 }
 
 // DeleteImageByID deletes a single image by ID
-func (r *ItemImageRepository) DeleteImageByID(imageID string) error {
+func (r *ItemImageRepository) DeleteImageByID(ctx context.Context, imageID string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
 	query := `DELETE FROM item_images WHERE id = $1`
-	_, err := r.db.Exec(query, imageID)
+	_, err := r.db.ExecContext(ctx, query, imageID)
 	return err
 }
 
 // GetImageByID retrieves a single image by ID
-func (r *ItemImageRepository) GetImageByID(imageID string) (*models.ItemImage, error) {
-	query := `SELECT id, item_id, image_path, display_order, created_at 
+func (r *ItemImageRepository) GetImageByID(ctx context.Context, imageID string) (*models.ItemImage, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, item_id, image_path, display_order, phash, size_bytes, created_at 
 		FROM item_images WHERE id = $1`
 
 	var img models.ItemImage
-	err := r.db.QueryRow(query, imageID).Scan(
+	err := r.db.QueryRowContext(ctx, query, imageID).Scan(
 		&img.Id,
 		&img.ItemId,
 		&img.ImagePath,
 		&img.DisplayOrder,
+		&img.PHash,
+		&img.SizeBytes,
 		&img.CreatedAt,
 	)
 	if err != nil {
@@ -88,3 +112,148 @@ func (r *ItemImageRepository) GetImageByID(imageID string) (*models.ItemImage, e
 
 	return &img, nil
 }
+
+// ImageReference is a database row that points at an image file on disk
+type ImageReference struct {
+	Source    string `json:"source"` // "item_images" or "items"
+	ID        string `json:"id"`
+	ItemID    string `json:"item_id"`
+	ImagePath string `json:"image_path"`
+}
+
+// GetAllImageReferences returns every image path referenced by item_images
+// rows and by the primary image column on items
+func (r *ItemImageRepository) GetAllImageReferences(ctx context.Context) ([]ImageReference, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT 'item_images', id, item_id, image_path FROM item_images
+		UNION ALL
+		SELECT 'items', id, id, image FROM items WHERE image IS NOT NULL AND image <> ''`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []ImageReference
+	for rows.Next() {
+		var ref ImageReference
+		if err := rows.Scan(&ref.Source, &ref.ID, &ref.ItemID, &ref.ImagePath); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+// GetItemIDByImagePath finds the item an image file belongs to, checking
+// both item_images and the primary image column
+func (r *ItemImageRepository) GetItemIDByImagePath(ctx context.Context, imagePath string) (string, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT item_id FROM item_images WHERE image_path = $1
+		UNION
+		SELECT id FROM items WHERE image = $1
+		LIMIT 1`
+
+	var itemID string
+	err := r.db.QueryRowContext(ctx, query, imagePath).Scan(&itemID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return itemID, nil
+}
+
+// SimilarImage is an image whose perceptual hash is close to a probe hash
+type SimilarImage struct {
+	ImageID  string
+	ItemID   string
+	Distance int
+}
+
+// FindSimilarImages returns images on items not owned by excludeUserID whose
+// perceptual hash is within maxDistance bits of hash, closest first
+func (r *ItemImageRepository) FindSimilarImages(ctx context.Context, excludeUserID string, hash int64, maxDistance int) ([]SimilarImage, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, item_id, distance FROM (
+			SELECT ii.id, ii.item_id, ` + r.db.dialect().BitDistance("ii.phash", "$2") + ` AS distance
+			FROM item_images ii
+			JOIN items i ON i.id = ii.item_id
+			WHERE i.user_id <> $1 AND ii.phash IS NOT NULL
+		) candidates
+		WHERE distance <= $3
+		ORDER BY distance`
+
+	rows, err := r.db.QueryContext(ctx, query, excludeUserID, hash, maxDistance)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []SimilarImage
+	for rows.Next() {
+		var match SimilarImage
+		if err := rows.Scan(&match.ImageID, &match.ItemID, &match.Distance); err != nil {
+			return nil, err
+		}
+		matches = append(matches, match)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+// GetImagesMissingSize lists image rows recorded before sizes were tracked
+func (r *ItemImageRepository) GetImagesMissingSize(ctx context.Context) ([]models.ItemImage, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, item_id, image_path FROM item_images WHERE size_bytes = 0`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []models.ItemImage
+	for rows.Next() {
+		var img models.ItemImage
+		if err := rows.Scan(&img.Id, &img.ItemId, &img.ImagePath); err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// UpdateImageSize records the size of an image file
+func (r *ItemImageRepository) UpdateImageSize(ctx context.Context, imageID string, sizeBytes int64) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE item_images SET size_bytes = $1 WHERE id = $2`
+	_, err := r.db.ExecContext(ctx, query, sizeBytes, imageID)
+	return err
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"primeauction/api/models"
+)
+
+type ItemInviteRepository struct {
+	db conn
+}
+
+func NewItemInviteRepository(db *sql.DB) *ItemInviteRepository {
+	return &ItemInviteRepository{db: conn{db}}
+}
+
+// AddInvite grants a user access to a private item
+func (r *ItemInviteRepository) AddInvite(ctx context.Context, itemID, userID string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO item_invites (item_id, user_id) VALUES ($1, $2)
+		ON CONFLICT (item_id, user_id) DO NOTHING`
+	_, err := r.db.ExecContext(ctx, query, itemID, userID)
+	return err
+}
+
+// RemoveInvite revokes a user's access to a private item
+func (r *ItemInviteRepository) RemoveInvite(ctx context.Context, itemID, userID string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM item_invites WHERE item_id = $1 AND user_id = $2`
+	_, err := r.db.ExecContext(ctx, query, itemID, userID)
+	return err
+}
+
+// IsInvited reports whether a user has been invited to an item
+func (r *ItemInviteRepository) IsInvited(ctx context.Context, itemID, userID string) (bool, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT EXISTS(SELECT 1 FROM item_invites WHERE item_id = $1 AND user_id = $2)`
+	var invited bool
+	err := r.db.QueryRowContext(ctx, query, itemID, userID).Scan(&invited)
+	return invited, err
+}
+
+// GetInvitesByItemID lists the users invited to an item
+func (r *ItemInviteRepository) GetInvitesByItemID(ctx context.Context, itemID string) ([]models.ItemInvite, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT item_id, user_id, created_at FROM item_invites WHERE item_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := []models.ItemInvite{}
+	for rows.Next() {
+		var invite models.ItemInvite
+		if err := rows.Scan(&invite.ItemId, &invite.UserId, &invite.CreatedAt); err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return invites, nil
+}
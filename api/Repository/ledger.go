@@ -0,0 +1,403 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"primeauction/api/models"
+	"strings"
+)
+
+// ErrInsufficientBalance is returned when an entry would overdraw an
+// account that must not go negative, such as a seller's balance on payout
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// ledgerLockKey is the lock that serialises ledger writes, so
+// every entry chains onto the hash of the one before it
+const ledgerLockKey = 7310035
+
+// RefundMismatch is a payment whose refunded total differs from the
+// refunds recorded in the ledger
+type RefundMismatch struct {
+	PaymentID       string `json:"payment_id"`
+	PaymentRefunded int64  `json:"payment_refunded"`
+	LedgerRefunded  int64  `json:"ledger_refunded"`
+}
+
+type LedgerRepository struct {
+	db conn
+}
+
+func NewLedgerRepository(db *sql.DB) *LedgerRepository {
+	return &LedgerRepository{db: conn{db}}
+}
+
+// PostEntry appends an entry with its postings. It reports false without
+// writing anything when an entry with the same idempotency key already
+// exists. Accounts are created the first time they are posted to, from
+// each posting's AccountCode, AccountType and OwnerId. Accounts listed in
+// noOverdraft must still have a balance of their normal sign afterwards.
+func (r *LedgerRepository) PostEntry(ctx context.Context, entry *models.LedgerEntry, noOverdraft ...string) (bool, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	if len(entry.Postings) == 0 {
+		return false, errors.New("ledger entry has no postings")
+	}
+	if unbalanced := entry.Unbalanced(); len(unbalanced) > 0 {
+		return false, fmt.Errorf("ledger entry does not balance in %s", strings.Join(unbalanced, ", "))
+	}
+
+	ctx, tx, err := r.db.begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err := tx.dialect.LockTx(ctx, tx, ledgerLockKey); err != nil {
+		return false, err
+	}
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM ledger_entries WHERE idempotency_key = $1)`, entry.IdempotencyKey).Scan(&exists); err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+
+	for i := range entry.Postings {
+		p := &entry.Postings[i]
+		var ownerID *string
+		if p.OwnerId != "" {
+			ownerID = &p.OwnerId
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO ledger_accounts (code, type, owner_id, currency)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (code) DO NOTHING`, p.AccountCode, p.AccountType, ownerID, p.Amount.Currency)
+		if err != nil {
+			return false, err
+		}
+		if err := tx.QueryRowContext(ctx, `SELECT id FROM ledger_accounts WHERE code = $1`, p.AccountCode).Scan(&p.AccountId); err != nil {
+			return false, err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `SELECT hash FROM ledger_entries ORDER BY seq DESC LIMIT 1`).Scan(&entry.PrevHash)
+	if err == sql.ErrNoRows {
+		entry.PrevHash = models.LedgerGenesisHash
+	} else if err != nil {
+		return false, err
+	}
+	entry.Hash = entry.ComputeHash()
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO ledger_entries (kind, reference_id, idempotency_key, description, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, seq, created_at`,
+		entry.Kind, entry.ReferenceId, entry.IdempotencyKey, entry.Description, entry.PrevHash, entry.Hash,
+	).Scan(&entry.Id, &entry.Seq, &entry.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+
+	for i := range entry.Postings {
+		p := &entry.Postings[i]
+		p.EntryId = entry.Id
+		err := tx.QueryRowContext(ctx, `INSERT INTO ledger_postings (entry_id, account_id, memo, amount_minor, currency)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id`, p.EntryId, p.AccountId, p.Memo, p.Amount.Amount, p.Amount.Currency).Scan(&p.Id)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	for _, code := range noOverdraft {
+		var accountType string
+		var balance int64
+		err := tx.QueryRowContext(ctx, `SELECT a.type, COALESCE(SUM(p.amount_minor), 0)
+			FROM ledger_accounts a
+			LEFT JOIN ledger_postings p ON p.account_id = a.id
+			WHERE a.code = $1
+			GROUP BY a.type`, code).Scan(&accountType, &balance)
+		if err != nil {
+			return false, err
+		}
+		creditNormal := accountType == models.AccountLiability || accountType == models.AccountRevenue
+		if (creditNormal && balance > 0) || (!creditNormal && balance < 0) {
+			return false, ErrInsufficientBalance
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+const postingSelect = `SELECT p.id, p.entry_id, p.account_id, a.code, a.type, COALESCE(CAST(a.owner_id AS TEXT), ''), p.memo, p.amount_minor, p.currency
+	FROM ledger_postings p
+	JOIN ledger_accounts a ON a.id = p.account_id`
+
+func scanPosting(row interface{ Scan(...any) error }) (models.LedgerPosting, error) {
+	var p models.LedgerPosting
+	err := row.Scan(&p.Id, &p.EntryId, &p.AccountId, &p.AccountCode, &p.AccountType, &p.OwnerId, &p.Memo, &p.Amount.Amount, &p.Amount.Currency)
+	return p, err
+}
+
+const entryColumns = `id, seq, kind, reference_id, idempotency_key, description, prev_hash, hash, created_at`
+
+func scanEntry(row interface{ Scan(...any) error }) (*models.LedgerEntry, error) {
+	var e models.LedgerEntry
+	err := row.Scan(&e.Id, &e.Seq, &e.Kind, &e.ReferenceId, &e.IdempotencyKey, &e.Description, &e.PrevHash, &e.Hash, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// GetEntryByKey returns the entry with an idempotency key, or nil if there
+// is none
+func (r *LedgerRepository) GetEntryByKey(ctx context.Context, key string) (*models.LedgerEntry, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	entry, err := scanEntry(r.db.QueryRowContext(ctx, `SELECT `+entryColumns+` FROM ledger_entries WHERE idempotency_key = $1`, key))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, postingSelect+` WHERE p.entry_id = $1 ORDER BY p.id`, entry.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		p, err := scanPosting(rows)
+		if err != nil {
+			return nil, err
+		}
+		entry.Postings = append(entry.Postings, p)
+	}
+	return entry, rows.Err()
+}
+
+// GetEntries returns up to limit entries with a sequence number above
+// afterSeq, in order, with their postings
+func (r *LedgerRepository) GetEntries(ctx context.Context, afterSeq int64, limit int) ([]*models.LedgerEntry, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+entryColumns+` FROM ledger_entries WHERE seq > $1 ORDER BY seq LIMIT $2`, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.LedgerEntry
+	byID := map[string]*models.LedgerEntry{}
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+		byID[entry.Id] = entry
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return entries, nil
+	}
+
+	postingRows, err := r.db.QueryContext(ctx, postingSelect+`
+		JOIN ledger_entries e ON e.id = p.entry_id
+		WHERE e.seq > $1 AND e.seq <= $2
+		ORDER BY p.id`, afterSeq, entries[len(entries)-1].Seq)
+	if err != nil {
+		return nil, err
+	}
+	defer postingRows.Close()
+	for postingRows.Next() {
+		p, err := scanPosting(postingRows)
+		if err != nil {
+			return nil, err
+		}
+		if entry := byID[p.EntryId]; entry != nil {
+			entry.Postings = append(entry.Postings, p)
+		}
+	}
+	return entries, postingRows.Err()
+}
+
+// GetBalances sums a user's liability accounts (what the platform owes
+// them) per currency, as positive amounts
+func (r *LedgerRepository) GetBalances(ctx context.Context, ownerID string) ([]models.LedgerBalance, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT a.currency, COALESCE(-SUM(p.amount_minor), 0)
+		FROM ledger_accounts a
+		LEFT JOIN ledger_postings p ON p.account_id = a.id
+		WHERE a.owner_id = $1 AND a.type = $2
+		GROUP BY a.currency
+		ORDER BY a.currency`
+	rows, err := r.db.QueryContext(ctx, query, ownerID, models.AccountLiability)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := []models.LedgerBalance{}
+	for rows.Next() {
+		var b models.LedgerBalance
+		if err := rows.Scan(&b.Currency, &b.Available.Amount); err != nil {
+			return nil, err
+		}
+		b.Available.Currency = b.Currency
+		balances = append(balances, b)
+	}
+	return balances, rows.Err()
+}
+
+// GetStatement returns the most recent postings on a user's balance in one
+// currency, newest first, each with the running balance after it
+func (r *LedgerRepository) GetStatement(ctx context.Context, ownerID, currency string, limit int) ([]models.StatementLine, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT entry_id, kind, reference_id, description, memo, amount, balance, currency, created_at
+		FROM (
+			SELECT e.id AS entry_id, e.kind, e.reference_id, e.description, p.memo, -p.amount_minor AS amount,
+				SUM(-p.amount_minor) OVER (ORDER BY e.seq, p.id) AS balance,
+				p.currency, e.created_at, e.seq, p.id AS posting_id
+			FROM ledger_postings p
+			JOIN ledger_entries e ON e.id = p.entry_id
+			JOIN ledger_accounts a ON a.id = p.account_id
+			WHERE a.owner_id = $1 AND a.type = $2 AND a.currency = $3
+		) s
+		ORDER BY seq DESC, posting_id DESC
+		LIMIT $4`
+	rows, err := r.db.QueryContext(ctx, query, ownerID, models.AccountLiability, currency, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []models.StatementLine{}
+	for rows.Next() {
+		var l models.StatementLine
+		var cur string
+		if err := rows.Scan(&l.EntryId, &l.Kind, &l.ReferenceId, &l.Description, &l.Memo, &l.Amount.Amount, &l.Balance.Amount, &cur, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		l.Amount.Currency = cur
+		l.Balance.Currency = cur
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+// GetRefundedTotal returns how much of a payment the ledger shows as
+// refunded to the buyer
+func (r *LedgerRepository) GetRefundedTotal(ctx context.Context, paymentID string) (int64, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT COALESCE(-SUM(p.amount_minor), 0)
+		FROM ledger_postings p
+		JOIN ledger_entries e ON e.id = p.entry_id
+		JOIN ledger_accounts a ON a.id = p.account_id
+		WHERE e.kind = $1 AND e.reference_id = $2 AND a.type = $3`
+	var total int64
+	err := r.db.QueryRowContext(ctx, query, models.EntryRefund, paymentID, models.AccountAsset).Scan(&total)
+	return total, err
+}
+
+// GetTrialBalance sums every posting per currency; each sum must be zero
+func (r *LedgerRepository) GetTrialBalance(ctx context.Context) (map[string]int64, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT currency, SUM(amount_minor) FROM ledger_postings GROUP BY currency`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sums := map[string]int64{}
+	for rows.Next() {
+		var currency string
+		var sum int64
+		if err := rows.Scan(&currency, &sum); err != nil {
+			return nil, err
+		}
+		sums[currency] = sum
+	}
+	return sums, rows.Err()
+}
+
+// GetPaymentsMissingSale lists captured payments with no sale entry
+func (r *LedgerRepository) GetPaymentsMissingSale(ctx context.Context) ([]string, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT p.id FROM payments p
+		WHERE p.status IN ($1, $2, $3)
+		AND NOT EXISTS (SELECT 1 FROM ledger_entries e WHERE e.kind = $4 AND e.reference_id = CAST(p.id AS TEXT))
+		ORDER BY p.created_at`
+	rows, err := r.db.QueryContext(ctx, query, models.PaymentCaptured, models.PaymentPartiallyRefunded, models.PaymentRefunded, models.EntrySale)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetRefundMismatches lists payments whose refunded total differs from the
+// refunds the ledger recorded for them
+func (r *LedgerRepository) GetRefundMismatches(ctx context.Context) ([]RefundMismatch, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT p.id, p.refunded_minor, COALESCE(l.refunded, 0)
+		FROM payments p
+		LEFT JOIN (
+			SELECT e.reference_id, -SUM(po.amount_minor) AS refunded
+			FROM ledger_entries e
+			JOIN ledger_postings po ON po.entry_id = e.id
+			JOIN ledger_accounts a ON a.id = po.account_id
+			WHERE e.kind = $1 AND a.type = $2
+			GROUP BY e.reference_id
+		) l ON l.reference_id = CAST(p.id AS TEXT)
+		WHERE p.refunded_minor <> COALESCE(l.refunded, 0)
+		ORDER BY p.created_at`
+	rows, err := r.db.QueryContext(ctx, query, models.EntryRefund, models.AccountAsset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mismatches []RefundMismatch
+	for rows.Next() {
+		var m RefundMismatch
+		if err := rows.Scan(&m.PaymentID, &m.PaymentRefunded, &m.LedgerRefunded); err != nil {
+			return nil, err
+		}
+		mismatches = append(mismatches, m)
+	}
+	return mismatches, rows.Err()
+}
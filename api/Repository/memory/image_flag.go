@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+)
+
+// ImageFlagStore is an in-memory repository.ImageFlagStore
+type ImageFlagStore struct {
+	mu    sync.RWMutex
+	flags []models.ImageFlag // oldest first
+}
+
+var _ repository.ImageFlagStore = (*ImageFlagStore)(nil)
+
+func NewImageFlagStore() *ImageFlagStore {
+	return &ImageFlagStore{}
+}
+
+func (s *ImageFlagStore) CreateFlag(ctx context.Context, flag *models.ImageFlag) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, f := range s.flags {
+		if f.ImageId == flag.ImageId && f.MatchedImageId == flag.MatchedImageId {
+			return nil
+		}
+	}
+	c := *flag
+	c.Id = newID()
+	c.CreatedAt = now()
+	s.flags = append(s.flags, c)
+	return nil
+}
+
+// Flags returns every flag recorded, oldest first
+func (s *ImageFlagStore) Flags() []models.ImageFlag {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]models.ImageFlag(nil), s.flags...)
+}
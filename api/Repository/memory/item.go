@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+)
+
+// ItemStore is an in-memory repository.ItemStore. Images aren't kept: items
+// come back without them, as they would from a database without any.
+type ItemStore struct {
+	mu    sync.RWMutex
+	items map[string]models.Item
+	order []string // ids, oldest first
+}
+
+var _ repository.ItemStore = (*ItemStore)(nil)
+
+func NewItemStore() *ItemStore {
+	return &ItemStore{items: make(map[string]models.Item)}
+}
+
+func (s *ItemStore) CreateItem(ctx context.Context, item *models.Item) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item.Id = newID()
+	item.CreatedAt = now()
+	item.UpdatedAt = item.CreatedAt
+	s.items[item.Id] = stored(item)
+	s.order = append(s.order, item.Id)
+	return nil
+}
+
+func (s *ItemStore) GetItemById(ctx context.Context, id string) (*models.Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, ok := s.items[id]
+	if !ok {
+		return nil, repository.ErrItemNotFound
+	}
+	return loaded(item), nil
+}
+
+func (s *ItemStore) UpdateItem(ctx context.Context, item *models.Item) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.items[item.Id]
+	if !ok {
+		return repository.ErrItemNotFound
+	}
+	updated := stored(item)
+	updated.UserId = existing.UserId
+	updated.CreatedAt = existing.CreatedAt
+	updated.Quantity, updated.IsSold = existing.Quantity, existing.IsSold
+	updated.UpdatedAt = now()
+	s.items[item.Id] = updated
+	item.Quantity, item.IsSold = updated.Quantity, updated.IsSold
+	item.UpdatedAt = updated.UpdatedAt
+	return nil
+}
+
+func (s *ItemStore) SetQuantity(ctx context.Context, id string, quantity int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[id]
+	if !ok {
+		return repository.ErrItemNotFound
+	}
+	item.Quantity = quantity
+	item.IsSold = quantity <= 0
+	item.UpdatedAt = now()
+	s.items[id] = item
+	return nil
+}
+
+func (s *ItemStore) DeleteItem(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.items[id]; !ok {
+		return repository.ErrItemNotFound
+	}
+	delete(s.items, id)
+	s.order = remove(s.order, id)
+	return nil
+}
+
+func (s *ItemStore) GetAllItems(ctx context.Context) ([]*models.Item, error) {
+	return s.list(ctx, func(models.Item) bool { return true })
+}
+
+func (s *ItemStore) GetItemsByUserID(ctx context.Context, userID string) ([]*models.Item, error) {
+	return s.list(ctx, func(item models.Item) bool { return item.UserId == userID })
+}
+
+// list returns the items match accepts, newest first
+func (s *ItemStore) list(ctx context.Context, match func(models.Item) bool) ([]*models.Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var items []*models.Item
+	for i := len(s.order) - 1; i >= 0; i-- {
+		if item := s.items[s.order[i]]; match(item) {
+			items = append(items, loaded(item))
+		}
+	}
+	return items, nil
+}
+
+// stored copies the columns the items table has, so later changes to item
+// don't reach the store
+func stored(item *models.Item) models.Item {
+	c := *item
+	c.Images = nil
+	c.ImageURL = ""
+	c.Converted = nil
+	if item.ShippingProfileId != nil {
+		id := *item.ShippingProfileId
+		c.ShippingProfileId = &id
+	}
+	return c
+}
+
+// loaded returns a copy of a stored item the caller is free to change
+func loaded(item models.Item) *models.Item {
+	c := stored(&item)
+	c.SellingPrice.Currency = c.Price.Currency // both prices share the item's currency
+	return &c
+}
@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"math/bits"
+	"sort"
+	"sync"
+
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+)
+
+// ItemImageStore is an in-memory repository.ItemImageStore. It asks items,
+// the store the images' items are kept in, who sells them and which images
+// are items' primary ones.
+type ItemImageStore struct {
+	mu     sync.RWMutex
+	items  repository.ItemStore
+	images []models.ItemImage // oldest first
+}
+
+var _ repository.ItemImageStore = (*ItemImageStore)(nil)
+
+func NewItemImageStore(items repository.ItemStore) *ItemImageStore {
+	return &ItemImageStore{items: items}
+}
+
+func (s *ItemImageStore) CreateImages(ctx context.Context, itemID string, images []models.ItemImage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range images {
+		images[i].Id = newID()
+		images[i].ItemId = itemID
+		images[i].DisplayOrder = i
+		images[i].CreatedAt = now()
+		s.images = append(s.images, images[i])
+	}
+	return nil
+}
+
+func (s *ItemImageStore) DeleteImagesByItemID(ctx context.Context, itemID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.images[:0]
+	for _, img := range s.images {
+		if img.ItemId != itemID {
+			kept = append(kept, img)
+		}
+	}
+	s.images = kept
+	return nil
+}
+
+func (s *ItemImageStore) GetItemIDByImagePath(ctx context.Context, imagePath string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	s.mu.RLock()
+	for _, img := range s.images {
+		if img.ImagePath == imagePath {
+			s.mu.RUnlock()
+			return img.ItemId, nil
+		}
+	}
+	s.mu.RUnlock()
+
+	items, err := s.items.GetAllItems(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, item := range items {
+		if item.Image == imagePath {
+			return item.Id, nil
+		}
+	}
+	return "", nil
+}
+
+func (s *ItemImageStore) FindSimilarImages(ctx context.Context, excludeUserID string, hash int64, maxDistance int) ([]repository.SimilarImage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	images := append([]models.ItemImage(nil), s.images...)
+	s.mu.RUnlock()
+
+	var matches []repository.SimilarImage
+	for _, img := range images {
+		if img.PHash == nil {
+			continue
+		}
+		distance := bits.OnesCount64(uint64(*img.PHash ^ hash))
+		if distance > maxDistance {
+			continue
+		}
+		item, err := s.items.GetItemById(ctx, img.ItemId)
+		if err == repository.ErrItemNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if item.UserId != excludeUserID {
+			matches = append(matches, repository.SimilarImage{ImageID: img.Id, ItemID: img.ItemId, Distance: distance})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Distance < matches[j].Distance })
+	return matches, nil
+}
@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+)
+
+// ItemInviteStore is an in-memory repository.ItemInviteStore
+type ItemInviteStore struct {
+	mu      sync.RWMutex
+	invites []models.ItemInvite // oldest first
+}
+
+var _ repository.ItemInviteStore = (*ItemInviteStore)(nil)
+
+func NewItemInviteStore() *ItemInviteStore {
+	return &ItemInviteStore{}
+}
+
+func (s *ItemInviteStore) AddInvite(ctx context.Context, itemID, userID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.find(itemID, userID) < 0 {
+		s.invites = append(s.invites, models.ItemInvite{ItemId: itemID, UserId: userID, CreatedAt: now()})
+	}
+	return nil
+}
+
+func (s *ItemInviteStore) RemoveInvite(ctx context.Context, itemID, userID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.find(itemID, userID); i >= 0 {
+		s.invites = append(s.invites[:i], s.invites[i+1:]...)
+	}
+	return nil
+}
+
+func (s *ItemInviteStore) IsInvited(ctx context.Context, itemID, userID string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.find(itemID, userID) >= 0, nil
+}
+
+func (s *ItemInviteStore) GetInvitesByItemID(ctx context.Context, itemID string) ([]models.ItemInvite, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	invites := []models.ItemInvite{}
+	for _, invite := range s.invites {
+		if invite.ItemId == itemID {
+			invites = append(invites, invite)
+		}
+	}
+	return invites, nil
+}
+
+// find returns the index of a user's invite to an item, or -1
+func (s *ItemInviteStore) find(itemID, userID string) int {
+	for i, invite := range s.invites {
+		if invite.ItemId == itemID && invite.UserId == userID {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,49 @@
+// Package memory keeps repository data in memory, for tests that shouldn't
+// need Postgres. Its stores are safe for concurrent use and behave like the
+// Postgres repositories as far as the contract tests in storetest go.
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	repository "primeauction/api/Repository"
+)
+
+// TxRunner is a repository.TxRunner for memory stores. They can't roll
+// back, so a failed unit of work keeps the writes it made, but the OnCommit
+// and OnRollback hooks run as they would around a database transaction.
+type TxRunner struct{}
+
+var _ repository.TxRunner = TxRunner{}
+
+func (TxRunner) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return repository.WithHooks(ctx, fn)
+}
+
+// newID returns a random version 4 UUID, like the ids Postgres generates
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// now returns the time a write happened at. Postgres timestamps only keep
+// microseconds, so neither do these.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// remove deletes the first id from ids, keeping the rest in order
+func remove(ids []string, id string) []string {
+	for i, v := range ids {
+		if v == id {
+			return append(ids[:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,57 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+
+	repository "primeauction/api/Repository"
+	"primeauction/api/Repository/memory"
+	"primeauction/api/Repository/storetest"
+	"primeauction/api/models"
+)
+
+func TestUserStore(t *testing.T) {
+	storetest.UserStores(t, func(t *testing.T) repository.UserStore {
+		return memory.NewUserStore()
+	})
+}
+
+func TestItemStore(t *testing.T) {
+	storetest.ItemStores(t, func(t *testing.T) (repository.ItemStore, repository.UserStore) {
+		return memory.NewItemStore(), memory.NewUserStore()
+	})
+}
+
+func TestItemImageStore(t *testing.T) {
+	storetest.ItemImageStores(t, func(t *testing.T) (repository.ItemImageStore, repository.ItemStore, repository.UserStore) {
+		items := memory.NewItemStore()
+		return memory.NewItemImageStore(items), items, memory.NewUserStore()
+	})
+}
+
+func TestItemInviteStore(t *testing.T) {
+	storetest.ItemInviteStores(t, func(t *testing.T) (repository.ItemInviteStore, repository.ItemStore, repository.UserStore) {
+		return memory.NewItemInviteStore(), memory.NewItemStore(), memory.NewUserStore()
+	})
+}
+
+func TestImageFlagStore(t *testing.T) {
+	storetest.ImageFlagStores(t, func(t *testing.T) (repository.ImageFlagStore, func(ctx context.Context) ([]models.ImageFlag, error),
+		repository.ItemImageStore, repository.ItemStore, repository.UserStore) {
+		flags, items := memory.NewImageFlagStore(), memory.NewItemStore()
+		list := func(ctx context.Context) ([]models.ImageFlag, error) { return flags.Flags(), nil }
+		return flags, list, memory.NewItemImageStore(items), items, memory.NewUserStore()
+	})
+}
+
+func TestShippingProfileStore(t *testing.T) {
+	storetest.ShippingProfileStores(t, func(t *testing.T) (repository.ShippingProfileStore,
+		func(ctx context.Context, profile *models.ShippingProfile) error, repository.UserStore) {
+		profiles := memory.NewShippingProfileStore()
+		add := func(ctx context.Context, profile *models.ShippingProfile) error {
+			profiles.AddProfile(profile)
+			return nil
+		}
+		return profiles, add, memory.NewUserStore()
+	})
+}
@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+)
+
+// ShippingProfileStore is an in-memory repository.ShippingProfileStore.
+// Profiles are put in it with AddProfile.
+type ShippingProfileStore struct {
+	mu       sync.RWMutex
+	profiles map[string]models.ShippingProfile
+}
+
+var _ repository.ShippingProfileStore = (*ShippingProfileStore)(nil)
+
+func NewShippingProfileStore() *ShippingProfileStore {
+	return &ShippingProfileStore{profiles: make(map[string]models.ShippingProfile)}
+}
+
+// AddProfile saves a profile and sets its Id, CreatedAt and UpdatedAt
+func (s *ShippingProfileStore) AddProfile(profile *models.ShippingProfile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profile.Id = newID()
+	profile.CreatedAt = now()
+	profile.UpdatedAt = profile.CreatedAt
+	s.profiles[profile.Id] = *profile
+}
+
+func (s *ShippingProfileStore) GetProfile(ctx context.Context, id string) (*models.ShippingProfile, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	profile, ok := s.profiles[id]
+	if !ok {
+		return nil, repository.ErrProfileNotFound
+	}
+	return &profile, nil
+}
@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+)
+
+// UserStore is an in-memory repository.UserStore. Like the users table it
+// keeps usernames and emails unique.
+type UserStore struct {
+	mu    sync.RWMutex
+	users map[string]models.User
+	order []string // ids, oldest first
+}
+
+var _ repository.UserStore = (*UserStore)(nil)
+
+func NewUserStore() *UserStore {
+	return &UserStore{users: make(map[string]models.User)}
+}
+
+func (s *UserStore) CreateUser(ctx context.Context, user *models.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.taken(user.Username, user.Email, "") {
+		return repository.ErrUserExists
+	}
+	user.Id = newID()
+	user.CreatedAt = now()
+	user.UpdatedAt = user.CreatedAt
+	c := *user
+	c.PreferredCurrency = "" // not set on sign up
+	s.users[user.Id] = c
+	s.order = append(s.order, user.Id)
+	return nil
+}
+
+func (s *UserStore) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (s *UserStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (s *UserStore) GetAllUsers(ctx context.Context) ([]models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := []models.User{}
+	for _, id := range s.order {
+		user := s.users[id]
+		users = append(users, models.User{Id: user.Id, Username: user.Username, Email: user.Email, Password: user.Password})
+	}
+	return users, nil
+}
+
+func (s *UserStore) UpdateUser(ctx context.Context, id string, user *models.User) error {
+	return s.update(ctx, id, func(u *models.User) error {
+		if s.taken(user.Username, user.Email, id) {
+			return repository.ErrUserExists
+		}
+		u.Username, u.Email, u.Password = user.Username, user.Email, user.Password
+		return nil
+	})
+}
+
+func (s *UserStore) UpdatePreferredCurrency(ctx context.Context, id, currency string) error {
+	return s.update(ctx, id, func(u *models.User) error {
+		u.PreferredCurrency = currency
+		return nil
+	})
+}
+
+func (s *UserStore) SetAdmin(ctx context.Context, id string, isAdmin bool) error {
+	return s.update(ctx, id, func(u *models.User) error {
+		u.IsAdmin = isAdmin
+		return nil
+	})
+}
+
+func (s *UserStore) UpdatePassword(ctx context.Context, id, hash string) error {
+	return s.update(ctx, id, func(u *models.User) error {
+		u.Password = hash
+		return nil
+	})
+}
+
+func (s *UserStore) DeleteUser(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[id]; !ok {
+		return repository.ErrUserNotFound
+	}
+	delete(s.users, id)
+	s.order = remove(s.order, id)
+	return nil
+}
+
+// update applies change to the user with id and stamps UpdatedAt, unless
+// change fails
+func (s *UserStore) update(ctx context.Context, id string, change func(*models.User) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	if err := change(&user); err != nil {
+		return err
+	}
+	user.UpdatedAt = now()
+	s.users[id] = user
+	return nil
+}
+
+// taken reports whether a user other than except has the username or
+// email. The caller holds mu.
+func (s *UserStore) taken(username, email, except string) bool {
+	for id, user := range s.users {
+		if id != except && (user.Username == username || user.Email == email) {
+			return true
+		}
+	}
+	return false
+}
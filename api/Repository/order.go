@@ -0,0 +1,553 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"primeauction/api/models"
+	"sort"
+	"time"
+)
+
+// Errors raised while reserving stock, inside the checkout transaction
+var (
+	ErrItemNotFound      = errors.New("item not found")
+	ErrItemUnavailable   = errors.New("item is not available for purchase")
+	ErrInsufficientStock = errors.New("not enough stock")
+	ErrMixedCurrencies   = errors.New("all items in an order must be priced in the same currency")
+	ErrOrderNotPending   = errors.New("order is no longer pending")
+)
+
+// ErrOrderNotFound is returned for an unknown order
+var ErrOrderNotFound = errors.New("order not found")
+
+type OrderRepository struct {
+	db conn
+}
+
+func NewOrderRepository(db *sql.DB) *OrderRepository {
+	return &OrderRepository{db: conn{db}}
+}
+
+const orderColumns = `id, buyer_id, status, subtotal_minor, tax_minor, shipping_minor, total_minor, currency, expires_at, paid_at,
+	created_at, updated_at, fee_schedule_version, tax_region, ship_to_region`
+
+func scanOrder(row interface{ Scan(...any) error }) (*models.Order, error) {
+	var order models.Order
+	var paidAt sql.NullTime
+	var feeVersion sql.NullInt64
+	var taxRegion, shipTo sql.NullString
+	err := row.Scan(&order.Id, &order.BuyerId, &order.Status, &order.Subtotal.Amount, &order.Tax.Amount, &order.Shipping.Amount,
+		&order.Total.Amount, &order.Total.Currency, &order.ExpiresAt, &paidAt, &order.CreatedAt, &order.UpdatedAt, &feeVersion,
+		&taxRegion, &shipTo)
+	if err != nil {
+		return nil, err
+	}
+	order.Subtotal.Currency = order.Total.Currency
+	order.Tax.Currency = order.Total.Currency
+	order.Shipping.Currency = order.Total.Currency
+	order.TaxRegion = taxRegion.String
+	order.ShipToRegion = shipTo.String
+	if paidAt.Valid {
+		order.PaidAt = &paidAt.Time
+	}
+	if feeVersion.Valid {
+		version := int(feeVersion.Int64)
+		order.FeeScheduleVersion = &version
+	}
+	return &order, nil
+}
+
+// CreateReservedOrder places an order for the requested lines and reserves
+// their stock in one transaction. Each item row is locked with FOR UPDATE
+// (in ID order, so concurrent checkouts can't deadlock) before its quantity
+// is checked and decremented, which is what stops two buyers from taking
+// the last unit. Items that reach zero stock are marked sold.
+//
+// Each line is taxed with taxes for the buyer's region (order.TaxRegion),
+// added on top of the price or backed out of it depending on whether the
+// seller's prices include tax, and the seller's fee is assessed on the net
+// amount with fees. Category, tier and tax setting are read as they are at
+// checkout.
+//
+// Each seller's items are grouped into one shipment per shipping profile
+// and quoted for order.ShipToRegion with the profiles as they are now.
+// Shipping is charged on top of the items, with no tax or fee on it.
+func (r *OrderRepository) CreateReservedOrder(ctx context.Context, order *models.Order, requests []models.OrderLineRequest, fees *models.FeeSchedule, taxes *models.TaxTable) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	sorted := append([]models.OrderLineRequest(nil), requests...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ItemId < sorted[j].ItemId })
+
+	ctx, tx, err := r.db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	lockQuery := `SELECT items.user_id, items.name, items.category, items.selling_price_minor, items.currency, items.quantity,
+			items.is_sold, items.visibility,
+			EXISTS (SELECT 1 FROM item_invites WHERE item_id = items.id AND user_id = $2),
+			users.seller_tier, users.prices_include_tax,
+			items.weight_grams, items.length_cm, items.width_cm, items.height_cm, items.shipping_profile_id
+		FROM items
+		JOIN users ON users.id = items.user_id
+		WHERE items.id = $1
+		FOR UPDATE OF items`
+
+	lines := make([]models.OrderLine, 0, len(sorted))
+	lineTaxes := make([]*models.TaxLine, 0, len(sorted))
+	parcels := make([]models.ShippingItem, 0, len(sorted))
+	var subtotal, tax models.Money
+	for i, req := range sorted {
+		var line models.OrderLine
+		var stock int
+		var isSold, invited bool
+		var visibility string
+		var sellerTier string
+		var pricesIncludeTax bool
+		var size models.Item
+		err := tx.QueryRowContext(ctx, lockQuery, req.ItemId, order.BuyerId).Scan(&line.SellerId, &line.ItemName, &line.Category,
+			&line.UnitPrice.Amount, &line.UnitPrice.Currency, &stock, &isSold, &visibility, &invited, &sellerTier, &pricesIncludeTax,
+			&size.WeightGrams, &size.LengthCm, &size.WidthCm, &size.HeightCm, &size.ShippingProfileId)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("item %s: %w", req.ItemId, ErrItemNotFound)
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case line.SellerId == order.BuyerId:
+			return fmt.Errorf("item %s: %w: you cannot buy your own item", req.ItemId, ErrItemUnavailable)
+		case visibility == models.VisibilityDraft, visibility == models.VisibilityPrivate && !invited:
+			// Same error as a missing item so drafts don't leak their existence
+			return fmt.Errorf("item %s: %w", req.ItemId, ErrItemNotFound)
+		case isSold && stock <= 0:
+			return fmt.Errorf("item %s: %w: sold out", req.ItemId, ErrItemUnavailable)
+		case stock < req.Quantity:
+			return fmt.Errorf("item %s: %w: %d available", req.ItemId, ErrInsufficientStock, stock)
+		}
+
+		lineTotal, err := line.UnitPrice.Mul(int64(req.Quantity))
+		if err != nil {
+			return err
+		}
+		net := lineTotal
+		var lineTax *models.TaxLine
+		if t, ok := taxes.Calculate(line.Category, lineTotal, pricesIncludeTax); ok {
+			lineTax = &t
+			net = t.Taxable
+		}
+		if i == 0 {
+			subtotal = models.NewMoney(0, lineTotal.Currency)
+			tax = models.NewMoney(0, lineTotal.Currency)
+		}
+		if subtotal, err = subtotal.Add(net); err != nil {
+			return ErrMixedCurrencies
+		}
+		if lineTax != nil {
+			if tax, err = tax.Add(lineTax.Tax); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE items
+			SET quantity = quantity - $1, is_sold = (quantity - $1 <= 0), updated_at = CURRENT_TIMESTAMP
+			WHERE id = $2`, req.Quantity, req.ItemId)
+		if err != nil {
+			return err
+		}
+
+		fee := fees.Assess(models.FeeOnSale, line.Category, sellerTier, net, req.Quantity)
+
+		itemID := req.ItemId
+		line.ItemId = &itemID
+		line.Quantity = req.Quantity
+		line.LineTotal = lineTotal
+		line.Fee = &fee
+		lines = append(lines, line)
+		lineTaxes = append(lineTaxes, lineTax)
+		parcels = append(parcels, models.ShippingItem{
+			ItemId:    itemID,
+			SellerId:  line.SellerId,
+			ProfileId: size.ShippingProfileId,
+			Grams:     size.ChargeableGrams() * int64(req.Quantity),
+			Value:     lineTotal,
+		})
+	}
+
+	profiles := map[string]*models.ShippingProfile{}
+	for _, parcel := range parcels {
+		if parcel.ProfileId == nil || profiles[*parcel.ProfileId] != nil {
+			continue
+		}
+		profile, err := scanShippingProfile(tx.QueryRowContext(ctx, `SELECT `+shippingProfileColumns+` FROM shipping_profiles WHERE id = $1`,
+			*parcel.ProfileId))
+		if err != nil {
+			return err
+		}
+		profiles[profile.Id] = profile
+	}
+	quotes, err := models.QuoteShipments(parcels, profiles, order.ShipToRegion)
+	if err != nil {
+		return err
+	}
+	shipping := models.NewMoney(0, subtotal.Currency)
+	for _, quote := range quotes {
+		if shipping, err = shipping.Add(quote.Cost); err != nil {
+			return err
+		}
+	}
+
+	total, err := subtotal.Add(tax)
+	if err != nil {
+		return err
+	}
+	if total, err = total.Add(shipping); err != nil {
+		return err
+	}
+	order.Status = models.OrderPending
+	order.Subtotal = subtotal
+	order.Tax = tax
+	order.Shipping = shipping
+	order.Total = total
+	order.FeeScheduleVersion = &fees.Version
+	var taxRegion, shipTo *string
+	if order.TaxRegion != "" {
+		taxRegion = &order.TaxRegion
+	}
+	if order.ShipToRegion != "" {
+		shipTo = &order.ShipToRegion
+	}
+	err = tx.QueryRowContext(ctx, `INSERT INTO orders (buyer_id, status, subtotal_minor, tax_minor, shipping_minor, total_minor, currency,
+			expires_at, fee_schedule_version, tax_region, ship_to_region)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at, updated_at`,
+		order.BuyerId, order.Status, order.Subtotal.Amount, order.Tax.Amount, order.Shipping.Amount, order.Total.Amount,
+		order.Total.Currency, order.ExpiresAt, fees.Version, taxRegion, shipTo,
+	).Scan(&order.Id, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	order.Shipments = make([]models.Shipment, 0, len(quotes))
+	shipmentOf := map[string]*string{}
+	for _, quote := range quotes {
+		shipment := models.Shipment{
+			OrderId:        order.Id,
+			SellerId:       quote.SellerId,
+			ProfileId:      quote.ProfileId,
+			Method:         quote.Method,
+			WeightGrams:    quote.WeightGrams,
+			Cost:           quote.Cost,
+			Status:         models.ShipmentPending,
+			PickupLocation: quote.PickupLocation,
+		}
+		if err := insertShipment(ctx, tx, &shipment); err != nil {
+			return err
+		}
+		for _, itemID := range quote.ItemIds {
+			shipmentOf[itemID] = &shipment.Id
+		}
+		order.Shipments = append(order.Shipments, shipment)
+	}
+
+	for i := range lines {
+		line := &lines[i]
+		line.OrderId = order.Id
+		line.ShipmentId = shipmentOf[*line.ItemId]
+		fee, err := json.Marshal(line.Fee)
+		if err != nil {
+			return err
+		}
+		err = tx.QueryRowContext(ctx, `INSERT INTO order_lines (order_id, item_id, seller_id, item_name, category, quantity, unit_price_minor, currency, fee_minor, fee,
+				shipment_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			RETURNING id, created_at`,
+			line.OrderId, line.ItemId, line.SellerId, line.ItemName, line.Category, line.Quantity, line.UnitPrice.Amount, line.UnitPrice.Currency,
+			line.Fee.Amount.Amount, fee, line.ShipmentId,
+		).Scan(&line.Id, &line.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	order.TaxLines = []models.TaxLine{}
+	for i, t := range lineTaxes {
+		if t == nil {
+			continue
+		}
+		t.OrderId = order.Id
+		t.OrderLineId = lines[i].Id
+		err := tx.QueryRowContext(ctx, `INSERT INTO order_tax_lines (order_id, order_line_id, region, name, rate_bps, inclusive,
+				taxable_minor, tax_minor, currency)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id`,
+			t.OrderId, t.OrderLineId, t.Region, t.Name, t.RateBPS, t.Inclusive, t.Taxable.Amount, t.Tax.Amount, t.Tax.Currency,
+		).Scan(&t.Id)
+		if err != nil {
+			return err
+		}
+		order.TaxLines = append(order.TaxLines, *t)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	order.Lines = lines
+	return nil
+}
+
+// CancelOrder releases a pending order's reserved stock and marks it
+// cancelled. Only the buyer may cancel.
+func (r *OrderRepository) CancelOrder(ctx context.Context, id, buyerID string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	ctx, tx, err := r.db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = $1 AND buyer_id = $2 FOR UPDATE`, id, buyerID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrOrderNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status != models.OrderPending {
+		return ErrOrderNotPending
+	}
+	if err := releaseOrder(ctx, tx, id, models.OrderCancelled); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ExpireReservations releases every pending order whose reservation lapsed
+// before now and returns their IDs. Orders locked by a concurrent payment
+// or cancellation are skipped and picked up on a later pass.
+func (r *OrderRepository) ExpireReservations(ctx context.Context, now time.Time) ([]string, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	ctx, tx, err := r.db.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM orders
+		WHERE status = $1 AND expires_at < $2
+		ORDER BY expires_at
+		FOR UPDATE SKIP LOCKED`, models.OrderPending, now)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		if err := releaseOrder(ctx, tx, id, models.OrderExpired); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// releaseOrder puts an order's reserved quantities back on its items and
+// moves the order to status. The caller must hold the order row lock.
+func releaseOrder(ctx context.Context, tx *Tx, orderID, status string) error {
+	// Lock the items in ID order, the same order checkout locks them in
+	_, err := tx.ExecContext(ctx, `SELECT id FROM items
+		WHERE id IN (SELECT item_id FROM order_lines WHERE order_id = $1)
+		ORDER BY id
+		FOR UPDATE`, orderID)
+	if err != nil {
+		return err
+	}
+
+	// Items that sold out because of this order become available again;
+	// items still in stock keep whatever sold flag the seller gave them
+	_, err = tx.ExecContext(ctx, `UPDATE items
+		SET quantity = items.quantity + l.quantity,
+			is_sold = CASE WHEN items.quantity <= 0 THEN FALSE ELSE items.is_sold END,
+			updated_at = CURRENT_TIMESTAMP
+		FROM order_lines l
+		WHERE l.order_id = $1 AND l.item_id = items.id`, orderID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE orders SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, status, orderID)
+	return err
+}
+
+// GetOrderByID retrieves an order with its lines
+func (r *OrderRepository) GetOrderByID(ctx context.Context, id string) (*models.Order, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1`
+	order, err := scanOrder(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if order.Lines, err = r.getLines(ctx, order.Id); err != nil {
+		return nil, err
+	}
+	if order.TaxLines, err = r.getTaxLines(ctx, order.Id); err != nil {
+		return nil, err
+	}
+	if order.Shipments, err = getShipments(ctx, r.db, order.Id); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// GetOrdersByBuyer lists a buyer's orders, newest first
+func (r *OrderRepository) GetOrdersByBuyer(ctx context.Context, buyerID string) ([]*models.Order, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE buyer_id = $1 ORDER BY created_at DESC`
+	return r.getOrders(ctx, query, buyerID)
+}
+
+// GetOrdersBySeller lists the orders with at least one of a seller's items
+// on them, newest first
+func (r *OrderRepository) GetOrdersBySeller(ctx context.Context, sellerID string) ([]*models.Order, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + orderColumns + ` FROM orders
+		WHERE id IN (SELECT order_id FROM order_lines WHERE seller_id = $1)
+		ORDER BY created_at DESC`
+	return r.getOrders(ctx, query, sellerID)
+}
+
+func (r *OrderRepository) getOrders(ctx context.Context, query string, args ...any) ([]*models.Order, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []*models.Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, order := range orders {
+		if order.Lines, err = r.getLines(ctx, order.Id); err != nil {
+			return nil, err
+		}
+		if order.TaxLines, err = r.getTaxLines(ctx, order.Id); err != nil {
+			return nil, err
+		}
+		if order.Shipments, err = getShipments(ctx, r.db, order.Id); err != nil {
+			return nil, err
+		}
+	}
+	return orders, nil
+}
+
+func (r *OrderRepository) getLines(ctx context.Context, orderID string) ([]models.OrderLine, error) {
+	query := `SELECT id, order_id, item_id, seller_id, item_name, category, quantity, unit_price_minor, currency, created_at, fee,
+			shipment_id
+		FROM order_lines
+		WHERE order_id = $1
+		ORDER BY created_at, id`
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []models.OrderLine{}
+	for rows.Next() {
+		var line models.OrderLine
+		var itemID, shipmentID sql.NullString
+		var fee []byte
+		err := rows.Scan(&line.Id, &line.OrderId, &itemID, &line.SellerId, &line.ItemName, &line.Category, &line.Quantity,
+			&line.UnitPrice.Amount, &line.UnitPrice.Currency, &line.CreatedAt, &fee, &shipmentID)
+		if err != nil {
+			return nil, err
+		}
+		if itemID.Valid {
+			line.ItemId = &itemID.String
+		}
+		if shipmentID.Valid {
+			line.ShipmentId = &shipmentID.String
+		}
+		if fee != nil {
+			line.Fee = &models.FeeAssessment{}
+			if err := json.Unmarshal(fee, line.Fee); err != nil {
+				return nil, err
+			}
+		}
+		line.LineTotal, err = line.UnitPrice.Mul(int64(line.Quantity))
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+func (r *OrderRepository) getTaxLines(ctx context.Context, orderID string) ([]models.TaxLine, error) {
+	query := `SELECT t.id, t.order_id, t.order_line_id, t.region, t.name, t.rate_bps, t.inclusive, t.taxable_minor, t.tax_minor, t.currency
+		FROM order_tax_lines t
+		JOIN order_lines l ON l.id = t.order_line_id
+		WHERE t.order_id = $1
+		ORDER BY l.created_at, l.id`
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	taxLines := []models.TaxLine{}
+	for rows.Next() {
+		var t models.TaxLine
+		err := rows.Scan(&t.Id, &t.OrderId, &t.OrderLineId, &t.Region, &t.Name, &t.RateBPS, &t.Inclusive,
+			&t.Taxable.Amount, &t.Tax.Amount, &t.Tax.Currency)
+		if err != nil {
+			return nil, err
+		}
+		t.Taxable.Currency = t.Tax.Currency
+		taxLines = append(taxLines, t)
+	}
+	return taxLines, rows.Err()
+}
@@ -0,0 +1,352 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"primeauction/api/models"
+)
+
+var (
+	// ErrPaymentNotFound is returned for an unknown payment
+	ErrPaymentNotFound = errors.New("payment not found")
+	// ErrPaymentChanged is returned when a concurrent change got to a
+	// payment first
+	ErrPaymentChanged = errors.New("payment changed concurrently")
+)
+
+type PaymentRepository struct {
+	db conn
+}
+
+func NewPaymentRepository(db *sql.DB) *PaymentRepository {
+	return &PaymentRepository{db: conn{db}}
+}
+
+const paymentColumns = `id, order_id, provider, provider_ref, status, amount_minor, refunded_minor, currency, client_secret, created_at, updated_at`
+
+func scanPayment(row interface{ Scan(...any) error }) (*models.Payment, error) {
+	var payment models.Payment
+	var providerRef sql.NullString
+	err := row.Scan(&payment.Id, &payment.OrderId, &payment.Provider, &providerRef, &payment.Status,
+		&payment.Amount.Amount, &payment.Refunded.Amount, &payment.Amount.Currency, &payment.ClientSecret,
+		&payment.CreatedAt, &payment.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	payment.ProviderRef = providerRef.String
+	payment.Refunded.Currency = payment.Amount.Currency
+	return &payment, nil
+}
+
+const refundColumns = `id, payment_id, idempotency_key, amount_minor, currency, status, provider_ref, seller_id, created_at, updated_at`
+
+func scanRefund(row interface{ Scan(...any) error }) (*models.PaymentRefund, error) {
+	var refund models.PaymentRefund
+	var providerRef, sellerID sql.NullString
+	err := row.Scan(&refund.Id, &refund.PaymentId, &refund.IdempotencyKey, &refund.Amount.Amount, &refund.Amount.Currency,
+		&refund.Status, &providerRef, &sellerID, &refund.CreatedAt, &refund.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	refund.ProviderRef = providerRef.String
+	if sellerID.Valid {
+		refund.SellerId = &sellerID.String
+	}
+	return &refund, nil
+}
+
+// CreatePayment records a payment attempt before the provider is called,
+// so its ID can serve as the provider idempotency key
+func (r *PaymentRepository) CreatePayment(ctx context.Context, payment *models.Payment) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO payments (order_id, provider, status, amount_minor, currency)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`
+	return r.db.QueryRowContext(ctx, query, payment.OrderId, payment.Provider, payment.Status, payment.Amount.Amount, payment.Amount.Currency).
+		Scan(&payment.Id, &payment.CreatedAt, &payment.UpdatedAt)
+}
+
+// SetIntent stores the provider's intent for a payment attempt
+func (r *PaymentRepository) SetIntent(ctx context.Context, id, providerRef, clientSecret string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE payments SET provider_ref = $1, client_secret = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`
+	_, err := r.db.ExecContext(ctx, query, providerRef, clientSecret, id)
+	return err
+}
+
+// GetPaymentByID retrieves a payment by ID
+func (r *PaymentRepository) GetPaymentByID(ctx context.Context, id string) (*models.Payment, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE id = $1`
+	payment, err := scanPayment(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// GetPaymentByRef retrieves a payment by the provider's ID for its intent
+func (r *PaymentRepository) GetPaymentByRef(ctx context.Context, provider, providerRef string) (*models.Payment, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE provider = $1 AND provider_ref = $2`
+	payment, err := scanPayment(r.db.QueryRowContext(ctx, query, provider, providerRef))
+	if err == sql.ErrNoRows {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// GetActivePayment returns the order's payment attempt that is still in
+// progress, or nil if there is none
+func (r *PaymentRepository) GetActivePayment(ctx context.Context, orderID string) (*models.Payment, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + paymentColumns + ` FROM payments
+		WHERE order_id = $1 AND status IN ($2, $3) AND provider_ref IS NOT NULL
+		ORDER BY created_at DESC
+		LIMIT 1`
+	payment, err := scanPayment(r.db.QueryRowContext(ctx, query, orderID, models.PaymentPending, models.PaymentAuthorized))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// GetPaymentsByOrder lists every payment attempt for an order, newest first
+func (r *PaymentRepository) GetPaymentsByOrder(ctx context.Context, orderID string) ([]models.Payment, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE order_id = $1 ORDER BY created_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []models.Payment{}
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, *payment)
+	}
+	return payments, rows.Err()
+}
+
+// ApplyEvent records a webhook event and applies it to the payment it
+// refers to, all in one transaction, and returns the updated payment and
+// whether its status or refunded amount changed. It returns nil without
+// calling apply when the event was already recorded, which makes
+// duplicate deliveries harmless; events for unknown intents are recorded
+// and skipped. apply may change the payment's status and refunded amount
+// and sees the order's status (with the order row locked). It runs with
+// both rows locked, so it must not call out to the provider. If it fails
+// nothing is recorded, so the provider's retry will be processed again. A
+// payment that ends up captured marks a pending order paid.
+func (r *PaymentRepository) ApplyEvent(ctx context.Context, provider, eventID, eventType, providerRef string, apply func(payment *models.Payment, orderStatus string) error) (*models.Payment, bool, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	ctx, tx, err := r.db.begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `INSERT INTO payment_events (provider, event_id, event_type)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (provider, event_id) DO NOTHING`, provider, eventID, eventType)
+	if err != nil {
+		return nil, false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return nil, false, err
+	}
+
+	payment, err := scanPayment(tx.QueryRowContext(ctx, `SELECT `+paymentColumns+` FROM payments
+		WHERE provider = $1 AND provider_ref = $2
+		FOR UPDATE`, provider, providerRef))
+	if err == sql.ErrNoRows {
+		return nil, false, tx.Commit()
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var orderStatus string
+	if err := tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, payment.OrderId).Scan(&orderStatus); err != nil {
+		return nil, false, err
+	}
+
+	fromStatus, fromRefunded := payment.Status, payment.Refunded.Amount
+	if err := apply(payment, orderStatus); err != nil {
+		return nil, false, err
+	}
+	changed := payment.Status != fromStatus || payment.Refunded.Amount != fromRefunded
+
+	_, err = tx.ExecContext(ctx, `UPDATE payments SET status = $1, refunded_minor = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`,
+		payment.Status, payment.Refunded.Amount, payment.Id)
+	if err != nil {
+		return nil, false, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE payment_events SET payment_id = $1, from_status = $2, to_status = $3
+		WHERE provider = $4 AND event_id = $5`, payment.Id, fromStatus, payment.Status, provider, eventID)
+	if err != nil {
+		return nil, false, err
+	}
+	if payment.Status == models.PaymentCaptured && orderStatus == models.OrderPending {
+		_, err = tx.ExecContext(ctx, `UPDATE orders SET status = $1, paid_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $2`, models.OrderPaid, payment.OrderId)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return payment, changed, nil
+}
+
+// CreateRefund records a refund before the provider is asked for it. A
+// refund with the same idempotency key is kept, and refund is filled in
+// from it, so a retried request resumes the refund it started.
+func (r *PaymentRepository) CreateRefund(ctx context.Context, refund *models.PaymentRefund) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `INSERT INTO payment_refunds (payment_id, idempotency_key, amount_minor, currency, status, seller_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (idempotency_key) DO NOTHING`,
+		refund.PaymentId, refund.IdempotencyKey, refund.Amount.Amount, refund.Amount.Currency, models.RefundPending, refund.SellerId)
+	if err != nil {
+		return err
+	}
+	stored, err := scanRefund(r.db.QueryRowContext(ctx, `SELECT `+refundColumns+` FROM payment_refunds WHERE idempotency_key = $1`,
+		refund.IdempotencyKey))
+	if err != nil {
+		return err
+	}
+	*refund = *stored
+	return nil
+}
+
+// GetRefunds lists the refunds recorded for a payment, oldest first
+func (r *PaymentRepository) GetRefunds(ctx context.Context, paymentID string) ([]models.PaymentRefund, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+refundColumns+` FROM payment_refunds
+		WHERE payment_id = $1
+		ORDER BY created_at, id`, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refunds := []models.PaymentRefund{}
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, *refund)
+	}
+	return refunds, rows.Err()
+}
+
+// CompleteRefund records that the provider made a refund and moves the
+// payment's refunded total forward from fromRefunded, in one transaction.
+// It returns ErrPaymentChanged when a concurrent refund moved the total
+// instead; a webhook that already counted this refund is not a conflict.
+func (r *PaymentRepository) CompleteRefund(ctx context.Context, refund *models.PaymentRefund, fromRefunded, toRefunded int64, status string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	ctx, tx, err := r.db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE payments SET refunded_minor = $1, status = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND refunded_minor = $4`, toRefunded, status, refund.PaymentId, fromRefunded)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.New("failed to get rows affected")
+	}
+	if rowsAffected == 0 {
+		var refunded int64
+		if err := tx.QueryRowContext(ctx, `SELECT refunded_minor FROM payments WHERE id = $1`, refund.PaymentId).Scan(&refunded); err != nil {
+			return err
+		}
+		if refunded < toRefunded {
+			return ErrPaymentChanged
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE payment_refunds SET status = $1, provider_ref = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3`, models.RefundSucceeded, refund.ProviderRef, refund.Id)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	refund.Status = models.RefundSucceeded
+	return nil
+}
+
+// GetEvents lists the webhook events applied to a payment, oldest first
+func (r *PaymentRepository) GetEvents(ctx context.Context, paymentID string) ([]models.PaymentEvent, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT provider, event_id, event_type, payment_id, from_status, to_status, received_at
+		FROM payment_events
+		WHERE payment_id = $1
+		ORDER BY received_at`
+	rows, err := r.db.QueryContext(ctx, query, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.PaymentEvent{}
+	for rows.Next() {
+		var event models.PaymentEvent
+		var id sql.NullString
+		if err := rows.Scan(&event.Provider, &event.EventId, &event.EventType, &id, &event.FromStatus, &event.ToStatus, &event.ReceivedAt); err != nil {
+			return nil, err
+		}
+		if id.Valid {
+			event.PaymentId = &id.String
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"primeauction/api/models"
+)
+
+// Errors the shipping service passes on to its callers
+var (
+	ErrProfileInUse     = errors.New("shipping profile is still used by items")
+	ErrShipmentNotFound = errors.New("shipment not found")
+	ErrProfileNotFound  = errors.New("shipping profile not found")
+)
+
+type ShippingRepository struct {
+	db conn
+}
+
+func NewShippingRepository(db *sql.DB) *ShippingRepository {
+	return &ShippingRepository{db: conn{db}}
+}
+
+const shippingProfileColumns = `id, seller_id, name, type, currency, flat_minor, rates, free_over_minor, excluded_regions, pickup_location,
+	created_at, updated_at`
+
+func scanShippingProfile(row interface{ Scan(...any) error }) (*models.ShippingProfile, error) {
+	var profile models.ShippingProfile
+	var flat, freeOver sql.NullInt64
+	var rates, excluded []byte
+	err := row.Scan(&profile.Id, &profile.SellerId, &profile.Name, &profile.Type, &profile.Currency, &flat, &rates, &freeOver,
+		&excluded, &profile.PickupLocation, &profile.CreatedAt, &profile.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if flat.Valid {
+		m := models.NewMoney(flat.Int64, profile.Currency)
+		profile.Flat = &m
+	}
+	if freeOver.Valid {
+		m := models.NewMoney(freeOver.Int64, profile.Currency)
+		profile.FreeOver = &m
+	}
+	if err := json.Unmarshal(rates, &profile.Rates); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(excluded, &profile.ExcludedRegions); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// profileArgs returns the stored form of a profile's amounts, rates and
+// excluded regions
+func profileArgs(profile *models.ShippingProfile) (flat, freeOver *int64, rates, excluded []byte, err error) {
+	if profile.Flat != nil {
+		flat = &profile.Flat.Amount
+	}
+	if profile.FreeOver != nil {
+		freeOver = &profile.FreeOver.Amount
+	}
+	if profile.Rates == nil {
+		profile.Rates = []models.ShippingRate{}
+	}
+	if profile.ExcludedRegions == nil {
+		profile.ExcludedRegions = []string{}
+	}
+	if rates, err = json.Marshal(profile.Rates); err != nil {
+		return
+	}
+	excluded, err = json.Marshal(profile.ExcludedRegions)
+	return
+}
+
+// CreateProfile stores a new shipping profile
+func (r *ShippingRepository) CreateProfile(ctx context.Context, profile *models.ShippingProfile) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	flat, freeOver, rates, excluded, err := profileArgs(profile)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO shipping_profiles (seller_id, name, type, currency, flat_minor, rates, free_over_minor, excluded_regions,
+			pickup_location)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at`
+	return r.db.QueryRowContext(ctx, query, profile.SellerId, profile.Name, profile.Type, profile.Currency, flat, rates, freeOver, excluded,
+		profile.PickupLocation,
+	).Scan(&profile.Id, &profile.CreatedAt, &profile.UpdatedAt)
+}
+
+// UpdateProfile replaces a profile's settings. Orders already placed keep
+// the shipping cost they were quoted.
+func (r *ShippingRepository) UpdateProfile(ctx context.Context, profile *models.ShippingProfile) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	flat, freeOver, rates, excluded, err := profileArgs(profile)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE shipping_profiles
+		SET name = $1, type = $2, currency = $3, flat_minor = $4, rates = $5, free_over_minor = $6, excluded_regions = $7,
+			pickup_location = $8, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $9
+		RETURNING created_at, updated_at`
+	err = r.db.QueryRowContext(ctx, query, profile.Name, profile.Type, profile.Currency, flat, rates, freeOver, excluded,
+		profile.PickupLocation, profile.Id,
+	).Scan(&profile.CreatedAt, &profile.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrProfileNotFound
+	}
+	return err
+}
+
+// DeleteProfile removes a profile no item uses any more
+func (r *ShippingRepository) DeleteProfile(ctx context.Context, id string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	var inUse bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM items WHERE shipping_profile_id = $1)`, id).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrProfileInUse
+	}
+	result, err := r.db.ExecContext(ctx, `DELETE FROM shipping_profiles WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.New("failed to get rows affected")
+	}
+	if rowsAffected == 0 {
+		return ErrProfileNotFound
+	}
+	return nil
+}
+
+// GetProfile retrieves a shipping profile by ID
+func (r *ShippingRepository) GetProfile(ctx context.Context, id string) (*models.ShippingProfile, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + shippingProfileColumns + ` FROM shipping_profiles WHERE id = $1`
+	profile, err := scanShippingProfile(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrProfileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// GetProfilesBySeller lists a seller's shipping profiles by name
+func (r *ShippingRepository) GetProfilesBySeller(ctx context.Context, sellerID string) ([]models.ShippingProfile, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + shippingProfileColumns + ` FROM shipping_profiles WHERE seller_id = $1 ORDER BY name`
+	rows, err := r.db.QueryContext(ctx, query, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profiles := []models.ShippingProfile{}
+	for rows.Next() {
+		profile, err := scanShippingProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, *profile)
+	}
+	return profiles, rows.Err()
+}
+
+const shipmentColumns = `id, order_id, seller_id, profile_id, method, weight_grams, cost_minor, currency, status, carrier,
+	tracking_number, pickup_location, shipped_at, delivered_at, created_at, updated_at`
+
+func scanShipment(row interface{ Scan(...any) error }) (*models.Shipment, error) {
+	var shipment models.Shipment
+	err := row.Scan(&shipment.Id, &shipment.OrderId, &shipment.SellerId, &shipment.ProfileId, &shipment.Method,
+		&shipment.WeightGrams, &shipment.Cost.Amount, &shipment.Cost.Currency, &shipment.Status, &shipment.Carrier,
+		&shipment.TrackingNumber, &shipment.PickupLocation, &shipment.ShippedAt, &shipment.DeliveredAt,
+		&shipment.CreatedAt, &shipment.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+// UpdateShipment locks a shipment of an order and lets apply change it,
+// given the order's status; the event apply returns, if any, is added to
+// the shipment's history
+func (r *ShippingRepository) UpdateShipment(ctx context.Context, orderID, shipmentID string, apply func(shipment *models.Shipment, orderStatus string) (*models.ShipmentEvent, error)) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	ctx, tx, err := r.db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	shipment, err := scanShipment(tx.QueryRowContext(ctx, `SELECT `+shipmentColumns+` FROM shipments
+		WHERE id = $1 AND order_id = $2
+		FOR UPDATE`, shipmentID, orderID))
+	if err == sql.ErrNoRows {
+		return ErrShipmentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	var orderStatus string
+	if err := tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = $1`, orderID).Scan(&orderStatus); err != nil {
+		return err
+	}
+
+	event, err := apply(shipment, orderStatus)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE shipments
+		SET status = $1, carrier = $2, tracking_number = $3, shipped_at = $4, delivered_at = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6`,
+		shipment.Status, shipment.Carrier, shipment.TrackingNumber, shipment.ShippedAt, shipment.DeliveredAt, shipment.Id)
+	if err != nil {
+		return err
+	}
+	if event != nil {
+		_, err = tx.ExecContext(ctx, `INSERT INTO shipment_events (shipment_id, status, note) VALUES ($1, $2, $3)`,
+			shipment.Id, event.Status, event.Note)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// insertShipment stores a shipment quoted at checkout
+func insertShipment(ctx context.Context, tx *Tx, shipment *models.Shipment) error {
+	return tx.QueryRowContext(ctx, `INSERT INTO shipments (order_id, seller_id, profile_id, method, weight_grams, cost_minor, currency, status,
+			pickup_location)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at`,
+		shipment.OrderId, shipment.SellerId, shipment.ProfileId, shipment.Method, shipment.WeightGrams, shipment.Cost.Amount,
+		shipment.Cost.Currency, shipment.Status, shipment.PickupLocation,
+	).Scan(&shipment.Id, &shipment.CreatedAt, &shipment.UpdatedAt)
+}
+
+// getShipments loads an order's shipments with their status history
+func getShipments(ctx context.Context, db conn, orderID string) ([]models.Shipment, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+shipmentColumns+` FROM shipments WHERE order_id = $1 ORDER BY seller_id, created_at, id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	shipments := []models.Shipment{}
+	index := map[string]int{}
+	for rows.Next() {
+		shipment, err := scanShipment(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[shipment.Id] = len(shipments)
+		shipments = append(shipments, *shipment)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(shipments) == 0 {
+		return shipments, nil
+	}
+
+	rows, err = db.QueryContext(ctx, `SELECT e.shipment_id, e.status, e.note, e.created_at
+		FROM shipment_events e
+		JOIN shipments s ON s.id = e.shipment_id
+		WHERE s.order_id = $1
+		ORDER BY e.created_at, e.id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var shipmentID string
+		var event models.ShipmentEvent
+		if err := rows.Scan(&shipmentID, &event.Status, &event.Note, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		shipment := &shipments[index[shipmentID]]
+		shipment.Events = append(shipment.Events, event)
+	}
+	return shipments, rows.Err()
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"primeauction/api/models"
+)
+
+type StorageRepository struct {
+	db conn
+}
+
+func NewStorageRepository(db *sql.DB) *StorageRepository {
+	return &StorageRepository{db: conn{db}}
+}
+
+// GetStorageUsage sums the image bytes attached to a user's items and the
+// bytes reserved by their pending resumable uploads. Usage is derived from
+// the rows themselves so deleting items or replacing images is reflected
+// without separate bookkeeping.
+func (r *StorageRepository) GetStorageUsage(ctx context.Context, userID string) (*models.StorageUsage, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT
+			COALESCE((SELECT SUM(ii.size_bytes) FROM item_images ii JOIN items i ON i.id = ii.item_id WHERE i.user_id = $1), 0),
+			(SELECT COUNT(*) FROM item_images ii JOIN items i ON i.id = ii.item_id WHERE i.user_id = $1),
+			COALESCE((SELECT SUM(size) FROM uploads WHERE user_id = $1), 0)`
+
+	usage := &models.StorageUsage{UserId: userID}
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&usage.ImageBytes, &usage.ImageCount, &usage.StagedBytes)
+	if err != nil {
+		return nil, err
+	}
+	usage.UsedBytes = usage.ImageBytes + usage.StagedBytes
+	return usage, nil
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"primeauction/api/models"
+)
+
+// Errors every UserStore reports the same way
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists   = errors.New("username or email is already taken")
+)
+
+// ItemStore keeps items. ItemRepository is the SQL implementation, for
+// Postgres and SQLite; the memory package has one for tests. Both pass the contract tests in
+// storetest.
+type ItemStore interface {
+	// CreateItem saves a new item and sets its Id, CreatedAt and UpdatedAt
+	CreateItem(ctx context.Context, item *models.Item) error
+	// GetItemById returns ErrItemNotFound for an unknown id
+	GetItemById(ctx context.Context, id string) (*models.Item, error)
+	// UpdateItem saves every field but UserId, CreatedAt, Quantity and
+	// IsSold, which checkout changes under a seller's edit. It sets those
+	// two and UpdatedAt to what is stored, and returns ErrItemNotFound for
+	// an unknown id.
+	UpdateItem(ctx context.Context, item *models.Item) error
+	// SetQuantity sets an item's stock; the item is sold when none is left.
+	// It returns ErrItemNotFound for an unknown id.
+	SetQuantity(ctx context.Context, id string, quantity int) error
+	// DeleteItem returns ErrItemNotFound for an unknown id
+	DeleteItem(ctx context.Context, id string) error
+	// GetAllItems returns every item, newest first
+	GetAllItems(ctx context.Context) ([]*models.Item, error)
+	// GetItemsByUserID returns a seller's items, newest first
+	GetItemsByUserID(ctx context.Context, userID string) ([]*models.Item, error)
+}
+
+// ItemImageStore keeps the images listed on items
+type ItemImageStore interface {
+	// CreateImages saves an item's images in slice order, setting their Id,
+	// ItemId, DisplayOrder and CreatedAt
+	CreateImages(ctx context.Context, itemID string, images []models.ItemImage) error
+	DeleteImagesByItemID(ctx context.Context, itemID string) error
+	// GetItemIDByImagePath returns "" when no item uses the image
+	GetItemIDByImagePath(ctx context.Context, imagePath string) (string, error)
+	// FindSimilarImages returns the images on other sellers' items whose
+	// perceptual hash is within maxDistance bits of hash, closest first
+	FindSimilarImages(ctx context.Context, excludeUserID string, hash int64, maxDistance int) ([]SimilarImage, error)
+}
+
+// ItemInviteStore keeps who may see private items
+type ItemInviteStore interface {
+	// AddInvite does nothing for a user already invited
+	AddInvite(ctx context.Context, itemID, userID string) error
+	RemoveInvite(ctx context.Context, itemID, userID string) error
+	IsInvited(ctx context.Context, itemID, userID string) (bool, error)
+	// GetInvitesByItemID returns the invites oldest first, and an empty
+	// slice rather than nil when there are none
+	GetInvitesByItemID(ctx context.Context, itemID string) ([]models.ItemInvite, error)
+}
+
+// ImageFlagStore records images suspected of being copied
+type ImageFlagStore interface {
+	// CreateFlag does nothing for a pair of images already flagged
+	CreateFlag(ctx context.Context, flag *models.ImageFlag) error
+}
+
+// ShippingProfileStore looks up sellers' shipping profiles
+type ShippingProfileStore interface {
+	// GetProfile returns ErrProfileNotFound for an unknown id
+	GetProfile(ctx context.Context, id string) (*models.ShippingProfile, error)
+}
+
+// UserStore keeps user accounts. UserRepository is the SQL implementation,
+// for Postgres and SQLite; the memory package has one for tests. Both pass the
+// contract tests in storetest.
+type UserStore interface {
+	// CreateUser saves a new user and sets its Id, CreatedAt and UpdatedAt.
+	// It returns ErrUserExists when the username or email is taken.
+	CreateUser(ctx context.Context, user *models.User) error
+	// GetUserByID and GetUserByEmail return ErrUserNotFound when there is
+	// no such user
+	GetUserByID(ctx context.Context, id string) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	// GetAllUsers returns every user's id, username, email and password
+	GetAllUsers(ctx context.Context) ([]models.User, error)
+	// UpdateUser replaces the username, email and password, and like
+	// CreateUser returns ErrUserExists when they clash with another user's.
+	// It and the methods below return ErrUserNotFound for an unknown id.
+	UpdateUser(ctx context.Context, id string, user *models.User) error
+	UpdatePreferredCurrency(ctx context.Context, id, currency string) error
+	SetAdmin(ctx context.Context, id string, isAdmin bool) error
+	UpdatePassword(ctx context.Context, id, hash string) error
+	DeleteUser(ctx context.Context, id string) error
+}
+
+var (
+	_ ItemStore            = (*ItemRepository)(nil)
+	_ ItemImageStore       = (*ItemImageRepository)(nil)
+	_ ItemInviteStore      = (*ItemInviteRepository)(nil)
+	_ ImageFlagStore       = (*ImageFlagRepository)(nil)
+	_ ShippingProfileStore = (*ShippingRepository)(nil)
+	_ UserStore            = (*UserRepository)(nil)
+)
+
+// sqlStateUniqueViolation is the SQLSTATE Postgres reports when a write
+// would duplicate a unique column, and the SQLite codes are what SQLite
+// reports for a unique column or primary key
+const (
+	sqlStateUniqueViolation    = "23505"
+	sqliteConstraintUnique     = 2067
+	sqliteConstraintPrimaryKey = 1555
+)
+
+func isUniqueViolation(err error) bool {
+	var sqlErr interface{ SQLState() string }
+	var sqliteErr interface{ Code() int }
+	return errors.As(err, &sqlErr) && sqlErr.SQLState() == sqlStateUniqueViolation ||
+		errors.As(err, &sqliteErr) && (sqliteErr.Code() == sqliteConstraintUnique || sqliteErr.Code() == sqliteConstraintPrimaryKey)
+}
+
+// sqlStateInvalidText is the SQLSTATE Postgres reports for a value that
+// can't be parsed as its column type, such as an id that isn't a UUID
+const sqlStateInvalidText = "22P02"
+
+// IsMalformedID reports whether err is the database rejecting a value as
+// unparseable, which for the ids in paths means nothing can have that id
+func IsMalformedID(err error) bool {
+	var sqlErr interface{ SQLState() string }
+	return errors.As(err, &sqlErr) && sqlErr.SQLState() == sqlStateInvalidText
+}
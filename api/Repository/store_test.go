@@ -0,0 +1,131 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	database "primeauction/api/Database"
+	repository "primeauction/api/Repository"
+	"primeauction/api/Repository/storetest"
+	"primeauction/api/models"
+
+	_ "github.com/lib/pq"
+)
+
+// testDB connects to the database TEST_DATABASE_URL names and migrates it.
+// The contract tests only add rows, so any scratch database will do.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("opening the test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.RunMigration(db); err != nil {
+		t.Fatalf("migrating the test database: %v", err)
+	}
+	return db
+}
+
+// sqliteDB creates a SQLite database in a temporary directory and migrates
+// it
+func sqliteDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := database.Open(database.SQLite, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening the test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.RunMigration(db); err != nil {
+		t.Fatalf("migrating the test database: %v", err)
+	}
+	return db
+}
+
+// databases are the databases the SQL repositories are tested against
+var databases = map[string]func(t *testing.T) *sql.DB{
+	"Postgres": testDB,
+	"SQLite":   sqliteDB,
+}
+
+func TestUserRepository(t *testing.T) {
+	for name, open := range databases {
+		t.Run(name, func(t *testing.T) {
+			users := repository.NewUserRepository(open(t))
+			storetest.UserStores(t, func(t *testing.T) repository.UserStore {
+				return users
+			})
+		})
+	}
+}
+
+func TestItemRepository(t *testing.T) {
+	for name, open := range databases {
+		t.Run(name, func(t *testing.T) {
+			db := open(t)
+			items, users := repository.NewItemRepository(db), repository.NewUserRepository(db)
+			storetest.ItemStores(t, func(t *testing.T) (repository.ItemStore, repository.UserStore) {
+				return items, users
+			})
+		})
+	}
+}
+
+func TestItemImageRepository(t *testing.T) {
+	for name, open := range databases {
+		t.Run(name, func(t *testing.T) {
+			db := open(t)
+			images, items, users := repository.NewItemImageRepository(db), repository.NewItemRepository(db), repository.NewUserRepository(db)
+			storetest.ItemImageStores(t, func(t *testing.T) (repository.ItemImageStore, repository.ItemStore, repository.UserStore) {
+				return images, items, users
+			})
+		})
+	}
+}
+
+func TestItemInviteRepository(t *testing.T) {
+	for name, open := range databases {
+		t.Run(name, func(t *testing.T) {
+			db := open(t)
+			invites, items, users := repository.NewItemInviteRepository(db), repository.NewItemRepository(db), repository.NewUserRepository(db)
+			storetest.ItemInviteStores(t, func(t *testing.T) (repository.ItemInviteStore, repository.ItemStore, repository.UserStore) {
+				return invites, items, users
+			})
+		})
+	}
+}
+
+func TestImageFlagRepository(t *testing.T) {
+	for name, open := range databases {
+		t.Run(name, func(t *testing.T) {
+			db := open(t)
+			flags := repository.NewImageFlagRepository(db)
+			list := func(ctx context.Context) ([]models.ImageFlag, error) { return flags.GetFlags(ctx, "") }
+			images, items, users := repository.NewItemImageRepository(db), repository.NewItemRepository(db), repository.NewUserRepository(db)
+			storetest.ImageFlagStores(t, func(t *testing.T) (repository.ImageFlagStore, func(ctx context.Context) ([]models.ImageFlag, error),
+				repository.ItemImageStore, repository.ItemStore, repository.UserStore) {
+				return flags, list, images, items, users
+			})
+		})
+	}
+}
+
+func TestShippingRepository(t *testing.T) {
+	for name, open := range databases {
+		t.Run(name, func(t *testing.T) {
+			db := open(t)
+			profiles, users := repository.NewShippingRepository(db), repository.NewUserRepository(db)
+			storetest.ShippingProfileStores(t, func(t *testing.T) (repository.ShippingProfileStore,
+				func(ctx context.Context, profile *models.ShippingProfile) error, repository.UserStore) {
+				return profiles, profiles.CreateProfile, users
+			})
+		})
+	}
+}
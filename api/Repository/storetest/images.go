@@ -0,0 +1,216 @@
+package storetest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"testing"
+
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+)
+
+// ItemImageStores runs the ItemImageStore contract against the stores
+// newStore builds. Images belong to items, which are kept in the ItemStore
+// that comes with it, and their sellers in the UserStore.
+func ItemImageStores(t *testing.T, newStore func(t *testing.T) (repository.ItemImageStore, repository.ItemStore, repository.UserStore)) {
+	ctx := context.Background()
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		images, items, users := newStore(t)
+		item := newItem(t, users)
+		item.Image = unique("images/primary") + ".jpg"
+		mustCreateItem(t, items, item)
+
+		created := mustCreateImages(t, images, item.Id, 0, 0)
+		seen := map[string]bool{}
+		for i, img := range created {
+			if img.Id == "" || seen[img.Id] || img.ItemId != item.Id || img.DisplayOrder != i || img.CreatedAt.IsZero() {
+				t.Errorf("CreateImages left image %d as %+v", i, img)
+			}
+			seen[img.Id] = true
+		}
+
+		// The item's primary image is found as well as its listed ones
+		for _, path := range []string{created[0].ImagePath, created[1].ImagePath, item.Image} {
+			if got := mustGetItemIDByImagePath(t, images, path); got != item.Id {
+				t.Errorf("GetItemIDByImagePath(%s) = %q, want %q", path, got, item.Id)
+			}
+		}
+		if got := mustGetItemIDByImagePath(t, images, unique("images/unknown")+".jpg"); got != "" {
+			t.Errorf("GetItemIDByImagePath of an unknown image = %q, want none", got)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		images, items, users := newStore(t)
+		item, other := newItem(t, users), newItem(t, users)
+		mustCreateItem(t, items, item)
+		mustCreateItem(t, items, other)
+		deleted := mustCreateImages(t, images, item.Id, 0, 0)
+		kept := mustCreateImages(t, images, other.Id, 0, 0)
+
+		if err := images.DeleteImagesByItemID(ctx, item.Id); err != nil {
+			t.Fatalf("DeleteImagesByItemID: %v", err)
+		}
+		if got := mustGetItemIDByImagePath(t, images, deleted[0].ImagePath); got != "" {
+			t.Errorf("a deleted image still belongs to item %q", got)
+		}
+		if got := mustGetItemIDByImagePath(t, images, kept[0].ImagePath); got != other.Id {
+			t.Errorf("another item's image belongs to %q, want %q", got, other.Id)
+		}
+	})
+
+	t.Run("FindSimilar", func(t *testing.T) {
+		images, items, users := newStore(t)
+		item, own := newItem(t, users), newItem(t, users)
+		mustCreateItem(t, items, item)
+		mustCreateItem(t, items, own)
+		hash := randomHash()
+		// Images 1, 3 and 8 bits away from the probe, and one without a hash
+		listed := mustCreateImages(t, images, item.Id, hash^0b111, hash^1, hash^0xff)
+		listed = append(listed, mustCreateImages(t, images, item.Id, 0)...)
+		owned := mustCreateImages(t, images, own.Id, hash)
+
+		matches, err := images.FindSimilarImages(ctx, own.UserId, hash, 3)
+		if err != nil {
+			t.Fatalf("FindSimilarImages: %v", err)
+		}
+		got := similarIn(matches, listed, owned)
+		want := []repository.SimilarImage{
+			{ImageID: listed[1].Id, ItemID: item.Id, Distance: 1},
+			{ImageID: listed[0].Id, ItemID: item.Id, Distance: 3},
+		}
+		if !sameSimilar(got, want) {
+			t.Errorf("FindSimilarImages = %+v, want %+v", got, want)
+		}
+
+		// The seller's own images are left out, not anyone else's
+		matches, err = images.FindSimilarImages(ctx, item.UserId, hash, 0)
+		if err != nil {
+			t.Fatalf("FindSimilarImages: %v", err)
+		}
+		got = similarIn(matches, listed, owned)
+		want = []repository.SimilarImage{{ImageID: owned[0].Id, ItemID: own.Id, Distance: 0}}
+		if !sameSimilar(got, want) {
+			t.Errorf("FindSimilarImages excluding the other seller = %+v, want %+v", got, want)
+		}
+	})
+}
+
+// ImageFlagStores runs the ImageFlagStore contract against the stores
+// newStore builds. list returns every flag recorded; flagged images are
+// created in the stores that come with it.
+func ImageFlagStores(t *testing.T, newStore func(t *testing.T) (flags repository.ImageFlagStore, list func(ctx context.Context) ([]models.ImageFlag, error),
+	images repository.ItemImageStore, items repository.ItemStore, users repository.UserStore)) {
+	ctx := context.Background()
+
+	t.Run("CreateOnce", func(t *testing.T) {
+		flags, list, images, items, users := newStore(t)
+		item, copied := newItem(t, users), newItem(t, users)
+		mustCreateItem(t, items, item)
+		mustCreateItem(t, items, copied)
+		original := mustCreateImages(t, images, item.Id, 0)[0]
+		duplicate := mustCreateImages(t, images, copied.Id, 0)[0]
+
+		flag := models.ImageFlag{ItemId: copied.Id, ImageId: duplicate.Id, MatchedItemId: item.Id, MatchedImageId: original.Id,
+			Distance: 2, Status: models.FlagPending}
+		for range 2 {
+			if err := flags.CreateFlag(ctx, &flag); err != nil {
+				t.Fatalf("CreateFlag: %v", err)
+			}
+		}
+		// The same images the other way round are another pair
+		reverse := models.ImageFlag{ItemId: item.Id, ImageId: original.Id, MatchedItemId: copied.Id, MatchedImageId: duplicate.Id,
+			Distance: 2, Status: models.FlagPending}
+		if err := flags.CreateFlag(ctx, &reverse); err != nil {
+			t.Fatalf("CreateFlag: %v", err)
+		}
+
+		all, err := list(ctx)
+		if err != nil {
+			t.Fatalf("listing flags: %v", err)
+		}
+		var got []models.ImageFlag
+		for _, f := range all {
+			if f.ImageId == duplicate.Id || f.ImageId == original.Id {
+				got = append(got, f)
+			}
+		}
+		if len(got) != 2 {
+			t.Fatalf("recorded %d flags for the pair, want one each way: %+v", len(got), got)
+		}
+		for _, f := range got {
+			want := flag
+			if f.ImageId == original.Id {
+				want = reverse
+			}
+			if f.Id == "" || f.CreatedAt.IsZero() || f.ItemId != want.ItemId || f.MatchedItemId != want.MatchedItemId ||
+				f.MatchedImageId != want.MatchedImageId || f.Distance != want.Distance || f.Status != want.Status {
+				t.Errorf("recorded flag %+v, want %+v", f, want)
+			}
+		}
+	})
+}
+
+// mustCreateImages lists an image on an item for each hash, with no hash
+// for 0, and returns them as stored
+func mustCreateImages(t *testing.T, images repository.ItemImageStore, itemID string, hashes ...int64) []models.ItemImage {
+	t.Helper()
+	list := make([]models.ItemImage, len(hashes))
+	for i, hash := range hashes {
+		list[i] = models.ItemImage{ImagePath: unique("images/item") + ".jpg", SizeBytes: 1024}
+		if hash != 0 {
+			list[i].PHash = &hash
+		}
+	}
+	if err := images.CreateImages(context.Background(), itemID, list); err != nil {
+		t.Fatalf("CreateImages: %v", err)
+	}
+	return list
+}
+
+func mustGetItemIDByImagePath(t *testing.T, images repository.ItemImageStore, path string) string {
+	t.Helper()
+	itemID, err := images.GetItemIDByImagePath(context.Background(), path)
+	if err != nil {
+		t.Fatalf("GetItemIDByImagePath: %v", err)
+	}
+	return itemID
+}
+
+// randomHash returns a perceptual hash no other test's images are near
+func randomHash() int64 {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return int64(binary.LittleEndian.Uint64(b))
+}
+
+// similarIn keeps the matches that are among the given images, in order
+func similarIn(matches []repository.SimilarImage, lists ...[]models.ItemImage) []repository.SimilarImage {
+	ours := map[string]bool{}
+	for _, list := range lists {
+		for _, img := range list {
+			ours[img.Id] = true
+		}
+	}
+	var kept []repository.SimilarImage
+	for _, m := range matches {
+		if ours[m.ImageID] {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+func sameSimilar(a, b []repository.SimilarImage) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
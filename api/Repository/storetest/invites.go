@@ -0,0 +1,72 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	repository "primeauction/api/Repository"
+)
+
+// ItemInviteStores runs the ItemInviteStore contract against the stores
+// newStore builds. Invites are to items kept in the ItemStore that comes
+// with it, for users kept in the UserStore.
+func ItemInviteStores(t *testing.T, newStore func(t *testing.T) (repository.ItemInviteStore, repository.ItemStore, repository.UserStore)) {
+	ctx := context.Background()
+
+	t.Run("AddAndRemove", func(t *testing.T) {
+		invites, items, users := newStore(t)
+		item, other := newItem(t, users), newItem(t, users)
+		mustCreateItem(t, items, item)
+		mustCreateItem(t, items, other)
+		guest, stranger := newUser(), newUser()
+		mustCreateUser(t, users, guest)
+		mustCreateUser(t, users, stranger)
+
+		got, err := invites.GetInvitesByItemID(ctx, item.Id)
+		if err != nil {
+			t.Fatalf("GetInvitesByItemID: %v", err)
+		}
+		if got == nil || len(got) != 0 {
+			t.Errorf("GetInvitesByItemID before any invites = %#v, want an empty slice", got)
+		}
+
+		// Inviting a user again changes nothing
+		for range 2 {
+			if err := invites.AddInvite(ctx, item.Id, guest.Id); err != nil {
+				t.Fatalf("AddInvite: %v", err)
+			}
+		}
+		if err := invites.AddInvite(ctx, other.Id, stranger.Id); err != nil {
+			t.Fatalf("AddInvite: %v", err)
+		}
+		got, err = invites.GetInvitesByItemID(ctx, item.Id)
+		if err != nil {
+			t.Fatalf("GetInvitesByItemID: %v", err)
+		}
+		if len(got) != 1 || got[0].ItemId != item.Id || got[0].UserId != guest.Id || got[0].CreatedAt.IsZero() {
+			t.Errorf("GetInvitesByItemID = %+v, want the one invite", got)
+		}
+		wantInvited(t, invites, item.Id, guest.Id, true)
+		wantInvited(t, invites, item.Id, stranger.Id, false)
+
+		if err := invites.RemoveInvite(ctx, item.Id, guest.Id); err != nil {
+			t.Fatalf("RemoveInvite: %v", err)
+		}
+		if err := invites.RemoveInvite(ctx, item.Id, guest.Id); err != nil {
+			t.Errorf("removing an invite that is gone: %v", err)
+		}
+		wantInvited(t, invites, item.Id, guest.Id, false)
+		wantInvited(t, invites, other.Id, stranger.Id, true)
+	})
+}
+
+func wantInvited(t *testing.T, invites repository.ItemInviteStore, itemID, userID string, want bool) {
+	t.Helper()
+	invited, err := invites.IsInvited(context.Background(), itemID, userID)
+	if err != nil {
+		t.Fatalf("IsInvited: %v", err)
+	}
+	if invited != want {
+		t.Errorf("IsInvited = %v, want %v", invited, want)
+	}
+}
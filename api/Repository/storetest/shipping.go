@@ -0,0 +1,59 @@
+package storetest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+)
+
+// ShippingProfileStores runs the ShippingProfileStore contract against the
+// stores newStore builds. add saves a profile the way the implementation
+// is filled, setting its Id and timestamps; profiles belong to sellers kept
+// in the UserStore that comes with it.
+func ShippingProfileStores(t *testing.T, newStore func(t *testing.T) (profiles repository.ShippingProfileStore,
+	add func(ctx context.Context, profile *models.ShippingProfile) error, users repository.UserStore)) {
+	ctx := context.Background()
+
+	t.Run("Get", func(t *testing.T) {
+		profiles, add, users := newStore(t)
+		seller := newUser()
+		mustCreateUser(t, users, seller)
+		upTo := int64(2000)
+		freeOver := models.NewMoney(10000, "EUR")
+		profile := &models.ShippingProfile{
+			SellerId: seller.Id,
+			Name:     unique("profile"),
+			Type:     models.ShippingByWeight,
+			Currency: "EUR",
+			Rates: []models.ShippingRate{
+				{UpToGrams: &upTo, Cost: models.NewMoney(450, "EUR")},
+				{Cost: models.NewMoney(990, "EUR")},
+			},
+			FreeOver:        &freeOver,
+			ExcludedRegions: []string{"FR-20", "GB"},
+		}
+		if err := add(ctx, profile); err != nil {
+			t.Fatalf("adding a profile: %v", err)
+		}
+		if profile.Id == "" || profile.CreatedAt.IsZero() || profile.UpdatedAt.IsZero() {
+			t.Fatalf("adding a profile left id or timestamps unset: %+v", profile)
+		}
+
+		got, err := profiles.GetProfile(ctx, profile.Id)
+		if err != nil {
+			t.Fatalf("GetProfile: %v", err)
+		}
+		if got.Id != profile.Id || got.SellerId != profile.SellerId || got.Name != profile.Name || got.Type != profile.Type ||
+			got.Currency != profile.Currency || got.Flat != nil || got.FreeOver == nil || *got.FreeOver != freeOver ||
+			!reflect.DeepEqual(got.Rates, profile.Rates) || !reflect.DeepEqual(got.ExcludedRegions, profile.ExcludedRegions) ||
+			!got.CreatedAt.Equal(profile.CreatedAt) {
+			t.Errorf("GetProfile = %+v, want %+v", got, profile)
+		}
+
+		_, err = profiles.GetProfile(ctx, newID())
+		wantErr(t, "GetProfile", err, repository.ErrProfileNotFound)
+	})
+}
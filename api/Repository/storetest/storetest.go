@@ -0,0 +1,485 @@
+// Package storetest holds the contract every implementation of the
+// repository store interfaces must meet. Each implementation's tests call
+// these with a way to build the store under test, so the in-memory stores
+// tests rely on are held to exactly what Postgres does.
+//
+// The tests only add data, named uniquely, and never assume a store starts
+// out empty; against a real database they can share one that is kept.
+package storetest
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+)
+
+// UserStores runs the UserStore contract against the stores newStore builds
+func UserStores(t *testing.T, newStore func(t *testing.T) repository.UserStore) {
+	ctx := context.Background()
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		users := newStore(t)
+		user := newUser()
+		password := user.Password
+		mustCreateUser(t, users, user)
+		if user.Id == "" || user.CreatedAt.IsZero() || user.UpdatedAt.IsZero() {
+			t.Fatalf("CreateUser left id or timestamps unset: %+v", user)
+		}
+
+		for name, get := range map[string]func() (*models.User, error){
+			"GetUserByID":    func() (*models.User, error) { return users.GetUserByID(ctx, user.Id) },
+			"GetUserByEmail": func() (*models.User, error) { return users.GetUserByEmail(ctx, user.Email) },
+		} {
+			got, err := get()
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if got.Id != user.Id || got.Username != user.Username || got.Email != user.Email ||
+				got.Password != password || got.IsAdmin || got.PreferredCurrency != "" || got.CreatedAt.IsZero() {
+				t.Errorf("%s = %+v, want the user created as %+v", name, got, user)
+			}
+		}
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		users := newStore(t)
+		user := newUser()
+		user.IsAdmin = true
+		mustCreateUser(t, users, user)
+		if got := mustGetUser(t, users, user.Id); !got.IsAdmin {
+			t.Error("IsAdmin was not saved")
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		users := newStore(t)
+		id := newID()
+		_, err := users.GetUserByID(ctx, id)
+		wantErr(t, "GetUserByID", err, repository.ErrUserNotFound)
+		_, err = users.GetUserByEmail(ctx, unique("nobody")+"@example.com")
+		wantErr(t, "GetUserByEmail", err, repository.ErrUserNotFound)
+		wantErr(t, "UpdateUser", users.UpdateUser(ctx, id, newUser()), repository.ErrUserNotFound)
+		wantErr(t, "UpdatePreferredCurrency", users.UpdatePreferredCurrency(ctx, id, "EUR"), repository.ErrUserNotFound)
+		wantErr(t, "SetAdmin", users.SetAdmin(ctx, id, true), repository.ErrUserNotFound)
+		wantErr(t, "UpdatePassword", users.UpdatePassword(ctx, id, "hash"), repository.ErrUserNotFound)
+		wantErr(t, "DeleteUser", users.DeleteUser(ctx, id), repository.ErrUserNotFound)
+	})
+
+	t.Run("Taken", func(t *testing.T) {
+		users := newStore(t)
+		user := newUser()
+		mustCreateUser(t, users, user)
+
+		sameEmail := newUser()
+		sameEmail.Email = user.Email
+		wantErr(t, "CreateUser with a taken email", users.CreateUser(ctx, sameEmail), repository.ErrUserExists)
+		sameName := newUser()
+		sameName.Username = user.Username
+		wantErr(t, "CreateUser with a taken username", users.CreateUser(ctx, sameName), repository.ErrUserExists)
+
+		other := newUser()
+		mustCreateUser(t, users, other)
+		other.Email = user.Email
+		wantErr(t, "UpdateUser to a taken email", users.UpdateUser(ctx, other.Id, other), repository.ErrUserExists)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		users := newStore(t)
+		user := newUser()
+		mustCreateUser(t, users, user)
+
+		changed := newUser()
+		if err := users.UpdateUser(ctx, user.Id, changed); err != nil {
+			t.Fatalf("UpdateUser: %v", err)
+		}
+		if err := users.UpdatePreferredCurrency(ctx, user.Id, "EUR"); err != nil {
+			t.Fatalf("UpdatePreferredCurrency: %v", err)
+		}
+		if err := users.SetAdmin(ctx, user.Id, true); err != nil {
+			t.Fatalf("SetAdmin: %v", err)
+		}
+		got := mustGetUser(t, users, user.Id)
+		if got.Username != changed.Username || got.Email != changed.Email || got.Password != changed.Password ||
+			got.PreferredCurrency != "EUR" || !got.IsAdmin {
+			t.Errorf("after updates got %+v, want the details of %+v, EUR and admin", got, changed)
+		}
+		if got.UpdatedAt.Before(got.CreatedAt) {
+			t.Errorf("UpdatedAt %v is before CreatedAt %v", got.UpdatedAt, got.CreatedAt)
+		}
+
+		if err := users.UpdatePassword(ctx, user.Id, "new hash"); err != nil {
+			t.Fatalf("UpdatePassword: %v", err)
+		}
+		if err := users.SetAdmin(ctx, user.Id, false); err != nil {
+			t.Fatalf("SetAdmin: %v", err)
+		}
+		got = mustGetUser(t, users, user.Id)
+		if got.Password != "new hash" || got.IsAdmin {
+			t.Errorf("got password %q and admin %v, want the new hash and no admin", got.Password, got.IsAdmin)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		users := newStore(t)
+		user := newUser()
+		mustCreateUser(t, users, user)
+		if err := users.DeleteUser(ctx, user.Id); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+		_, err := users.GetUserByID(ctx, user.Id)
+		wantErr(t, "GetUserByID after DeleteUser", err, repository.ErrUserNotFound)
+		wantErr(t, "DeleteUser again", users.DeleteUser(ctx, user.Id), repository.ErrUserNotFound)
+	})
+
+	t.Run("GetAllUsers", func(t *testing.T) {
+		users := newStore(t)
+		a, b := newUser(), newUser()
+		mustCreateUser(t, users, a)
+		mustCreateUser(t, users, b)
+		all, err := users.GetAllUsers(ctx)
+		if err != nil {
+			t.Fatalf("GetAllUsers: %v", err)
+		}
+		found := 0
+		for _, u := range all {
+			if u.Id == a.Id && u.Email == a.Email || u.Id == b.Id && u.Email == b.Email {
+				found++
+			}
+		}
+		if found != 2 {
+			t.Errorf("GetAllUsers returned %d of the 2 users created", found)
+		}
+	})
+
+	t.Run("Copies", func(t *testing.T) {
+		users := newStore(t)
+		user := newUser()
+		mustCreateUser(t, users, user)
+		email := user.Email
+		user.Email = unique("changed") + "@example.com"
+		got := mustGetUser(t, users, user.Id)
+		got.Username = "changed"
+		if got = mustGetUser(t, users, user.Id); got.Email != email || got.Username == "changed" {
+			t.Errorf("changing users outside the store changed it: got %+v", got)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		users := newStore(t)
+		const n = 20
+		created := make([]*models.User, n)
+		errs := make([]error, n)
+		var wg sync.WaitGroup
+		for i := range created {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				created[i] = newUser()
+				errs[i] = users.CreateUser(ctx, created[i])
+			}()
+		}
+		wg.Wait()
+		ids := make(map[string]bool)
+		for i, user := range created {
+			if errs[i] != nil {
+				t.Fatalf("CreateUser: %v", errs[i])
+			}
+			ids[user.Id] = true
+		}
+		if len(ids) != n {
+			t.Errorf("%d users created at once got %d distinct ids", n, len(ids))
+		}
+	})
+}
+
+// ItemStores runs the ItemStore contract against the stores newStore builds.
+// Items need a seller, who is created in the UserStore that comes with it.
+func ItemStores(t *testing.T, newStore func(t *testing.T) (repository.ItemStore, repository.UserStore)) {
+	ctx := context.Background()
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		items, users := newStore(t)
+		item := newItem(t, users)
+		mustCreateItem(t, items, item)
+		if item.Id == "" || item.CreatedAt.IsZero() || item.UpdatedAt.IsZero() {
+			t.Fatalf("CreateItem left id or timestamps unset: %+v", item)
+		}
+
+		got := mustGetItem(t, items, item.Id)
+		if !sameItem(got, item) {
+			t.Errorf("GetItemById = %+v, want %+v", got, item)
+		}
+		if got.SellingPrice.Currency != got.Price.Currency {
+			t.Errorf("selling price in %q, price in %q; want the same currency", got.SellingPrice.Currency, got.Price.Currency)
+		}
+		if got.ShippingProfileId != nil || len(got.Images) != 0 {
+			t.Errorf("got shipping profile %v and %d images, want neither", got.ShippingProfileId, len(got.Images))
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		items, users := newStore(t)
+		id := newID()
+		_, err := items.GetItemById(ctx, id)
+		wantErr(t, "GetItemById", err, repository.ErrItemNotFound)
+		item := newItem(t, users)
+		item.Id = id
+		wantErr(t, "UpdateItem", items.UpdateItem(ctx, item), repository.ErrItemNotFound)
+		wantErr(t, "DeleteItem", items.DeleteItem(ctx, id), repository.ErrItemNotFound)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		items, users := newStore(t)
+		item := newItem(t, users)
+		mustCreateItem(t, items, item)
+		seller := item.UserId
+
+		item.Name = "Renamed"
+		item.Description = "Now with a case"
+		item.Price = models.Money{Amount: 2500, Currency: "EUR"}
+		item.SellingPrice = models.Money{Amount: 2000, Currency: "EUR"}
+		item.Quantity = 1  // nor stock, which checkout changes
+		item.IsSold = true // under the seller's edit
+		item.Visibility = models.VisibilityPrivate
+		item.Category = "books"
+		item.WeightGrams, item.LengthCm, item.WidthCm, item.HeightCm = 900, 30, 20, 5
+		item.UserId = newID() // not something UpdateItem changes
+		if err := items.UpdateItem(ctx, item); err != nil {
+			t.Fatalf("UpdateItem: %v", err)
+		}
+
+		if item.Quantity != 3 || item.IsSold {
+			t.Errorf("UpdateItem left quantity %d and sold %v on the item, want the stored 3 and false", item.Quantity, item.IsSold)
+		}
+
+		got := mustGetItem(t, items, item.Id)
+		item.UserId = seller
+		if !sameItem(got, item) {
+			t.Errorf("after UpdateItem got %+v, want %+v", got, item)
+		}
+		if got.UpdatedAt.Before(got.CreatedAt) {
+			t.Errorf("UpdatedAt %v is before CreatedAt %v", got.UpdatedAt, got.CreatedAt)
+		}
+	})
+
+	t.Run("SetQuantity", func(t *testing.T) {
+		items, users := newStore(t)
+		item := newItem(t, users)
+		mustCreateItem(t, items, item)
+
+		if err := items.SetQuantity(ctx, item.Id, 0); err != nil {
+			t.Fatalf("SetQuantity: %v", err)
+		}
+		if got := mustGetItem(t, items, item.Id); got.Quantity != 0 || !got.IsSold {
+			t.Errorf("after SetQuantity 0 got quantity %d and sold %v, want 0 and sold", got.Quantity, got.IsSold)
+		}
+		if err := items.SetQuantity(ctx, item.Id, 5); err != nil {
+			t.Fatalf("SetQuantity: %v", err)
+		}
+		if got := mustGetItem(t, items, item.Id); got.Quantity != 5 || got.IsSold {
+			t.Errorf("after SetQuantity 5 got quantity %d and sold %v, want 5 and not sold", got.Quantity, got.IsSold)
+		}
+		wantErr(t, "SetQuantity", items.SetQuantity(ctx, newID(), 1), repository.ErrItemNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		items, users := newStore(t)
+		item := newItem(t, users)
+		mustCreateItem(t, items, item)
+		if err := items.DeleteItem(ctx, item.Id); err != nil {
+			t.Fatalf("DeleteItem: %v", err)
+		}
+		_, err := items.GetItemById(ctx, item.Id)
+		wantErr(t, "GetItemById after DeleteItem", err, repository.ErrItemNotFound)
+		wantErr(t, "DeleteItem again", items.DeleteItem(ctx, item.Id), repository.ErrItemNotFound)
+	})
+
+	t.Run("Lists", func(t *testing.T) {
+		items, users := newStore(t)
+		older := newItem(t, users)
+		mustCreateItem(t, items, older)
+		newer := newItem(t, users)
+		newer.UserId = older.UserId
+		mustCreateItem(t, items, newer)
+		other := newItem(t, users)
+		mustCreateItem(t, items, other)
+
+		byUser, err := items.GetItemsByUserID(ctx, older.UserId)
+		if err != nil {
+			t.Fatalf("GetItemsByUserID: %v", err)
+		}
+		if got := ids(byUser); len(got) != 2 || got[0] != newer.Id || got[1] != older.Id {
+			t.Errorf("GetItemsByUserID = %v, want [%s %s]", got, newer.Id, older.Id)
+		}
+
+		all, err := items.GetAllItems(ctx)
+		if err != nil {
+			t.Fatalf("GetAllItems: %v", err)
+		}
+		want := []string{other.Id, newer.Id, older.Id}
+		var got []string
+		for _, id := range ids(all) {
+			if id == older.Id || id == newer.Id || id == other.Id {
+				got = append(got, id)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("GetAllItems listed the items created as %v, want newest first: %v", got, want)
+		}
+
+		none, err := items.GetItemsByUserID(ctx, newID())
+		if err != nil || len(none) != 0 {
+			t.Errorf("GetItemsByUserID for a user without items = %v, %v; want nothing", none, err)
+		}
+	})
+
+	t.Run("Copies", func(t *testing.T) {
+		items, users := newStore(t)
+		item := newItem(t, users)
+		mustCreateItem(t, items, item)
+		item.Name = "Changed after saving"
+		got := mustGetItem(t, items, item.Id)
+		got.Description = "Changed after loading"
+		if got = mustGetItem(t, items, item.Id); got.Name == item.Name || got.Description == "Changed after loading" {
+			t.Errorf("changing items outside the store changed it: got %+v", got)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		items, users := newStore(t)
+		seller := newItem(t, users).UserId
+		const n = 20
+		created := make([]*models.Item, n)
+		for i := range created {
+			created[i] = newItem(t, users)
+			created[i].UserId = seller
+		}
+		var wg sync.WaitGroup
+		errs := make([]error, n)
+		for i, item := range created {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if errs[i] = items.CreateItem(ctx, item); errs[i] != nil {
+					return
+				}
+				item.Name += " (edited)"
+				errs[i] = items.UpdateItem(ctx, item)
+			}()
+		}
+		wg.Wait()
+		for _, err := range errs {
+			if err != nil {
+				t.Fatalf("creating and updating items at once: %v", err)
+			}
+		}
+		byUser, err := items.GetItemsByUserID(ctx, seller)
+		if err != nil {
+			t.Fatalf("GetItemsByUserID: %v", err)
+		}
+		if len(byUser) != n {
+			t.Errorf("GetItemsByUserID returned %d items, want %d", len(byUser), n)
+		}
+	})
+}
+
+// unique returns prefix with a random suffix, for names that must not clash
+// with those of other tests or earlier runs
+func unique(prefix string) string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%x", prefix, b)
+}
+
+// newID returns a random UUID that no stored row has
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func newUser() *models.User {
+	name := unique("user")
+	return &models.User{Username: name, Email: name + "@example.com", Password: unique("hash")}
+}
+
+// newItem returns an unsaved item listed by a new seller
+func newItem(t *testing.T, users repository.UserStore) *models.Item {
+	t.Helper()
+	seller := newUser()
+	mustCreateUser(t, users, seller)
+	return &models.Item{
+		UserId:       seller.Id,
+		Name:         unique("item"),
+		Description:  "A test item",
+		Category:     "electronics",
+		Price:        models.Money{Amount: 1999, Currency: "USD"},
+		SellingPrice: models.Money{Amount: 1499, Currency: "USD"},
+		Quantity:     3,
+		Visibility:   models.VisibilityPublic,
+		WeightGrams:  250,
+	}
+}
+
+func mustCreateUser(t *testing.T, users repository.UserStore, user *models.User) {
+	t.Helper()
+	if err := users.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+}
+
+func mustGetUser(t *testing.T, users repository.UserStore, id string) *models.User {
+	t.Helper()
+	user, err := users.GetUserByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	return user
+}
+
+func mustCreateItem(t *testing.T, items repository.ItemStore, item *models.Item) {
+	t.Helper()
+	if err := items.CreateItem(context.Background(), item); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+}
+
+func mustGetItem(t *testing.T, items repository.ItemStore, id string) *models.Item {
+	t.Helper()
+	item, err := items.GetItemById(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetItemById: %v", err)
+	}
+	return item
+}
+
+// sameItem compares the fields an item is saved with
+func sameItem(a, b *models.Item) bool {
+	return a.Id == b.Id && a.UserId == b.UserId && a.Name == b.Name && a.Description == b.Description &&
+		a.Category == b.Category && a.Price == b.Price && a.SellingPrice.Amount == b.SellingPrice.Amount &&
+		a.Image == b.Image && a.Quantity == b.Quantity && a.IsSold == b.IsSold && a.Visibility == b.Visibility &&
+		a.WeightGrams == b.WeightGrams && a.LengthCm == b.LengthCm && a.WidthCm == b.WidthCm && a.HeightCm == b.HeightCm
+}
+
+func ids(items []*models.Item) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.Id
+	}
+	return ids
+}
+
+func wantErr(t *testing.T, what string, err, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Errorf("%s: got error %v, want %v", what, err, want)
+	}
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"primeauction/api/models"
+)
+
+// ErrTaxRateNotFound is returned for a region and category without a rate
+var ErrTaxRateNotFound = errors.New("tax rate not found")
+
+type TaxRepository struct {
+	db conn
+}
+
+func NewTaxRepository(db *sql.DB) *TaxRepository {
+	return &TaxRepository{db: conn{db}}
+}
+
+func scanTaxRates(rows *sql.Rows) ([]models.TaxRate, error) {
+	defer rows.Close()
+	rates := []models.TaxRate{}
+	for rows.Next() {
+		var rate models.TaxRate
+		if err := rows.Scan(&rate.Region, &rate.Category, &rate.Name, &rate.RateBPS, &rate.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
+
+// GetRates lists every configured tax rate
+func (r *TaxRepository) GetRates(ctx context.Context) ([]models.TaxRate, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT region, category, name, rate_bps, updated_at FROM tax_rates ORDER BY region, category`)
+	if err != nil {
+		return nil, err
+	}
+	return scanTaxRates(rows)
+}
+
+// GetRatesForRegion lists the rates that can apply in a region: its own
+// and those of its country
+func (r *TaxRepository) GetRatesForRegion(ctx context.Context, region, country string) ([]models.TaxRate, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT region, category, name, rate_bps, updated_at FROM tax_rates
+		WHERE region = $1 OR region = $2
+		ORDER BY region, category`, region, country)
+	if err != nil {
+		return nil, err
+	}
+	return scanTaxRates(rows)
+}
+
+// SaveRate creates or replaces the rate for a region and category
+func (r *TaxRepository) SaveRate(ctx context.Context, rate *models.TaxRate) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO tax_rates (region, category, name, rate_bps)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (region, category) DO UPDATE
+		SET name = EXCLUDED.name, rate_bps = EXCLUDED.rate_bps, updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at`
+	return r.db.QueryRowContext(ctx, query, rate.Region, rate.Category, rate.Name, rate.RateBPS).Scan(&rate.UpdatedAt)
+}
+
+// DeleteRate removes the rate for a region and category
+func (r *TaxRepository) DeleteRate(ctx context.Context, region, category string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM tax_rates WHERE region = $1 AND category = $2`, region, category)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.New("failed to get rows affected")
+	}
+	if rowsAffected == 0 {
+		return ErrTaxRateNotFound
+	}
+	return nil
+}
+
+// GetTaxSettings returns a user's own tax region and whether their prices
+// as a seller include tax
+func (r *TaxRepository) GetTaxSettings(ctx context.Context, userID string) (*models.TaxSettings, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	var settings models.TaxSettings
+	var region sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT tax_region, prices_include_tax FROM users WHERE id = $1`, userID).
+		Scan(&region, &settings.PricesIncludeTax)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	settings.TaxRegion = region.String
+	return &settings, nil
+}
+
+// SetTaxSettings stores a user's tax region and price setting
+func (r *TaxRepository) SetTaxSettings(ctx context.Context, userID string, settings *models.TaxSettings) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	var region *string
+	if settings.TaxRegion != "" {
+		region = &settings.TaxRegion
+	}
+	result, err := r.db.ExecContext(ctx, `UPDATE users SET tax_region = $1, prices_include_tax = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3`, region, settings.PricesIncludeTax, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.New("failed to get rows affected")
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	database "primeauction/api/Database"
+)
+
+type txKey struct{}
+
+// Tx is a transaction shared by every repository call made with the
+// context it was started in
+type Tx struct {
+	*sql.Tx                      // nil for a unit of work started by WithHooks
+	dialect    *database.Dialect // of the database the transaction is on
+	owner      *Tx               // set when this joined an outer transaction
+	done       bool
+	onCommit   []func()
+	onRollback []func()
+}
+
+// WithTx runs fn as one unit of work: repository calls made with the
+// context fn is given share a transaction, which is committed if fn
+// returns nil and rolled back otherwise. A WithTx inside another joins the
+// outer transaction, and only the outermost one commits.
+func WithTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	ctx, tx, err := begin(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// TxRunner runs units of work as WithTx does. NewTxRunner returns the one
+// for a database; the memory package has one for its stores.
+type TxRunner interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type dbTxRunner struct {
+	db *sql.DB
+}
+
+func NewTxRunner(db *sql.DB) TxRunner {
+	return dbTxRunner{db: db}
+}
+
+func (r dbTxRunner) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return WithTx(ctx, r.db, fn)
+}
+
+// WithHooks runs fn as a unit of work without a database, for stores that
+// can't roll back: the OnCommit hooks fn registers run if it returns nil,
+// and the OnRollback ones otherwise. Inside another unit of work it joins
+// that one.
+func WithHooks(ctx context.Context, fn func(ctx context.Context) error) error {
+	if txFrom(ctx) != nil {
+		return fn(ctx)
+	}
+	tx := &Tx{}
+	ctx = context.WithValue(ctx, txKey{}, tx)
+	defer tx.Rollback()
+
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// OnCommit arranges for fn to run once the transaction in ctx has
+// committed, e.g. to delete files the unit of work made unused. Without a
+// transaction fn runs straight away.
+func OnCommit(ctx context.Context, fn func()) {
+	if tx := txFrom(ctx); tx != nil {
+		tx.onCommit = append(tx.onCommit, fn)
+		return
+	}
+	fn()
+}
+
+// OnRollback arranges for fn to run if the transaction in ctx is rolled
+// back, e.g. to delete files written for a unit of work that didn't
+// happen. Without a transaction fn never runs.
+func OnRollback(ctx context.Context, fn func()) {
+	if tx := txFrom(ctx); tx != nil {
+		tx.onRollback = append(tx.onRollback, fn)
+	}
+}
+
+// txFrom returns the transaction ctx carries, if it is still open
+func txFrom(ctx context.Context) *Tx {
+	tx, _ := ctx.Value(txKey{}).(*Tx)
+	if tx == nil || tx.done {
+		return nil
+	}
+	return tx
+}
+
+// begin starts a transaction and returns it with a context that carries
+// it. When ctx already carries one it is joined instead, and Commit and
+// Rollback are left to whoever started it.
+func begin(ctx context.Context, db *sql.DB) (context.Context, *Tx, error) {
+	if owner := txFrom(ctx); owner != nil {
+		return ctx, &Tx{Tx: owner.Tx, dialect: owner.dialect, owner: owner}, nil
+	}
+	sqlTx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return ctx, nil, err
+	}
+	tx := &Tx{Tx: sqlTx, dialect: database.DialectOf(db)}
+	return context.WithValue(ctx, txKey{}, tx), tx, nil
+}
+
+// Commit commits the transaction and runs the OnCommit hooks, or the
+// OnRollback ones if the commit fails
+func (t *Tx) Commit() error {
+	if t.owner != nil || t.done {
+		return nil
+	}
+	t.done = true
+	if t.Tx != nil {
+		if err := t.Tx.Commit(); err != nil {
+			runHooks(t.onRollback)
+			return err
+		}
+	}
+	runHooks(t.onCommit)
+	return nil
+}
+
+// Rollback rolls the transaction back and runs the OnRollback hooks. It
+// does nothing once the transaction has committed, so it can be deferred.
+func (t *Tx) Rollback() error {
+	if t.owner != nil || t.done {
+		return nil
+	}
+	t.done = true
+	var err error
+	if t.Tx != nil {
+		err = t.Tx.Rollback()
+	}
+	runHooks(t.onRollback)
+	return err
+}
+
+func runHooks(hooks []func()) {
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+// ExecContext, QueryContext and QueryRowContext run a query written for
+// Postgres in the transaction, in the dialect of the database
+func (t *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.Tx.ExecContext(ctx, t.dialect.Rewrite(query), args...)
+}
+
+func (t *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return t.Tx.QueryContext(ctx, t.dialect.Rewrite(query), args...)
+}
+
+func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return t.Tx.QueryRowContext(ctx, t.dialect.Rewrite(query), args...)
+}
+
+// conn runs queries in the transaction ctx carries, if there is one, and
+// on the database otherwise
+type conn struct {
+	*sql.DB
+}
+
+func (c conn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if tx := txFrom(ctx); tx != nil && tx.Tx != nil {
+		return tx.ExecContext(ctx, query, args...)
+	}
+	return c.DB.ExecContext(ctx, c.dialect().Rewrite(query), args...)
+}
+
+func (c conn) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if tx := txFrom(ctx); tx != nil && tx.Tx != nil {
+		return tx.QueryContext(ctx, query, args...)
+	}
+	return c.DB.QueryContext(ctx, c.dialect().Rewrite(query), args...)
+}
+
+func (c conn) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if tx := txFrom(ctx); tx != nil && tx.Tx != nil {
+		return tx.QueryRowContext(ctx, query, args...)
+	}
+	return c.DB.QueryRowContext(ctx, c.dialect().Rewrite(query), args...)
+}
+
+// dialect returns the dialect of the database
+func (c conn) dialect() *database.Dialect {
+	return database.DialectOf(c.DB)
+}
+
+// begin starts a transaction on the database, or joins the one in ctx
+func (c conn) begin(ctx context.Context) (context.Context, *Tx, error) {
+	return begin(ctx, c.DB)
+}
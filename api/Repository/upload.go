@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"primeauction/api/models"
+	"time"
+)
+
+// ErrUploadNotFound is returned for an unknown upload
+var ErrUploadNotFound = errors.New("upload not found")
+
+type UploadRepository struct {
+	db conn
+}
+
+func NewUploadRepository(db *sql.DB) *UploadRepository {
+	return &UploadRepository{db: conn{db}}
+}
+
+const uploadColumns = `id, user_id, filename, size, upload_offset, status, created_at, updated_at, expires_at`
+
+func scanUpload(row interface{ Scan(...any) error }) (*models.Upload, error) {
+	var upload models.Upload
+	err := row.Scan(&upload.Id, &upload.UserId, &upload.Filename, &upload.Size, &upload.Offset,
+		&upload.Status, &upload.CreatedAt, &upload.UpdatedAt, &upload.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// CreateUpload registers a new resumable upload
+func (r *UploadRepository) CreateUpload(ctx context.Context, upload *models.Upload) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO uploads (user_id, filename, size, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, upload_offset, created_at, updated_at`
+	return r.db.QueryRowContext(ctx, query, upload.UserId, upload.Filename, upload.Size, upload.Status, upload.ExpiresAt).
+		Scan(&upload.Id, &upload.Offset, &upload.CreatedAt, &upload.UpdatedAt)
+}
+
+// GetUploadByID retrieves an upload by ID
+func (r *UploadRepository) GetUploadByID(ctx context.Context, id string) (*models.Upload, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + uploadColumns + ` FROM uploads WHERE id = $1`
+	upload, err := scanUpload(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+// UpdateProgress moves the offset forward, guarding against a concurrent
+// writer having already moved it
+func (r *UploadRepository) UpdateProgress(ctx context.Context, id string, fromOffset, toOffset int64, status string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE uploads SET upload_offset = $1, status = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND upload_offset = $4`
+	result, err := r.db.ExecContext(ctx, query, toOffset, status, id, fromOffset)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.New("failed to get rows affected")
+	}
+	if rowsAffected == 0 {
+		return errors.New("upload offset changed concurrently")
+	}
+	return nil
+}
+
+// DeleteUpload removes an upload row
+func (r *UploadRepository) DeleteUpload(ctx context.Context, id string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM uploads WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// GetExpiredUploads lists uploads whose expiry has passed
+func (r *UploadRepository) GetExpiredUploads(ctx context.Context, now time.Time) ([]*models.Upload, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + uploadColumns + ` FROM uploads WHERE expires_at < $1`
+	rows, err := r.db.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uploads []*models.Upload
+	for rows.Next() {
+		upload, err := scanUpload(rows)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return uploads, nil
+}
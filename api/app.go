@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+
+	database "primeauction/api/Database"
+	repository "primeauction/api/Repository"
+	"primeauction/api/config"
+	"primeauction/api/payment"
+	"primeauction/api/service"
+)
+
+// app holds the services shared by the server and the admin commands, all
+// built from the same configuration and database
+type app struct {
+	cfg              *config.Config
+	userService      *service.UserService
+	itemService      *service.ItemService
+	uploadService    *service.UploadService
+	imageFlagService *service.ImageFlagService
+	storageService   *service.StorageService
+	currencyService  *service.CurrencyService
+	orderService     *service.OrderService
+	paymentService   *service.PaymentService
+	ledgerService    *service.LedgerService
+	feeService       *service.FeeService
+	taxService       *service.TaxService
+	invoiceService   *service.InvoiceService
+	disputeService   *service.DisputeService
+	shippingService  *service.ShippingService
+	fakePayments     *payment.FakeProvider // nil unless the fake provider is in use
+}
+
+// newApp wires the repositories and services to database.DB, which must be
+// open
+func newApp(cfg *config.Config) (*app, error) {
+	// Initialize repositories
+	itemRepo := repository.NewItemRepository(database.DB)
+	userRepo := repository.NewUserRepository(database.DB)
+	uploadRepo := repository.NewUploadRepository(database.DB)
+	orderRepo := repository.NewOrderRepository(database.DB)
+	paymentRepo := repository.NewPaymentRepository(database.DB)
+
+	// Initialize services
+	a := &app{cfg: cfg}
+	a.ledgerService = service.NewLedgerService(repository.NewLedgerRepository(database.DB), paymentRepo, orderRepo)
+	a.feeService = service.NewFeeService(repository.NewFeeRepository(database.DB), a.ledgerService)
+	a.taxService = service.NewTaxService(repository.NewTaxRepository(database.DB))
+	a.uploadService = service.NewUploadService(uploadRepo)
+	a.itemService = service.NewItemService(repository.NewTxRunner(database.DB), itemRepo,
+		repository.NewItemImageRepository(database.DB), repository.NewItemInviteRepository(database.DB),
+		repository.NewImageFlagRepository(database.DB), repository.NewShippingRepository(database.DB), a.uploadService, a.feeService)
+	a.userService = service.NewUserService(userRepo)
+	a.imageFlagService = service.NewImageFlagService(repository.NewImageFlagRepository(database.DB))
+	a.storageService = service.NewStorageService(repository.NewStorageRepository(database.DB))
+	a.orderService = service.NewOrderService(orderRepo, a.feeService, a.taxService)
+
+	// Payment provider: the in-memory fake unless a real gateway is configured
+	var paymentProvider payment.Provider
+	switch provider := cfg.Payments.Provider; provider {
+	case "fake":
+		a.fakePayments = payment.NewFakeProvider(cfg.Payments.FakeWebhookSecret)
+		paymentProvider = a.fakePayments
+	case "stripe":
+		paymentProvider = &payment.StripeProvider{
+			BaseURL:       cfg.Payments.StripeAPIBase,
+			SecretKey:     cfg.Payments.StripeSecretKey,
+			WebhookSecret: cfg.Payments.StripeWebhookSecret,
+		}
+	default:
+		return nil, fmt.Errorf("unknown PAYMENT_PROVIDER %q", provider)
+	}
+	a.invoiceService = service.NewInvoiceService(repository.NewInvoiceRepository(database.DB), orderRepo, userRepo)
+	a.paymentService = service.NewPaymentService(paymentRepo, orderRepo, paymentProvider, a.ledgerService, a.invoiceService)
+	a.disputeService = service.NewDisputeService(repository.NewDisputeRepository(database.DB), orderRepo, a.paymentService, a.ledgerService)
+	a.shippingService = service.NewShippingService(repository.NewShippingRepository(database.DB), itemRepo, orderRepo, a.taxService)
+
+	// Exchange rates come from EXCHANGE_RATES_URL when set, else a local file
+	var rateProvider service.RateProvider = &service.FileRateProvider{Path: cfg.Currency.RatesFile}
+	if cfg.Currency.RatesURL != "" {
+		rateProvider = &service.HTTPRateProvider{URL: cfg.Currency.RatesURL}
+	}
+	a.currencyService = service.NewCurrencyService(rateProvider, cfg.Currency.RatesTTL, userRepo)
+	return a, nil
+}
+
+// newUploadGC builds the orphaned upload garbage collector
+func (a *app) newUploadGC(dryRun bool) *service.UploadGC {
+	return service.NewUploadGC(repository.NewItemImageRepository(database.DB), a.uploadService, a.cfg.Uploads.GCGracePeriod, dryRun)
+}
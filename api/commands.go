@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	database "primeauction/api/Database"
+	"primeauction/api/config"
+	"primeauction/api/models"
+	"primeauction/api/service"
+)
+
+// runMigrate applies, rolls back or lists the schema migrations
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("migrate: expected up, down or status")
+	}
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	steps := flags.Int("steps", 1, "with down, how many migrations to roll back")
+	flags.Parse(args[1:])
+
+	switch args[0] {
+	case "up":
+		return database.RunMigration(database.DB)
+	case "down":
+		return database.RollbackMigrations(database.DB, *steps)
+	case "status":
+		statuses, err := database.GetMigrationStatus(database.DB)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-40s  %s\n", status.Version, status.Name, applied)
+		}
+		return nil
+	}
+	return fmt.Errorf("migrate: unknown subcommand %q", args[0])
+}
+
+// runUser creates users, grants admin rights and resets passwords
+func runUser(ctx context.Context, a *app, args []string) error {
+	if len(args) == 0 {
+		return errors.New("user: expected create, promote or reset-password")
+	}
+	flags := flag.NewFlagSet("user "+args[0], flag.ExitOnError)
+	email := flags.String("email", "", "the user's email")
+	username := flags.String("username", "", "with create, the user's name")
+	passwordFile := flags.String("password-file", "", "with create and reset-password, read the password from this file, or standard input if -; generated when not given")
+	admin := flags.Bool("admin", false, "with create, give the user admin rights")
+	revoke := flags.Bool("revoke", false, "with promote, take admin rights away instead")
+	flags.Parse(args[1:])
+	if *email == "" {
+		return fmt.Errorf("user %s: -email is required", args[0])
+	}
+
+	// Passwords are never taken as arguments, where other users of the
+	// machine can see them in the process list and they end up in shell
+	// history
+	password, err := readPassword(*passwordFile)
+	if err != nil {
+		return fmt.Errorf("user %s: %w", args[0], err)
+	}
+
+	switch args[0] {
+	case "create":
+		user := &models.User{Username: *username, Email: *email}
+		generated, err := passwordOrGenerate(&password)
+		if err != nil {
+			return err
+		}
+		user.Password = password
+		if *admin {
+			err = a.userService.CreateAdmin(ctx, user)
+		} else {
+			err = a.userService.CreateUser(ctx, user)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created user %s (%s), admin: %t\n", user.Email, user.Id, user.IsAdmin)
+		printPassword(generated, password)
+		return nil
+	case "promote":
+		user, err := a.userService.SetAdmin(ctx, *email, !*revoke)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("User %s (%s), admin: %t\n", user.Email, user.Id, user.IsAdmin)
+		return nil
+	case "reset-password":
+		generated, err := passwordOrGenerate(&password)
+		if err != nil {
+			return err
+		}
+		if err := a.userService.ResetPassword(ctx, *email, password); err != nil {
+			return err
+		}
+		fmt.Printf("Password reset for %s\n", *email)
+		printPassword(generated, password)
+		return nil
+	}
+	return fmt.Errorf("user: unknown subcommand %q", args[0])
+}
+
+// seedUsers are the demo accounts seed creates
+var seedUsers = []struct {
+	username, email string
+	admin           bool
+}{
+	{"admin", "admin@primeauction.local", true},
+	{"demo-seller", "seller@primeauction.local", false},
+	{"demo-buyer", "buyer@primeauction.local", false},
+}
+
+// runSeed adds demo data through the services, so it is validated like
+// anything else. It is safe to run again: what already exists is kept. It
+// refuses to run in production, where it would add an admin whose password
+// ends up in the terminal and its logs.
+func runSeed(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	flags.Parse(args)
+	if a.cfg.IsProduction() {
+		return errors.New("seed: demo data can't be added in production")
+	}
+
+	users := map[string]*models.User{}
+	for _, seed := range seedUsers {
+		if user, err := a.userService.GetUserByEmail(ctx, seed.email); err == nil {
+			fmt.Printf("User %s already exists\n", seed.email)
+			users[seed.username] = user
+			continue
+		}
+		password := ""
+		if _, err := passwordOrGenerate(&password); err != nil {
+			return err
+		}
+		user := &models.User{Username: seed.username, Email: seed.email, Password: password}
+		var err error
+		if seed.admin {
+			err = a.userService.CreateAdmin(ctx, user)
+		} else {
+			err = a.userService.CreateUser(ctx, user)
+		}
+		if err != nil {
+			return fmt.Errorf("seeding user %s: %w", seed.email, err)
+		}
+		fmt.Printf("Created user %s with password %s\n", seed.email, password)
+		users[seed.username] = user
+	}
+
+	seller := users["demo-seller"]
+	profiles, err := a.shippingService.GetProfiles(ctx, seller.Id)
+	if err != nil {
+		return err
+	}
+	if len(profiles) == 0 {
+		flat := models.NewMoney(500, models.DefaultCurrency)
+		freeOver := models.NewMoney(10000, models.DefaultCurrency)
+		profile := models.ShippingProfile{Name: "Standard", Type: models.ShippingFlat, Currency: models.DefaultCurrency,
+			Flat: &flat, FreeOver: &freeOver}
+		if err := a.shippingService.CreateProfile(ctx, seller.Id, &profile); err != nil {
+			return fmt.Errorf("seeding shipping profile: %w", err)
+		}
+		profiles = append(profiles, profile)
+		fmt.Println("Created shipping profile Standard")
+	}
+
+	items, err := a.itemService.GetItemsByUserID(ctx, seller.Id)
+	if err != nil {
+		return err
+	}
+	if len(items) > 0 {
+		fmt.Printf("Seller already has %d items\n", len(items))
+		return nil
+	}
+	for _, seed := range []struct {
+		name, category  string
+		price, selling  int64
+		quantity, grams int
+	}{
+		{"Vintage film camera", "electronics", 4000, 8900, 1, 900},
+		{"Hardback atlas", "books", 1200, 2500, 3, 1500},
+		{"Ceramic tea set", "home", 2000, 4500, 2, 2200},
+	} {
+		item := models.Item{
+			Name:              seed.name,
+			Description:       "Demo listing",
+			Category:          seed.category,
+			Price:             models.NewMoney(seed.price, models.DefaultCurrency),
+			SellingPrice:      models.NewMoney(seed.selling, models.DefaultCurrency),
+			Quantity:          seed.quantity,
+			WeightGrams:       seed.grams,
+			ShippingProfileId: &profiles[0].Id,
+			Images:            []models.ItemImage{},
+		}
+		if err := a.itemService.CreateItem(ctx, seller.Id, &item, nil, nil); err != nil {
+			return fmt.Errorf("seeding item %s: %w", seed.name, err)
+		}
+		fmt.Printf("Created item %s\n", seed.name)
+	}
+	return nil
+}
+
+// runConfig prints the configuration and any problems with it
+func runConfig(cfg *config.Config, loadErr error, args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return errors.New("config: expected print")
+	}
+	if cfg == nil {
+		return loadErr
+	}
+	cfg.Print(os.Stdout)
+	if loadErr != nil {
+		return fmt.Errorf("invalid configuration:\n%w", loadErr)
+	}
+	return nil
+}
+
+// runGC runs the orphaned upload garbage collector once
+func runGC(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("gc", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "report orphans without deleting anything")
+	flags.Parse(args)
+
+	report, err := a.newUploadGC(*dryRun).Run(ctx)
+	if err != nil {
+		return fmt.Errorf("upload garbage collection failed: %w", err)
+	}
+	service.LogGCReport(report)
+	return nil
+}
+
+// runBackfillSizes fills in the sizes of images saved before storage quotas
+// counted them, without waiting for the collector
+func runBackfillSizes(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("backfill-sizes", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "count the images missing a size without writing anything")
+	flags.Parse(args)
+
+	filled, err := a.newUploadGC(*dryRun).BackfillImageSizes(ctx)
+	if *dryRun {
+		fmt.Printf("%d image sizes would be filled in\n", filled)
+	} else {
+		fmt.Printf("%d image sizes filled in\n", filled)
+	}
+	return err
+}
+
+// runReconcile verifies the ledger against payments, failing when they
+// disagree
+func runReconcile(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	fix := flags.Bool("fix", false, "post missing sale and refund entries")
+	flags.Parse(args)
+
+	report, err := a.ledgerService.Reconcile(ctx, *fix)
+	if err != nil {
+		return fmt.Errorf("ledger reconciliation failed: %w", err)
+	}
+	service.LogReconcileReport(report)
+	if !report.OK() {
+		return errors.New("the ledger does not match payments")
+	}
+	return nil
+}
+
+// readPassword reads a password from the file at path, or from standard
+// input if path is -. The line ending after it is dropped. No path means
+// no password.
+func readPassword(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	var b []byte
+	var err error
+	if path == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading the password: %w", err)
+	}
+	password := strings.TrimRight(string(b), "\r\n")
+	if password == "" {
+		return "", errors.New("the password is empty")
+	}
+	return password, nil
+}
+
+// passwordOrGenerate fills in a random password when none was given,
+// reporting whether it did
+func passwordOrGenerate(password *string) (bool, error) {
+	if *password != "" {
+		return false, nil
+	}
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return false, err
+	}
+	*password = base64.RawURLEncoding.EncodeToString(b)
+	return true, nil
+}
+
+func printPassword(generated bool, password string) {
+	if generated {
+		fmt.Printf("Generated password: %s\n", password)
+	}
+}
@@ -1,47 +1,222 @@
 package config
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"os"
-
-	"github.com/joho/godotenv"
+	"log"
+	"strconv"
+	"time"
 )
 
+// Config is every setting the API reads. Each field can come from, in
+// increasing order of precedence: its default, the YAML file named by
+// CONFIG_FILE, a .env file, and the environment variable in its env tag.
+// Fields tagged secret can also be read from the file named by the same
+// variable with a _FILE suffix, and are redacted when printed.
 type Config struct {
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	Port       string
+	Env       string          `yaml:"env" env:"APP_ENV" default:"development"` // development or production
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Payments  PaymentsConfig  `yaml:"payments"`
+	Currency  CurrencyConfig  `yaml:"currency"`
+	Uploads   UploadsConfig   `yaml:"uploads"`
+	Orders    OrdersConfig    `yaml:"orders"`
+	Tax       TaxConfig       `yaml:"tax"`
+	Migration MigrationConfig `yaml:"migration"`
 }
 
-func Loadconfig() (*Config, error) {
-	//load env if got any error retrun error
-	if err := godotenv.Load(); err != nil {
-		return nil, err
-	}
-	config := &Config{
-		DBHost:     os.Getenv("DB_HOST"),
-		DBPort:     os.Getenv("DB_PORT"),
-		DBUser:     os.Getenv("DB_USER"),
-		DBPassword: os.Getenv("DB_PASSWORD"),
-		DBName:     os.Getenv("DB_NAME"),
-		Port:       os.Getenv("PORT"),
-	}
-	return config, nil
+type ServerConfig struct {
+	Port              string        `yaml:"port" env:"PORT" default:"8080"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" default:"5s"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT" default:"60s"` // covers upload bodies
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" default:"60s"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" default:"120s"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" default:"30s"` // how long to drain requests on SIGTERM
+}
+
+type DatabaseConfig struct {
+	Driver       string        `yaml:"driver" env:"DB_DRIVER" default:"postgres"`    // postgres or sqlite
+	Path         string        `yaml:"path" env:"DB_PATH" default:"primeauction.db"` // the SQLite database file
+	Host         string        `yaml:"host" env:"DB_HOST" default:"localhost"`
+	Port         string        `yaml:"port" env:"DB_PORT" default:"5432"`
+	User         string        `yaml:"user" env:"DB_USER" default:"postgres"`
+	Password     string        `yaml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name         string        `yaml:"name" env:"DB_NAME" default:"primeauction"`
+	SSLMode      string        `yaml:"sslmode" env:"DB_SSLMODE" default:"disable"`
+	QueryTimeout time.Duration `yaml:"query_timeout" env:"DB_QUERY_TIMEOUT" default:"10s"` // longest a single repository operation may take
 }
-func (c *Config) GetDBConnectionString() string {
-	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		c.DBHost, c.DBPort, c.DBUser, c.DBPassword, c.DBName)
+
+type AuthConfig struct {
+	JWTSecret     string `yaml:"jwt_secret" env:"JWT_SECRET" secret:"true"`
+	URLSigningKey string `yaml:"url_signing_key" env:"URL_SIGNING_KEY" secret:"true"` // derived from the JWT secret outside production
+}
+
+type PaymentsConfig struct {
+	Provider            string `yaml:"provider" env:"PAYMENT_PROVIDER" default:"fake"`                      // fake or stripe
+	FakeWebhookSecret   string `yaml:"fake_webhook_secret" env:"FAKE_PAYMENT_WEBHOOK_SECRET" secret:"true"` // derived from the JWT secret when unset
+	StripeAPIBase       string `yaml:"stripe_api_base" env:"STRIPE_API_BASE"`
+	StripeSecretKey     string `yaml:"stripe_secret_key" env:"STRIPE_SECRET_KEY" secret:"true"`
+	StripeWebhookSecret string `yaml:"stripe_webhook_secret" env:"STRIPE_WEBHOOK_SECRET" secret:"true"`
+}
+
+type CurrencyConfig struct {
+	RatesFile string        `yaml:"rates_file" env:"EXCHANGE_RATES_FILE" default:"config/exchange_rates.json"`
+	RatesURL  string        `yaml:"rates_url" env:"EXCHANGE_RATES_URL"` // used instead of the file when set
+	RatesTTL  time.Duration `yaml:"rates_ttl" env:"EXCHANGE_RATES_TTL" default:"1h"`
 }
-func GetEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+
+type UploadsConfig struct {
+	Expiry           time.Duration `yaml:"expiry" env:"UPLOAD_EXPIRY" default:"24h"`
+	SignedURLTTL     time.Duration `yaml:"signed_url_ttl" env:"SIGNED_URL_TTL" default:"15m"`
+	RateLimit        int           `yaml:"rate_limit" env:"UPLOAD_RATE_LIMIT" default:"30"`
+	RateWindow       time.Duration `yaml:"rate_window" env:"UPLOAD_RATE_WINDOW" default:"1h"`
+	QuotaBytes       int64         `yaml:"quota_bytes" env:"STORAGE_QUOTA_BYTES" default:"209715200"`              // 200MB
+	AdminQuotaBytes  int64         `yaml:"admin_quota_bytes" env:"STORAGE_QUOTA_ADMIN_BYTES" default:"2147483648"` // 2GB
+	PHashMaxDistance int           `yaml:"phash_max_distance" env:"PHASH_MAX_DISTANCE" default:"10"`
+	GCInterval       time.Duration `yaml:"gc_interval" env:"GC_INTERVAL" default:"1h"` // 0 disables the collector
+	GCGracePeriod    time.Duration `yaml:"gc_grace_period" env:"GC_GRACE_PERIOD" default:"24h"`
+	GCDryRun         bool          `yaml:"gc_dry_run" env:"GC_DRY_RUN" default:"false"`
+}
+
+type OrdersConfig struct {
+	ReservationTTL time.Duration `yaml:"reservation_ttl" env:"ORDER_RESERVATION_TTL" default:"15m"`
+	ExpiryInterval time.Duration `yaml:"expiry_interval" env:"ORDER_EXPIRY_INTERVAL" default:"1m"` // 0 disables expiry
+	DisputeWindow  time.Duration `yaml:"dispute_window" env:"DISPUTE_WINDOW" default:"720h"`
+}
+
+type TaxConfig struct {
+	Rounding string `yaml:"rounding" env:"TAX_ROUNDING" default:"half_up"` // half_up or half_even
+}
+
+type MigrationConfig struct {
+	AutoMigrate bool `yaml:"auto_migrate" env:"AUTO_MIGRATE" default:"true"` // apply pending migrations when serving
+}
+
+// devJWTSecret is only ever used outside production, when no secret is set
+const devJWTSecret = "your-secret-key"
+
+// deriveKey returns a key for one purpose made from secret, so the keys
+// derived for different purposes are unrelated
+func deriveKey(secret, purpose string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsProduction reports whether the API runs with production safeguards
+func (c *Config) IsProduction() bool {
+	return c.Env == "production"
+}
+
+// DatabaseURL returns the connection string for DB_DRIVER: the file for
+// SQLite, and a lib/pq one for Postgres
+func (c *Config) DatabaseURL() string {
+	d := c.Database
+	if d.Driver == "sqlite" {
+		return d.Path
 	}
-	return defaultValue
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		d.Host, d.Port, d.User, d.Password, d.Name, d.SSLMode)
 }
 
-func GetJWTSecret()string{
-	return GetEnv("JWT_SECRET","your-secret-key")
+// Validate fills in settings derived from others and reports every problem
+// with the configuration at once
+func (c *Config) Validate() error {
+	var problems []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Env == "development" || c.Env == "production", "APP_ENV must be development or production, not %q", c.Env)
+	port, err := strconv.Atoi(c.Server.Port)
+	check(err == nil && port > 0 && port < 65536, "PORT must be a port number, not %q", c.Server.Port)
+
+	switch c.Database.Driver {
+	case "postgres":
+		check(c.Database.Host != "", "DB_HOST is required")
+		check(c.Database.User != "", "DB_USER is required")
+		check(c.Database.Name != "", "DB_NAME is required")
+		_, err = strconv.Atoi(c.Database.Port)
+		check(err == nil, "DB_PORT must be a port number, not %q", c.Database.Port)
+		switch c.Database.SSLMode {
+		case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+		default:
+			check(false, "DB_SSLMODE %q is not a valid sslmode", c.Database.SSLMode)
+		}
+	case "sqlite":
+		check(c.Database.Path != "", "DB_PATH is required with DB_DRIVER=sqlite")
+	default:
+		check(false, "DB_DRIVER must be postgres or sqlite, not %q", c.Database.Driver)
+	}
+
+	if c.Auth.JWTSecret == "" {
+		if c.IsProduction() {
+			check(false, "JWT_SECRET is required in production")
+		} else {
+			log.Printf("JWT_SECRET is not set; using an insecure development secret")
+			c.Auth.JWTSecret = devJWTSecret
+		}
+	}
+	if c.IsProduction() {
+		check(c.Auth.JWTSecret == "" || len(c.Auth.JWTSecret) >= 32 && c.Auth.JWTSecret != devJWTSecret,
+			"JWT_SECRET must be at least 32 characters in production")
+	}
+	// A key that leaked from one use must not unlock the others, so
+	// production needs a key of its own for each
+	if c.IsProduction() {
+		check(c.Auth.URLSigningKey != "", "URL_SIGNING_KEY is required in production")
+		check(c.Auth.URLSigningKey == "" || c.Auth.URLSigningKey != c.Auth.JWTSecret, "URL_SIGNING_KEY must differ from JWT_SECRET")
+	} else if c.Auth.URLSigningKey == "" {
+		c.Auth.URLSigningKey = deriveKey(c.Auth.JWTSecret, "url-signing")
+	}
+
+	switch c.Payments.Provider {
+	case "fake":
+		check(!c.IsProduction(), "PAYMENT_PROVIDER=fake is not allowed in production")
+		if c.Payments.FakeWebhookSecret == "" {
+			c.Payments.FakeWebhookSecret = deriveKey(c.Auth.JWTSecret, "fake-payment-webhook")
+		}
+	case "stripe":
+		check(c.Payments.StripeSecretKey != "", "STRIPE_SECRET_KEY is required with PAYMENT_PROVIDER=stripe")
+		check(c.Payments.StripeWebhookSecret != "", "STRIPE_WEBHOOK_SECRET is required with PAYMENT_PROVIDER=stripe")
+		check(!c.IsProduction() || c.Payments.StripeWebhookSecret == "" || c.Payments.StripeWebhookSecret != c.Auth.JWTSecret,
+			"STRIPE_WEBHOOK_SECRET must differ from JWT_SECRET")
+	default:
+		check(false, "PAYMENT_PROVIDER must be fake or stripe, not %q", c.Payments.Provider)
+	}
+
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"HTTP_READ_HEADER_TIMEOUT", c.Server.ReadHeaderTimeout},
+		{"HTTP_READ_TIMEOUT", c.Server.ReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.Server.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.Server.IdleTimeout},
+		{"SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout},
+		{"DB_QUERY_TIMEOUT", c.Database.QueryTimeout},
+		{"EXCHANGE_RATES_TTL", c.Currency.RatesTTL},
+		{"UPLOAD_EXPIRY", c.Uploads.Expiry},
+		{"SIGNED_URL_TTL", c.Uploads.SignedURLTTL},
+		{"UPLOAD_RATE_WINDOW", c.Uploads.RateWindow},
+		{"GC_GRACE_PERIOD", c.Uploads.GCGracePeriod},
+		{"ORDER_RESERVATION_TTL", c.Orders.ReservationTTL},
+		{"DISPUTE_WINDOW", c.Orders.DisputeWindow},
+	} {
+		check(d.value > 0, "%s must be a positive duration", d.name)
+	}
+	check(c.Uploads.GCInterval >= 0, "GC_INTERVAL cannot be negative")
+	check(c.Orders.ExpiryInterval >= 0, "ORDER_EXPIRY_INTERVAL cannot be negative")
+	check(c.Uploads.RateLimit > 0, "UPLOAD_RATE_LIMIT must be at least 1")
+	check(c.Uploads.QuotaBytes > 0 && c.Uploads.AdminQuotaBytes > 0, "storage quotas must be positive")
+	check(c.Uploads.PHashMaxDistance >= 0 && c.Uploads.PHashMaxDistance <= 64, "PHASH_MAX_DISTANCE must be between 0 and 64")
+	check(c.Tax.Rounding == "half_up" || c.Tax.Rounding == "half_even", "TAX_ROUNDING must be half_up or half_even, not %q", c.Tax.Rounding)
+
+	return errors.Join(problems...)
 }
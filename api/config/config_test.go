@@ -0,0 +1,95 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// validConfig returns the defaults, which are valid outside production
+func validConfig(t *testing.T) *Config {
+	t.Helper()
+	clearEnv(t)
+	c, err := load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	c := validConfig(t)
+	c.Env = "staging"
+	c.Server.Port = "http"
+	c.Database.Driver = "mysql"
+	c.Tax.Rounding = "up"
+
+	err := c.Validate()
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("Validate error = %v, want the problems joined", err)
+	}
+	if n := len(joined.Unwrap()); n != 4 {
+		t.Errorf("Validate found %d problems, want 4: %v", n, err)
+	}
+	for _, want := range []string{"APP_ENV", "PORT", "DB_DRIVER", "TAX_ROUNDING"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate error doesn't mention %s: %v", want, err)
+		}
+	}
+}
+
+func TestValidateDerivesDevelopmentKeys(t *testing.T) {
+	c := validConfig(t)
+	if c.Auth.JWTSecret != devJWTSecret {
+		t.Errorf("JWT secret = %q, want the development secret", c.Auth.JWTSecret)
+	}
+	for name, key := range map[string]string{
+		"URL signing key":     c.Auth.URLSigningKey,
+		"fake webhook secret": c.Payments.FakeWebhookSecret,
+	} {
+		if key == "" || key == c.Auth.JWTSecret {
+			t.Errorf("%s = %q, want a key derived from the JWT secret", name, key)
+		}
+	}
+	if c.Auth.URLSigningKey == c.Payments.FakeWebhookSecret {
+		t.Error("the URL signing key and the fake webhook secret are the same")
+	}
+}
+
+func TestValidateProduction(t *testing.T) {
+	secret := strings.Repeat("j", 32)
+	production := func(t *testing.T) *Config {
+		c := validConfig(t)
+		c.Env = "production"
+		c.Auth.JWTSecret = secret
+		c.Auth.URLSigningKey = strings.Repeat("u", 32)
+		c.Payments.Provider = "stripe"
+		c.Payments.StripeSecretKey = "sk_live_1"
+		c.Payments.StripeWebhookSecret = "whsec_1"
+		return c
+	}
+	if err := production(t).Validate(); err != nil {
+		t.Fatalf("a complete production config: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		change func(c *Config)
+		want   string
+	}{
+		{"no JWT secret", func(c *Config) { c.Auth.JWTSecret = "" }, "JWT_SECRET is required"},
+		{"short JWT secret", func(c *Config) { c.Auth.JWTSecret = "short" }, "at least 32 characters"},
+		{"no URL signing key", func(c *Config) { c.Auth.URLSigningKey = "" }, "URL_SIGNING_KEY is required"},
+		{"URL signing key reused", func(c *Config) { c.Auth.URLSigningKey = secret }, "URL_SIGNING_KEY must differ"},
+		{"fake payments", func(c *Config) { c.Payments.Provider = "fake" }, "PAYMENT_PROVIDER=fake is not allowed"},
+		{"no webhook secret", func(c *Config) { c.Payments.StripeWebhookSecret = "" }, "STRIPE_WEBHOOK_SECRET is required"},
+		{"webhook secret reused", func(c *Config) { c.Payments.StripeWebhookSecret = secret }, "STRIPE_WEBHOOK_SECRET must differ"},
+	} {
+		c := production(t)
+		tc.change(c)
+		err := c.Validate()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: Validate error = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
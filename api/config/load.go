@@ -0,0 +1,167 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// current is the configuration Load last produced
+var current *Config
+
+// Get returns the loaded configuration. Before Load has run, e.g. in
+// tests, it is built from defaults and the environment alone.
+func Get() *Config {
+	if current == nil {
+		c, _ := load("")
+		current = c
+	}
+	return current
+}
+
+// Load reads the configuration from its sources and validates it. The
+// configuration is returned, and made current, even when it is invalid, so
+// it can still be printed; the error lists every problem found.
+func Load() (*Config, error) {
+	// A .env file is optional; the real environment wins over it
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading .env: %w", err)
+	}
+	c, err := load(os.Getenv("CONFIG_FILE"))
+	current = c
+	return c, err
+}
+
+func load(file string) (*Config, error) {
+	c := &Config{}
+	var problems []error
+	walk(reflect.ValueOf(c).Elem(), func(f field) {
+		if def, ok := f.tag.Lookup("default"); ok {
+			if err := f.set(def); err != nil {
+				problems = append(problems, fmt.Errorf("default for %s: %w", f.env, err))
+			}
+		}
+	})
+
+	if file != "" {
+		if err := loadFile(c, file); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	walk(reflect.ValueOf(c).Elem(), func(f field) {
+		value, ok := os.LookupEnv(f.env)
+		if path := os.Getenv(f.env + "_FILE"); f.tag.Get("secret") == "true" && path != "" {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				problems = append(problems, fmt.Errorf("%s_FILE: %w", f.env, err))
+				return
+			}
+			value, ok = strings.TrimRight(string(b), "\r\n"), true
+		}
+		if !ok {
+			return
+		}
+		if err := f.set(value); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", f.env, err))
+		}
+	})
+
+	if err := c.Validate(); err != nil {
+		problems = append(problems, err)
+	}
+	return c, errors.Join(problems...)
+}
+
+// loadFile applies a YAML config file. Unknown keys are reported so typos
+// don't go unnoticed.
+func loadFile(c *Config, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && err != io.EOF {
+		return fmt.Errorf("config file %s: %w", file, err)
+	}
+	return nil
+}
+
+// field is a setting found by walk
+type field struct {
+	env   string
+	path  string // e.g. database.host
+	tag   reflect.StructTag
+	value reflect.Value
+}
+
+// walk calls fn for every setting in v, a struct, descending into sections
+func walk(v reflect.Value, fn func(field)) {
+	walkPath(v, "", fn)
+}
+
+func walkPath(v reflect.Value, prefix string, fn func(field)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := prefix + sf.Tag.Get("yaml")
+		if sf.Type.Kind() == reflect.Struct {
+			walkPath(v.Field(i), name+".", fn)
+			continue
+		}
+		fn(field{env: sf.Tag.Get("env"), path: name, tag: sf.Tag, value: v.Field(i)})
+	}
+}
+
+// set parses s into the field according to its type
+func (f field) set(s string) error {
+	switch f.value.Interface().(type) {
+	case string:
+		f.value.SetString(s)
+	case time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		f.value.SetInt(int64(d))
+	case int, int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		f.value.SetInt(n)
+	case bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		f.value.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported setting type %s", f.value.Type())
+	}
+	return nil
+}
+
+// Print writes every setting with the variable it comes from. Secrets that
+// are set are shown as <redacted>.
+func (c *Config) Print(w io.Writer) {
+	walk(reflect.ValueOf(c).Elem(), func(f field) {
+		value := fmt.Sprint(f.value.Interface())
+		if f.tag.Get("secret") == "true" && value != "" {
+			value = "<redacted>"
+		}
+		fmt.Fprintf(w, "%-32s %-28s %s\n", f.path, f.env, value)
+	})
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets every variable the configuration reads, and their _FILE
+// variants, for the rest of the test
+func clearEnv(t *testing.T) {
+	t.Helper()
+	walk(reflect.ValueOf(&Config{}).Elem(), func(f field) {
+		for _, name := range []string{f.env, f.env + "_FILE"} {
+			t.Setenv(name, "")
+			os.Unsetenv(name)
+		}
+	})
+}
+
+// writeFile writes content to name in a temporary directory and returns
+// its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	file := writeFile(t, "config.yaml", `
+server:
+  port: "9000"
+  shutdown_timeout: 10s
+database:
+  driver: sqlite
+  path: from-file.db
+orders:
+  reservation_ttl: 30m
+`)
+	t.Setenv("PORT", "9100")
+	t.Setenv("DB_PATH", "from-env.db")
+
+	c, err := load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		setting   string
+		got, want any
+	}{
+		{"server.port", c.Server.Port, "9100"},            // environment over file
+		{"database.path", c.Database.Path, "from-env.db"}, // environment over file
+		{"database.driver", c.Database.Driver, "sqlite"},  // file over default
+		{"orders.reservation_ttl", c.Orders.ReservationTTL, 30 * time.Minute},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout, 10 * time.Second},
+		{"orders.dispute_window", c.Orders.DisputeWindow, 720 * time.Hour}, // default
+		{"tax.rounding", c.Tax.Rounding, "half_up"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %v, want %v", tc.setting, tc.got, tc.want)
+		}
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	clearEnv(t)
+	if _, err := load(writeFile(t, "config.yaml", "server:\n  prot: \"9000\"\n")); err == nil || !strings.Contains(err.Error(), "prot") {
+		t.Errorf("an unknown key: error = %v", err)
+	}
+	if _, err := load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil || !strings.Contains(err.Error(), "config file") {
+		t.Errorf("a missing file: error = %v", err)
+	}
+
+	t.Setenv("HTTP_READ_TIMEOUT", "soon")
+	t.Setenv("UPLOAD_RATE_LIMIT", "many")
+	_, err := load("")
+	if err == nil || !strings.Contains(err.Error(), `HTTP_READ_TIMEOUT: invalid duration "soon"`) ||
+		!strings.Contains(err.Error(), `UPLOAD_RATE_LIMIT: invalid number "many"`) {
+		t.Errorf("unparsable variables: error = %v", err)
+	}
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+	clearEnv(t)
+	secret := strings.Repeat("s", 40)
+	t.Setenv("JWT_SECRET", "from-the-environment")
+	t.Setenv("JWT_SECRET_FILE", writeFile(t, "jwt", secret+"\n"))
+	// Only secrets can be read from files
+	t.Setenv("PORT_FILE", writeFile(t, "port", "9100"))
+
+	c, err := load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Auth.JWTSecret != secret {
+		t.Errorf("JWT secret = %q, want the file's content without the newline", c.Auth.JWTSecret)
+	}
+	if c.Server.Port != "8080" {
+		t.Errorf("port = %q, want the default", c.Server.Port)
+	}
+
+	t.Setenv("JWT_SECRET_FILE", filepath.Join(t.TempDir(), "missing"))
+	if _, err := load(""); err == nil || !strings.Contains(err.Error(), "JWT_SECRET_FILE") {
+		t.Errorf("a missing secret file: error = %v", err)
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DB_PASSWORD", "hunter2")
+	c, err := load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	c.Print(&out)
+	if strings.Contains(out.String(), "hunter2") || !strings.Contains(out.String(), "<redacted>") {
+		t.Errorf("Print shows a secret:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "DB_HOST") {
+		t.Errorf("Print leaves out settings:\n%s", out.String())
+	}
+}
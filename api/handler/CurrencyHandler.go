@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"primeauction/api/service"
+	"primeauction/api/utils"
+)
+
+type CurrencyHandler struct {
+	CurrencyService *service.CurrencyService
+}
+
+func NewCurrencyHandler(currencyService *service.CurrencyService) *CurrencyHandler {
+	return &CurrencyHandler{CurrencyService: currencyService}
+}
+
+// GetRates lists the supported currencies with the rates currently in use
+func (h *CurrencyHandler) GetRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.CurrencyService.Rates()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rates)
+}
+
+// UpdatePreferences saves the current user's display currency
+func (h *CurrencyHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	var body struct {
+		PreferredCurrency string `json:"preferred_currency"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if err := h.CurrencyService.SetPreferredCurrency(r.Context(), userID, body.PreferredCurrency); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"primeauction/api/models"
+	"primeauction/api/service"
+	"primeauction/api/utils"
+)
+
+type DisputeHandler struct {
+	DisputeService *service.DisputeService
+}
+
+func NewDisputeHandler(disputeService *service.DisputeService) *DisputeHandler {
+	return &DisputeHandler{DisputeService: disputeService}
+}
+
+// OpenDispute lets the buyer of a paid order dispute one seller's items
+func (h *DisputeHandler) OpenDispute(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	var body struct {
+		SellerId string `json:"seller_id"` // may be left out when the order has one seller
+		Reason   string `json:"reason"`
+		Message  string `json:"message"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	dispute, err := h.DisputeService.OpenDispute(r.Context(), r.PathValue("id"), userID, body.SellerId, body.Reason, body.Message)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/api/disputes/"+dispute.Id)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dispute)
+}
+
+// GetDispute returns a dispute and its messages to the buyer, the seller
+// or an admin
+func (h *DisputeHandler) GetDispute(w http.ResponseWriter, r *http.Request) {
+	dispute, err := h.DisputeService.GetDispute(r.Context(), r.PathValue("id"), r.Header.Get("X-User-ID"), r.Header.Get("X-Is-Admin") == "true")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dispute)
+}
+
+// GetMyDisputes lists the disputes the current user is the buyer or seller in
+func (h *DisputeHandler) GetMyDisputes(w http.ResponseWriter, r *http.Request) {
+	disputes, err := h.DisputeService.GetMyDisputes(r.Context(), r.Header.Get("X-User-ID"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(disputes)
+}
+
+// GetDisputes lists disputes, optionally only those in ?status= (admin only)
+func (h *DisputeHandler) GetDisputes(w http.ResponseWriter, r *http.Request) {
+	disputes, err := h.DisputeService.GetDisputes(r.Context(), r.URL.Query().Get("status"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(disputes)
+}
+
+// AddMessage posts a message to a dispute's thread
+func (h *DisputeHandler) AddMessage(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Body string `json:"body"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	dispute, err := h.DisputeService.AddMessage(r.Context(), r.PathValue("id"), r.Header.Get("X-User-ID"), r.Header.Get("X-Is-Admin") == "true", body.Body)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dispute)
+}
+
+// Escalate hands a dispute to the admins, with an optional note
+func (h *DisputeHandler) Escalate(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if !decodeJSON(w, r, &body) {
+			return
+		}
+	}
+	dispute, err := h.DisputeService.Escalate(r.Context(), r.PathValue("id"), r.Header.Get("X-User-ID"), r.Header.Get("X-Is-Admin") == "true", body.Note)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dispute)
+}
+
+// Resolve settles a dispute. The seller may refund in full or in part;
+// admins may also close it without a refund.
+func (h *DisputeHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	var resolution models.DisputeResolution
+	if !decodeJSON(w, r, &resolution) {
+		return
+	}
+	dispute, err := h.DisputeService.Resolve(r.Context(), r.PathValue("id"), r.Header.Get("X-User-ID"), r.Header.Get("X-Is-Admin") == "true", resolution)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dispute)
+}
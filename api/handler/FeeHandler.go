@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"primeauction/api/models"
+	"primeauction/api/service"
+	"primeauction/api/utils"
+	"strconv"
+)
+
+type FeeHandler struct {
+	FeeService *service.FeeService
+}
+
+func NewFeeHandler(feeService *service.FeeService) *FeeHandler {
+	return &FeeHandler{FeeService: feeService}
+}
+
+// GetFees returns the fee rules in effect and the current user's seller
+// tier, so sellers can see what they will be charged
+func (h *FeeHandler) GetFees(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	schedule, err := h.FeeService.CurrentSchedule(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	tier, err := h.FeeService.GetSellerTier(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"seller_tier": tier,
+		"schedule":    schedule,
+	})
+}
+
+// GetSchedules lists every version of the fee rules (admin only)
+func (h *FeeHandler) GetSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := h.FeeService.GetSchedules(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(schedules)
+}
+
+// GetSchedule returns one version of the fee rules (admin only)
+func (h *FeeHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.Atoi(r.PathValue("version"))
+	if err != nil {
+		utils.WriteProblem(w, r, http.StatusBadRequest, utils.CodeBadRequest, "Invalid version")
+		return
+	}
+	schedule, err := h.FeeService.GetSchedule(r.Context(), version)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// PublishSchedule makes a complete set of rules the new current version
+// (admin only). To change one rule, send the whole list with it changed.
+func (h *FeeHandler) PublishSchedule(w http.ResponseWriter, r *http.Request) {
+	var schedule models.FeeSchedule
+	if !decodeJSON(w, r, &schedule) {
+		return
+	}
+	if err := h.FeeService.PublishSchedule(r.Context(), &schedule, r.Header.Get("X-User-ID")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/api/admin/fee-schedules/"+strconv.Itoa(schedule.Version))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// SetSellerTier moves a user to another fee tier (admin only)
+func (h *FeeHandler) SetSellerTier(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Tier string `json:"tier"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if err := h.FeeService.SetSellerTier(r.Context(), r.PathValue("id"), body.Tier); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Seller tier updated successfully"})
+}
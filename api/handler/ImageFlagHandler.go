@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"primeauction/api/service"
+)
+
+type ImageFlagHandler struct {
+	ImageFlagService *service.ImageFlagService
+}
+
+func NewImageFlagHandler(imageFlagService *service.ImageFlagService) *ImageFlagHandler {
+	return &ImageFlagHandler{ImageFlagService: imageFlagService}
+}
+func (h *ImageFlagHandler) GetFlags(w http.ResponseWriter, r *http.Request) {
+	flags, err := h.ImageFlagService.GetFlags(r.Context(), r.URL.Query().Get("status"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(flags)
+}
+func (h *ImageFlagHandler) ReviewFlag(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Status string `json:"status"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	flag, err := h.ImageFlagService.ReviewFlag(r.Context(), r.PathValue("id"), body.Status, r.Header.Get("X-User-ID"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(flag)
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"primeauction/api/service"
+)
+
+type InvoiceHandler struct {
+	InvoiceService *service.InvoiceService
+}
+
+func NewInvoiceHandler(invoiceService *service.InvoiceService) *InvoiceHandler {
+	return &InvoiceHandler{InvoiceService: invoiceService}
+}
+
+// GetOrderInvoices lists the invoices for a paid order that the current
+// user may download
+func (h *InvoiceHandler) GetOrderInvoices(w http.ResponseWriter, r *http.Request) {
+	invoices, err := h.InvoiceService.GetInvoices(r.Context(), r.PathValue("id"), r.Header.Get("X-User-ID"), r.Header.Get("X-Is-Admin") == "true")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invoices)
+}
+
+// GetOrderInvoice serves an order's invoice PDF. ?seller_id= picks the
+// seller's invoice when the order has items from more than one.
+func (h *InvoiceHandler) GetOrderInvoice(w http.ResponseWriter, r *http.Request) {
+	invoice, err := h.InvoiceService.GetInvoice(r.Context(), r.PathValue("id"), r.URL.Query().Get("seller_id"),
+		r.Header.Get("X-User-ID"), r.Header.Get("X-Is-Admin") == "true")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="`+invoice.Number+`.pdf"`)
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	http.ServeFile(w, r, invoice.FilePath)
+}
@@ -2,45 +2,63 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
+	"log"
 	"mime/multipart"
 	"net/http"
 	"primeauction/api/models"
 	"primeauction/api/service"
 	"primeauction/api/utils"
+	"primeauction/api/validate"
 	"strconv"
+	"strings"
 )
 
 type ItemHandler struct {
-	ItemService *service.ItemService
+	ItemService     *service.ItemService
+	StorageService  *service.StorageService
+	CurrencyService *service.CurrencyService
 }
 
-func NewItemHandler(itemService *service.ItemService) *ItemHandler {
-	return &ItemHandler{ItemService: itemService}
+func NewItemHandler(itemService *service.ItemService, storageService *service.StorageService, currencyService *service.CurrencyService) *ItemHandler {
+	return &ItemHandler{ItemService: itemService, StorageService: storageService, CurrencyService: currencyService}
 }
 func (h *ItemHandler) GetAllItems(w http.ResponseWriter, r *http.Request) {
-	items, err := h.ItemService.GetAllItems()
+	// Viewer headers are only present when OptionalAuthMiddleware saw a valid token
+	items, err := h.ItemService.GetAllItems(r.Context(), r.Header.Get("X-User-ID"), r.Header.Get("X-Is-Admin") == "true")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	// Ensure we always return an array, even if nil
 	if items == nil {
 		items = []*models.Item{}
 	}
+	currency, ok := h.displayCurrency(w, r)
+	if !ok {
+		return
+	}
+	for _, item := range items {
+		// A missing rate for one item's currency shouldn't fail the whole listing
+		if err := h.CurrencyService.ConvertItem(item, currency); err != nil {
+			log.Printf("currency conversion for item %s: %v", item.Id, err)
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(items)
 }
 func (h *ItemHandler) CreateItem(w http.ResponseWriter, r *http.Request) {
-	// Parse multipart form (max 50MB for multiple images)
-	if err := r.ParseMultipartForm(50 << 20); err != nil {
-		http.Error(w, "Failed to parse form: "+err.Error(), http.StatusBadRequest)
+	// Parse multipart form (max 50MB for multiple images). Clients that sent
+	// their images through the resumable upload API may post a plain form.
+	if err := parseItemForm(r); err != nil {
+		utils.WriteProblem(w, r, http.StatusBadRequest, utils.CodeBadRequest, "Failed to parse form: "+err.Error())
 		return
 	}
 
 	// Get userID from JWT token (set by auth middleware)
 	userID := r.Header.Get("X-User-ID")
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -48,62 +66,59 @@ func (h *ItemHandler) CreateItem(w http.ResponseWriter, r *http.Request) {
 	item := models.Item{
 		Name:        r.FormValue("name"),
 		Description: r.FormValue("description"),
-		Images:      []models.ItemImage{}, // Initialize empty array
+		Category:    r.FormValue("category"),
+		Visibility:  r.FormValue("visibility"), // Defaults to public in the service
+		Images:      []models.ItemImage{},      // Initialize empty array
 	}
 
-	// Parse price and selling_price
-	if priceStr := r.FormValue("price"); priceStr != "" {
-		if price, err := strconv.ParseFloat(priceStr, 64); err == nil {
-			item.Price = price
-		}
+	// Sellers list in their own currency. Every field that can't be read is
+	// reported, along with the rules the rest of the item breaks.
+	var errs validate.Errors
+	currency := strings.ToUpper(r.FormValue("currency"))
+	if currency == "" {
+		currency = models.DefaultCurrency
 	}
-	if sellingPriceStr := r.FormValue("selling_price"); sellingPriceStr != "" {
-		if sellingPrice, err := strconv.ParseFloat(sellingPriceStr, 64); err == nil {
-			item.SellingPrice = sellingPrice
-		}
-	}
-	if quantityStr := r.FormValue("quantity"); quantityStr != "" {
-		if quantity, err := strconv.Atoi(quantityStr); err == nil {
-			item.Quantity = quantity
-		}
+	if !h.CurrencyService.Supported(currency) {
+		errs.Add("currency", "unsupported currency: "+currency)
 	}
 
-	// Handle multiple image uploads
-	var imagePaths []string
-	form := r.MultipartForm
-	files := form.File["images"] // Note: "images" (plural) in form
-
-	if len(files) > 0 {
-		// Validate images first
-		fileHeaders := make([]*multipart.FileHeader, len(files))
-		copy(fileHeaders, files)
-
-		if err := h.ItemService.ValidateImages(fileHeaders); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		// Save images
-		var err error
-		imagePaths, err = utils.SaveMultipleImages(fileHeaders, userID)
+	// Parse price and selling_price as exact decimal amounts
+	item.Price = models.NewMoney(0, currency)
+	item.SellingPrice = models.NewMoney(0, currency)
+	if !errs.Has("currency") {
+		parseMoneyField(r, "price", currency, &item.Price, &errs)
+		parseMoneyField(r, "selling_price", currency, &item.SellingPrice, &errs)
+	}
+	parseIntField(r, "quantity", &item.Quantity, &errs)
+	parseShippingFields(r, &item, &errs)
+	if len(errs) > 0 {
+		itemErrs, err := h.ItemService.ValidateItem(r.Context(), userID, &item)
 		if err != nil {
-			http.Error(w, "Failed to save images: "+err.Error(), http.StatusBadRequest)
+			writeError(w, r, err)
 			return
 		}
+		errs.Merge(itemErrs)
+		writeError(w, r, service.Invalid(errs))
+		return
 	}
 
-	// Create item with images
-	if err := h.ItemService.CreateItem(userID, &item, imagePaths); err != nil {
-		// Cleanup images on failure
-		utils.DeleteMultipleImages(imagePaths)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// Handle multiple image uploads
+	imagePaths, uploadIDs, err := h.collectImages(r, userID, 0)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Create item with images; the service removes them again on failure
+	if err := h.ItemService.CreateItem(r.Context(), userID, &item, imagePaths, uploadIDs); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	// Reload item to get images
-	createdItem, err := h.ItemService.GetItemById(item.Id)
+	createdItem, err := h.ItemService.GetItemById(r.Context(), item.Id)
 	if err != nil {
-		http.Error(w, "Item created but failed to load: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, r, fmt.Errorf("item created but failed to load: %w", err))
 		return
 	}
 
@@ -118,14 +133,21 @@ func (h *ItemHandler) GetItemById(w http.ResponseWriter, r *http.Request) {
 		id = r.URL.Query().Get("id") // Fallback for old style
 	}
 	if id == "" {
-		http.Error(w, "ID is required", http.StatusBadRequest)
+		utils.WriteProblem(w, r, http.StatusBadRequest, utils.CodeBadRequest, "ID is required")
 		return
 	}
-	item, err := h.ItemService.GetItemById(id)
+	item, err := h.ItemService.GetItemForViewer(r.Context(), id, r.Header.Get("X-User-ID"), r.Header.Get("X-Is-Admin") == "true")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+	currency, ok := h.displayCurrency(w, r)
+	if !ok {
 		return
 	}
+	if err := h.CurrencyService.ConvertItem(item, currency); err != nil {
+		log.Printf("currency conversion for item %s: %v", item.Id, err)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(item)
@@ -136,27 +158,28 @@ func (h *ItemHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
 		id = r.URL.Query().Get("id") // Fallback
 	}
 	if id == "" {
-		http.Error(w, "ID is required", http.StatusBadRequest)
+		utils.WriteProblem(w, r, http.StatusBadRequest, utils.CodeBadRequest, "ID is required")
 		return
 	}
 
 	// Get userID from JWT token (set by auth middleware)
 	userID := r.Header.Get("X-User-ID")
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
 		return
 	}
 
-	// Parse multipart form
-	if err := r.ParseMultipartForm(50 << 20); err != nil {
-		http.Error(w, "Failed to parse form: "+err.Error(), http.StatusBadRequest)
+	// Get existing item to preserve data. Only its seller gets further, so
+	// nobody else's images are read, counted against a quota or saved.
+	existingItem, err := h.ItemService.GetItemForUpdate(r.Context(), id, userID)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	// Get existing item to preserve data
-	existingItem, err := h.ItemService.GetItemById(id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+	// Parse multipart form
+	if err := parseItemForm(r); err != nil {
+		utils.WriteProblem(w, r, http.StatusBadRequest, utils.CodeBadRequest, "Failed to parse form: "+err.Error())
 		return
 	}
 
@@ -165,6 +188,7 @@ func (h *ItemHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
 		Id:          id,
 		Name:        existingItem.Name,
 		Description: existingItem.Description,
+		Category:    existingItem.Category,
 		Image:       existingItem.Image, // Keep existing image by default
 	}
 
@@ -174,74 +198,84 @@ func (h *ItemHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
 	if description := r.FormValue("description"); description != "" {
 		item.Description = description
 	}
+	if category, ok := r.Form["category"]; ok {
+		item.Category = category[0] // may be cleared
+	}
+	item.Visibility = existingItem.Visibility
+	if visibility := r.FormValue("visibility"); visibility != "" {
+		item.Visibility = visibility
+	}
 
-	// Parse other fields with fallback to existing values
-	item.Price = existingItem.Price
-	if priceStr := r.FormValue("price"); priceStr != "" {
-		if price, err := strconv.ParseFloat(priceStr, 64); err == nil {
-			item.Price = price
+	// Changing the currency would silently reinterpret the stored amounts,
+	// so a new currency must come with both prices
+	var errs validate.Errors
+	currency := existingItem.Price.Currency
+	if newCurrency := strings.ToUpper(r.FormValue("currency")); newCurrency != "" && newCurrency != currency {
+		switch {
+		case !h.CurrencyService.Supported(newCurrency):
+			errs.Add("currency", "unsupported currency: "+newCurrency)
+		case r.FormValue("price") == "" || r.FormValue("selling_price") == "":
+			errs.Add("currency", "price and selling_price are required when changing currency")
 		}
+		currency = newCurrency
 	}
 
+	// Parse other fields with fallback to existing values
+	item.Price = existingItem.Price
 	item.SellingPrice = existingItem.SellingPrice
-	if sellingPriceStr := r.FormValue("selling_price"); sellingPriceStr != "" {
-		if sellingPrice, err := strconv.ParseFloat(sellingPriceStr, 64); err == nil {
-			item.SellingPrice = sellingPrice
-		}
+	if !errs.Has("currency") {
+		parseMoneyField(r, "price", currency, &item.Price, &errs)
+		parseMoneyField(r, "selling_price", currency, &item.SellingPrice, &errs)
 	}
 
+	// Stock is only changed when the form sets it
 	item.Quantity = existingItem.Quantity
-	if quantityStr := r.FormValue("quantity"); quantityStr != "" {
-		if quantity, err := strconv.Atoi(quantityStr); err == nil {
-			item.Quantity = quantity
-		}
+	var quantity *int
+	if r.FormValue("quantity") != "" {
+		n := item.Quantity
+		parseIntField(r, "quantity", &n, &errs)
+		quantity, item.Quantity = &n, n
 	}
 
-	// Handle new image uploads
-	var imagePaths []string
-	form := r.MultipartForm
-	files := form.File["images"]
-
-	if len(files) > 0 {
-		// Validate images
-		fileHeaders := make([]*multipart.FileHeader, len(files))
-		copy(fileHeaders, files)
-
-		if err := h.ItemService.ValidateImages(fileHeaders); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		// Save new images first
-		var err error
-		imagePaths, err = utils.SaveMultipleImages(fileHeaders, userID)
+	item.WeightGrams = existingItem.WeightGrams
+	item.LengthCm = existingItem.LengthCm
+	item.WidthCm = existingItem.WidthCm
+	item.HeightCm = existingItem.HeightCm
+	item.ShippingProfileId = existingItem.ShippingProfileId
+	parseShippingFields(r, &item, &errs)
+	if len(errs) > 0 {
+		itemErrs, err := h.ItemService.ValidateItem(r.Context(), userID, &item)
 		if err != nil {
-			http.Error(w, "Failed to save images: "+err.Error(), http.StatusBadRequest)
+			writeError(w, r, err)
 			return
 		}
+		errs.Merge(itemErrs)
+		writeError(w, r, service.Invalid(errs))
+		return
 	}
 
-	if err := h.ItemService.UpdateItem(userID, &item, imagePaths); err != nil {
-		// Cleanup new images on failure
-		utils.DeleteMultipleImages(imagePaths)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// Handle new image uploads (saved before the update, removed again on failure).
+	// New images replace the existing ones, so their bytes are freed.
+	var replacedBytes int64
+	for _, img := range existingItem.Images {
+		replacedBytes += img.SizeBytes
+	}
+	imagePaths, uploadIDs, err := h.collectImages(r, userID, replacedBytes)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	// SUCCESS: Now delete old images ONLY if new ones were successfully saved
-	if len(imagePaths) > 0 {
-		for _, img := range existingItem.Images {
-			utils.DeleteImage(img.ImagePath)
-		}
-		if existingItem.Image != "" {
-			utils.DeleteImage(existingItem.Image)
-		}
+	// The service removes the new images on failure and the replaced ones on success
+	if err := h.ItemService.UpdateItem(r.Context(), userID, &item, quantity, imagePaths, uploadIDs); err != nil {
+		writeError(w, r, err)
+		return
 	}
 
 	// Reload item to get updated images
-	updatedItem, err := h.ItemService.GetItemById(id)
+	updatedItem, err := h.ItemService.GetItemById(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Item updated but failed to load: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, r, fmt.Errorf("item updated but failed to load: %w", err))
 		return
 	}
 
@@ -255,20 +289,199 @@ func (h *ItemHandler) DeleteItem(w http.ResponseWriter, r *http.Request) {
 		id = r.URL.Query().Get("id")
 	}
 	if id == "" {
-		http.Error(w, "Id is required", http.StatusBadRequest)
+		utils.WriteProblem(w, r, http.StatusBadRequest, utils.CodeBadRequest, "Id is required")
 		return
 	}
 	// Get userID from JWT token (set by auth middleware)
 	userID := r.Header.Get("X-User-ID")
 	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
 		return
 	}
-	if err := h.ItemService.DeleteItem(id, userID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.ItemService.DeleteItem(r.Context(), id, userID); err != nil {
+		writeError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Item deleted successfully"})
 }
+
+func (h *ItemHandler) GetInvites(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	invites, err := h.ItemService.GetInvites(r.Context(), id, userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invites)
+}
+func (h *ItemHandler) InviteUser(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	var body struct {
+		UserId string `json:"user_id" validate:"required"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if err := h.ItemService.InviteUser(r.Context(), id, userID, body.UserId); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"message": "User invited successfully"})
+}
+func (h *ItemHandler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	if err := h.ItemService.RevokeInvite(r.Context(), id, userID, r.PathValue("userId")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Invite revoked successfully"})
+}
+
+// parseShippingFields reads the weight, size and shipping profile fields
+// present in an item form. An empty shipping_profile_id detaches the profile.
+func parseShippingFields(r *http.Request, item *models.Item, errs *validate.Errors) {
+	parseIntField(r, "weight_grams", &item.WeightGrams, errs)
+	parseIntField(r, "length_cm", &item.LengthCm, errs)
+	parseIntField(r, "width_cm", &item.WidthCm, errs)
+	parseIntField(r, "height_cm", &item.HeightCm, errs)
+	if values, ok := r.Form["shipping_profile_id"]; ok {
+		item.ShippingProfileId = nil
+		if values[0] != "" {
+			id := values[0]
+			item.ShippingProfileId = &id
+		}
+	}
+}
+
+// parseIntField reads the whole number in the form field name into dst,
+// leaving dst alone when the field is missing or empty
+func parseIntField(r *http.Request, name string, dst *int, errs *validate.Errors) {
+	value := r.FormValue(name)
+	if value == "" {
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		errs.Add(name, name+" must be a whole number")
+		return
+	}
+	*dst = n
+}
+
+// parseMoneyField reads the amount in the form field name into dst, in
+// currency, leaving dst alone when the field is missing or empty
+func parseMoneyField(r *http.Request, name, currency string, dst *models.Money, errs *validate.Errors) {
+	value := r.FormValue(name)
+	if value == "" {
+		return
+	}
+	amount, err := models.ParseMoney(value, currency)
+	if err != nil {
+		errs.Add(name, "invalid "+name+": "+err.Error())
+		return
+	}
+	*dst = amount
+}
+
+// parseItemForm parses a multipart item form, falling back to a plain
+// urlencoded form when no files are embedded
+func parseItemForm(r *http.Request) error {
+	err := r.ParseMultipartForm(50 << 20)
+	if err == http.ErrNotMultipart {
+		return r.ParseForm()
+	}
+	return err
+}
+
+// collectImages saves the files embedded in the "images" form field and
+// returns their paths, with the ids of the resumable uploads listed in
+// "upload_ids" for the service to claim along with the item. Embedded files
+// are checked against the user's storage quota; resumable uploads were
+// counted when they started.
+func (h *ItemHandler) collectImages(r *http.Request, userID string, replacedBytes int64) ([]string, []string, error) {
+	var files []*multipart.FileHeader
+	if r.MultipartForm != nil {
+		files = r.MultipartForm.File["images"] // Note: "images" (plural) in form
+	}
+
+	var uploadIDs []string
+	for _, value := range r.Form["upload_ids"] {
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				uploadIDs = append(uploadIDs, id)
+			}
+		}
+	}
+
+	if len(files)+len(uploadIDs) > utils.MaxImages {
+		return nil, nil, service.InvalidField("images", fmt.Sprintf("maximum %d images allowed per item", utils.MaxImages))
+	}
+
+	var imagePaths []string
+	if len(files) > 0 {
+		// Validate images first
+		fileHeaders := make([]*multipart.FileHeader, len(files))
+		copy(fileHeaders, files)
+
+		if err := h.ItemService.ValidateImages(fileHeaders); err != nil {
+			return nil, nil, err
+		}
+
+		var incoming int64
+		for _, fileHeader := range fileHeaders {
+			incoming += fileHeader.Size
+		}
+		if err := h.StorageService.CheckQuota(r.Context(), userID, r.Header.Get("X-Is-Admin") == "true", incoming-replacedBytes); err != nil {
+			return nil, nil, err
+		}
+
+		saved, err := utils.SaveMultipleImages(fileHeaders, userID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to save images: %w", err)
+		}
+		imagePaths = append(imagePaths, saved...)
+	}
+
+	return imagePaths, uploadIDs, nil
+}
+
+// displayCurrency picks the currency to show prices in: the ?currency=
+// query parameter, else the signed-in user's preference. An unsupported
+// explicit currency is a client error; a stale preference is ignored.
+func (h *ItemHandler) displayCurrency(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if currency := strings.ToUpper(r.URL.Query().Get("currency")); currency != "" {
+		if !h.CurrencyService.Supported(currency) {
+			writeError(w, r, service.InvalidField("currency", "unsupported currency: "+currency))
+			return "", false
+		}
+		return currency, true
+	}
+	currency := h.CurrencyService.PreferredCurrency(r.Context(), r.Header.Get("X-User-ID"))
+	if currency != "" && !h.CurrencyService.Supported(currency) {
+		return "", true
+	}
+	return currency, true
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"primeauction/api/models"
+	"primeauction/api/service"
+	"primeauction/api/utils"
+	"strconv"
+)
+
+type LedgerHandler struct {
+	LedgerService *service.LedgerService
+}
+
+func NewLedgerHandler(ledgerService *service.LedgerService) *LedgerHandler {
+	return &LedgerHandler{LedgerService: ledgerService}
+}
+
+// GetMyBalance returns what the platform owes the current user, per currency
+func (h *LedgerHandler) GetMyBalance(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	balances, err := h.LedgerService.GetBalance(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(balances)
+}
+
+// GetMyStatement lists the postings on the current user's balance in one
+// currency (?currency=, default USD), newest first, up to ?limit= lines
+func (h *LedgerHandler) GetMyStatement(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	currency := r.URL.Query().Get("currency")
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	lines, err := h.LedgerService.GetStatement(r.Context(), userID, currency, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(lines)
+}
+
+// CreatePayout records money paid out to a seller. The Idempotency-Key
+// header is required so a retried request can't pay out twice.
+func (h *LedgerHandler) CreatePayout(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		SellerId string `json:"seller_id" validate:"required"`
+		Amount   string `json:"amount" validate:"required"`
+		Currency string `json:"currency" validate:"required"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	amount, err := models.ParseMoney(body.Amount, body.Currency)
+	if err != nil {
+		writeError(w, r, service.InvalidField("amount", err.Error()))
+		return
+	}
+
+	entry, err := h.LedgerService.RecordPayout(r.Context(), body.SellerId, amount, r.Header.Get("Idempotency-Key"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"primeauction/api/models"
+	"primeauction/api/service"
+	"primeauction/api/utils"
+)
+
+type OrderHandler struct {
+	OrderService *service.OrderService
+}
+
+func NewOrderHandler(orderService *service.OrderService) *OrderHandler {
+	return &OrderHandler{OrderService: orderService}
+}
+
+// CreateOrder checks out a list of items, reserving their stock until the
+// order is paid or the reservation expires
+func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	var body struct {
+		Lines     []models.OrderLineRequest `json:"lines"`
+		TaxRegion string                    `json:"tax_region"`     // defaults to the buyer's saved region
+		ShipTo    string                    `json:"ship_to_region"` // defaults to the tax region
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	order, err := h.OrderService.CreateOrder(r.Context(), userID, body.TaxRegion, body.ShipTo, body.Lines)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/api/orders/"+order.Id)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+// GetMyOrders lists the orders the current user has placed
+func (h *OrderHandler) GetMyOrders(w http.ResponseWriter, r *http.Request) {
+	orders, err := h.OrderService.GetOrdersByBuyer(r.Context(), r.Header.Get("X-User-ID"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(orders)
+}
+
+// GetMySales lists the orders containing the current user's items, with
+// the fee charged on each line
+func (h *OrderHandler) GetMySales(w http.ResponseWriter, r *http.Request) {
+	orders, err := h.OrderService.GetSales(r.Context(), r.Header.Get("X-User-ID"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(orders)
+}
+
+func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	order, err := h.OrderService.GetOrder(r.Context(), r.PathValue("id"), r.Header.Get("X-User-ID"), r.Header.Get("X-Is-Admin") == "true")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
+
+// CancelOrder cancels a pending order and puts its stock back on sale
+func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	order, err := h.OrderService.CancelOrder(r.Context(), r.PathValue("id"), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"primeauction/api/models"
+	"primeauction/api/payment"
+	"primeauction/api/service"
+	"primeauction/api/utils"
+)
+
+// maxWebhookBytes bounds the size of a webhook body
+const maxWebhookBytes = 1 << 20
+
+type PaymentHandler struct {
+	PaymentService *service.PaymentService
+	// Fake is set when the local fake provider is in use and enables the
+	// endpoint that simulates a buyer completing payment
+	Fake *payment.FakeProvider
+}
+
+func NewPaymentHandler(paymentService *service.PaymentService, fake *payment.FakeProvider) *PaymentHandler {
+	return &PaymentHandler{PaymentService: paymentService, Fake: fake}
+}
+
+// StartPayment creates (or resumes) the payment for an order and returns
+// the client secret the buyer's browser confirms it with
+func (h *PaymentHandler) StartPayment(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	p, err := h.PaymentService.StartPayment(r.Context(), r.PathValue("id"), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(p)
+}
+
+func (h *PaymentHandler) GetOrderPayments(w http.ResponseWriter, r *http.Request) {
+	payments, err := h.PaymentService.GetOrderPayments(r.Context(), r.PathValue("id"), r.Header.Get("X-User-ID"), r.Header.Get("X-Is-Admin") == "true")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(payments)
+}
+
+// Webhook receives payment provider events. It answers 2xx for events that
+// were applied or were duplicates and 5xx when the provider should retry.
+func (h *PaymentHandler) Webhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBytes))
+	if err != nil {
+		utils.WriteProblem(w, r, http.StatusBadRequest, utils.CodeBadRequest, "Failed to read body")
+		return
+	}
+	if err := h.PaymentService.HandleWebhook(r.Context(), payload, r.Header); err != nil {
+		writeError(w, r, err) // invalid events get 400; other failures are logged and get 500
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// ConfirmFakePayment plays the buyer's part with the fake provider and
+// delivers the resulting webhook in-process. It only exists in development.
+func (h *PaymentHandler) ConfirmFakePayment(w http.ResponseWriter, r *http.Request) {
+	if h.Fake == nil {
+		http.NotFound(w, r)
+		return
+	}
+	var body struct {
+		Fail bool `json:"fail"` // simulate a declined card
+	}
+	if r.ContentLength != 0 {
+		if !decodeJSON(w, r, &body) {
+			return
+		}
+	}
+
+	payload, header, err := h.Fake.Confirm(r.PathValue("intentId"), !body.Fail)
+	switch {
+	case errors.Is(err, payment.ErrIntentNotFound):
+		utils.WriteProblem(w, r, http.StatusNotFound, utils.CodeIntentNotFound, "payment intent not found")
+		return
+	case errors.Is(err, payment.ErrIntentNotPending):
+		utils.WriteProblem(w, r, http.StatusConflict, utils.CodeIntentNotPending, "payment intent is not awaiting payment")
+		return
+	case err != nil:
+		writeError(w, r, err)
+		return
+	}
+	if err := h.PaymentService.HandleWebhook(r.Context(), payload, header); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Payment confirmed"})
+}
+
+// GetPayment shows a payment with its event history, for admins
+func (h *PaymentHandler) GetPayment(w http.ResponseWriter, r *http.Request) {
+	p, events, err := h.PaymentService.GetPayment(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Payment *models.Payment       `json:"payment"`
+		Events  []models.PaymentEvent `json:"events"`
+	}{p, events})
+}
+
+// RefundPayment refunds a captured payment. An empty amount refunds
+// whatever has not been refunded yet.
+func (h *PaymentHandler) RefundPayment(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Amount string `json:"amount"`
+	}
+	if r.ContentLength != 0 {
+		if !decodeJSON(w, r, &body) {
+			return
+		}
+	}
+	p, err := h.PaymentService.RefundPayment(r.Context(), r.PathValue("id"), body.Amount)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"primeauction/api/models"
+	"primeauction/api/service"
+	"primeauction/api/utils"
+)
+
+type ShippingHandler struct {
+	ShippingService *service.ShippingService
+}
+
+func NewShippingHandler(shippingService *service.ShippingService) *ShippingHandler {
+	return &ShippingHandler{ShippingService: shippingService}
+}
+
+// GetMyProfiles lists the current user's shipping profiles
+func (h *ShippingHandler) GetMyProfiles(w http.ResponseWriter, r *http.Request) {
+	profiles, err := h.ShippingService.GetProfiles(r.Context(), r.Header.Get("X-User-ID"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(profiles)
+}
+
+// GetProfile returns a shipping profile, so buyers can see what an item
+// costs to ship
+func (h *ShippingHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	profile, err := h.ShippingService.GetProfile(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(profile)
+}
+
+// CreateProfile adds a shipping profile for the current user
+func (h *ShippingHandler) CreateProfile(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	var profile models.ShippingProfile
+	if !decodeJSON(w, r, &profile) {
+		return
+	}
+	if err := h.ShippingService.CreateProfile(r.Context(), userID, &profile); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(profile)
+}
+
+// UpdateProfile replaces one of the current user's shipping profiles
+func (h *ShippingHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	var profile models.ShippingProfile
+	if !decodeJSON(w, r, &profile) {
+		return
+	}
+	profile.Id = r.PathValue("id")
+	if err := h.ShippingService.UpdateProfile(r.Context(), userID, &profile); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(profile)
+}
+
+// DeleteProfile removes one of the current user's shipping profiles
+func (h *ShippingHandler) DeleteProfile(w http.ResponseWriter, r *http.Request) {
+	if err := h.ShippingService.DeleteProfile(r.Context(), r.Header.Get("X-User-ID"), r.PathValue("id")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Quote prices the shipping for a would-be order without placing it
+func (h *ShippingHandler) Quote(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Lines        []models.OrderLineRequest `json:"lines"`
+		ShipToRegion string                    `json:"ship_to_region"` // defaults to the buyer's saved region
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	quotes, err := h.ShippingService.QuoteOrder(r.Context(), r.Header.Get("X-User-ID"), body.ShipToRegion, body.Lines)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(quotes)
+}
+
+// UpdateShipment lets a seller add tracking details to one of their
+// shipments on a paid order, or change its status
+func (h *ShippingHandler) UpdateShipment(w http.ResponseWriter, r *http.Request) {
+	var update models.ShipmentUpdate
+	if !decodeJSON(w, r, &update) {
+		return
+	}
+	shipment, err := h.ShippingService.UpdateShipment(r.Context(), r.PathValue("id"), r.PathValue("shipmentId"),
+		r.Header.Get("X-User-ID"), r.Header.Get("X-Is-Admin") == "true", update)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(shipment)
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"primeauction/api/service"
+	"primeauction/api/utils"
+)
+
+type StorageHandler struct {
+	StorageService *service.StorageService
+}
+
+func NewStorageHandler(storageService *service.StorageService) *StorageHandler {
+	return &StorageHandler{StorageService: storageService}
+}
+func (h *StorageHandler) GetMyStorage(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	usage, err := h.StorageService.GetUsage(r.Context(), userID, r.Header.Get("X-Is-Admin") == "true")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(usage)
+}
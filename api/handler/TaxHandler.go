@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"primeauction/api/models"
+	"primeauction/api/service"
+	"primeauction/api/utils"
+)
+
+type TaxHandler struct {
+	TaxService *service.TaxService
+}
+
+func NewTaxHandler(taxService *service.TaxService) *TaxHandler {
+	return &TaxHandler{TaxService: taxService}
+}
+
+// GetRates lists every configured tax rate (admin only)
+func (h *TaxHandler) GetRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.TaxService.GetRates(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rates)
+}
+
+// SaveRate creates or replaces the rate for a region and category (admin only)
+func (h *TaxHandler) SaveRate(w http.ResponseWriter, r *http.Request) {
+	var rate models.TaxRate
+	if !decodeJSON(w, r, &rate) {
+		return
+	}
+	if err := h.TaxService.SaveRate(r.Context(), &rate); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rate)
+}
+
+// DeleteRate removes a region's rate; ?category= selects a category
+// override instead of the standard rate (admin only)
+func (h *TaxHandler) DeleteRate(w http.ResponseWriter, r *http.Request) {
+	if err := h.TaxService.DeleteRate(r.Context(), r.PathValue("region"), r.URL.Query().Get("category")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Tax rate deleted successfully"})
+}
+
+// GetMySettings returns the current user's tax region and price setting
+func (h *TaxHandler) GetMySettings(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	settings, err := h.TaxService.GetSettings(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(settings)
+}
+
+// UpdateMySettings sets the current user's tax region and whether their
+// prices as a seller include tax
+func (h *TaxHandler) UpdateMySettings(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	var settings models.TaxSettings
+	if !decodeJSON(w, r, &settings) {
+		return
+	}
+	if err := h.TaxService.UpdateSettings(r.Context(), userID, &settings); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(settings)
+}
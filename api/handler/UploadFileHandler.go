@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"primeauction/api/service"
+	"primeauction/api/utils"
+	"strings"
+)
+
+// UploadFileHandler serves files under /uploads/. Images that belong to a
+// non-public item are only served when the request carries a valid,
+// unexpired signature issued by the API.
+type UploadFileHandler struct {
+	ItemService *service.ItemService
+	root        string
+}
+
+func NewUploadFileHandler(itemService *service.ItemService) *UploadFileHandler {
+	return &UploadFileHandler{ItemService: itemService, root: "."}
+}
+
+func (h *UploadFileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		utils.WriteProblem(w, r, http.StatusMethodNotAllowed, utils.CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Stored paths look like "uploads/images/<file>"; staged resumable
+	// uploads and anything else under uploads/ are never served
+	filePath := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if !strings.HasPrefix(filePath, utils.UploadDir+"/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	requiresSignature, err := h.ItemService.ImageRequiresSignature(r.Context(), filePath)
+	if err != nil {
+		log.Printf("upload file handler: %v", err)
+		utils.WriteProblem(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+	if requiresSignature {
+		query := r.URL.Query()
+		if err := utils.VerifyImageSignature(filePath, query.Get("expires"), query.Get("sig")); err != nil {
+			switch {
+			case errors.Is(err, utils.ErrSignatureMissing):
+				utils.WriteProblem(w, r, http.StatusForbidden, utils.CodeSignatureRequired, "this image is only served through a signed link")
+			case errors.Is(err, utils.ErrSignatureExpired):
+				utils.WriteProblem(w, r, http.StatusForbidden, utils.CodeSignatureExpired, "this link has expired")
+			default:
+				utils.WriteProblem(w, r, http.StatusForbidden, utils.CodeSignatureInvalid, "this link's signature is not valid")
+			}
+			return
+		}
+		// Signed responses must not end up in shared caches
+		w.Header().Set("Cache-Control", "private, no-store")
+	}
+
+	fullPath := path.Join(h.root, filePath)
+	info, err := os.Stat(fullPath)
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, fullPath)
+}
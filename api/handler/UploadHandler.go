@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"primeauction/api/models"
+	"primeauction/api/service"
+	"primeauction/api/utils"
+	"strconv"
+	"strings"
+)
+
+// tusVersion is the tus protocol version the upload endpoints follow
+const tusVersion = "1.0.0"
+
+// UploadHandler exposes the resumable upload API. It follows the tus core
+// protocol closely enough for tus clients: POST creates an upload from
+// Upload-Length, HEAD reports Upload-Offset and PATCH appends a chunk at
+// Upload-Offset.
+type UploadHandler struct {
+	UploadService  *service.UploadService
+	StorageService *service.StorageService
+}
+
+func NewUploadHandler(uploadService *service.UploadService, storageService *service.StorageService) *UploadHandler {
+	return &UploadHandler{UploadService: uploadService, StorageService: storageService}
+}
+
+func (h *UploadHandler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil {
+		utils.WriteProblem(w, r, http.StatusBadRequest, utils.CodeBadRequest, "Upload-Length header is required")
+		return
+	}
+
+	// The declared length is reserved against the quota until the upload is
+	// attached to an item or expires
+	if err := h.StorageService.CheckQuota(r.Context(), userID, r.Header.Get("X-Is-Admin") == "true", size); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	upload, err := h.UploadService.CreateUpload(r.Context(), userID, parseUploadMetadata(r.Header.Get("Upload-Metadata"))["filename"], size)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeUploadHeaders(w, upload)
+	w.Header().Set("Location", "/api/uploads/"+upload.Id)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(upload)
+}
+
+func (h *UploadHandler) HeadUpload(w http.ResponseWriter, r *http.Request) {
+	upload, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+	writeUploadHeaders(w, upload)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *UploadHandler) GetUpload(w http.ResponseWriter, r *http.Request) {
+	upload, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+	writeUploadHeaders(w, upload)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(upload)
+}
+
+func (h *UploadHandler) PatchUpload(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		utils.WriteProblem(w, r, http.StatusUnsupportedMediaType, utils.CodeUnsupportedMedia, "Content-Type must be application/offset+octet-stream")
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		utils.WriteProblem(w, r, http.StatusBadRequest, utils.CodeBadRequest, "Upload-Offset header is required")
+		return
+	}
+
+	upload, err := h.UploadService.AppendChunk(r.Context(), r.PathValue("id"), userID, offset, r.Body)
+	if upload != nil {
+		writeUploadHeaders(w, upload)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UploadHandler) DeleteUpload(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return
+	}
+	if err := h.UploadService.CancelUpload(r.Context(), r.PathValue("id"), userID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UploadHandler) lookup(w http.ResponseWriter, r *http.Request) (*models.Upload, bool) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
+		return nil, false
+	}
+	upload, err := h.UploadService.GetUpload(r.Context(), r.PathValue("id"), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	return upload, true
+}
+
+func writeUploadHeaders(w http.ResponseWriter, upload *models.Upload) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+}
+
+// parseUploadMetadata decodes a tus Upload-Metadata header, a comma
+// separated list of "key base64value" pairs
+func parseUploadMetadata(header string) map[string]string {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), " ", 2)
+		if parts[0] == "" {
+			continue
+		}
+		value := ""
+		if len(parts) == 2 {
+			if decoded, err := base64.StdEncoding.DecodeString(parts[1]); err == nil {
+				value = string(decoded)
+			}
+		}
+		metadata[parts[0]] = value
+	}
+	return metadata
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"primeauction/api/service"
+	"primeauction/api/utils"
+)
+
+// StatusClientClosedRequest is the non-standard status recorded when the
+// client went away before its response was ready
+const StatusClientClosedRequest = 499
+
+// kindStatus is the status each kind of domain error is reported with
+var kindStatus = map[service.Kind]int{
+	service.KindInvalid:         http.StatusBadRequest,
+	service.KindUnauthenticated: http.StatusUnauthorized,
+	service.KindForbidden:       http.StatusForbidden,
+	service.KindNotFound:        http.StatusNotFound,
+	service.KindConflict:        http.StatusConflict,
+	service.KindTooLarge:        http.StatusRequestEntityTooLarge,
+	service.KindUnavailable:     http.StatusServiceUnavailable,
+}
+
+// writeError reports err as a problem+json response. Domain errors get the
+// status of their kind and their code; anything else is logged and
+// reported as a bare 500, so database and other internal errors never
+// reach clients. A request that failed because a context ended gets 499
+// when the client went away, and 504 when an operation ran out of time.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(r.Context().Err(), context.Canceled):
+		utils.WriteProblem(w, r, StatusClientClosedRequest, "request_canceled", "the client closed the request")
+		return
+	case service.IsCanceled(err):
+		utils.WriteProblem(w, r, http.StatusGatewayTimeout, "timeout", "the request took too long")
+		return
+	}
+
+	e := service.Classify(err)
+	if e == nil {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+		utils.WriteProblem(w, r, http.StatusInternalServerError, utils.CodeInternal, "internal server error")
+		return
+	}
+	status, ok := kindStatus[e.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	utils.WriteProblem(w, r, status, e.Code, e.Message, e.Fields...)
+}
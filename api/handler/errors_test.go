@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+	"primeauction/api/service"
+)
+
+func TestWriteError(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, tc := range []struct {
+		name   string
+		ctx    context.Context
+		err    error
+		status int
+		code   string
+		detail string
+	}{
+		{"invalid", nil, service.InvalidField("name", "name is required"), http.StatusBadRequest, "validation_failed", "name is required"},
+		{"unauthenticated", nil, service.ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials", "invalid email or password"},
+		{"not found", nil, fmt.Errorf("loading: %w", service.ErrOrderNotFound), http.StatusNotFound, "order_not_found", "order not found"},
+		{"detail", nil, fmt.Errorf("placing: %w", fmt.Errorf("%w: quantity is too large", service.ErrInvalidOrder)), http.StatusBadRequest, "invalid_order", "invalid order: quantity is too large"},
+		{"conflict", nil, repository.ErrInsufficientStock, http.StatusConflict, "insufficient_stock", repository.ErrInsufficientStock.Error()},
+		{"too large", nil, service.ErrQuotaExceeded, http.StatusRequestEntityTooLarge, "quota_exceeded", "storage quota exceeded"},
+		{"unavailable", nil, service.ErrRatesUnavailable, http.StatusServiceUnavailable, "rates_unavailable", "exchange rates are unavailable"},
+		{"internal", nil, errors.New("pq: password authentication failed"), http.StatusInternalServerError, "internal_error", "internal server error"},
+		{"client gone", canceled, context.Canceled, StatusClientClosedRequest, "request_canceled", "the client closed the request"},
+		{"client gone mid-query", canceled, errors.New("pq: connection reset"), StatusClientClosedRequest, "request_canceled", "the client closed the request"},
+		{"timed out", nil, fmt.Errorf("listing items: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, "timeout", "the request took too long"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+		if tc.ctx != nil {
+			r = r.WithContext(tc.ctx)
+		}
+		w := httptest.NewRecorder()
+		writeError(w, r, tc.err)
+
+		var problem models.Problem
+		if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+			t.Fatalf("%s: decoding the problem: %v", tc.name, err)
+		}
+		if w.Code != tc.status || problem.Status != tc.status || problem.Code != tc.code || problem.Detail != tc.detail {
+			t.Errorf("%s: got %d %q %q, want %d %q %q", tc.name, w.Code, problem.Code, problem.Detail, tc.status, tc.code, tc.detail)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+			t.Errorf("%s: Content-Type = %q", tc.name, ct)
+		}
+	}
+}
+
+func TestWriteErrorListsFields(t *testing.T) {
+	fields := []models.FieldError{
+		{Field: "name", Message: "name is required"},
+		{Field: "price", Message: "price cannot be negative"},
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/items", nil)
+	w := httptest.NewRecorder()
+	writeError(w, r, service.Invalid(fields))
+
+	var problem models.Problem
+	if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusBadRequest || len(problem.Errors) != 2 || problem.Errors[1] != fields[1] {
+		t.Errorf("got %d with errors %v, want 400 with %v", w.Code, problem.Errors, fields)
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+
+	"primeauction/api/service"
+	"primeauction/api/utils"
+	"primeauction/api/validate"
+)
+
+// decodeJSON reads a JSON request body into dst, a pointer to a struct,
+// and checks it against the validation rules on its fields. When the body
+// is malformed or breaks rules it writes the problem, with every field at
+// fault, and returns false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			writeError(w, r, service.InvalidField(typeErr.Field, typeErr.Field+" must be "+describeType(typeErr.Type)))
+			return false
+		}
+		utils.WriteProblem(w, r, http.StatusBadRequest, utils.CodeInvalidBody, "Invalid request body: "+err.Error())
+		return false
+	}
+	if err := service.Invalid(validate.Struct(dst)); err != nil {
+		writeError(w, r, err)
+		return false
+	}
+	return true
+}
+
+// describeType names the sort of JSON value a Go type is decoded from
+func describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	}
+	return "an object"
+}
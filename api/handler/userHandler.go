@@ -16,9 +16,9 @@ func NewUserHandler(userService *service.UserService) *UserHandler {
 	return &UserHandler{UserService: userService}
 }
 func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := h.UserService.GetAllUsers()
+	users, err := h.UserService.GetAllUsers(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -27,9 +27,9 @@ func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 }
 func (h *UserHandler) GetAllUser(w http.ResponseWriter, r *http.Request) {
 
-	users, err := h.UserService.GetAllUsers()
+	users, err := h.UserService.GetAllUsers(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -42,12 +42,12 @@ func (h *UserHandler) GetUserById(w http.ResponseWriter, r *http.Request) {
 		id = r.URL.Query().Get("id")
 	}
 	if id == "" {
-		http.Error(w, "ID is required", http.StatusBadRequest)
+		utils.WriteProblem(w, r, http.StatusBadRequest, utils.CodeBadRequest, "ID is required")
 		return
 	}
-	user, err := h.UserService.GetUserById(id)
+	user, err := h.UserService.GetUserById(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -57,20 +57,19 @@ func (h *UserHandler) GetUserById(w http.ResponseWriter, r *http.Request) {
 
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var user models.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &user) {
 		return
 	}
 
-	if err := h.UserService.CreateUser(&user); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := h.UserService.CreateUser(r.Context(), &user); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	// Generate JWT token
 	token, err := utils.GenerateToken(user.Id, user.Email, user.IsAdmin)
 	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		utils.WriteProblem(w, r, http.StatusInternalServerError, utils.CodeInternal, "Failed to generate token")
 		return
 	}
 
@@ -86,25 +85,24 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var credentials struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email    string `json:"email" validate:"required"`
+		Password string `json:"password" validate:"required"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &credentials) {
 		return
 	}
 
-	user, err := h.UserService.LoginUser(credentials.Email, credentials.Password)
+	user, err := h.UserService.LoginUser(r.Context(), credentials.Email, credentials.Password)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		writeError(w, r, err)
 		return
 	}
 
 	// Generate JWT token
 	token, err := utils.GenerateToken(user.Id, user.Email, user.IsAdmin)
 	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		utils.WriteProblem(w, r, http.StatusInternalServerError, utils.CodeInternal, "Failed to generate token")
 		return
 	}
 
@@ -117,14 +115,12 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var user models.User
-	err := json.NewDecoder(r.Body).Decode(&user)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !decodeJSON(w, r, &user) {
 		return
 	}
-	err = h.UserService.CreateUser(&user)
+	err := h.UserService.CreateUser(r.Context(), &user)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	user.Password = ""
@@ -138,18 +134,16 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		id = r.URL.Query().Get("id")
 	}
 	if id == "" {
-		http.Error(w, "ID is required", http.StatusBadRequest)
+		utils.WriteProblem(w, r, http.StatusBadRequest, utils.CodeBadRequest, "ID is required")
 		return
 	}
 	var user models.User
-	err := json.NewDecoder(r.Body).Decode(&user)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !decodeJSON(w, r, &user) {
 		return
 	}
-	err = h.UserService.UpdateUser(id, &user)
+	err := h.UserService.UpdateUser(r.Context(), id, &user)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -162,12 +156,12 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		id = r.URL.Query().Get("id")
 	}
 	if id == "" {
-		http.Error(w, "ID is required", http.StatusBadRequest)
+		utils.WriteProblem(w, r, http.StatusBadRequest, utils.CodeBadRequest, "ID is required")
 		return
 	}
-	err := h.UserService.DeleteUser(id)
+	err := h.UserService.DeleteUser(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
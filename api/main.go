@@ -1,45 +1,98 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
-	"net/http"
+	"os"
 
 	database "primeauction/api/Database"
-	repository "primeauction/api/Repository"
-	"primeauction/api/handler"
-	"primeauction/api/middleware"
-	"primeauction/api/routes"
-	"primeauction/api/service"
+	"primeauction/api/config"
 )
 
+const usage = `Usage: api [command] [flags]
+
+Commands:
+  serve                  run the HTTP API (the default)
+  migrate up             apply pending migrations
+  migrate down [-steps]  roll back the latest migrations (1 by default)
+  migrate status         list migrations and when they were applied
+  seed                   add demo users, a shipping profile and items (not in production)
+  user create            create a user: -email, -username, [-password-file], [-admin]
+  user promote           grant admin rights: -email, [-revoke]
+  user reset-password    set a new password: -email, [-password-file]
+  gc                     delete orphaned uploads once: [-dry-run]
+  backfill-sizes         record the sizes of images saved before quotas: [-dry-run]
+  reconcile              verify the ledger against payments: [-fix]
+  config print           show the configuration, with secrets redacted
+
+Every command reads the same configuration as the server: environment
+variables, an optional .env file and the optional YAML file named by
+CONFIG_FILE. Passwords are read from the file -password-file names, or
+from standard input with -password-file -; when none is given one is
+generated and printed.
+`
+
 func main() {
-	// Initialize database
-	if err := database.InitDB(); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
 	}
-	defer database.CloseDB()
-
-	// Initialize repositories
-	itemRepo := repository.NewItemRepository(database.DB)
-	userRepo := repository.NewUserRepository(database.DB)
+	if err := run(context.Background(), command, args); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	// Initialize services
-	itemService := service.NewItemService(itemRepo)
-	userService := service.NewUserService(userRepo)
+func run(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return nil
+	}
 
-	// Initialize handlers
-	itemHandler := handler.NewItemHandler(itemService)
-	userHandler := handler.NewUserHandler(userService)
+	cfg, err := config.Load()
+	if command == "config" {
+		return runConfig(cfg, err, args)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
 
-	// Serve static files (uploaded images) with CORS
-	fs := http.FileServer(http.Dir("./uploads"))
-	http.Handle("/uploads/", middleware.CORSHandler(http.StripPrefix("/uploads/", fs)))
+	switch command {
+	case "migrate":
+		// Connect without migrating, so a broken migration can be rolled back
+		if err := database.Connect(); err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		defer database.CloseDB()
+		return runMigrate(args)
+	case "serve", "seed", "user", "gc", "backfill-sizes", "reconcile":
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", command)
+	}
 
-	// Setup and register routes
-	routesList := routes.SetupRoutes(itemHandler, userHandler)
-	routes.RegisterRoutes(&routesList)
+	// Initialize database
+	if err := database.InitDB(); err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer database.CloseDB()
 
-	// Start server
-	log.Println("Server is running on port 8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	a, err := newApp(cfg)
+	if err != nil {
+		return err
+	}
+	switch command {
+	case "seed":
+		return runSeed(ctx, a, args)
+	case "user":
+		return runUser(ctx, a, args)
+	case "gc":
+		return runGC(ctx, a, args)
+	case "backfill-sizes":
+		return runBackfillSizes(ctx, a, args)
+	case "reconcile":
+		return runReconcile(ctx, a, args)
+	}
+	return serve(a)
 }
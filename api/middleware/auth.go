@@ -8,20 +8,21 @@ import (
 
 func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		clearIdentityHeaders(r)
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
 			return
 		}
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
 			return
 		}
 		token := parts[1]
 		claims, err := utils.ValidateToken(token)
 		if err != nil {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			utils.WriteProblem(w, r, http.StatusUnauthorized, utils.CodeUnauthorized, "Unauthorized")
 			return
 		}
 		r.Header.Set("X-User-ID", claims.UserID)
@@ -36,9 +37,38 @@ func AdminMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		adminHeader := r.Header.Get("X-Is-Admin")
 		if adminHeader != "true" {
-			http.Error(w, "Forbidden: Admin access required", http.StatusForbidden)
+			utils.WriteProblem(w, r, http.StatusForbidden, utils.CodeForbidden, "Forbidden: Admin access required")
 			return
 		}
 		next(w, r)
 	}
 }
+
+
+// OptionalAuthMiddleware identifies the caller when a valid bearer token is
+// present but lets anonymous requests through, so public routes can show
+// extra data to owners and admins
+func OptionalAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clearIdentityHeaders(r)
+		parts := strings.Split(r.Header.Get("Authorization"), " ")
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			if claims, err := utils.ValidateToken(parts[1]); err == nil {
+				r.Header.Set("X-User-ID", claims.UserID)
+				r.Header.Set("X-User-Email", claims.Email)
+				if claims.IsAdmin {
+					r.Header.Set("X-Is-Admin", "true")
+				}
+			}
+		}
+		next(w, r)
+	}
+}
+
+// clearIdentityHeaders drops identity headers sent by the client so they
+// can only ever come from a verified token
+func clearIdentityHeaders(r *http.Request) {
+	r.Header.Del("X-User-ID")
+	r.Header.Del("X-User-Email")
+	r.Header.Del("X-Is-Admin")
+}
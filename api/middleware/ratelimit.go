@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"primeauction/api/utils"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter allows up to limit requests per key within a sliding window
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu   sync.Mutex
+	hits map[string][]time.Time
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:  limit,
+		window: window,
+		hits:   make(map[string][]time.Time),
+	}
+}
+
+// Allow records a hit for key and reports whether it is within the limit.
+// When it is not, the returned duration is how long until a slot frees up.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-l.window)
+	recent := l.hits[key][:0]
+	for _, t := range l.hits[key] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= l.limit {
+		l.hits[key] = recent
+		return false, recent[0].Sub(cutoff)
+	}
+	l.hits[key] = append(recent, now)
+
+	// Forget idle users so the map doesn't grow without bound
+	if len(l.hits) > 10000 {
+		for k, times := range l.hits {
+			if len(times) == 0 || times[len(times)-1].Before(cutoff) {
+				delete(l.hits, k)
+			}
+		}
+	}
+	return true, 0
+}
+
+// UploadRateLimitMiddleware rejects authenticated users who exceed the
+// limiter with 429 Too Many Requests. It must run after AuthMiddleware.
+func UploadRateLimitMiddleware(limiter *RateLimiter, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed, retryAfter := limiter.Allow(r.Header.Get("X-User-ID"))
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			utils.WriteProblem(w, r, http.StatusTooManyRequests, utils.CodeRateLimited, "Too many uploads, please try again later")
+			return
+		}
+		next(w, r)
+	}
+}
@@ -2,10 +2,11 @@ package models
 import "time"
 type User struct {
 	Id string `json:"id"`
-	Username string `json:"username"`
-	Email string `json:"email"`
-	Password string `json:"password"`
+	Username string `json:"username" validate:"required,min=3,max=50"`
+	Email string `json:"email" validate:"required,email,max=100"`
+	Password string `json:"password" validate:"required,password"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 IsAdmin bool `json:"is_admin"`
+	PreferredCurrency string `json:"preferred_currency"`
 }
\ No newline at end of file
@@ -0,0 +1,99 @@
+package models
+
+import "time"
+
+// Dispute is a buyer's complaint about one seller's part of a paid order,
+// e.g. an item that never arrived or is being returned. The buyer and
+// seller talk it through in the dispute's messages; either may escalate it
+// to an admin. It ends with a refund, a partial refund or no refund.
+type Dispute struct {
+	Id         string           `json:"id"`
+	OrderId    string           `json:"order_id"`
+	BuyerId    string           `json:"buyer_id"`
+	SellerId   string           `json:"seller_id"`
+	Reason     string           `json:"reason"`
+	Status     string           `json:"status"`
+	Resolution string           `json:"resolution,omitempty"`
+	Refund     Money            `json:"refund"`    // refunded to the buyer on resolution
+	Restocked  bool             `json:"restocked"` // the items were put back in stock
+	ResolvedBy *string          `json:"resolved_by,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+	ResolvedAt *time.Time       `json:"resolved_at,omitempty"`
+	Messages   []DisputeMessage `json:"messages,omitempty"`
+}
+
+// DisputeMessage is one message in a dispute's thread
+type DisputeMessage struct {
+	Id        string    `json:"id"`
+	DisputeId string    `json:"dispute_id"`
+	AuthorId  string    `json:"author_id"`
+	Role      string    `json:"role"` // buyer, seller or admin
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Dispute reasons
+const (
+	DisputeNotReceived    = "not_received"
+	DisputeNotAsDescribed = "not_as_described"
+	DisputeReturn         = "return" // the buyer is sending the items back
+	DisputeOther          = "other"
+)
+
+// Dispute statuses
+const (
+	DisputeOpen            = "open" // waiting for the seller
+	DisputeSellerResponded = "seller_responded"
+	DisputeEscalated       = "escalated" // waiting for an admin
+	DisputeResolved        = "resolved"
+)
+
+// Dispute resolutions
+const (
+	DisputeRefund        = "refund" // everything paid for the seller's items
+	DisputePartialRefund = "partial_refund"
+	DisputeNoRefund      = "no_refund"
+)
+
+// Roles of the people taking part in a dispute
+const (
+	DisputeRoleBuyer  = "buyer"
+	DisputeRoleSeller = "seller"
+	DisputeRoleAdmin  = "admin"
+)
+
+// disputeTransitions lists the statuses each status may move to
+var disputeTransitions = map[string][]string{
+	DisputeOpen:            {DisputeSellerResponded, DisputeEscalated, DisputeResolved},
+	DisputeSellerResponded: {DisputeEscalated, DisputeResolved},
+	DisputeEscalated:       {DisputeResolved},
+}
+
+// CanTransitionDispute reports whether a dispute may move from one status
+// to another
+func CanTransitionDispute(from, to string) bool {
+	for _, next := range disputeTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDisputeReason reports whether reason is a known dispute reason
+func IsDisputeReason(reason string) bool {
+	switch reason {
+	case DisputeNotReceived, DisputeNotAsDescribed, DisputeReturn, DisputeOther:
+		return true
+	}
+	return false
+}
+
+// DisputeResolution is how a seller or admin settles a dispute
+type DisputeResolution struct {
+	Resolution string `json:"resolution"`
+	Amount     string `json:"amount,omitempty"`  // decimal; required for a partial refund
+	Restock    bool   `json:"restock,omitempty"` // put the items back in stock; full refunds only
+	Note       string `json:"note,omitempty"`    // added to the thread
+}
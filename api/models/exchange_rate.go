@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// RateTable is a set of exchange rates quoted against a base currency:
+// one unit of Base buys Rates[code] units of code. Rates are decimal
+// strings so they can be converted without floating point error.
+type RateTable struct {
+	Base      string            `json:"base"`
+	Rates     map[string]string `json:"rates"`
+	AsOf      time.Time         `json:"as_of"`      // when the provider published the rates
+	FetchedAt time.Time         `json:"fetched_at"` // when we loaded them
+	Source    string            `json:"source"`
+}
+
+// ConvertedPrice is an item's price expressed in a buyer's currency. It is
+// informational only; the item's own price stays authoritative.
+type ConvertedPrice struct {
+	Price        Money     `json:"price"`
+	SellingPrice Money     `json:"selling_price"`
+	Rate         string    `json:"rate"` // units of the target currency per unit of the item's currency
+	RateAsOf     time.Time `json:"rate_as_of"`
+	RateSource   string    `json:"rate_source"`
+}
@@ -0,0 +1,255 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Events a fee can be charged on
+const (
+	FeeOnSale    = "sale"    // commission on each order line, charged at checkout
+	FeeOnListing = "listing" // charged once when an item is first listed
+)
+
+// Fee rule types
+const (
+	FeePercentage = "percentage" // bps of the amount, plus an optional fixed part
+	FeeFixed      = "fixed"      // flat amount per unit
+	FeeTiered     = "tiered"     // marginal rates by price band, like tax brackets
+)
+
+// DefaultSellerTier is the tier every seller starts on
+const DefaultSellerTier = "standard"
+
+// FeeSchedule is one published version of the fee rules. Schedules are
+// never edited: publishing creates a new version, and orders keep the
+// version that priced them.
+type FeeSchedule struct {
+	Version   int       `json:"version"`
+	Rules     []FeeRule `json:"rules"`
+	Note      string    `json:"note"`
+	CreatedBy *string   `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FeeRule is a fee for one event, optionally limited to a category and
+// seller tier. Rules are tried in order and the first match applies.
+// Amounts in a rule (fixed, tier bounds, min and cap) must share one
+// currency and the rule then only matches amounts in that currency.
+type FeeRule struct {
+	Name       string    `json:"name"`
+	Event      string    `json:"event"`
+	Category   string    `json:"category,omitempty"`    // empty matches every category
+	SellerTier string    `json:"seller_tier,omitempty"` // empty matches every tier
+	Type       string    `json:"type"`
+	BPS        int64     `json:"bps,omitempty"`   // percentage: basis points of the amount
+	Fixed      *Money    `json:"fixed,omitempty"` // fixed, or added to a percentage; per unit
+	Tiers      []FeeTier `json:"tiers,omitempty"` // tiered: ascending bands
+	Min        *Money    `json:"min,omitempty"`   // lower bound on the fee
+	Cap        *Money    `json:"cap,omitempty"`   // upper bound on the fee
+}
+
+// FeeTier charges BPS on the part of the amount up to UpTo that the
+// previous tier didn't cover. The last tier has no UpTo.
+type FeeTier struct {
+	UpTo *Money `json:"up_to,omitempty"`
+	BPS  int64  `json:"bps"`
+}
+
+// FeeAssessment is the fee charged on one order line or listing and how it
+// was worked out
+type FeeAssessment struct {
+	ScheduleVersion int    `json:"schedule_version"`
+	Rule            string `json:"rule,omitempty"` // empty when no rule matched
+	Type            string `json:"type,omitempty"`
+	Base            Money  `json:"base"`       // the amount the fee was computed on
+	Calculated      Money  `json:"calculated"` // before min and cap
+	Capped          bool   `json:"capped,omitempty"`
+	Amount          Money  `json:"amount"`
+}
+
+// currency returns the currency of the rule's amounts, or "" if it has none
+func (r *FeeRule) currency() string {
+	if r.Fixed != nil {
+		return normalizeCurrency(r.Fixed.Currency)
+	}
+	if r.Min != nil {
+		return normalizeCurrency(r.Min.Currency)
+	}
+	if r.Cap != nil {
+		return normalizeCurrency(r.Cap.Currency)
+	}
+	for _, t := range r.Tiers {
+		if t.UpTo != nil {
+			return normalizeCurrency(t.UpTo.Currency)
+		}
+	}
+	return ""
+}
+
+// Matches reports whether the rule applies to an event for an item in
+// category sold by a seller on tier, priced in currency
+func (r *FeeRule) Matches(event, category, tier, currency string) bool {
+	if r.Event != event {
+		return false
+	}
+	if r.Category != "" && !strings.EqualFold(r.Category, category) {
+		return false
+	}
+	if r.SellerTier != "" && !strings.EqualFold(r.SellerTier, tier) {
+		return false
+	}
+	c := r.currency()
+	return c == "" || c == normalizeCurrency(currency)
+}
+
+// Validate checks that a rule is complete and its amounts are consistent
+func (r *FeeRule) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return errors.New("name is required")
+	}
+	if r.Event != FeeOnSale && r.Event != FeeOnListing {
+		return fmt.Errorf("event must be %s or %s", FeeOnSale, FeeOnListing)
+	}
+	if r.BPS < 0 || r.BPS > 10000 {
+		return errors.New("bps must be between 0 and 10000")
+	}
+
+	currency := r.currency()
+	for _, m := range []*Money{r.Fixed, r.Min, r.Cap} {
+		if m == nil {
+			continue
+		}
+		if m.IsNegative() {
+			return errors.New("amounts cannot be negative")
+		}
+		if normalizeCurrency(m.Currency) != currency {
+			return errors.New("all amounts in a rule must use the same currency")
+		}
+	}
+	if r.Min != nil && r.Cap != nil && r.Min.Amount > r.Cap.Amount {
+		return errors.New("min cannot be greater than cap")
+	}
+
+	switch r.Type {
+	case FeePercentage:
+		if len(r.Tiers) > 0 {
+			return errors.New("a percentage rule cannot have tiers")
+		}
+	case FeeFixed:
+		if r.Fixed == nil {
+			return errors.New("a fixed rule needs a fixed amount")
+		}
+		if r.BPS != 0 || len(r.Tiers) > 0 {
+			return errors.New("a fixed rule cannot have bps or tiers")
+		}
+	case FeeTiered:
+		if len(r.Tiers) == 0 {
+			return errors.New("a tiered rule needs at least one tier")
+		}
+		if r.BPS != 0 || r.Fixed != nil {
+			return errors.New("a tiered rule cannot have bps or a fixed amount")
+		}
+		var prev int64
+		for i, t := range r.Tiers {
+			if t.BPS < 0 || t.BPS > 10000 {
+				return fmt.Errorf("tier %d: bps must be between 0 and 10000", i+1)
+			}
+			last := i == len(r.Tiers)-1
+			if t.UpTo == nil {
+				if !last {
+					return fmt.Errorf("tier %d: only the last tier may be open-ended", i+1)
+				}
+				continue
+			}
+			if normalizeCurrency(t.UpTo.Currency) != currency {
+				return errors.New("all amounts in a rule must use the same currency")
+			}
+			if t.UpTo.Amount <= prev {
+				return fmt.Errorf("tier %d: bounds must be positive and ascending", i+1)
+			}
+			prev = t.UpTo.Amount
+		}
+	default:
+		return fmt.Errorf("type must be %s, %s or %s", FeePercentage, FeeFixed, FeeTiered)
+	}
+	return nil
+}
+
+// Validate checks every rule in the schedule
+func (s *FeeSchedule) Validate() error {
+	if len(s.Rules) > 200 {
+		return errors.New("a schedule can have at most 200 rules")
+	}
+	for i := range s.Rules {
+		if err := s.Rules[i].Validate(); err != nil {
+			return fmt.Errorf("rule %d (%s): %w", i+1, s.Rules[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// Assess works out the fee for an event on quantity units worth base in
+// total (net of tax) using the first matching rule. No matching rule means
+// no fee.
+func (s *FeeSchedule) Assess(event, category, tier string, base Money, quantity int) FeeAssessment {
+	currency := normalizeCurrency(base.Currency)
+	assessment := FeeAssessment{
+		ScheduleVersion: s.Version,
+		Base:            base,
+		Calculated:      NewMoney(0, currency),
+		Amount:          NewMoney(0, currency),
+	}
+
+	for i := range s.Rules {
+		rule := &s.Rules[i]
+		if !rule.Matches(event, category, tier, currency) {
+			continue
+		}
+
+		var fee int64
+		switch rule.Type {
+		case FeePercentage:
+			fee = bpsOf(base.Amount, rule.BPS)
+		case FeeTiered:
+			var lower int64
+			for _, t := range rule.Tiers {
+				upper := base.Amount
+				if t.UpTo != nil && t.UpTo.Amount < upper {
+					upper = t.UpTo.Amount
+				}
+				if upper > lower {
+					fee += bpsOf(upper-lower, t.BPS)
+					lower = upper
+				}
+			}
+		}
+		if rule.Fixed != nil {
+			fee += rule.Fixed.Amount * int64(quantity)
+		}
+
+		amount := fee
+		if rule.Min != nil && amount < rule.Min.Amount {
+			amount = rule.Min.Amount
+		}
+		if rule.Cap != nil && amount > rule.Cap.Amount {
+			amount = rule.Cap.Amount
+			assessment.Capped = true
+		}
+
+		assessment.Rule = rule.Name
+		assessment.Type = rule.Type
+		assessment.Calculated = NewMoney(fee, currency)
+		assessment.Amount = NewMoney(amount, currency)
+		return assessment
+	}
+	return assessment
+}
+
+// bpsOf returns bps basis points of a non-negative amount, rounded half up,
+// without overflowing for large amounts
+func bpsOf(amount, bps int64) int64 {
+	return amount/10000*bps + (amount%10000*bps+5000)/10000
+}
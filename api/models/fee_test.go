@@ -0,0 +1,81 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func money(amount int64) *Money {
+	m := NewMoney(amount, "USD")
+	return &m
+}
+
+func TestBPSOf(t *testing.T) {
+	for _, tc := range []struct {
+		amount, bps int64
+		want        int64
+	}{
+		{1250, 1000, 125},
+		{5, 1000, 1}, // 0.5 rounds up
+		{4, 1000, 0},
+		{15, 300, 0}, // 0.45 rounds down
+		{2999, 1290, 387},
+		{0, 1000, 0},
+		{1000, 0, 0},
+		{math.MaxInt64, 10000, math.MaxInt64},
+		{math.MaxInt64, 1, 922337203685478},
+	} {
+		if got := bpsOf(tc.amount, tc.bps); got != tc.want {
+			t.Errorf("bpsOf(%d, %d) = %d, want %d", tc.amount, tc.bps, got, tc.want)
+		}
+	}
+}
+
+func TestFeeScheduleAssess(t *testing.T) {
+	schedule := FeeSchedule{Version: 3, Rules: []FeeRule{
+		{Name: "books", Event: FeeOnSale, Category: "books", Type: FeePercentage, BPS: 1290, Fixed: money(30)},
+		{Name: "pro", Event: FeeOnSale, SellerTier: "pro", Type: FeeTiered, Tiers: []FeeTier{
+			{UpTo: money(10000), BPS: 1000},
+			{UpTo: money(50000), BPS: 500},
+			{BPS: 250},
+		}},
+		{Name: "standard", Event: FeeOnSale, Type: FeePercentage, BPS: 1000, Min: money(50), Cap: money(1000)},
+	}}
+	if err := schedule.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		event      string
+		category   string
+		tier       string
+		base       Money
+		quantity   int
+		rule       string
+		calculated int64
+		amount     int64
+		capped     bool
+	}{
+		// 12.9% of 29.99 is 3.8687, plus 0.30 for each of three units
+		{"percentage and fixed", FeeOnSale, "Books", "pro", NewMoney(2999, "USD"), 3, "books", 477, 477, false},
+		// Each band is rounded on its own: 10.00 + 20.00 + 2.50125
+		{"tiered", FeeOnSale, "tools", "pro", NewMoney(60005, "USD"), 1, "pro", 3250, 3250, false},
+		{"tiered within the first band", FeeOnSale, "tools", "pro", NewMoney(10005, "USD"), 1, "pro", 1000, 1000, false},
+		{"raised to the minimum", FeeOnSale, "tools", "standard", NewMoney(100, "USD"), 1, "standard", 10, 50, false},
+		{"capped", FeeOnSale, "tools", "standard", NewMoney(100000, "USD"), 1, "standard", 10000, 1000, true},
+		{"between min and cap", FeeOnSale, "tools", "standard", NewMoney(1005, "USD"), 1, "standard", 101, 101, false},
+		// Rules with USD amounts don't apply to another currency
+		{"other currency", FeeOnSale, "books", "pro", NewMoney(1000, "EUR"), 1, "", 0, 0, false},
+		{"no matching rule", FeeOnListing, "books", "pro", NewMoney(1000, "USD"), 1, "", 0, 0, false},
+	} {
+		got := schedule.Assess(tc.event, tc.category, tc.tier, tc.base, tc.quantity)
+		if got.Rule != tc.rule || got.Calculated.Amount != tc.calculated || got.Amount.Amount != tc.amount || got.Capped != tc.capped {
+			t.Errorf("%s: Assess = rule %q, calculated %d, amount %d, capped %v; want rule %q, calculated %d, amount %d, capped %v",
+				tc.name, got.Rule, got.Calculated.Amount, got.Amount.Amount, got.Capped, tc.rule, tc.calculated, tc.amount, tc.capped)
+		}
+		if got.ScheduleVersion != 3 || got.Amount.Currency != tc.base.Currency {
+			t.Errorf("%s: Assess = version %d in %s", tc.name, got.ScheduleVersion, got.Amount.Currency)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// ImageFlag records that an item image closely matches an image listed by
+// another seller. Flags are queued for admin review and never block uploads.
+type ImageFlag struct {
+	Id             string     `json:"id"`
+	ItemId         string     `json:"item_id"`
+	ImageId        string     `json:"image_id"`
+	MatchedItemId  string     `json:"matched_item_id"`
+	MatchedImageId string     `json:"matched_image_id"`
+	Distance       int        `json:"distance"` // Hamming distance between the perceptual hashes
+	Status         string     `json:"status"`
+	ReviewedBy     *string    `json:"reviewed_by,omitempty"`
+	ReviewedAt     *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// Image flag review statuses
+const (
+	FlagPending   = "pending"
+	FlagDismissed = "dismissed"
+	FlagConfirmed = "confirmed"
+)
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Invoice is the bill a seller issues for their part of a paid order. Each
+// seller numbers their invoices in one gap-free sequence.
+type Invoice struct {
+	Id       string    `json:"id"`
+	Number   string    `json:"number"` // e.g. "INV-1A2B3C4D-000042"
+	Sequence int64     `json:"sequence"`
+	OrderId  string    `json:"order_id"`
+	SellerId string    `json:"seller_id"`
+	BuyerId  string    `json:"buyer_id"`
+	Subtotal Money     `json:"subtotal"`
+	Tax      Money     `json:"tax"`
+	Shipping Money     `json:"shipping"`
+	Total    Money     `json:"total"`
+	FilePath string    `json:"-"`
+	IssuedAt time.Time `json:"issued_at"`
+	URL      string    `json:"url"` // where to download the PDF
+}
+
+// InvoiceNumber formats a seller's invoice sequence number. The seller
+// prefix keeps numbers readable and unique across sellers.
+func InvoiceNumber(sellerID string, sequence int64) string {
+	prefix := strings.ToUpper(strings.ReplaceAll(sellerID, "-", ""))
+	if len(prefix) > 8 {
+		prefix = prefix[:8]
+	}
+	return fmt.Sprintf("INV-%s-%06d", prefix, sequence)
+}
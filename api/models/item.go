@@ -4,15 +4,36 @@ import "time"
 
 type Item struct {
 	Id           string      `json:"id"`
-	UserId       string      `json:"user_id"`
-	Name         string      `json:"name"`
+	UserId       string      `json:"user_id" validate:"required"`
+	Name         string      `json:"name" validate:"required,max=255"`
 	Description  string      `json:"description"`
-	Price        float64     `json:"price"`
-	SellingPrice float64     `json:"selling_price"`
+	Category     string      `json:"category" validate:"max=50"` // lower-case; used to pick the fee rules
+	Price        Money       `json:"price"`
+	SellingPrice Money       `json:"selling_price"`
 	Image        string      `json:"image"`  // Primary/thumbnail image (backward compatibility)
 	Images       []ItemImage `json:"images"` // All images for the item
-	Quantity     int         `json:"quantity"`
+	Quantity     int         `json:"quantity" validate:"min=0"`
 	CreatedAt    time.Time   `json:"created_at"`
 	UpdatedAt    time.Time   `json:"updated_at"`
 	IsSold       bool        `json:"is_sold"`
+	Visibility   string      `json:"visibility" validate:"required,oneof=public draft private"`
+	ImageURL     string      `json:"image_url"` // URL for Image, signed when the item is not public
+	// Parcel size of one unit, for shipping quotes; zero when not given
+	WeightGrams int `json:"weight_grams" validate:"min=0,max=1000000"`
+	LengthCm    int `json:"length_cm" validate:"min=0,max=1000"`
+	WidthCm     int `json:"width_cm" validate:"min=0,max=1000"`
+	HeightCm    int `json:"height_cm" validate:"min=0,max=1000"`
+	// How the item is shipped; nil when the seller arranges it themselves
+	ShippingProfileId *string `json:"shipping_profile_id"`
+	// Prices converted to the currency the buyer asked for, if any
+	Converted *ConvertedPrice `json:"converted,omitempty"`
 }
+
+// Item visibility levels, as listed in the oneof rule on Item.Visibility.
+// Images of non-public items are only reachable through signed, expiring
+// URLs.
+const (
+	VisibilityPublic  = "public"
+	VisibilityDraft   = "draft"   // only the owner and admins
+	VisibilityPrivate = "private" // owner, admins and invited users
+)
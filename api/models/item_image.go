@@ -7,5 +7,8 @@ type ItemImage struct {
 	ItemId       string    `json:"item_id"`
 	ImagePath    string    `json:"image_path"`
 	DisplayOrder int       `json:"display_order"`
+	SizeBytes    int64     `json:"size_bytes"`
+	URL          string    `json:"url"` // signed when the item is not public
+	PHash        *int64    `json:"-"`   // perceptual hash, nil when the format can't be decoded
 	CreatedAt    time.Time `json:"created_at"`
 }
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// ItemInvite lets a user view a private item and its images
+type ItemInvite struct {
+	ItemId    string    `json:"item_id"`
+	UserId    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Ledger account types. Assets and expenses normally carry a debit
+// (positive) balance; liabilities and revenue a credit (negative) one.
+const (
+	AccountAsset     = "asset"
+	AccountLiability = "liability"
+	AccountRevenue   = "revenue"
+	AccountExpense   = "expense"
+)
+
+// Ledger entry kinds
+const (
+	EntrySale       = "sale"        // buyer payment captured, split into seller earnings and fees
+	EntryRefund     = "refund"      // money returned to the buyer, with the matching fee share
+	EntryPayout     = "payout"      // seller earnings paid out
+	EntryListingFee = "listing_fee" // charged to a seller for listing an item
+)
+
+// LedgerGenesisHash is the previous hash of the first ledger entry
+var LedgerGenesisHash = strings.Repeat("0", 64)
+
+// LedgerAccount is one account in the double-entry ledger. Accounts are
+// per currency and are created the first time something is posted to them.
+type LedgerAccount struct {
+	Id        string    `json:"id"`
+	Code      string    `json:"code"` // e.g. "seller:<id>:payable:USD"
+	Type      string    `json:"type"`
+	OwnerId   *string   `json:"owner_id,omitempty"`
+	Currency  string    `json:"currency"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LedgerEntry is a journal entry. Its postings always sum to zero per
+// currency. Entries are append-only and chained by hash so any later
+// change to a recorded entry can be detected.
+type LedgerEntry struct {
+	Id             string          `json:"id"`
+	Seq            int64           `json:"seq"`
+	Kind           string          `json:"kind"`
+	ReferenceId    string          `json:"reference_id"` // e.g. the payment ID
+	IdempotencyKey string          `json:"idempotency_key"`
+	Description    string          `json:"description"`
+	PrevHash       string          `json:"prev_hash"`
+	Hash           string          `json:"hash"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Postings       []LedgerPosting `json:"postings"`
+}
+
+// LedgerPosting moves an amount into (debit, positive) or out of (credit,
+// negative) an account
+type LedgerPosting struct {
+	Id          string `json:"id"`
+	EntryId     string `json:"entry_id"`
+	AccountId   string `json:"account_id"`
+	AccountCode string `json:"account_code"`
+	AccountType string `json:"-"`
+	OwnerId     string `json:"-"`
+	Memo        string `json:"memo"`
+	Amount      Money  `json:"amount"`
+}
+
+// ComputeHash hashes the entry's content together with the previous
+// entry's hash
+func (e *LedgerEntry) ComputeHash() string {
+	postings := make([]string, 0, len(e.Postings))
+	for _, p := range e.Postings {
+		postings = append(postings, fmt.Sprintf("%s|%s|%d|%s", p.AccountCode, p.Memo, p.Amount.Amount, normalizeCurrency(p.Amount.Currency)))
+	}
+	sort.Strings(postings)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n%s\n", e.PrevHash, e.Kind, e.ReferenceId, e.IdempotencyKey, e.Description)
+	h.Write([]byte(strings.Join(postings, "\n")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Unbalanced returns the currencies whose postings don't sum to zero
+func (e *LedgerEntry) Unbalanced() []string {
+	sums := map[string]int64{}
+	for _, p := range e.Postings {
+		sums[normalizeCurrency(p.Amount.Currency)] += p.Amount.Amount
+	}
+	var currencies []string
+	for currency, sum := range sums {
+		if sum != 0 {
+			currencies = append(currencies, currency)
+		}
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// LedgerBalance is what the platform owes a seller in one currency
+type LedgerBalance struct {
+	Currency  string `json:"currency"`
+	Available Money  `json:"available"`
+}
+
+// StatementLine is one posting on a seller's account, signed from the
+// seller's point of view (earnings positive, fees and payouts negative)
+type StatementLine struct {
+	EntryId     string    `json:"entry_id"`
+	Kind        string    `json:"kind"`
+	ReferenceId string    `json:"reference_id"`
+	Description string    `json:"description"`
+	Memo        string    `json:"memo"`
+	Amount      Money     `json:"amount"`
+	Balance     Money     `json:"balance"` // running balance after this line
+	CreatedAt   time.Time `json:"created_at"`
+}
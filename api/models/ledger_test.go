@@ -0,0 +1,81 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLedgerEntryUnbalanced(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		postings []LedgerPosting
+		want     []string
+	}{
+		{"balanced", []LedgerPosting{
+			{AccountCode: "platform:cash:USD", Amount: NewMoney(1000, "USD")},
+			{AccountCode: "seller:1:payable:USD", Amount: NewMoney(-900, "USD")},
+			{AccountCode: "platform:fees:USD", Amount: NewMoney(-100, "USD")},
+		}, nil},
+		{"unbalanced", []LedgerPosting{
+			{AccountCode: "platform:cash:USD", Amount: NewMoney(1000, "USD")},
+			{AccountCode: "seller:1:payable:USD", Amount: NewMoney(-999, "USD")},
+		}, []string{"USD"}},
+		{"each currency on its own", []LedgerPosting{
+			{AccountCode: "platform:cash:USD", Amount: NewMoney(1000, "USD")},
+			{AccountCode: "seller:1:payable:EUR", Amount: NewMoney(-1000, "EUR")},
+			{AccountCode: "platform:cash:GBP", Amount: NewMoney(500, "gbp")},
+			{AccountCode: "seller:1:payable:GBP", Amount: NewMoney(-500, "GBP")},
+		}, []string{"EUR", "USD"}},
+		{"no postings", nil, nil},
+	} {
+		entry := LedgerEntry{Postings: tc.postings}
+		if got := entry.Unbalanced(); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Unbalanced() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestLedgerEntryComputeHash(t *testing.T) {
+	entry := func() LedgerEntry {
+		return LedgerEntry{
+			Kind:           EntrySale,
+			ReferenceId:    "payment-1",
+			IdempotencyKey: "sale:payment-1",
+			Description:    "Order order-1",
+			PrevHash:       LedgerGenesisHash,
+			Postings: []LedgerPosting{
+				{AccountCode: "platform:cash:USD", Memo: "buyer payment", Amount: NewMoney(1000, "USD")},
+				{AccountCode: "seller:1:payable:USD", Memo: "sale", Amount: NewMoney(-1000, "USD")},
+			},
+		}
+	}
+	base := entry()
+	hash := base.ComputeHash()
+
+	reordered := entry()
+	reordered.Postings[0], reordered.Postings[1] = reordered.Postings[1], reordered.Postings[0]
+	if got := reordered.ComputeHash(); got != hash {
+		t.Error("the hash depends on the order of the postings")
+	}
+	// Ids and timestamps are assigned when the entry is stored, so they
+	// aren't part of the hash
+	stored := entry()
+	stored.Id, stored.Seq = "entry-1", 1
+	if got := stored.ComputeHash(); got != hash {
+		t.Error("the hash depends on the stored id")
+	}
+
+	for name, change := range map[string]func(e *LedgerEntry){
+		"amount":        func(e *LedgerEntry) { e.Postings[0].Amount.Amount++ },
+		"account":       func(e *LedgerEntry) { e.Postings[1].AccountCode = "seller:2:payable:USD" },
+		"memo":          func(e *LedgerEntry) { e.Postings[1].Memo = "commission" },
+		"previous hash": func(e *LedgerEntry) { e.PrevHash = hash },
+		"reference":     func(e *LedgerEntry) { e.ReferenceId = "payment-2" },
+	} {
+		changed := entry()
+		change(&changed)
+		if changed.ComputeHash() == hash {
+			t.Errorf("changing the %s doesn't change the hash", name)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used for prices that don't name a currency
+const DefaultCurrency = "USD"
+
+// currencyExponents lists currencies whose minor unit is not 1/100. The
+// down file of migration 0011 repeats it.
+var currencyExponents = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+	"VND": 0,
+	"CLP": 0,
+	"ISK": 0,
+	"BHD": 3,
+	"KWD": 3,
+	"JOD": 3,
+	"OMR": 3,
+	"TND": 3,
+}
+
+// Money is an exact amount in the minor unit of a currency (cents for USD),
+// so comparisons and sums never suffer from floating point rounding
+type Money struct {
+	Amount   int64  // minor units
+	Currency string // ISO 4217 code
+}
+
+// NewMoney builds a Money from minor units
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: normalizeCurrency(currency)}
+}
+
+// CurrencyExponent returns the number of decimal places of a currency
+func CurrencyExponent(currency string) int {
+	if exp, ok := currencyExponents[normalizeCurrency(currency)]; ok {
+		return exp
+	}
+	return 2
+}
+
+// ValidCurrencyCode reports whether code looks like an ISO 4217 code
+func ValidCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeCurrency(currency string) string {
+	if currency == "" {
+		return DefaultCurrency
+	}
+	return strings.ToUpper(currency)
+}
+
+// ParseMoney strictly parses a decimal string such as "19.99". It rejects
+// more decimal places than the currency has, exponents, NaN, Inf, a leading
+// "+", negative zero and values that overflow int64 minor units.
+func ParseMoney(value, currency string) (Money, error) {
+	currency = normalizeCurrency(currency)
+	if !ValidCurrencyCode(currency) {
+		return Money{}, fmt.Errorf("invalid currency code %q", currency)
+	}
+	exp := CurrencyExponent(currency)
+
+	s := strings.TrimSpace(value)
+	if s == "" {
+		return Money{}, errors.New("amount is required")
+	}
+	negative := false
+	if s[0] == '-' {
+		negative = true
+		s = s[1:]
+	}
+
+	whole, frac, hasPoint := strings.Cut(s, ".")
+	if whole == "" || !allDigits(whole) || (hasPoint && (frac == "" || !allDigits(frac))) {
+		return Money{}, fmt.Errorf("invalid amount %q", value)
+	}
+	if len(frac) > exp {
+		if exp == 0 {
+			return Money{}, fmt.Errorf("amount %q: %s has no decimal places", value, currency)
+		}
+		return Money{}, fmt.Errorf("amount %q has more than %d decimal places", value, exp)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	digits := strings.TrimLeft(whole+frac, "0")
+	if digits == "" {
+		if negative {
+			return Money{}, fmt.Errorf("invalid amount %q: negative zero", value)
+		}
+		return Money{Amount: 0, Currency: currency}, nil
+	}
+	if negative {
+		digits = "-" + digits // so the most negative int64 parses too
+	}
+	amount, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("amount %q is out of range", value)
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+func allDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the amount as a plain decimal, e.g. "19.99"
+func (m Money) String() string {
+	exp := CurrencyExponent(m.Currency)
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+	}
+	abs := strconv.FormatUint(absInt64(amount), 10)
+	if exp == 0 {
+		return sign + abs
+	}
+	if len(abs) <= exp {
+		abs = strings.Repeat("0", exp-len(abs)+1) + abs
+	}
+	return sign + abs[:len(abs)-exp] + "." + abs[len(abs)-exp:]
+}
+
+func absInt64(v int64) uint64 {
+	if v < 0 {
+		return uint64(-(v + 1)) + 1
+	}
+	return uint64(v)
+}
+
+// IsNegative reports whether the amount is below zero
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// SameCurrency reports whether two amounts can be compared or added
+func (m Money) SameCurrency(other Money) bool {
+	return normalizeCurrency(m.Currency) == normalizeCurrency(other.Currency)
+}
+
+// Add returns m + other; both must share a currency
+func (m Money) Add(other Money) (Money, error) {
+	if !m.SameCurrency(other) {
+		return Money{}, fmt.Errorf("cannot add %s to %s", other.Currency, m.Currency)
+	}
+	sum := m.Amount + other.Amount
+	if (other.Amount > 0 && sum < m.Amount) || (other.Amount < 0 && sum > m.Amount) {
+		return Money{}, errors.New("amount overflow")
+	}
+	return Money{Amount: sum, Currency: normalizeCurrency(m.Currency)}, nil
+}
+
+// Mul returns m multiplied by a whole quantity
+func (m Money) Mul(quantity int64) (Money, error) {
+	// The division check can't catch MinInt64 * -1: it wraps to MinInt64,
+	// and so does MinInt64 / -1
+	if m.Amount == math.MinInt64 && quantity == -1 || m.Amount == -1 && quantity == math.MinInt64 {
+		return Money{}, errors.New("amount overflow")
+	}
+	if quantity != 0 && (m.Amount*quantity)/quantity != m.Amount {
+		return Money{}, errors.New("amount overflow")
+	}
+	return Money{Amount: m.Amount * quantity, Currency: normalizeCurrency(m.Currency)}, nil
+}
+
+// moneyJSON is the wire format: exact minor units plus a decimal string
+// for display
+type moneyJSON struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	Decimal  string `json:"decimal"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{
+		Amount:   m.Amount,
+		Currency: normalizeCurrency(m.Currency),
+		Decimal:  m.String(),
+	})
+}
+
+// UnmarshalJSON accepts either the object form produced by MarshalJSON
+// ({"amount": 1999, "currency": "USD"}) or a strict decimal string in the
+// default currency. Bare JSON numbers are rejected because they are floats.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		parsed, err := ParseMoney(s, DefaultCurrency)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	}
+
+	var raw struct {
+		Amount   *int64 `json:"amount"`
+		Currency string `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("money must be an object with integer minor units: %w", err)
+	}
+	if raw.Amount == nil {
+		return errors.New("money amount is required")
+	}
+	currency := normalizeCurrency(raw.Currency)
+	if !ValidCurrencyCode(currency) {
+		return fmt.Errorf("invalid currency code %q", raw.Currency)
+	}
+	*m = Money{Amount: *raw.Amount, Currency: currency}
+	return nil
+}
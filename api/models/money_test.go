@@ -0,0 +1,134 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		currency string
+		want     int64
+		wantErr  bool
+	}{
+		{"19.99", "USD", 1999, false},
+		{"19.9", "usd", 1990, false},
+		{"19", "", 1900, false},
+		{"0", "USD", 0, false},
+		{"0.00", "USD", 0, false},
+		{"007.50", "EUR", 750, false},
+
+		// Negatives
+		{"-5.25", "USD", -525, false},
+		{"-0", "USD", 0, true},
+		{"-0.00", "USD", 0, true},
+		{"--5", "USD", 0, true},
+		{"+5", "USD", 0, true},
+		{"- 5", "USD", 0, true},
+
+		// Decimal places the currency doesn't have
+		{"1.999", "USD", 0, true},
+		{"1500", "JPY", 1500, false},
+		{"1500.0", "JPY", 0, true},
+		{"1.234", "KWD", 1234, false},
+		{"1.2345", "KWD", 0, true},
+
+		// Malformed numbers
+		{"1e3", "USD", 0, true},
+		{"1E-2", "USD", 0, true},
+		{"NaN", "USD", 0, true},
+		{"Inf", "USD", 0, true},
+		{"0x10", "USD", 0, true},
+		{"1,000.00", "USD", 0, true},
+		{".5", "USD", 0, true},
+		{"5.", "USD", 0, true},
+		{"", "USD", 0, true},
+
+		// Whitespace around the amount is ignored, but not inside it
+		{" 19.99 ", "USD", 1999, false},
+		{"\t5\n", "USD", 500, false},
+		{"1 000", "USD", 0, true},
+		{"   ", "USD", 0, true},
+
+		// Limits of int64 minor units
+		{"92233720368547758.07", "USD", 9223372036854775807, false},
+		{"92233720368547758.08", "USD", 0, true},
+		{"-92233720368547758.08", "USD", -9223372036854775808, false},
+		{"-92233720368547758.09", "USD", 0, true},
+		{"9223372036854775807", "JPY", 9223372036854775807, false},
+		{"9223372036854775808", "JPY", 0, true},
+		{"99999999999999999999999", "USD", 0, true},
+
+		{"5", "US", 0, true},
+	} {
+		got, err := ParseMoney(tc.value, tc.currency)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("ParseMoney(%q, %q) = %v, want an error", tc.value, tc.currency, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseMoney(%q, %q): %v", tc.value, tc.currency, err)
+			continue
+		}
+		if got.Amount != tc.want || got.Currency != normalizeCurrency(tc.currency) {
+			t.Errorf("ParseMoney(%q, %q) = %d %s, want %d", tc.value, tc.currency, got.Amount, got.Currency, tc.want)
+		}
+	}
+}
+
+func TestParseMoneyRoundTrips(t *testing.T) {
+	for _, m := range []Money{
+		NewMoney(1999, "USD"),
+		NewMoney(-5, "USD"),
+		NewMoney(1500, "JPY"),
+		NewMoney(1234, "KWD"),
+		NewMoney(-9223372036854775808, "USD"),
+	} {
+		got, err := ParseMoney(m.String(), m.Currency)
+		if err != nil || got != m {
+			t.Errorf("ParseMoney(%q) = %v, %v; want %v", m.String(), got, err, m)
+		}
+	}
+}
+
+func TestMoneyMul(t *testing.T) {
+	for _, tc := range []struct {
+		amount   int64
+		quantity int64
+		want     int64
+		wantErr  bool
+	}{
+		{1999, 3, 5997, false},
+		{1999, 0, 0, false},
+		{0, 5, 0, false},
+		{1999, -2, -3998, false},
+		{-1999, -2, 3998, false},
+		{math.MinInt64, 1, math.MinInt64, false},
+		{math.MaxInt64, -1, -math.MaxInt64, false},
+
+		// Overflow
+		{math.MaxInt64, 2, 0, true},
+		{math.MinInt64, 2, 0, true},
+		{math.MinInt64, -1, 0, true},
+		{-1, math.MinInt64, 0, true},
+		{1 << 32, 1 << 32, 0, true},
+	} {
+		got, err := NewMoney(tc.amount, "usd").Mul(tc.quantity)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%d.Mul(%d) = %d, want an error", tc.amount, tc.quantity, got.Amount)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d.Mul(%d): %v", tc.amount, tc.quantity, err)
+			continue
+		}
+		if got.Amount != tc.want || got.Currency != "USD" {
+			t.Errorf("%d.Mul(%d) = %d %s, want %d USD", tc.amount, tc.quantity, got.Amount, got.Currency, tc.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package models
+
+import "time"
+
+// Order is a purchase of one or more fixed-price items. Stock is reserved
+// when the order is placed and released again if the order is cancelled or
+// its reservation expires before it is paid.
+type Order struct {
+	Id        string      `json:"id"`
+	BuyerId   string      `json:"buyer_id"`
+	Status    string      `json:"status"`
+	Subtotal  Money       `json:"subtotal"` // lines net of tax
+	Tax       Money       `json:"tax"`
+	Shipping  Money       `json:"shipping"`
+	Total     Money       `json:"total"`      // what the buyer pays, tax and shipping included
+	ExpiresAt time.Time   `json:"expires_at"` // when a pending reservation lapses
+	PaidAt    *time.Time  `json:"paid_at,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	Lines     []OrderLine `json:"lines"`
+	TaxRegion string      `json:"tax_region,omitempty"` // buyer region the tax was worked out for
+	TaxLines  []TaxLine   `json:"tax_lines"`
+	// Where the order is shipped to and the parcels it is shipped in
+	ShipToRegion string     `json:"ship_to_region,omitempty"`
+	Shipments    []Shipment `json:"shipments"`
+	// Version of the fee rules the order was priced with
+	FeeScheduleVersion *int `json:"fee_schedule_version,omitempty"`
+}
+
+// OrderLine is one item on an order. The name, category and price are
+// copied from the item so later edits to the listing don't change what was
+// bought, and the seller's fee is fixed when the order is placed.
+type OrderLine struct {
+	Id        string    `json:"id"`
+	OrderId   string    `json:"order_id"`
+	ItemId    *string   `json:"item_id"` // nil once the item has been deleted
+	SellerId  string    `json:"seller_id"`
+	ItemName  string    `json:"item_name"`
+	Category  string    `json:"category"`
+	Quantity  int       `json:"quantity"`
+	UnitPrice Money     `json:"unit_price"`
+	LineTotal Money     `json:"line_total"`
+	CreatedAt time.Time `json:"created_at"`
+	// The shipment the line travels in
+	ShipmentId *string `json:"shipment_id,omitempty"`
+	// Only shown to the line's seller and admins
+	Fee *FeeAssessment `json:"fee,omitempty"`
+}
+
+// Order statuses
+const (
+	OrderPending   = "pending" // stock reserved, awaiting payment
+	OrderPaid      = "paid"
+	OrderCancelled = "cancelled"
+	OrderExpired   = "expired"
+)
+
+// OrderLineRequest is a line a buyer asks for at checkout
+type OrderLineRequest struct {
+	ItemId   string `json:"item_id"`
+	Quantity int    `json:"quantity"`
+}
+
+// LineTax returns the tax line for an order line, if it was taxed
+func (o *Order) LineTax(lineID string) *TaxLine {
+	for i := range o.TaxLines {
+		if o.TaxLines[i].OrderLineId == lineID {
+			return &o.TaxLines[i]
+		}
+	}
+	return nil
+}
+
+// SellerShipping returns the shipping charged for a seller's shipments
+func (o *Order) SellerShipping(sellerID string) int64 {
+	var total int64
+	for _, shipment := range o.Shipments {
+		if shipment.SellerId == sellerID {
+			total += shipment.Cost.Amount
+		}
+	}
+	return total
+}
+
+// LineNet returns what a line is worth net of tax: its total, less the
+// tax when the seller's price included it
+func (o *Order) LineNet(line *OrderLine) Money {
+	if tax := o.LineTax(line.Id); tax != nil {
+		return tax.Taxable
+	}
+	return line.LineTotal
+}
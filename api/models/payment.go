@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// Payment tracks one attempt to pay for an order through a payment
+// provider. An order may have several attempts, e.g. after a declined card.
+type Payment struct {
+	Id           string    `json:"id"`
+	OrderId      string    `json:"order_id"`
+	Provider     string    `json:"provider"`
+	ProviderRef  string    `json:"provider_ref"` // the provider's intent ID
+	Status       string    `json:"status"`
+	Amount       Money     `json:"amount"`
+	Refunded     Money     `json:"refunded"`
+	ClientSecret string    `json:"client_secret,omitempty"` // only shown to the buyer
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Payment statuses
+const (
+	PaymentPending           = "pending"    // waiting for the buyer
+	PaymentAuthorized        = "authorized" // funds held, not yet captured
+	PaymentCaptured          = "captured"
+	PaymentFailed            = "failed" // declined; the buyer may retry
+	PaymentCancelled         = "cancelled"
+	PaymentPartiallyRefunded = "partially_refunded"
+	PaymentRefunded          = "refunded"
+)
+
+// paymentTransitions lists the statuses each status may move to
+var paymentTransitions = map[string][]string{
+	PaymentPending:           {PaymentAuthorized, PaymentCaptured, PaymentFailed, PaymentCancelled},
+	PaymentFailed:            {PaymentAuthorized, PaymentCaptured, PaymentFailed, PaymentCancelled},
+	PaymentAuthorized:        {PaymentCaptured, PaymentCancelled},
+	PaymentCaptured:          {PaymentPartiallyRefunded, PaymentRefunded},
+	PaymentPartiallyRefunded: {PaymentPartiallyRefunded, PaymentRefunded},
+}
+
+// CanTransitionPayment reports whether a payment may move from one status
+// to another
+func CanTransitionPayment(from, to string) bool {
+	for _, next := range paymentTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// PaymentEvent is a provider webhook as it was applied to a payment
+type PaymentEvent struct {
+	Provider   string    `json:"provider"`
+	EventId    string    `json:"event_id"`
+	EventType  string    `json:"event_type"`
+	PaymentId  *string   `json:"payment_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// PaymentRefund is a refund of a payment, recorded before the provider is
+// asked for it so a retry reuses its idempotency key
+type PaymentRefund struct {
+	Id             string `json:"id"`
+	PaymentId      string `json:"payment_id"`
+	IdempotencyKey string `json:"-"`
+	Amount         Money  `json:"amount"`
+	// The seller whose items it refunds, e.g. when a dispute settles; nil
+	// for a refund of the whole order
+	SellerId    *string   `json:"seller_id,omitempty"`
+	Status      string    `json:"status"`
+	ProviderRef string    `json:"provider_ref,omitempty"` // the provider's refund ID
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Refund statuses
+const (
+	RefundPending   = "pending" // asked for, not yet confirmed by the provider
+	RefundSucceeded = "succeeded"
+)
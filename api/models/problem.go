@@ -0,0 +1,19 @@
+package models
+
+// Problem is the body of every error response: an RFC 7807 problem details
+// object, served as application/problem+json, with a machine-readable code
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`             // e.g. item_not_found; stable across releases
+	Errors   []FieldError `json:"errors,omitempty"` // the inputs that were rejected, if any
+}
+
+// FieldError says what is wrong with one input
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,322 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ShippingProfile is a seller's way of charging for delivery, attached to
+// any number of their items. Items from one seller that share a profile
+// travel as one shipment and are quoted together.
+type ShippingProfile struct {
+	Id              string         `json:"id"`
+	SellerId        string         `json:"seller_id"`
+	Name            string         `json:"name"`
+	Type            string         `json:"type"`
+	Currency        string         `json:"currency"`
+	Flat            *Money         `json:"flat,omitempty"`             // cost of a flat-rate shipment
+	Rates           []ShippingRate `json:"rates,omitempty"`            // weight bands, lightest first
+	FreeOver        *Money         `json:"free_over,omitempty"`        // shipments worth at least this ship free
+	ExcludedRegions []string       `json:"excluded_regions,omitempty"` // countries or subdivisions not shipped to
+	PickupLocation  string         `json:"pickup_location,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// ShippingRate is the cost of a shipment weighing up to UpToGrams; a nil
+// bound covers any weight
+type ShippingRate struct {
+	UpToGrams *int64 `json:"up_to_grams"`
+	Cost      Money  `json:"cost"`
+}
+
+// Shipping profile types
+const (
+	ShippingFlat     = "flat"
+	ShippingByWeight = "by_weight"
+	ShippingPickup   = "pickup" // collected from the seller; never charged
+)
+
+// volumetricDivisor converts a parcel's volume in cm³ to the grams carriers
+// charge for (5000 cm³ per kg)
+const volumetricDivisor = 5
+
+// ErrNotShippable is returned when a profile can't deliver to a region or
+// has no rate for a shipment's weight
+var ErrNotShippable = errors.New("cannot be shipped")
+
+// ChargeableGrams is what one unit of an item weighs for shipping: its
+// weight or its volumetric weight, whichever is more
+func (i *Item) ChargeableGrams() int64 {
+	volumetric := int64(i.LengthCm) * int64(i.WidthCm) * int64(i.HeightCm) / volumetricDivisor
+	return max(int64(i.WeightGrams), volumetric)
+}
+
+// Validate checks a profile and normalizes its currency and regions
+func (p *ShippingProfile) Validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" || len(p.Name) > 100 {
+		return errors.New("name is required and at most 100 characters")
+	}
+	p.Currency = normalizeCurrency(p.Currency)
+	if !ValidCurrencyCode(p.Currency) {
+		return fmt.Errorf("invalid currency code %q", p.Currency)
+	}
+
+	amounts := []*Money{p.Flat, p.FreeOver}
+	for i := range p.Rates {
+		amounts = append(amounts, &p.Rates[i].Cost)
+	}
+	for _, m := range amounts {
+		if m == nil {
+			continue
+		}
+		if m.IsNegative() {
+			return errors.New("amounts cannot be negative")
+		}
+		if normalizeCurrency(m.Currency) != p.Currency {
+			return errors.New("all amounts must be in the profile's currency")
+		}
+	}
+
+	switch p.Type {
+	case ShippingFlat:
+		if p.Flat == nil {
+			return errors.New("a flat profile needs a flat amount")
+		}
+		if len(p.Rates) > 0 {
+			return errors.New("a flat profile cannot have weight rates")
+		}
+	case ShippingByWeight:
+		if len(p.Rates) == 0 || len(p.Rates) > 20 {
+			return errors.New("a by_weight profile needs between 1 and 20 rates")
+		}
+		if p.Flat != nil {
+			return errors.New("a by_weight profile cannot have a flat amount")
+		}
+		var last int64 = -1
+		for i, rate := range p.Rates {
+			if rate.UpToGrams == nil {
+				if i != len(p.Rates)-1 {
+					return errors.New("only the last rate can have no weight limit")
+				}
+				continue
+			}
+			if *rate.UpToGrams <= last {
+				return errors.New("rates must be in increasing order of weight")
+			}
+			last = *rate.UpToGrams
+		}
+	case ShippingPickup:
+		p.PickupLocation = strings.TrimSpace(p.PickupLocation)
+		if p.PickupLocation == "" {
+			return errors.New("a pickup profile needs a pickup_location")
+		}
+		if p.Flat != nil || len(p.Rates) > 0 || p.FreeOver != nil || len(p.ExcludedRegions) > 0 {
+			return errors.New("a pickup profile has no costs or region exclusions")
+		}
+	default:
+		return fmt.Errorf("type must be %s, %s or %s", ShippingFlat, ShippingByWeight, ShippingPickup)
+	}
+
+	if len(p.ExcludedRegions) > 100 {
+		return errors.New("at most 100 excluded regions")
+	}
+	for i, region := range p.ExcludedRegions {
+		normalized, err := NormalizeTaxRegion(region)
+		if err != nil {
+			return fmt.Errorf("excluded region %q: %w", region, err)
+		}
+		p.ExcludedRegions[i] = normalized
+	}
+	return nil
+}
+
+// Excludes reports whether the profile doesn't ship to region. Excluding a
+// country excludes all of its subdivisions.
+func (p *ShippingProfile) Excludes(region string) bool {
+	country, _, _ := strings.Cut(region, "-")
+	for _, excluded := range p.ExcludedRegions {
+		if excluded == region || excluded == country {
+			return true
+		}
+	}
+	return false
+}
+
+// Quote works out the cost of a shipment of grams, worth value, to region.
+// region may be empty only for pickup or when nothing is excluded.
+func (p *ShippingProfile) Quote(region string, grams int64, value Money) (Money, error) {
+	cost := NewMoney(0, p.Currency)
+	if p.Type == ShippingPickup {
+		return cost, nil
+	}
+	if normalizeCurrency(value.Currency) != p.Currency {
+		return cost, fmt.Errorf("%w: items are priced in %s but shipping profile %q charges in %s",
+			ErrNotShippable, value.Currency, p.Name, p.Currency)
+	}
+	if len(p.ExcludedRegions) > 0 && region == "" {
+		return cost, fmt.Errorf("%w: a shipping region is required", ErrNotShippable)
+	}
+	if p.Excludes(region) {
+		return cost, fmt.Errorf("%w: %q does not ship to %s", ErrNotShippable, p.Name, region)
+	}
+	if p.FreeOver != nil && value.Amount >= p.FreeOver.Amount {
+		return cost, nil
+	}
+
+	switch p.Type {
+	case ShippingFlat:
+		cost.Amount = p.Flat.Amount
+	case ShippingByWeight:
+		for _, rate := range p.Rates {
+			if rate.UpToGrams == nil || grams <= *rate.UpToGrams {
+				cost.Amount = rate.Cost.Amount
+				return cost, nil
+			}
+		}
+		return cost, fmt.Errorf("%w: %d g is over the heaviest rate of %q", ErrNotShippable, grams, p.Name)
+	}
+	return cost, nil
+}
+
+// Shipment is one parcel of an order: a seller's items that share a
+// shipping profile. Its cost is quoted at checkout and kept with the
+// order; the seller then adds tracking details and moves it along.
+type Shipment struct {
+	Id             string          `json:"id"`
+	OrderId        string          `json:"order_id"`
+	SellerId       string          `json:"seller_id"`
+	ProfileId      *string         `json:"profile_id"` // nil when the items had no profile
+	Method         string          `json:"method"`     // the profile's type, or "" for no profile
+	WeightGrams    int64           `json:"weight_grams"`
+	Cost           Money           `json:"cost"`
+	Status         string          `json:"status"`
+	Carrier        string          `json:"carrier,omitempty"`
+	TrackingNumber string          `json:"tracking_number,omitempty"`
+	PickupLocation string          `json:"pickup_location,omitempty"`
+	ShippedAt      *time.Time      `json:"shipped_at,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	Events         []ShipmentEvent `json:"events,omitempty"`
+}
+
+// ShipmentEvent records a shipment status change
+type ShipmentEvent struct {
+	Status    string    `json:"status"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Shipment statuses
+const (
+	ShipmentPending   = "pending" // waiting for the seller
+	ShipmentShipped   = "shipped"
+	ShipmentInTransit = "in_transit"
+	ShipmentDelivered = "delivered" // or collected, for pickup
+	ShipmentReturned  = "returned"
+)
+
+// shipmentTransitions lists the statuses each status may move to
+var shipmentTransitions = map[string][]string{
+	ShipmentPending:   {ShipmentShipped, ShipmentDelivered},
+	ShipmentShipped:   {ShipmentInTransit, ShipmentDelivered, ShipmentReturned},
+	ShipmentInTransit: {ShipmentInTransit, ShipmentDelivered, ShipmentReturned},
+	ShipmentDelivered: {ShipmentReturned},
+}
+
+// CanTransitionShipment reports whether a shipment may move from one
+// status to another
+func CanTransitionShipment(from, to string) bool {
+	for _, next := range shipmentTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ShipmentUpdate is a seller's change to a shipment
+type ShipmentUpdate struct {
+	Status         string `json:"status,omitempty"`
+	Carrier        string `json:"carrier,omitempty"`
+	TrackingNumber string `json:"tracking_number,omitempty"`
+	Note           string `json:"note,omitempty"`
+}
+
+// ShippingQuote is the cost of one would-be shipment, for showing before
+// checkout
+type ShippingQuote struct {
+	SellerId       string   `json:"seller_id"`
+	ProfileId      *string  `json:"profile_id"`
+	Method         string   `json:"method"`
+	WeightGrams    int64    `json:"weight_grams"`
+	Cost           Money    `json:"cost"`
+	PickupLocation string   `json:"pickup_location,omitempty"`
+	ItemIds        []string `json:"item_ids"`
+}
+
+// ShippingItem is an item being quoted for: quantity units of it weighing
+// Grams and worth Value in all
+type ShippingItem struct {
+	ItemId    string
+	SellerId  string
+	ProfileId *string
+	Grams     int64
+	Value     Money
+}
+
+// QuoteShipments groups items into shipments, one per seller and shipping
+// profile, and quotes each for region. profiles must hold every profile
+// the items name. Items without a profile form a free shipment the seller
+// arranges themselves.
+func QuoteShipments(items []ShippingItem, profiles map[string]*ShippingProfile, region string) ([]ShippingQuote, error) {
+	type key struct{ seller, profile string }
+	var keys []key
+	groups := map[key]*ShippingQuote{}
+	values := map[key]Money{}
+	for _, item := range items {
+		k := key{seller: item.SellerId}
+		if item.ProfileId != nil {
+			k.profile = *item.ProfileId
+		}
+		quote, ok := groups[k]
+		if !ok {
+			quote = &ShippingQuote{SellerId: item.SellerId, ProfileId: item.ProfileId}
+			groups[k] = quote
+			values[k] = NewMoney(0, item.Value.Currency)
+			keys = append(keys, k)
+		}
+		quote.ItemIds = append(quote.ItemIds, item.ItemId)
+		quote.WeightGrams += item.Grams
+		value, err := values[k].Add(item.Value)
+		if err != nil {
+			return nil, err
+		}
+		values[k] = value
+	}
+
+	quotes := make([]ShippingQuote, 0, len(keys))
+	for _, k := range keys {
+		quote := groups[k]
+		quote.Cost = NewMoney(0, values[k].Currency)
+		if k.profile != "" {
+			profile, ok := profiles[k.profile]
+			if !ok {
+				return nil, fmt.Errorf("%w: shipping profile %s not found", ErrNotShippable, k.profile)
+			}
+			cost, err := profile.Quote(region, quote.WeightGrams, values[k])
+			if err != nil {
+				return nil, err
+			}
+			quote.Cost = cost
+			quote.Method = profile.Type
+			quote.PickupLocation = profile.PickupLocation
+		}
+		quotes = append(quotes, *quote)
+	}
+	return quotes, nil
+}
@@ -0,0 +1,12 @@
+package models
+
+// StorageUsage is how much upload storage a user is consuming
+type StorageUsage struct {
+	UserId         string `json:"user_id"`
+	ImageBytes     int64  `json:"image_bytes"`  // images attached to the user's items
+	ImageCount     int    `json:"image_count"`  // number of attached images
+	StagedBytes    int64  `json:"staged_bytes"` // declared size of unfinished or unattached resumable uploads
+	UsedBytes      int64  `json:"used_bytes"`
+	QuotaBytes     int64  `json:"quota_bytes"`
+	RemainingBytes int64  `json:"remaining_bytes"`
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"errors"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Tax rounding modes, applied to the tax on each order line
+const (
+	TaxRoundHalfUp   = "half_up"   // 0.5 rounds away from zero
+	TaxRoundHalfEven = "half_even" // 0.5 rounds to the even neighbour
+)
+
+var taxRegionPattern = regexp.MustCompile(`^[A-Z]{2}(-[A-Z0-9]{1,3})?$`)
+
+// NormalizeTaxRegion upper-cases a region code and checks it is a country
+// ("DE") or a country subdivision ("US-CA")
+func NormalizeTaxRegion(region string) (string, error) {
+	region = strings.ToUpper(strings.TrimSpace(region))
+	if !taxRegionPattern.MatchString(region) {
+		return "", errors.New("region must be a country code like DE or a subdivision like US-CA")
+	}
+	return region, nil
+}
+
+// TaxSettings are a user's tax details: the region they buy from and, as
+// a seller, whether their listed prices already include tax
+type TaxSettings struct {
+	TaxRegion        string `json:"tax_region"`
+	PricesIncludeTax bool   `json:"prices_include_tax"`
+}
+
+// TaxRate is the rate charged in a region, optionally only for one item
+// category. Rates live in the tax_rates table and are managed by admins.
+type TaxRate struct {
+	Region    string    `json:"region"`
+	Category  string    `json:"category"` // empty for the region's standard rate
+	Name      string    `json:"name"`     // e.g. "VAT" or "Sales tax"
+	RateBPS   int64     `json:"rate_bps"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TaxTable holds the rates that can apply to one buyer region
+type TaxTable struct {
+	Region   string
+	Rates    []TaxRate
+	Rounding string
+}
+
+// TaxLine is the tax charged on one order line. It is stored with the
+// order so invoices show the rate that applied when the order was placed.
+type TaxLine struct {
+	Id          string `json:"id"`
+	OrderId     string `json:"order_id"`
+	OrderLineId string `json:"order_line_id"`
+	Region      string `json:"region"`
+	Name        string `json:"name"`
+	RateBPS     int64  `json:"rate_bps"`
+	Inclusive   bool   `json:"inclusive"` // the seller's price already included the tax
+	Taxable     Money  `json:"taxable"`   // net amount the rate was applied to
+	Tax         Money  `json:"tax"`
+}
+
+// Rate finds the rate for a category: the most specific of region and
+// category, region, country and category, then country
+func (t *TaxTable) Rate(category string) (TaxRate, bool) {
+	if t == nil || t.Region == "" {
+		return TaxRate{}, false
+	}
+	regions := []string{t.Region}
+	if country, _, ok := strings.Cut(t.Region, "-"); ok {
+		regions = append(regions, country)
+	}
+	for _, region := range regions {
+		for _, c := range []string{category, ""} {
+			for _, rate := range t.Rates {
+				if rate.Region == region && strings.EqualFold(rate.Category, c) {
+					return rate, true
+				}
+			}
+			if category == "" {
+				break
+			}
+		}
+	}
+	return TaxRate{}, false
+}
+
+// Calculate works out the tax on amount for an item in category. When
+// inclusive, amount already contains the tax and it is backed out of it;
+// otherwise the tax is added on top. It returns false if no rate applies.
+func (t *TaxTable) Calculate(category string, amount Money, inclusive bool) (TaxLine, bool) {
+	rate, ok := t.Rate(category)
+	if !ok || rate.RateBPS == 0 {
+		return TaxLine{}, false
+	}
+
+	var tax int64
+	if inclusive {
+		tax = roundDiv(big.NewInt(amount.Amount), rate.RateBPS, 10000+rate.RateBPS, t.Rounding)
+	} else {
+		tax = roundDiv(big.NewInt(amount.Amount), rate.RateBPS, 10000, t.Rounding)
+	}
+	taxable := amount.Amount
+	if inclusive {
+		taxable -= tax
+	}
+	return TaxLine{
+		Region:    rate.Region,
+		Name:      rate.Name,
+		RateBPS:   rate.RateBPS,
+		Inclusive: inclusive,
+		Taxable:   NewMoney(taxable, amount.Currency),
+		Tax:       NewMoney(tax, amount.Currency),
+	}, true
+}
+
+// roundDiv returns a*b/c rounded with mode (half up unless half_even)
+func roundDiv(a *big.Int, b, c int64, mode string) int64 {
+	num := new(big.Int).Mul(a, big.NewInt(b))
+	den := big.NewInt(c)
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+
+	// Compare twice the remainder with the divisor to find the half
+	twice := new(big.Int).Abs(r)
+	twice.Lsh(twice, 1)
+	switch cmp := twice.Cmp(den); {
+	case cmp > 0, cmp == 0 && mode != TaxRoundHalfEven, cmp == 0 && q.Bit(0) == 1:
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q.Int64()
+}
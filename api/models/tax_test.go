@@ -0,0 +1,79 @@
+package models
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestRoundDiv(t *testing.T) {
+	for _, tc := range []struct {
+		a, b, c  int64
+		halfUp   int64
+		halfEven int64
+	}{
+		{25, 1, 10, 3, 2},
+		{35, 1, 10, 4, 4},
+		{-25, 1, 10, -3, -2},
+		{-35, 1, 10, -4, -4},
+		{24, 1, 10, 2, 2},
+		{26, 1, 10, 3, 3},
+		{-26, 1, 10, -3, -3},
+		{0, 1900, 10000, 0, 0},
+		{math.MaxInt64, 10000, 10000, math.MaxInt64, math.MaxInt64},
+	} {
+		if got := roundDiv(big.NewInt(tc.a), tc.b, tc.c, TaxRoundHalfUp); got != tc.halfUp {
+			t.Errorf("roundDiv(%d, %d, %d, half up) = %d, want %d", tc.a, tc.b, tc.c, got, tc.halfUp)
+		}
+		if got := roundDiv(big.NewInt(tc.a), tc.b, tc.c, TaxRoundHalfEven); got != tc.halfEven {
+			t.Errorf("roundDiv(%d, %d, %d, half even) = %d, want %d", tc.a, tc.b, tc.c, got, tc.halfEven)
+		}
+	}
+}
+
+func TestTaxTableCalculate(t *testing.T) {
+	rates := []TaxRate{
+		{Region: "DE", Name: "VAT", RateBPS: 1900},
+		{Region: "DE", Category: "books", Name: "VAT", RateBPS: 700},
+		{Region: "FR", Name: "TVA", RateBPS: 2000},
+		{Region: "GB", Name: "VAT", RateBPS: 0},
+	}
+
+	for _, tc := range []struct {
+		name      string
+		region    string
+		rounding  string
+		category  string
+		amount    int64
+		inclusive bool
+		ok        bool
+		rateBPS   int64
+		taxable   int64
+		tax       int64
+	}{
+		{"standard rate", "DE", TaxRoundHalfUp, "tools", 1000, false, true, 1900, 1000, 190},
+		{"category rate from the country", "DE-BY", TaxRoundHalfUp, "Books", 1000, false, true, 700, 1000, 70},
+		// 7% of 1.50 is 10.5 cents
+		{"half up", "DE", TaxRoundHalfUp, "books", 150, false, true, 700, 150, 11},
+		{"half even", "DE", TaxRoundHalfEven, "books", 150, false, true, 700, 150, 10},
+		{"half even rounding up", "DE", TaxRoundHalfEven, "books", 50, false, true, 700, 50, 4},
+		{"unset rounding is half up", "DE", "", "books", 150, false, true, 700, 150, 11},
+		{"inclusive", "DE", TaxRoundHalfUp, "tools", 1190, true, true, 1900, 1000, 190},
+		// 20% backed out of 0.03 is half a cent
+		{"inclusive half up", "FR", TaxRoundHalfUp, "", 3, true, true, 2000, 2, 1},
+		{"inclusive half even", "FR", TaxRoundHalfEven, "", 3, true, true, 2000, 3, 0},
+		{"zero rate", "GB", TaxRoundHalfUp, "", 1000, false, false, 0, 0, 0},
+		{"no rate", "US-CA", TaxRoundHalfUp, "", 1000, false, false, 0, 0, 0},
+	} {
+		table := TaxTable{Region: tc.region, Rates: rates, Rounding: tc.rounding}
+		line, ok := table.Calculate(tc.category, NewMoney(tc.amount, "EUR"), tc.inclusive)
+		if ok != tc.ok {
+			t.Errorf("%s: Calculate ok = %v, want %v", tc.name, ok, tc.ok)
+			continue
+		}
+		if line.RateBPS != tc.rateBPS || line.Taxable.Amount != tc.taxable || line.Tax.Amount != tc.tax || line.Inclusive != tc.inclusive {
+			t.Errorf("%s: Calculate = rate %d, taxable %d, tax %d; want rate %d, taxable %d, tax %d",
+				tc.name, line.RateBPS, line.Taxable.Amount, line.Tax.Amount, tc.rateBPS, tc.taxable, tc.tax)
+		}
+	}
+}
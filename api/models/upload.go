@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Upload is an image being transferred in chunks through the resumable
+// upload API. Completed uploads are attached to items by ID and the row is
+// removed once the file has been moved into the image directory.
+type Upload struct {
+	Id        string    `json:"id"`
+	UserId    string    `json:"user_id"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Upload statuses
+const (
+	UploadInProgress = "in_progress"
+	UploadCompleted  = "completed"
+)
@@ -0,0 +1,195 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"primeauction/api/models"
+	"sync"
+)
+
+// Reasons Confirm refuses to pay an intent
+var (
+	ErrIntentNotFound   = errors.New("no such payment intent")
+	ErrIntentNotPending = errors.New("payment intent is not awaiting payment")
+)
+
+// fakeSignatureHeader carries the HMAC of a fake webhook payload
+const fakeSignatureHeader = "Fake-Signature"
+
+// FakeProvider is an in-memory gateway for development and tests. Nothing
+// leaves the process: Confirm stands in for the buyer completing payment
+// in the browser and returns the signed webhook the gateway would send.
+type FakeProvider struct {
+	webhookSecret []byte
+
+	mu         sync.Mutex
+	intents    map[string]*Intent
+	refunded   map[string]int64 // intent ID -> total refunded
+	idempotent map[string]any   // idempotency key -> earlier result
+	eventSeq   int
+}
+
+func NewFakeProvider(webhookSecret string) *FakeProvider {
+	return &FakeProvider{
+		webhookSecret: []byte(webhookSecret),
+		intents:       map[string]*Intent{},
+		refunded:      map[string]int64{},
+		idempotent:    map[string]any{},
+	}
+}
+
+func (p *FakeProvider) Name() string { return "fake" }
+
+func (p *FakeProvider) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if earlier, ok := p.idempotent[req.IdempotencyKey].(*Intent); ok {
+		copied := *earlier
+		return &copied, nil
+	}
+	if req.Amount.Amount <= 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+	id := "pi_fake_" + randomHex(12)
+	intent := &Intent{
+		ID:           id,
+		Status:       IntentRequiresPayment,
+		Amount:       req.Amount,
+		ClientSecret: id + "_secret_" + randomHex(12),
+	}
+	p.intents[id] = intent
+	if req.IdempotencyKey != "" {
+		p.idempotent[req.IdempotencyKey] = intent
+	}
+	copied := *intent
+	return &copied, nil
+}
+
+func (p *FakeProvider) Capture(ctx context.Context, intentID, idempotencyKey string) (*Intent, error) {
+	return p.transition(intentID, IntentRequiresCapture, IntentSucceeded)
+}
+
+func (p *FakeProvider) Cancel(ctx context.Context, intentID, idempotencyKey string) (*Intent, error) {
+	return p.transition(intentID, IntentRequiresCapture, IntentCanceled)
+}
+
+// transition moves an intent from one status to another. Repeating a
+// transition that already happened succeeds, like an idempotent retry.
+func (p *FakeProvider) transition(intentID, from, to string) (*Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return nil, fmt.Errorf("no such payment intent: %s", intentID)
+	}
+	if intent.Status != to {
+		if intent.Status != from && !(to == IntentCanceled && intent.Status == IntentRequiresPayment) {
+			return nil, fmt.Errorf("payment intent %s is %s", intentID, intent.Status)
+		}
+		intent.Status = to
+	}
+	copied := *intent
+	return &copied, nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, intentID string, amount models.Money, idempotencyKey string) (*Refund, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if earlier, ok := p.idempotent[idempotencyKey].(*Refund); ok {
+		copied := *earlier
+		return &copied, nil
+	}
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return nil, fmt.Errorf("no such payment intent: %s", intentID)
+	}
+	if intent.Status != IntentSucceeded {
+		return nil, fmt.Errorf("payment intent %s has not been captured", intentID)
+	}
+	if amount.Amount <= 0 || p.refunded[intentID]+amount.Amount > intent.Amount.Amount {
+		return nil, errors.New("refund amount exceeds the captured amount")
+	}
+	p.refunded[intentID] += amount.Amount
+	refund := &Refund{ID: "re_fake_" + randomHex(12), IntentID: intentID, Status: IntentSucceeded, Amount: amount}
+	if idempotencyKey != "" {
+		p.idempotent[idempotencyKey] = refund
+	}
+	copied := *refund
+	return &copied, nil
+}
+
+// Confirm simulates the buyer finishing payment. A successful payment
+// leaves the intent awaiting capture; a failed one stays payable. It
+// returns the webhook payload and headers the gateway would deliver.
+func (p *FakeProvider) Confirm(intentID string, succeed bool) ([]byte, http.Header, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrIntentNotFound, intentID)
+	}
+	if intent.Status != IntentRequiresPayment {
+		return nil, nil, fmt.Errorf("%w: %s is %s", ErrIntentNotPending, intentID, intent.Status)
+	}
+	kind := EventFailed
+	if succeed {
+		kind = EventAuthorized
+		intent.Status = IntentRequiresCapture
+	}
+
+	p.eventSeq++
+	payload, err := json.Marshal(fakeEvent{
+		ID:       fmt.Sprintf("evt_fake_%d_%s", p.eventSeq, randomHex(6)),
+		Kind:     kind,
+		IntentID: intent.ID,
+		Amount:   intent.Amount,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set(fakeSignatureHeader, p.sign(payload))
+	return payload, header, nil
+}
+
+type fakeEvent struct {
+	ID       string       `json:"id"`
+	Kind     string       `json:"kind"`
+	IntentID string       `json:"intent_id"`
+	Amount   models.Money `json:"amount"`
+}
+
+func (p *FakeProvider) VerifyWebhook(payload []byte, header http.Header) (*Event, error) {
+	signature := header.Get(fakeSignatureHeader)
+	if signature == "" || !hmac.Equal([]byte(signature), []byte(p.sign(payload))) {
+		return nil, ErrInvalidSignature
+	}
+	var e fakeEvent
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	return &Event{ID: e.ID, Type: e.Kind, Kind: e.Kind, IntentID: e.IntentID, Amount: e.Amount}, nil
+}
+
+func (p *FakeProvider) sign(payload []byte) string {
+	mac := hmac.New(sha256.New, p.webhookSecret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,81 @@
+package payment
+
+import (
+	"context"
+	"testing"
+
+	"primeauction/api/models"
+)
+
+func TestFakeIdempotency(t *testing.T) {
+	ctx := context.Background()
+	p := NewFakeProvider("secret")
+	req := IntentRequest{Amount: models.NewMoney(1000, "EUR"), Reference: "order-1", IdempotencyKey: "payment-1"}
+
+	first, err := p.CreateIntent(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := p.CreateIntent(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != first.ID || again.ClientSecret != first.ClientSecret {
+		t.Errorf("retried CreateIntent made intent %s, want %s", again.ID, first.ID)
+	}
+
+	if _, _, err := p.Confirm(first.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		intent, err := p.Capture(ctx, first.ID, "payment-1-capture")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if intent.Status != IntentSucceeded {
+			t.Errorf("captured intent is %s", intent.Status)
+		}
+	}
+
+	refund, err := p.Refund(ctx, first.ID, models.NewMoney(600, "EUR"), "payment-1-refund-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	retried, err := p.Refund(ctx, first.ID, models.NewMoney(600, "EUR"), "payment-1-refund-0")
+	if err != nil {
+		t.Fatalf("retried refund: %v", err)
+	}
+	if retried.ID != refund.ID {
+		t.Errorf("retried refund made refund %s, want %s", retried.ID, refund.ID)
+	}
+	// Only the first 600 was refunded, so 400 is left rather than none
+	if _, err := p.Refund(ctx, first.ID, models.NewMoney(400, "EUR"), "payment-1-refund-600"); err != nil {
+		t.Errorf("refunding the rest: %v", err)
+	}
+	if _, err := p.Refund(ctx, first.ID, models.NewMoney(1, "EUR"), "payment-1-refund-1000"); err == nil {
+		t.Error("refunded more than was captured")
+	}
+}
+
+func TestFakeVerifyWebhook(t *testing.T) {
+	p := NewFakeProvider("secret")
+	intent, err := p.CreateIntent(context.Background(), IntentRequest{Amount: models.NewMoney(1000, "EUR")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, header, err := p.Confirm(intent.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := p.VerifyWebhook(payload, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Kind != EventAuthorized || event.IntentID != intent.ID {
+		t.Errorf("VerifyWebhook = %+v", event)
+	}
+	if _, err := NewFakeProvider("other").VerifyWebhook(payload, header); err != ErrInvalidSignature {
+		t.Errorf("another secret's webhook: error = %v, want ErrInvalidSignature", err)
+	}
+}
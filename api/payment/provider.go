@@ -0,0 +1,79 @@
+// Package payment abstracts the payment gateway used to charge buyers.
+// Providers create payment intents, capture and refund them, and verify
+// the webhooks the gateway sends when an intent changes state.
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"primeauction/api/models"
+)
+
+// ErrInvalidSignature is returned when a webhook fails verification
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Intent statuses, using the gateway's names
+const (
+	IntentRequiresPayment = "requires_payment_method"
+	IntentRequiresCapture = "requires_capture"
+	IntentProcessing      = "processing"
+	IntentSucceeded       = "succeeded"
+	IntentCanceled        = "canceled"
+)
+
+// Normalised webhook event kinds. Events of any other kind are recorded
+// but otherwise ignored.
+const (
+	EventAuthorized = "authorized" // funds held, waiting for capture
+	EventSucceeded  = "succeeded"  // funds captured
+	EventFailed     = "failed"
+	EventCanceled   = "canceled"
+	EventRefunded   = "refunded" // Amount is the total refunded so far
+)
+
+// Provider is a payment gateway. Intents are created for manual capture so
+// money is only taken once the platform has confirmed the order can still
+// be fulfilled. Every mutating call takes an idempotency key so retries
+// never charge or refund twice. Calls that reach the gateway give up when
+// ctx ends; callers make them outside database transactions.
+type Provider interface {
+	Name() string
+	CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error)
+	Capture(ctx context.Context, intentID, idempotencyKey string) (*Intent, error)
+	Cancel(ctx context.Context, intentID, idempotencyKey string) (*Intent, error)
+	Refund(ctx context.Context, intentID string, amount models.Money, idempotencyKey string) (*Refund, error)
+	VerifyWebhook(payload []byte, header http.Header) (*Event, error)
+}
+
+// IntentRequest describes a charge to authorise
+type IntentRequest struct {
+	Amount         models.Money
+	Reference      string // our order ID, stored as gateway metadata
+	IdempotencyKey string
+}
+
+// Intent is the gateway's record of a charge
+type Intent struct {
+	ID           string
+	Status       string
+	Amount       models.Money
+	ClientSecret string // handed to the buyer's browser to confirm payment
+}
+
+// Refund is the gateway's record of a refund against an intent
+type Refund struct {
+	ID       string
+	IntentID string
+	Status   string
+	Amount   models.Money
+}
+
+// Event is a verified webhook, reduced to what the platform acts on
+type Event struct {
+	ID       string // unique per delivery source, used to drop duplicates
+	Type     string // the gateway's own event type
+	Kind     string // one of the Event* kinds, or "" if not relevant
+	IntentID string
+	Amount   models.Money
+}
@@ -0,0 +1,240 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"primeauction/api/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StripeProvider talks to the Stripe API, or anything that speaks it.
+// BaseURL can point at a mock server such as stripe-mock for local runs.
+type StripeProvider struct {
+	BaseURL       string // defaults to https://api.stripe.com
+	SecretKey     string
+	WebhookSecret string
+	// How old a webhook signature timestamp may be; defaults to 5 minutes
+	Tolerance time.Duration
+	Client    *http.Client // defaults to http.DefaultClient
+}
+
+// stripeIntent is the subset of a PaymentIntent object we read
+type stripeIntent struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	Amount       int64  `json:"amount"`
+	Currency     string `json:"currency"`
+	ClientSecret string `json:"client_secret"`
+}
+
+func (i stripeIntent) toIntent() *Intent {
+	return &Intent{
+		ID:           i.ID,
+		Status:       i.Status,
+		Amount:       models.NewMoney(i.Amount, i.Currency),
+		ClientSecret: i.ClientSecret,
+	}
+}
+
+func (p *StripeProvider) Name() string { return "stripe" }
+
+func (p *StripeProvider) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	form := url.Values{}
+	form.Set("amount", strconv.FormatInt(req.Amount.Amount, 10))
+	form.Set("currency", strings.ToLower(req.Amount.Currency))
+	form.Set("capture_method", "manual")
+	form.Set("metadata[order_id]", req.Reference)
+
+	var intent stripeIntent
+	if err := p.post(ctx, "/v1/payment_intents", form, req.IdempotencyKey, &intent); err != nil {
+		return nil, err
+	}
+	return intent.toIntent(), nil
+}
+
+func (p *StripeProvider) Capture(ctx context.Context, intentID, idempotencyKey string) (*Intent, error) {
+	var intent stripeIntent
+	if err := p.post(ctx, "/v1/payment_intents/"+url.PathEscape(intentID)+"/capture", url.Values{}, idempotencyKey, &intent); err != nil {
+		return nil, err
+	}
+	return intent.toIntent(), nil
+}
+
+func (p *StripeProvider) Cancel(ctx context.Context, intentID, idempotencyKey string) (*Intent, error) {
+	var intent stripeIntent
+	if err := p.post(ctx, "/v1/payment_intents/"+url.PathEscape(intentID)+"/cancel", url.Values{}, idempotencyKey, &intent); err != nil {
+		return nil, err
+	}
+	return intent.toIntent(), nil
+}
+
+func (p *StripeProvider) Refund(ctx context.Context, intentID string, amount models.Money, idempotencyKey string) (*Refund, error) {
+	form := url.Values{}
+	form.Set("payment_intent", intentID)
+	form.Set("amount", strconv.FormatInt(amount.Amount, 10))
+
+	var refund struct {
+		ID            string `json:"id"`
+		Status        string `json:"status"`
+		Amount        int64  `json:"amount"`
+		Currency      string `json:"currency"`
+		PaymentIntent string `json:"payment_intent"`
+	}
+	if err := p.post(ctx, "/v1/refunds", form, idempotencyKey, &refund); err != nil {
+		return nil, err
+	}
+	return &Refund{
+		ID:       refund.ID,
+		IntentID: refund.PaymentIntent,
+		Status:   refund.Status,
+		Amount:   models.NewMoney(refund.Amount, refund.Currency),
+	}, nil
+}
+
+// requestTimeout bounds a Stripe call when the caller's context allows longer
+const requestTimeout = 30 * time.Second
+
+func (p *StripeProvider) post(ctx context.Context, path string, form url.Values, idempotencyKey string, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	base := p.BaseURL
+	if base == "" {
+		base = "https://api.stripe.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(base, "/")+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.SecretKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("stripe request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		if apiErr.Error.Message == "" {
+			apiErr.Error.Message = resp.Status
+		}
+		return fmt.Errorf("stripe: %s", apiErr.Error.Message)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("stripe: failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// stripeEventKinds maps the Stripe event types we act on
+var stripeEventKinds = map[string]string{
+	"payment_intent.amount_capturable_updated": EventAuthorized,
+	"payment_intent.succeeded":                 EventSucceeded,
+	"payment_intent.payment_failed":            EventFailed,
+	"payment_intent.canceled":                  EventCanceled,
+	"charge.refunded":                          EventRefunded,
+}
+
+// VerifyWebhook checks the Stripe-Signature header (an HMAC-SHA256 of
+// "<timestamp>.<payload>") and decodes the event
+func (p *StripeProvider) VerifyWebhook(payload []byte, header http.Header) (*Event, error) {
+	if err := p.verifySignature(payload, header.Get("Stripe-Signature")); err != nil {
+		return nil, err
+	}
+
+	var raw struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object struct {
+				ID             string `json:"id"`
+				Object         string `json:"object"`
+				Amount         int64  `json:"amount"`
+				AmountReceived int64  `json:"amount_received"`
+				AmountRefunded int64  `json:"amount_refunded"`
+				Currency       string `json:"currency"`
+				PaymentIntent  string `json:"payment_intent"`
+			} `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+
+	obj := raw.Data.Object
+	event := &Event{ID: raw.ID, Type: raw.Type, Kind: stripeEventKinds[raw.Type]}
+	switch obj.Object {
+	case "payment_intent":
+		event.IntentID = obj.ID
+		event.Amount = models.NewMoney(obj.Amount, obj.Currency)
+		if event.Kind == EventSucceeded {
+			event.Amount = models.NewMoney(obj.AmountReceived, obj.Currency)
+		}
+	case "charge":
+		event.IntentID = obj.PaymentIntent
+		event.Amount = models.NewMoney(obj.AmountRefunded, obj.Currency)
+	}
+	return event, nil
+}
+
+func (p *StripeProvider) verifySignature(payload []byte, header string) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	tolerance := p.Tolerance
+	if tolerance == 0 {
+		tolerance = 5 * time.Minute
+	}
+	if age := time.Since(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside the allowed tolerance", ErrInvalidSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(p.WebhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
@@ -0,0 +1,163 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"primeauction/api/models"
+)
+
+// stripeServer answers every request with status and body and keeps the
+// last request it got
+func stripeServer(t *testing.T, status int, body string) (*StripeProvider, *http.Request) {
+	t.Helper()
+	got := new(http.Request)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		*got = *r.Clone(context.Background())
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return &StripeProvider{BaseURL: srv.URL, SecretKey: "sk_test_1", WebhookSecret: "whsec_1", Client: srv.Client()}, got
+}
+
+func TestStripeCreateIntent(t *testing.T) {
+	p, got := stripeServer(t, http.StatusOK, `{"id":"pi_1","status":"requires_payment_method","amount":1250,"currency":"eur","client_secret":"pi_1_secret"}`)
+
+	intent, err := p.CreateIntent(context.Background(), IntentRequest{
+		Amount:         models.NewMoney(1250, "EUR"),
+		Reference:      "order-1",
+		IdempotencyKey: "payment-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if intent.ID != "pi_1" || intent.ClientSecret != "pi_1_secret" || intent.Amount != models.NewMoney(1250, "EUR") {
+		t.Errorf("CreateIntent = %+v", intent)
+	}
+
+	if got.Method != http.MethodPost || got.URL.Path != "/v1/payment_intents" {
+		t.Errorf("request = %s %s", got.Method, got.URL.Path)
+	}
+	if auth := got.Header.Get("Authorization"); auth != "Bearer sk_test_1" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if key := got.Header.Get("Idempotency-Key"); key != "payment-1" {
+		t.Errorf("Idempotency-Key = %q", key)
+	}
+	for field, want := range map[string]string{
+		"amount":             "1250",
+		"currency":           "eur",
+		"capture_method":     "manual",
+		"metadata[order_id]": "order-1",
+	} {
+		if v := got.PostForm.Get(field); v != want {
+			t.Errorf("form %s = %q, want %q", field, v, want)
+		}
+	}
+}
+
+func TestStripeRefund(t *testing.T) {
+	p, got := stripeServer(t, http.StatusOK, `{"id":"re_1","status":"succeeded","amount":500,"currency":"eur","payment_intent":"pi_1"}`)
+
+	refund, err := p.Refund(context.Background(), "pi_1", models.NewMoney(500, "EUR"), "payment-1-refund-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.ID != "re_1" || refund.IntentID != "pi_1" || refund.Amount != models.NewMoney(500, "EUR") {
+		t.Errorf("Refund = %+v", refund)
+	}
+	if got.URL.Path != "/v1/refunds" || got.PostForm.Get("payment_intent") != "pi_1" || got.PostForm.Get("amount") != "500" {
+		t.Errorf("request = %s %v", got.URL.Path, got.PostForm)
+	}
+	if key := got.Header.Get("Idempotency-Key"); key != "payment-1-refund-0" {
+		t.Errorf("Idempotency-Key = %q", key)
+	}
+}
+
+func TestStripeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"api error", http.StatusPaymentRequired, `{"error":{"type":"card_error","message":"Your card was declined."}}`, "stripe: Your card was declined."},
+		{"no error body", http.StatusBadGateway, `<html>bad gateway</html>`, "stripe: 502 Bad Gateway"},
+		{"bad response", http.StatusOK, `not json`, "stripe: failed to decode response"},
+	} {
+		p, _ := stripeServer(t, tc.status, tc.body)
+		_, err := p.Capture(context.Background(), "pi_1", "payment-1-capture")
+		if err == nil || !strings.HasPrefix(err.Error(), tc.want) {
+			t.Errorf("%s: Capture error = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestStripeStopsWithContext(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+	p := &StripeProvider{BaseURL: srv.URL, SecretKey: "sk_test_1", Client: srv.Client()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := p.Cancel(ctx, "pi_1", "payment-1-cancel"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Cancel error = %v, want the context's deadline", err)
+	}
+}
+
+// signStripe returns a Stripe-Signature header for payload signed at ts
+func signStripe(secret string, ts time.Time, payload []byte) http.Header {
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	header := http.Header{}
+	header.Set("Stripe-Signature", "t="+timestamp+",v1="+hex.EncodeToString(mac.Sum(nil)))
+	return header
+}
+
+func TestStripeVerifyWebhook(t *testing.T) {
+	p := &StripeProvider{WebhookSecret: "whsec_1"}
+	payload := []byte(`{"id":"evt_1","type":"payment_intent.succeeded","data":{"object":{"id":"pi_1","object":"payment_intent","amount":1250,"amount_received":1250,"currency":"eur"}}}`)
+
+	event, err := p.VerifyWebhook(payload, signStripe("whsec_1", time.Now(), payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.ID != "evt_1" || event.Kind != EventSucceeded || event.IntentID != "pi_1" || event.Amount != models.NewMoney(1250, "EUR") {
+		t.Errorf("VerifyWebhook = %+v", event)
+	}
+
+	tampered := []byte(strings.Replace(string(payload), "1250", "9999", 1))
+	for _, tc := range []struct {
+		name    string
+		payload []byte
+		header  http.Header
+	}{
+		{"tampered", tampered, signStripe("whsec_1", time.Now(), payload)},
+		{"wrong secret", payload, signStripe("whsec_2", time.Now(), payload)},
+		{"stale", payload, signStripe("whsec_1", time.Now().Add(-10*time.Minute), payload)},
+		{"from the future", payload, signStripe("whsec_1", time.Now().Add(10*time.Minute), payload)},
+		{"unsigned", payload, http.Header{}},
+	} {
+		if _, err := p.VerifyWebhook(tc.payload, tc.header); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: VerifyWebhook error = %v, want ErrInvalidSignature", tc.name, err)
+		}
+	}
+}
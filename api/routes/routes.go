@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"primeauction/api/handler"
 	"primeauction/api/middleware"
+	"primeauction/api/utils"
 )
 
 type Route struct {
@@ -12,20 +13,123 @@ type Route struct {
 	Handler func(w http.ResponseWriter, r *http.Request)
 }
 
-func SetupRoutes(itemHandler *handler.ItemHandler, userHandler *handler.UserHandler) []Route {
+func SetupRoutes(itemHandler *handler.ItemHandler, userHandler *handler.UserHandler, uploadHandler *handler.UploadHandler, imageFlagHandler *handler.ImageFlagHandler, storageHandler *handler.StorageHandler, currencyHandler *handler.CurrencyHandler, orderHandler *handler.OrderHandler, paymentHandler *handler.PaymentHandler, ledgerHandler *handler.LedgerHandler, feeHandler *handler.FeeHandler, taxHandler *handler.TaxHandler, invoiceHandler *handler.InvoiceHandler, disputeHandler *handler.DisputeHandler, shippingHandler *handler.ShippingHandler, uploadLimiter *middleware.RateLimiter) []Route {
+	// Auth then per-user upload rate limiting, for routes that can store new images
+	uploadAuth := func(next http.HandlerFunc) http.HandlerFunc {
+		return middleware.AuthMiddleware(middleware.UploadRateLimitMiddleware(uploadLimiter, next))
+	}
+
 	return []Route{
 		// Public routes (no authentication required)
 		{Path: "/api/auth/register", Method: "POST", Handler: userHandler.Register},
 		{Path: "/api/auth/login", Method: "POST", Handler: userHandler.Login},
-		{Path: "/api/items", Method: "GET", Handler: itemHandler.GetAllItems},      // Public: view all items
-		{Path: "/api/items/{id}", Method: "GET", Handler: itemHandler.GetItemById}, // Public: view single item
+		// Public: view all items (owners and admins also see their non-public items)
+		{Path: "/api/items", Method: "GET", Handler: middleware.OptionalAuthMiddleware(itemHandler.GetAllItems)},
+		// Public: view single item (drafts and private lots need an authorized viewer)
+		{Path: "/api/items/{id}", Method: "GET", Handler: middleware.OptionalAuthMiddleware(itemHandler.GetItemById)},
+
+		// Public: supported currencies and the exchange rates in use. Item
+		// routes accept ?currency= to add converted prices.
+		{Path: "/api/currencies", Method: "GET", Handler: currencyHandler.GetRates},
 
 		// Protected routes (require authentication)
 		// Admin-only: Create items
-		{Path: "/api/items", Method: "POST", Handler: middleware.AuthMiddleware(middleware.AdminMiddleware(itemHandler.CreateItem))},
+		{Path: "/api/items", Method: "POST", Handler: uploadAuth(middleware.AdminMiddleware(itemHandler.CreateItem))},
 		// Authenticated users: Update and delete items
-		{Path: "/api/items/{id}", Method: "PUT", Handler: middleware.AuthMiddleware(itemHandler.UpdateItem)},
+		{Path: "/api/items/{id}", Method: "PUT", Handler: uploadAuth(itemHandler.UpdateItem)},
 		{Path: "/api/items/{id}", Method: "DELETE", Handler: middleware.AuthMiddleware(itemHandler.DeleteItem)},
+		// Owners: manage who may view a private item
+		{Path: "/api/items/{id}/invites", Method: "GET", Handler: middleware.AuthMiddleware(itemHandler.GetInvites)},
+		{Path: "/api/items/{id}/invites", Method: "POST", Handler: middleware.AuthMiddleware(itemHandler.InviteUser)},
+		{Path: "/api/items/{id}/invites/{userId}", Method: "DELETE", Handler: middleware.AuthMiddleware(itemHandler.RevokeInvite)},
+
+		// Resumable image uploads (tus-style); completed upload IDs are attached
+		// to items through the upload_ids form field
+		{Path: "/api/uploads", Method: "POST", Handler: uploadAuth(uploadHandler.CreateUpload)},
+		{Path: "/api/uploads/{id}", Method: "HEAD", Handler: middleware.AuthMiddleware(uploadHandler.HeadUpload)},
+		{Path: "/api/uploads/{id}", Method: "GET", Handler: middleware.AuthMiddleware(uploadHandler.GetUpload)},
+		{Path: "/api/uploads/{id}", Method: "PATCH", Handler: middleware.AuthMiddleware(uploadHandler.PatchUpload)},
+		{Path: "/api/uploads/{id}", Method: "DELETE", Handler: middleware.AuthMiddleware(uploadHandler.DeleteUpload)},
+
+		// Checkout: placing an order reserves stock until it is paid,
+		// cancelled or the reservation expires
+		{Path: "/api/orders", Method: "POST", Handler: middleware.AuthMiddleware(orderHandler.CreateOrder)},
+		{Path: "/api/orders", Method: "GET", Handler: middleware.AuthMiddleware(orderHandler.GetMyOrders)},
+		{Path: "/api/orders/{id}", Method: "GET", Handler: middleware.AuthMiddleware(orderHandler.GetOrder)},
+		{Path: "/api/orders/{id}/cancel", Method: "POST", Handler: middleware.AuthMiddleware(orderHandler.CancelOrder)},
+		// Paying for an order; money is captured when the provider's webhook
+		// confirms the payment was authorised
+		{Path: "/api/orders/{id}/payments", Method: "POST", Handler: middleware.AuthMiddleware(paymentHandler.StartPayment)},
+		{Path: "/api/orders/{id}/payments", Method: "GET", Handler: middleware.AuthMiddleware(paymentHandler.GetOrderPayments)},
+		// Invoices for a paid order, one per seller; the buyer sees all of
+		// them, a seller only their own
+		{Path: "/api/orders/{id}/invoices", Method: "GET", Handler: middleware.AuthMiddleware(invoiceHandler.GetOrderInvoices)},
+		{Path: "/api/orders/{id}/invoice", Method: "GET", Handler: middleware.AuthMiddleware(invoiceHandler.GetOrderInvoice)},
+		// Disputes about a seller's items on a paid order: the buyer opens
+		// one, both sides talk it through and either may escalate it; the
+		// seller may settle it with a refund
+		{Path: "/api/orders/{id}/disputes", Method: "POST", Handler: middleware.AuthMiddleware(disputeHandler.OpenDispute)},
+		{Path: "/api/disputes/{id}", Method: "GET", Handler: middleware.AuthMiddleware(disputeHandler.GetDispute)},
+		{Path: "/api/disputes/{id}/messages", Method: "POST", Handler: middleware.AuthMiddleware(disputeHandler.AddMessage)},
+		{Path: "/api/disputes/{id}/escalate", Method: "POST", Handler: middleware.AuthMiddleware(disputeHandler.Escalate)},
+		{Path: "/api/disputes/{id}/resolve", Method: "POST", Handler: middleware.AuthMiddleware(disputeHandler.Resolve)},
+		// Shipping: quote before checkout, then the seller tracks each
+		// shipment of a paid order
+		{Path: "/api/shipping/quotes", Method: "POST", Handler: middleware.AuthMiddleware(shippingHandler.Quote)},
+		{Path: "/api/shipping-profiles/{id}", Method: "GET", Handler: shippingHandler.GetProfile},
+		{Path: "/api/orders/{id}/shipments/{shipmentId}", Method: "PUT", Handler: middleware.AuthMiddleware(shippingHandler.UpdateShipment)},
+		// Public: provider webhooks, authenticated by their signature
+		{Path: "/api/payments/webhook", Method: "POST", Handler: paymentHandler.Webhook},
+		// Development only: complete a payment with the fake provider
+		{Path: "/api/payments/fake/{intentId}/confirm", Method: "POST", Handler: middleware.AuthMiddleware(paymentHandler.ConfirmFakePayment)},
+
+		// Admin-only: review listings whose photos match another seller's
+		{Path: "/api/admin/image-flags", Method: "GET", Handler: middleware.AuthMiddleware(middleware.AdminMiddleware(imageFlagHandler.GetFlags))},
+		{Path: "/api/admin/image-flags/{id}", Method: "PUT", Handler: middleware.AuthMiddleware(middleware.AdminMiddleware(imageFlagHandler.ReviewFlag))},
+
+		// Admin-only: inspect and refund payments
+		{Path: "/api/admin/payments/{id}", Method: "GET", Handler: middleware.AuthMiddleware(middleware.AdminMiddleware(paymentHandler.GetPayment))},
+		{Path: "/api/admin/payments/{id}/refunds", Method: "POST", Handler: middleware.AuthMiddleware(middleware.AdminMiddleware(paymentHandler.RefundPayment))},
+
+		// Admin-only: work through disputes and settle them, with or without a refund
+		{Path: "/api/admin/disputes", Method: "GET", Handler: middleware.AuthMiddleware(middleware.AdminMiddleware(disputeHandler.GetDisputes))},
+		{Path: "/api/admin/disputes/{id}/resolve", Method: "POST", Handler: middleware.AuthMiddleware(middleware.AdminMiddleware(disputeHandler.Resolve))},
+
+		// Admin-only: publish versioned fee rules and set seller fee tiers
+		{Path: "/api/admin/fee-schedules", Method: "GET", Handler: middleware.AuthMiddleware(middleware.AdminMiddleware(feeHandler.GetSchedules))},
+		{Path: "/api/admin/fee-schedules", Method: "POST", Handler: middleware.AuthMiddleware(middleware.AdminMiddleware(feeHandler.PublishSchedule))},
+		{Path: "/api/admin/fee-schedules/{version}", Method: "GET", Handler: middleware.AuthMiddleware(middleware.AdminMiddleware(feeHandler.GetSchedule))},
+		{Path: "/api/admin/users/{id}/seller-tier", Method: "PUT", Handler: middleware.AuthMiddleware(middleware.AdminMiddleware(feeHandler.SetSellerTier))},
+
+		// Admin-only: tax rates by buyer region and item category
+		{Path: "/api/admin/tax-rates", Method: "GET", Handler: middleware.AuthMiddleware(middleware.AdminMiddleware(taxHandler.GetRates))},
+		{Path: "/api/admin/tax-rates", Method: "PUT", Handler: middleware.AuthMiddleware(middleware.AdminMiddleware(taxHandler.SaveRate))},
+		{Path: "/api/admin/tax-rates/{region}", Method: "DELETE", Handler: middleware.AuthMiddleware(middleware.AdminMiddleware(taxHandler.DeleteRate))},
+
+		// Admin-only: record money paid out to a seller
+		{Path: "/api/admin/payouts", Method: "POST", Handler: middleware.AuthMiddleware(middleware.AdminMiddleware(ledgerHandler.CreatePayout))},
+
+		// Fee rules in effect and the current user's seller tier
+		{Path: "/api/fees", Method: "GET", Handler: middleware.AuthMiddleware(feeHandler.GetFees)},
+		// Orders containing the current user's items, with the fees charged
+		{Path: "/api/me/sales", Method: "GET", Handler: middleware.AuthMiddleware(orderHandler.GetMySales)},
+		// Disputes the current user is the buyer or seller in
+		{Path: "/api/me/disputes", Method: "GET", Handler: middleware.AuthMiddleware(disputeHandler.GetMyDisputes)},
+		// Current user's shipping profiles, attached to their items
+		{Path: "/api/me/shipping-profiles", Method: "GET", Handler: middleware.AuthMiddleware(shippingHandler.GetMyProfiles)},
+		{Path: "/api/me/shipping-profiles", Method: "POST", Handler: middleware.AuthMiddleware(shippingHandler.CreateProfile)},
+		{Path: "/api/me/shipping-profiles/{id}", Method: "PUT", Handler: middleware.AuthMiddleware(shippingHandler.UpdateProfile)},
+		{Path: "/api/me/shipping-profiles/{id}", Method: "DELETE", Handler: middleware.AuthMiddleware(shippingHandler.DeleteProfile)},
+		// Current user's balance as a seller and the ledger lines behind it
+		{Path: "/api/me/balance", Method: "GET", Handler: middleware.AuthMiddleware(ledgerHandler.GetMyBalance)},
+		{Path: "/api/me/statement", Method: "GET", Handler: middleware.AuthMiddleware(ledgerHandler.GetMyStatement)},
+		// Current user's tax region and whether their prices include tax
+		{Path: "/api/me/tax-settings", Method: "GET", Handler: middleware.AuthMiddleware(taxHandler.GetMySettings)},
+		{Path: "/api/me/tax-settings", Method: "PUT", Handler: middleware.AuthMiddleware(taxHandler.UpdateMySettings)},
+		// Current user's upload storage usage and quota
+		{Path: "/api/me/storage", Method: "GET", Handler: middleware.AuthMiddleware(storageHandler.GetMyStorage)},
+		// Current user's display currency
+		{Path: "/api/me/preferences", Method: "PUT", Handler: middleware.AuthMiddleware(currencyHandler.UpdatePreferences)},
 
 		// User routes (protected)
 		{Path: "/api/users", Method: "GET", Handler: middleware.AuthMiddleware(userHandler.GetUsers)},
@@ -54,7 +158,7 @@ func RegisterRoutes(routes *[]Route) {
 		http.HandleFunc(path, middleware.CORSMiddleware(func(w http.ResponseWriter, r *http.Request) {
 			handler, exists := methods[r.Method]
 			if !exists {
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				utils.WriteProblem(w, r, http.StatusMethodNotAllowed, utils.CodeMethodNotAllowed, "Method not allowed")
 				return
 			}
 			handler(w, r)
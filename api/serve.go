@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"primeauction/api/handler"
+	"primeauction/api/middleware"
+	"primeauction/api/routes"
+)
+
+// serve runs the HTTP API and its background jobs until SIGINT or SIGTERM.
+// It then stops accepting connections, lets in-flight requests finish, and
+// stops the background jobs; the caller closes the database last.
+func serve(a *app) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Background jobs get their own context so they keep running while
+	// requests drain, and are only stopped afterwards
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	var jobs sync.WaitGroup
+	startJob := func(run func(context.Context)) {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			run(jobsCtx)
+		}()
+	}
+	defer func() {
+		stopJobs()
+		jobs.Wait()
+		log.Println("Background jobs stopped")
+	}()
+
+	if interval := a.cfg.Uploads.GCInterval; interval > 0 {
+		uploadGC := a.newUploadGC(a.cfg.Uploads.GCDryRun)
+		startJob(func(ctx context.Context) { uploadGC.Start(ctx, interval) })
+	}
+
+	// Release stock held by orders that weren't paid in time
+	if interval := a.cfg.Orders.ExpiryInterval; interval > 0 {
+		startJob(func(ctx context.Context) { a.orderService.StartReservationExpiry(ctx, interval) })
+	}
+
+	// Initialize handlers
+	itemHandler := handler.NewItemHandler(a.itemService, a.storageService, a.currencyService)
+	userHandler := handler.NewUserHandler(a.userService)
+	uploadHandler := handler.NewUploadHandler(a.uploadService, a.storageService)
+	imageFlagHandler := handler.NewImageFlagHandler(a.imageFlagService)
+	storageHandler := handler.NewStorageHandler(a.storageService)
+	currencyHandler := handler.NewCurrencyHandler(a.currencyService)
+	orderHandler := handler.NewOrderHandler(a.orderService)
+	paymentHandler := handler.NewPaymentHandler(a.paymentService, a.fakePayments)
+	ledgerHandler := handler.NewLedgerHandler(a.ledgerService)
+	feeHandler := handler.NewFeeHandler(a.feeService)
+	taxHandler := handler.NewTaxHandler(a.taxService)
+	invoiceHandler := handler.NewInvoiceHandler(a.invoiceService)
+	disputeHandler := handler.NewDisputeHandler(a.disputeService)
+	shippingHandler := handler.NewShippingHandler(a.shippingService)
+
+	// Requests that can store new images, per user
+	uploadLimiter := middleware.NewRateLimiter(a.cfg.Uploads.RateLimit, a.cfg.Uploads.RateWindow)
+
+	// Serve uploaded images with CORS; images of non-public items need a signed URL
+	http.Handle("/uploads/", middleware.CORSHandler(handler.NewUploadFileHandler(a.itemService)))
+
+	// Setup and register routes
+	routesList := routes.SetupRoutes(itemHandler, userHandler, uploadHandler, imageFlagHandler, storageHandler, currencyHandler, orderHandler, paymentHandler, ledgerHandler, feeHandler, taxHandler, invoiceHandler, disputeHandler, shippingHandler, uploadLimiter)
+	routes.RegisterRoutes(&routesList)
+
+	// Start server
+	server := &http.Server{
+		Addr:              ":" + a.cfg.Server.Port,
+		ReadHeaderTimeout: a.cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       a.cfg.Server.ReadTimeout,
+		WriteTimeout:      a.cfg.Server.WriteTimeout,
+		IdleTimeout:       a.cfg.Server.IdleTimeout,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server is running on port %s", a.cfg.Server.Port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
+	stop() // a second signal kills the process
+
+	log.Printf("Shutting down; waiting up to %s for requests to finish", a.cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		server.Close()
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	log.Println("Server stopped")
+	return nil
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+
+	repository "primeauction/api/Repository"
+)
+
+// IsCanceled reports whether err means an operation was abandoned because
+// its context was cancelled or ran out of time
+var IsCanceled = repository.IsCanceled
+
+// lookupError is the error for a failed lookup: notFound when there is no
+// such record, and err itself for any other failure, such as the database
+// being down or the context ending, so it isn't mistaken for a missing
+// record
+func lookupError(err, notFound error) error {
+	if isNotFound(err) {
+		return notFound
+	}
+	return err
+}
+
+// isNotFound reports whether err is a store saying it has no such record
+func isNotFound(err error) bool {
+	if errors.Is(err, sql.ErrNoRows) {
+		return true
+	}
+	e := Classify(err)
+	return e != nil && e.Kind == KindNotFound
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateProvider loads a table of exchange rates. Implementations should be
+// cheap to call repeatedly; CurrencyService caches the result.
+type RateProvider interface {
+	FetchRates() (*models.RateTable, error)
+}
+
+// FileRateProvider reads rates from a local JSON file shaped like
+// models.RateTable. It is meant for tests, development and offline use.
+type FileRateProvider struct {
+	Path string
+}
+
+func (p *FileRateProvider) FetchRates() (*models.RateTable, error) {
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rates file: %w", err)
+	}
+	table, err := decodeRateTable(data)
+	if err != nil {
+		return nil, err
+	}
+	table.Source = "file:" + p.Path
+	return table, nil
+}
+
+// HTTPRateProvider fetches rates from a URL returning the same JSON shape
+// as FileRateProvider
+type HTTPRateProvider struct {
+	URL    string
+	Client *http.Client
+}
+
+func (p *HTTPRateProvider) FetchRates() (*models.RateTable, error) {
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Get(p.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rates: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch rates: %s", resp.Status)
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode rates: %w", err)
+	}
+	table, err := decodeRateTable(raw)
+	if err != nil {
+		return nil, err
+	}
+	table.Source = p.URL
+	return table, nil
+}
+
+func decodeRateTable(data []byte) (*models.RateTable, error) {
+	var table models.RateTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to decode rates: %w", err)
+	}
+	table.Base = strings.ToUpper(table.Base)
+	if !models.ValidCurrencyCode(table.Base) {
+		return nil, fmt.Errorf("rates have invalid base currency %q", table.Base)
+	}
+	rates := make(map[string]string, len(table.Rates))
+	for code, rate := range table.Rates {
+		code = strings.ToUpper(code)
+		r, ok := new(big.Rat).SetString(rate)
+		if !models.ValidCurrencyCode(code) || !ok || r.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid rate %q for %q", rate, code)
+		}
+		rates[code] = rate
+	}
+	rates[table.Base] = "1"
+	table.Rates = rates
+	return &table, nil
+}
+
+// ErrRatesUnavailable is returned when no exchange rates could be fetched
+var ErrRatesUnavailable = newError(KindUnavailable, "rates_unavailable", "exchange rates are unavailable")
+
+// CurrencyService converts prices into a buyer's currency using cached
+// rates from a RateProvider. Conversions are display-only and never
+// change an item's stored price.
+type CurrencyService struct {
+	provider RateProvider
+	ttl      time.Duration
+	userRepo repository.UserStore
+
+	mu     sync.Mutex
+	cached *models.RateTable
+}
+
+func NewCurrencyService(provider RateProvider, ttl time.Duration, userRepo repository.UserStore) *CurrencyService {
+	return &CurrencyService{provider: provider, ttl: ttl, userRepo: userRepo}
+}
+
+// Rates returns the cached rate table, refreshing it once it is older than
+// the TTL. If a refresh fails the stale table is kept, since its timestamp
+// tells clients how old it is.
+func (s *CurrencyService) Rates() (*models.RateTable, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil && time.Since(s.cached.FetchedAt) < s.ttl {
+		return s.cached, nil
+	}
+
+	table, err := s.provider.FetchRates()
+	if err != nil {
+		if s.cached != nil {
+			log.Printf("exchange rates: refresh failed, serving rates fetched at %s: %v", s.cached.FetchedAt.Format(time.RFC3339), err)
+			return s.cached, nil
+		}
+		log.Printf("exchange rates: %v", err)
+		return nil, ErrRatesUnavailable
+	}
+	table.FetchedAt = time.Now()
+	s.cached = table
+	return table, nil
+}
+
+// Supported reports whether a currency can be listed in and converted to
+func (s *CurrencyService) Supported(currency string) bool {
+	table, err := s.Rates()
+	if err != nil {
+		// Listing in the default currency must keep working without rates
+		return strings.EqualFold(currency, models.DefaultCurrency)
+	}
+	_, ok := table.Rates[strings.ToUpper(currency)]
+	return ok
+}
+
+// Convert expresses m in another currency, rounding half away from zero to
+// the target currency's minor unit. It returns the rate used.
+func (s *CurrencyService) Convert(m models.Money, to string) (models.Money, *big.Rat, *models.RateTable, error) {
+	table, err := s.Rates()
+	if err != nil {
+		return models.Money{}, nil, nil, err
+	}
+	to = strings.ToUpper(to)
+
+	rate, err := crossRate(table, m.Currency, to)
+	if err != nil {
+		return models.Money{}, nil, nil, err
+	}
+
+	// minor_to = minor_from / 10^exp_from * rate * 10^exp_to
+	value := new(big.Rat).SetInt64(m.Amount)
+	value.Mul(value, rate)
+	value.Mul(value, pow10Rat(models.CurrencyExponent(to)-models.CurrencyExponent(m.Currency)))
+
+	amount, err := roundHalfAwayFromZero(value)
+	if err != nil {
+		return models.Money{}, nil, nil, err
+	}
+	return models.NewMoney(amount, to), rate, table, nil
+}
+
+// ConvertItem fills in item.Converted with the item's prices in currency.
+// Nothing is added when the item is already priced in that currency.
+func (s *CurrencyService) ConvertItem(item *models.Item, currency string) error {
+	currency = strings.ToUpper(currency)
+	if currency == "" || strings.EqualFold(item.Price.Currency, currency) {
+		return nil
+	}
+	price, rate, table, err := s.Convert(item.Price, currency)
+	if err != nil {
+		return err
+	}
+	sellingPrice, _, _, err := s.Convert(item.SellingPrice, currency)
+	if err != nil {
+		return err
+	}
+	item.Converted = &models.ConvertedPrice{
+		Price:        price,
+		SellingPrice: sellingPrice,
+		Rate:         rate.FloatString(6),
+		RateAsOf:     table.AsOf,
+		RateSource:   table.Source,
+	}
+	return nil
+}
+
+// PreferredCurrency returns the user's saved display currency, or "" when
+// there is no user or no preference
+func (s *CurrencyService) PreferredCurrency(ctx context.Context, userID string) string {
+	if userID == "" {
+		return ""
+	}
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return ""
+	}
+	return user.PreferredCurrency
+}
+
+// SetPreferredCurrency saves the currency prices are shown in for a user.
+// An empty currency clears the preference.
+func (s *CurrencyService) SetPreferredCurrency(ctx context.Context, userID, currency string) error {
+	currency = strings.ToUpper(currency)
+	if currency != "" && !s.Supported(currency) {
+		return InvalidField("preferred_currency", fmt.Sprintf("unsupported currency %q", currency))
+	}
+	return s.userRepo.UpdatePreferredCurrency(ctx, userID, currency)
+}
+
+// crossRate returns how many units of to one unit of from buys
+func crossRate(table *models.RateTable, from, to string) (*big.Rat, error) {
+	fromRate, ok := new(big.Rat).SetString(table.Rates[strings.ToUpper(from)])
+	if !ok {
+		return nil, fmt.Errorf("no exchange rate for %s", from)
+	}
+	toRate, ok := new(big.Rat).SetString(table.Rates[to])
+	if !ok {
+		return nil, fmt.Errorf("no exchange rate for %s", to)
+	}
+	return new(big.Rat).Quo(toRate, fromRate), nil
+}
+
+func pow10Rat(exp int) *big.Rat {
+	p := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(absInt(exp))), nil)
+	if exp < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), p)
+	}
+	return new(big.Rat).SetInt(p)
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// roundHalfAwayFromZero rounds a rational to the nearest integer
+func roundHalfAwayFromZero(r *big.Rat) (int64, error) {
+	num, den := r.Num(), r.Denom()
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	twice := new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2))
+	if twice.Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	if !quo.IsInt64() {
+		return 0, errors.New("converted amount is out of range")
+	}
+	return quo.Int64(), nil
+}
@@ -0,0 +1,392 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	repository "primeauction/api/Repository"
+	"primeauction/api/config"
+	"primeauction/api/models"
+	"strings"
+	"time"
+)
+
+// Errors the dispute service reports to clients
+var (
+	ErrDisputeNotFound   = repository.ErrDisputeNotFound
+	ErrInvalidDispute    = newError(KindInvalid, "invalid_dispute", "invalid dispute")
+	ErrDisputeNotAllowed = newError(KindConflict, "dispute_not_allowed", "dispute cannot be changed in its current state")
+	ErrDisputeInProgress = repository.ErrDisputeInProgress
+)
+
+// maxDisputeMessage bounds the length of one message in a dispute thread
+const maxDisputeMessage = 5000
+
+// DisputeService runs the dispute workflow for paid orders. A buyer opens
+// a dispute against one seller's items; the seller answers in the thread
+// and may settle it with a refund; either side may escalate it, and an
+// admin can settle it any way. Refunds go back through the payment
+// provider and are taken from that seller's balance in the ledger.
+type DisputeService struct {
+	disputeRepo *repository.DisputeRepository
+	orderRepo   *repository.OrderRepository
+	payments    *PaymentService
+	ledger      *LedgerService
+	window      time.Duration
+}
+
+func NewDisputeService(disputeRepo *repository.DisputeRepository, orderRepo *repository.OrderRepository, payments *PaymentService, ledger *LedgerService) *DisputeService {
+	return &DisputeService{
+		disputeRepo: disputeRepo,
+		orderRepo:   orderRepo,
+		payments:    payments,
+		ledger:      ledger,
+		window:      config.Get().Orders.DisputeWindow,
+	}
+}
+
+// OpenDispute opens a dispute about a seller's items on one of the buyer's
+// paid orders. sellerID may be empty when the order has a single seller.
+func (s *DisputeService) OpenDispute(ctx context.Context, orderID, buyerID, sellerID, reason, message string) (*models.Dispute, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, lookupError(err, ErrOrderNotFound)
+	}
+	if order.BuyerId != buyerID {
+		return nil, ErrOrderNotFound
+	}
+	if order.Status != models.OrderPaid || order.PaidAt == nil {
+		return nil, fmt.Errorf("%w: only paid orders can be disputed", ErrDisputeNotAllowed)
+	}
+	if time.Since(*order.PaidAt) > s.window {
+		return nil, fmt.Errorf("%w: disputes must be opened within %s of payment", ErrDisputeNotAllowed, s.window)
+	}
+
+	sellers := map[string]bool{}
+	for _, line := range order.Lines {
+		sellers[line.SellerId] = true
+	}
+	if sellerID == "" && len(sellers) == 1 {
+		for id := range sellers {
+			sellerID = id
+		}
+	}
+	if !sellers[sellerID] {
+		return nil, fmt.Errorf("%w: seller_id must be a seller on the order", ErrInvalidDispute)
+	}
+	if !models.IsDisputeReason(reason) {
+		return nil, fmt.Errorf("%w: unknown reason %q", ErrInvalidDispute, reason)
+	}
+	body, err := messageBody(message)
+	if err != nil {
+		return nil, err
+	}
+
+	dispute := &models.Dispute{
+		OrderId:  orderID,
+		BuyerId:  buyerID,
+		SellerId: sellerID,
+		Reason:   reason,
+		Status:   models.DisputeOpen,
+		Refund:   models.NewMoney(0, order.Total.Currency),
+	}
+	first := &models.DisputeMessage{AuthorId: buyerID, Role: models.DisputeRoleBuyer, Body: body}
+	if err := s.disputeRepo.CreateDispute(ctx, dispute, first); err != nil {
+		return nil, err
+	}
+	return dispute, nil
+}
+
+// GetDispute returns a dispute with its thread to its buyer, its seller or
+// an admin
+func (s *DisputeService) GetDispute(ctx context.Context, id, viewerID string, isAdmin bool) (*models.Dispute, error) {
+	dispute, err := s.disputeRepo.GetDispute(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, ErrDisputeNotFound)
+	}
+	if disputeRole(dispute, viewerID, isAdmin) == "" {
+		return nil, ErrDisputeNotFound
+	}
+	return dispute, nil
+}
+
+// GetMyDisputes lists the disputes a user is the buyer or seller in
+func (s *DisputeService) GetMyDisputes(ctx context.Context, userID string) ([]models.Dispute, error) {
+	return s.disputeRepo.GetDisputesByUser(ctx, userID)
+}
+
+// GetDisputes lists disputes in a status, or all of them (admin only)
+func (s *DisputeService) GetDisputes(ctx context.Context, status string) ([]models.Dispute, error) {
+	return s.disputeRepo.GetDisputesByStatus(ctx, status)
+}
+
+// AddMessage adds a message to a dispute's thread. The seller's first
+// message marks an open dispute as answered.
+func (s *DisputeService) AddMessage(ctx context.Context, id, authorID string, isAdmin bool, message string) (*models.Dispute, error) {
+	body, err := messageBody(message)
+	if err != nil {
+		return nil, err
+	}
+	err = s.disputeRepo.UpdateDispute(ctx, id, func(dispute *models.Dispute) (*models.DisputeMessage, error) {
+		role := disputeRole(dispute, authorID, isAdmin)
+		if role == "" {
+			return nil, ErrDisputeNotFound
+		}
+		if dispute.Status == models.DisputeResolved {
+			return nil, fmt.Errorf("%w: the dispute is resolved", ErrDisputeNotAllowed)
+		}
+		if role == models.DisputeRoleSeller && dispute.Status == models.DisputeOpen {
+			dispute.Status = models.DisputeSellerResponded
+		}
+		return &models.DisputeMessage{AuthorId: authorID, Role: role, Body: body}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.disputeRepo.GetDispute(ctx, id)
+}
+
+// Escalate hands a dispute to the admins. Either side may escalate, with
+// an optional note for the thread.
+func (s *DisputeService) Escalate(ctx context.Context, id, userID string, isAdmin bool, note string) (*models.Dispute, error) {
+	err := s.disputeRepo.UpdateDispute(ctx, id, func(dispute *models.Dispute) (*models.DisputeMessage, error) {
+		role := disputeRole(dispute, userID, isAdmin)
+		if role == "" {
+			return nil, ErrDisputeNotFound
+		}
+		if !models.CanTransitionDispute(dispute.Status, models.DisputeEscalated) {
+			return nil, ErrDisputeNotAllowed
+		}
+		dispute.Status = models.DisputeEscalated
+		if strings.TrimSpace(note) == "" {
+			return nil, nil
+		}
+		body, err := messageBody(note)
+		if err != nil {
+			return nil, err
+		}
+		return &models.DisputeMessage{AuthorId: userID, Role: role, Body: body}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.disputeRepo.GetDispute(ctx, id)
+}
+
+// Resolve settles a dispute. The seller may settle it with a full or
+// partial refund; only an admin may close it without one. A full refund
+// returns everything paid for the seller's items, tax and shipping
+// included, less what earlier disputes already refunded, and may put the
+// items back in stock. The refund is made before anything is locked; if
+// the dispute or payment changes meanwhile, the resolution is worked out
+// again.
+func (s *DisputeService) Resolve(ctx context.Context, id, userID string, isAdmin bool, resolution models.DisputeResolution) (*models.Dispute, error) {
+	current, err := s.disputeRepo.GetDispute(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, ErrDisputeNotFound)
+	}
+	order, err := s.orderRepo.GetOrderByID(ctx, current.OrderId)
+	if err != nil {
+		return nil, err
+	}
+	paid := sellerTotal(order, current.SellerId)
+
+	for attempt := 1; ; attempt++ {
+		payment, err := s.resolve(ctx, id, userID, isAdmin, paid, resolution)
+		if errors.Is(err, ErrPaymentChanged) && attempt < eventAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if payment != nil {
+			// The refund is recorded for the seller, so the ledger takes it
+			// from them alone. Like other refunds, a failure here is
+			// repaired by reconciliation.
+			s.ledger.RecordPaymentEffects(ctx, payment)
+		}
+		return s.disputeRepo.GetDispute(ctx, id)
+	}
+}
+
+// resolve works out a resolution from the dispute and payment as they
+// are, has the provider make its refund, and stores it, provided neither
+// changed in between. It returns the payment when there was a refund.
+func (s *DisputeService) resolve(ctx context.Context, id, userID string, isAdmin bool, paid int64, resolution models.DisputeResolution) (*models.Payment, error) {
+	dispute, p, refunded, err := s.disputeRepo.GetResolution(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, ErrDisputeNotFound)
+	}
+	status := dispute.Status
+	message, err := settle(dispute, p, refunded, paid, userID, isAdmin, resolution)
+	if err != nil {
+		return nil, err
+	}
+
+	var made *models.PaymentRefund
+	var next *models.Payment
+	if dispute.Refund.Amount > 0 {
+		// One refund per dispute, so a retry reuses the provider's refund
+		// instead of making another
+		made = &models.PaymentRefund{
+			PaymentId:      p.Id,
+			IdempotencyKey: "dispute-" + dispute.Id,
+			Amount:         dispute.Refund,
+			SellerId:       &dispute.SellerId,
+		}
+		if err := s.payments.paymentRepo.CreateRefund(ctx, made); err != nil {
+			return nil, err
+		}
+		if made.Amount.Amount != dispute.Refund.Amount {
+			return nil, fmt.Errorf("%w: a refund of %s was already started for this dispute", ErrDisputeNotAllowed, made.Amount)
+		}
+		copied := *p
+		next = &copied
+		if err := s.payments.refund(ctx, next, made); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = s.disputeRepo.ResolveDispute(ctx, id, made, func(locked *models.Dispute, lockedPayment *models.Payment, lockedRefunded int64) (*models.DisputeMessage, error) {
+		if locked.Status != status || lockedRefunded != refunded || !unchangedPayment(lockedPayment, p, next) {
+			return nil, ErrPaymentChanged
+		}
+		locked.Status = dispute.Status
+		locked.Resolution = dispute.Resolution
+		locked.Refund = dispute.Refund
+		locked.Restocked = dispute.Restocked
+		locked.ResolvedBy = dispute.ResolvedBy
+		if next != nil {
+			lockedPayment.Refunded, lockedPayment.Status = next.Refunded, next.Status
+		}
+		return message, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// unchangedPayment reports whether the locked payment is still the one a
+// resolution was worked out from, or already shows its refund because the
+// provider's webhook got there first
+func unchangedPayment(locked, read, refunded *models.Payment) bool {
+	if locked == nil || read == nil {
+		return locked == read
+	}
+	return locked.Id == read.Id &&
+		(locked.Refunded.Amount == read.Refunded.Amount || refunded != nil && locked.Refunded.Amount == refunded.Refunded.Amount)
+}
+
+// settle sets the dispute's resolution and refund, checking that userID
+// may resolve it that way and that the refund fits what is left of what
+// the buyer paid for the seller's items. It returns the note to add to the
+// thread, if any.
+func settle(dispute *models.Dispute, p *models.Payment, refunded, paid int64, userID string, isAdmin bool, resolution models.DisputeResolution) (*models.DisputeMessage, error) {
+	if !isAdmin && dispute.SellerId != userID {
+		if dispute.BuyerId == userID {
+			return nil, fmt.Errorf("%w: only the seller or an admin can resolve a dispute", ErrDisputeNotAllowed)
+		}
+		return nil, ErrDisputeNotFound
+	}
+	if !models.CanTransitionDispute(dispute.Status, models.DisputeResolved) {
+		return nil, ErrDisputeNotAllowed
+	}
+
+	remaining := paid - refunded
+	if p != nil {
+		remaining = min(remaining, p.Amount.Amount-p.Refunded.Amount)
+	}
+	refund := models.NewMoney(0, dispute.Refund.Currency)
+	switch resolution.Resolution {
+	case models.DisputeRefund:
+		refund.Amount = remaining
+	case models.DisputePartialRefund:
+		amount, err := models.ParseMoney(resolution.Amount, dispute.Refund.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("%w: amount must be a decimal amount of %s such as 12.50", ErrInvalidDispute, refund.Currency)
+		}
+		if amount.Amount <= 0 || amount.Amount >= remaining {
+			return nil, fmt.Errorf("%w: a partial refund must be more than 0 and less than %s",
+				ErrInvalidDispute, models.NewMoney(remaining, refund.Currency))
+		}
+		refund = amount
+	case models.DisputeNoRefund:
+		if !isAdmin {
+			return nil, fmt.Errorf("%w: only an admin can close a dispute without a refund", ErrDisputeNotAllowed)
+		}
+	default:
+		return nil, fmt.Errorf("%w: resolution must be refund, partial_refund or no_refund", ErrInvalidDispute)
+	}
+	if resolution.Resolution == models.DisputeRefund && refund.Amount <= 0 {
+		return nil, fmt.Errorf("%w: nothing is left to refund for this seller's items", ErrDisputeNotAllowed)
+	}
+	if resolution.Restock && resolution.Resolution != models.DisputeRefund {
+		return nil, fmt.Errorf("%w: items can only be restocked on a full refund", ErrInvalidDispute)
+	}
+	if refund.Amount > 0 && p == nil {
+		return nil, fmt.Errorf("%w: the order has no captured payment to refund", ErrDisputeNotAllowed)
+	}
+
+	var message *models.DisputeMessage
+	if strings.TrimSpace(resolution.Note) != "" {
+		body, err := messageBody(resolution.Note)
+		if err != nil {
+			return nil, err
+		}
+		role := models.DisputeRoleAdmin
+		if dispute.SellerId == userID {
+			role = models.DisputeRoleSeller
+		}
+		message = &models.DisputeMessage{AuthorId: userID, Role: role, Body: body}
+	}
+
+	dispute.Status = models.DisputeResolved
+	dispute.Resolution = resolution.Resolution
+	dispute.Refund = refund
+	dispute.Restocked = resolution.Restock
+	dispute.ResolvedBy = &userID
+	return message, nil
+}
+
+// disputeRole returns the part a user plays in a dispute, or "" if they
+// may not see it
+func disputeRole(dispute *models.Dispute, userID string, isAdmin bool) string {
+	switch {
+	case dispute.BuyerId == userID:
+		return models.DisputeRoleBuyer
+	case dispute.SellerId == userID:
+		return models.DisputeRoleSeller
+	case isAdmin:
+		return models.DisputeRoleAdmin
+	}
+	return ""
+}
+
+// sellerTotal is what the buyer paid for a seller's items, tax and
+// shipping included
+func sellerTotal(order *models.Order, sellerID string) int64 {
+	total := order.SellerShipping(sellerID)
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		if line.SellerId != sellerID {
+			continue
+		}
+		total += order.LineNet(line).Amount
+		if tax := order.LineTax(line.Id); tax != nil {
+			total += tax.Tax.Amount
+		}
+	}
+	return total
+}
+
+func messageBody(message string) (string, error) {
+	body := strings.TrimSpace(message)
+	if body == "" {
+		return "", fmt.Errorf("%w: message is required", ErrInvalidDispute)
+	}
+	if len([]rune(body)) > maxDisputeMessage {
+		return "", fmt.Errorf("%w: message must be at most %d characters", ErrInvalidDispute, maxDisputeMessage)
+	}
+	return body, nil
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+)
+
+// Kind says what sort of failure a domain error is, which decides how it is
+// reported to clients
+type Kind int
+
+const (
+	KindInternal        Kind = iota // a bug or an outage; details stay in the log
+	KindInvalid                     // the request breaks a rule; Fields says where
+	KindUnauthenticated             // the caller couldn't be identified
+	KindForbidden                   // the caller may not do this
+	KindNotFound                    // what the request names doesn't exist
+	KindConflict                    // the request clashes with the current state
+	KindTooLarge                    // the request needs more room than is left
+	KindUnavailable                 // something the service relies on is down
+)
+
+// Error is a failure clients can act on. Code is stable and meant for
+// programs; Message is meant for people. Sentinels such as ErrOrderNotFound
+// are Errors, so errors.Is and errors.As both work through wrapping.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []models.FieldError
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// InvalidField is a validation failure of one input, for handlers too when
+// they find a malformed value while decoding a request
+func InvalidField(field, message string) *Error {
+	return &Error{
+		Kind:    KindInvalid,
+		Code:    "validation_failed",
+		Message: message,
+		Fields:  []models.FieldError{{Field: field, Message: message}},
+	}
+}
+
+// Invalid reports every field error found in a request at once, or returns
+// nil when there are none
+func Invalid(fields []models.FieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	messages := make([]string, len(fields))
+	for i, f := range fields {
+		messages[i] = f.Message
+	}
+	return &Error{
+		Kind:    KindInvalid,
+		Code:    "validation_failed",
+		Message: strings.Join(messages, "; "),
+		Fields:  fields,
+	}
+}
+
+// forbidden is a refusal to let the caller do something
+func forbidden(message string) *Error {
+	return newError(KindForbidden, "forbidden", message)
+}
+
+// passedOn classifies the errors of other packages that services return
+// as they are
+var passedOn = map[error]*Error{
+	repository.ErrItemNotFound:        newError(KindNotFound, "item_not_found", ""),
+	repository.ErrItemUnavailable:     newError(KindConflict, "item_unavailable", ""),
+	repository.ErrInsufficientStock:   newError(KindConflict, "insufficient_stock", ""),
+	repository.ErrMixedCurrencies:     newError(KindInvalid, "mixed_currencies", ""),
+	repository.ErrOrderNotPending:     newError(KindConflict, "order_not_pending", ""),
+	repository.ErrProfileInUse:        newError(KindConflict, "profile_in_use", ""),
+	repository.ErrShipmentNotFound:    newError(KindNotFound, "shipment_not_found", ""),
+	repository.ErrDisputeNotFound:     newError(KindNotFound, "dispute_not_found", ""),
+	repository.ErrDisputeInProgress:   newError(KindConflict, "dispute_in_progress", ""),
+	repository.ErrInsufficientBalance: newError(KindConflict, "insufficient_balance", ""),
+	repository.ErrUserNotFound:        newError(KindNotFound, "user_not_found", ""),
+	repository.ErrUserExists:          newError(KindConflict, "user_exists", ""),
+	repository.ErrOrderNotFound:       newError(KindNotFound, "order_not_found", ""),
+	repository.ErrPaymentNotFound:     newError(KindNotFound, "payment_not_found", ""),
+	repository.ErrInvoiceNotFound:     newError(KindNotFound, "invoice_not_found", ""),
+	repository.ErrProfileNotFound:     newError(KindNotFound, "shipping_profile_not_found", ""),
+	repository.ErrUploadNotFound:      newError(KindNotFound, "upload_not_found", ""),
+	repository.ErrFlagNotFound:        newError(KindNotFound, "flag_not_found", ""),
+	repository.ErrFeeScheduleNotFound: newError(KindNotFound, "fee_schedule_not_found", ""),
+	repository.ErrTaxRateNotFound:     newError(KindNotFound, "tax_rate_not_found", ""),
+	models.ErrNotShippable:            newError(KindConflict, "not_shippable", ""),
+}
+
+// Classify returns the domain error err is or wraps, or nil when err is an
+// internal failure whose details clients shouldn't see. Its message is the
+// one clients get: the domain error's own and any detail a service wrote
+// after it with fmt.Errorf("%w: …"), without the context callers wrapped
+// around that for the log.
+func Classify(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		if message := clientMessage(err, e.Message); message != e.Message {
+			return &Error{Kind: e.Kind, Code: e.Code, Message: message, Fields: e.Fields}
+		}
+		return e
+	}
+	if repository.IsMalformedID(err) {
+		return newError(KindNotFound, "not_found", "not found")
+	}
+	for other, e := range passedOn {
+		if errors.Is(err, other) {
+			return &Error{Kind: e.Kind, Code: e.Code, Message: clientMessage(err, other.Error())}
+		}
+	}
+	return nil
+}
+
+// clientMessage returns the text of err from where message, that of the
+// error it wraps, begins
+func clientMessage(err error, message string) string {
+	text := err.Error()
+	if i := strings.Index(text, message); i >= 0 {
+		return text[i:]
+	}
+	return message
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	repository "primeauction/api/Repository"
+)
+
+// sqlError is a database error with a SQLSTATE, like lib/pq's
+type sqlError string
+
+func (e sqlError) Error() string    { return "pq: " + string(e) }
+func (e sqlError) SQLState() string { return string(e) }
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		err     error
+		kind    Kind
+		code    string
+		message string
+	}{
+		{"domain error", ErrQuotaExceeded, KindTooLarge, "quota_exceeded", "storage quota exceeded"},
+		{"wrapped domain error", fmt.Errorf("upload 7: %w", ErrUploadNotFound), KindNotFound, "upload_not_found", "upload not found"},
+		{"invalid field", InvalidField("name", "name is required"), KindInvalid, "validation_failed", "name is required"},
+		{"forbidden", forbidden("you can only delete your own items"), KindForbidden, "forbidden", "you can only delete your own items"},
+		{"passed on", repository.ErrInsufficientStock, KindConflict, "insufficient_stock", repository.ErrInsufficientStock.Error()},
+		{"wrapped passed on", fmt.Errorf("reserving: %w", repository.ErrUserExists), KindConflict, "user_exists", repository.ErrUserExists.Error()},
+		{"detail", fmt.Errorf("order 7: %w", fmt.Errorf("%w: at most 50 items per order", ErrInvalidOrder)), KindInvalid, "invalid_order", "invalid order: at most 50 items per order"},
+		{"malformed id", sqlError("22P02"), KindNotFound, "not_found", "not found"},
+	} {
+		e := Classify(tc.err)
+		if e == nil {
+			t.Errorf("%s: Classify(%v) = nil", tc.name, tc.err)
+			continue
+		}
+		if e.Kind != tc.kind || e.Code != tc.code || e.Message != tc.message {
+			t.Errorf("%s: Classify(%v) = %v %q %q, want %v %q %q", tc.name, tc.err, e.Kind, e.Code, e.Message, tc.kind, tc.code, tc.message)
+		}
+	}
+
+	for _, err := range []error{errors.New("connection refused"), sqlError("23505")} {
+		if e := Classify(err); e != nil {
+			t.Errorf("Classify(%v) = %+v, want nil for an internal error", err, e)
+		}
+	}
+}
+
+func TestLookupError(t *testing.T) {
+	down := errors.New("connection refused")
+	for _, tc := range []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"no rows", sql.ErrNoRows, ErrOrderNotFound},
+		{"store not found", repository.ErrOrderNotFound, ErrOrderNotFound},
+		{"wrapped store not found", fmt.Errorf("loading: %w", repository.ErrUserNotFound), ErrOrderNotFound},
+		{"malformed id", sqlError("22P02"), ErrOrderNotFound},
+		{"database down", down, down},
+		{"canceled", context.Canceled, context.Canceled},
+		{"other domain error", repository.ErrOrderNotPending, repository.ErrOrderNotPending},
+	} {
+		if got := lookupError(tc.err, ErrOrderNotFound); got != tc.want {
+			t.Errorf("%s: lookupError(%v) = %v, want %v", tc.name, tc.err, got, tc.want)
+		}
+	}
+}
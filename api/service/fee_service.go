@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidFeeSchedule wraps validation failures of published fee rules
+var ErrInvalidFeeSchedule = newError(KindInvalid, "invalid_fee_schedule", "invalid fee schedule")
+
+var sellerTierPattern = regexp.MustCompile(`^[a-z0-9_-]{1,30}$`)
+
+// FeeService manages the versioned fee rules and charges listing fees.
+// Sale fees are assessed at checkout by the order service and recorded in
+// the ledger when the payment is captured.
+type FeeService struct {
+	feeRepo *repository.FeeRepository
+	ledger  *LedgerService
+}
+
+func NewFeeService(feeRepo *repository.FeeRepository, ledger *LedgerService) *FeeService {
+	return &FeeService{feeRepo: feeRepo, ledger: ledger}
+}
+
+// CurrentSchedule returns the fee rules in effect
+func (s *FeeService) CurrentSchedule(ctx context.Context) (*models.FeeSchedule, error) {
+	return s.feeRepo.GetCurrentSchedule(ctx)
+}
+
+// GetSchedule retrieves one version of the fee rules
+func (s *FeeService) GetSchedule(ctx context.Context, version int) (*models.FeeSchedule, error) {
+	return s.feeRepo.GetSchedule(ctx, version)
+}
+
+// GetSchedules lists every published version, newest first
+func (s *FeeService) GetSchedules(ctx context.Context) ([]models.FeeSchedule, error) {
+	return s.feeRepo.GetSchedules(ctx)
+}
+
+// PublishSchedule validates a complete set of rules and makes it the new
+// current version. Orders already placed keep the version they were
+// priced with.
+func (s *FeeService) PublishSchedule(ctx context.Context, schedule *models.FeeSchedule, adminID string) error {
+	for i := range schedule.Rules {
+		rule := &schedule.Rules[i]
+		rule.Name = strings.TrimSpace(rule.Name)
+		rule.Category = normalizeCategory(rule.Category)
+		rule.SellerTier = strings.ToLower(strings.TrimSpace(rule.SellerTier))
+	}
+	if err := schedule.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFeeSchedule, err)
+	}
+	if adminID != "" {
+		schedule.CreatedBy = &adminID
+	}
+	return s.feeRepo.CreateSchedule(ctx, schedule)
+}
+
+// GetSellerTier returns the fee tier of a seller
+func (s *FeeService) GetSellerTier(ctx context.Context, userID string) (string, error) {
+	return s.feeRepo.GetSellerTier(ctx, userID)
+}
+
+// SetSellerTier moves a seller to another fee tier. The new tier applies to
+// orders placed from now on.
+func (s *FeeService) SetSellerTier(ctx context.Context, userID, tier string) error {
+	tier = strings.ToLower(strings.TrimSpace(tier))
+	if !sellerTierPattern.MatchString(tier) {
+		return InvalidField("tier", "tier must be 1-30 lower-case letters, digits, '-' or '_'")
+	}
+	return s.feeRepo.SetSellerTier(ctx, userID, tier)
+}
+
+// ChargeListingFee records the listing fee for an item the first time it is
+// listed. Drafts aren't listed yet; an item that was already charged is
+// not charged again. Failures are logged rather than returned because the
+// item itself has already been saved.
+func (s *FeeService) ChargeListingFee(ctx context.Context, item *models.Item) {
+	if item.Visibility == models.VisibilityDraft {
+		return
+	}
+	ctx = context.WithoutCancel(ctx) // nor is it undone if the caller goes away
+	if err := s.chargeListingFee(ctx, item); err != nil {
+		log.Printf("fees: failed to charge listing fee for item %s: %v", item.Id, err)
+	}
+}
+
+func (s *FeeService) chargeListingFee(ctx context.Context, item *models.Item) error {
+	schedule, err := s.feeRepo.GetCurrentSchedule(ctx)
+	if err != nil {
+		return err
+	}
+	tier, err := s.feeRepo.GetSellerTier(ctx, item.UserId)
+	if err != nil {
+		return err
+	}
+	quantity := item.Quantity
+	if quantity < 1 {
+		quantity = 1
+	}
+	base, err := item.SellingPrice.Mul(int64(quantity))
+	if err != nil {
+		return err
+	}
+	fee := schedule.Assess(models.FeeOnListing, item.Category, tier, base, quantity)
+	if fee.Amount.Amount == 0 {
+		return nil
+	}
+	return s.ledger.RecordListingFee(ctx, item, fee)
+}
+
+// normalizeCategory trims and lower-cases a category so rules match it
+// regardless of how a seller typed it
+func normalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
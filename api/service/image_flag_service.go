@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+)
+
+// ImageFlagService backs the admin review queue for suspected duplicate or
+// stolen listing photos
+type ImageFlagService struct {
+	flagRepo *repository.ImageFlagRepository
+}
+
+func NewImageFlagService(flagRepo *repository.ImageFlagRepository) *ImageFlagService {
+	return &ImageFlagService{flagRepo: flagRepo}
+}
+
+// GetFlags lists flags, optionally filtered by status
+func (s *ImageFlagService) GetFlags(ctx context.Context, status string) ([]models.ImageFlag, error) {
+	if status != "" && !validFlagStatus(status) {
+		return nil, InvalidField("status", "status must be pending, dismissed or confirmed")
+	}
+	return s.flagRepo.GetFlags(ctx, status)
+}
+
+// ReviewFlag records an admin's decision on a flag
+func (s *ImageFlagService) ReviewFlag(ctx context.Context, id, status, reviewerID string) (*models.ImageFlag, error) {
+	if id == "" {
+		return nil, InvalidField("id", "flag id is required")
+	}
+	if !validFlagStatus(status) {
+		return nil, InvalidField("status", "status must be pending, dismissed or confirmed")
+	}
+	return s.flagRepo.ReviewFlag(ctx, id, status, reviewerID)
+}
+
+func validFlagStatus(status string) bool {
+	switch status {
+	case models.FlagPending, models.FlagDismissed, models.FlagConfirmed:
+		return true
+	}
+	return false
+}
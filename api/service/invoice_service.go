@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+	"primeauction/api/utils"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Errors the invoice service reports to clients
+var (
+	ErrInvoiceNotFound = newError(KindNotFound, "invoice_not_found", "invoice not found")
+	ErrOrderNotSettled = newError(KindConflict, "order_not_settled", "invoices are only issued for paid orders")
+	ErrSellerRequired  = newError(KindInvalid, "seller_required", "this order has several sellers; choose one with seller_id")
+)
+
+// InvoiceService issues a PDF invoice from each seller on an order once it
+// is paid. The PDFs are kept in upload storage and only served through the
+// API to the order's buyer, the invoicing seller and admins.
+type InvoiceService struct {
+	invoiceRepo *repository.InvoiceRepository
+	orderRepo   *repository.OrderRepository
+	userRepo    repository.UserStore
+	dir         string
+}
+
+func NewInvoiceService(invoiceRepo *repository.InvoiceRepository, orderRepo *repository.OrderRepository, userRepo repository.UserStore) *InvoiceService {
+	return &InvoiceService{
+		invoiceRepo: invoiceRepo,
+		orderRepo:   orderRepo,
+		userRepo:    userRepo,
+		dir:         utils.InvoiceDir,
+	}
+}
+
+// IssueForOrder issues the invoices a paid order is missing, one per
+// seller. It is safe to call repeatedly and concurrently.
+func (s *InvoiceService) IssueForOrder(ctx context.Context, orderID string) error {
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != models.OrderPaid {
+		return ErrOrderNotSettled
+	}
+
+	sellers := map[string]bool{}
+	for _, line := range order.Lines {
+		sellers[line.SellerId] = true
+	}
+	ids := make([]string, 0, len(sellers))
+	for id := range sellers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, sellerID := range ids {
+		if err := s.issue(ctx, order, sellerID); err != nil {
+			return fmt.Errorf("seller %s: %w", sellerID, err)
+		}
+	}
+	return nil
+}
+
+// IssueInvoices is IssueForOrder for callers that can't act on a failure:
+// errors are logged, and the invoices are issued when they are next asked for
+func (s *InvoiceService) IssueInvoices(ctx context.Context, orderID string) {
+	ctx = context.WithoutCancel(ctx) // the order is paid whether or not the caller waits
+	if err := s.IssueForOrder(ctx, orderID); err != nil {
+		log.Printf("invoices: failed to issue invoices for order %s: %v", orderID, err)
+	}
+}
+
+func (s *InvoiceService) issue(ctx context.Context, order *models.Order, sellerID string) error {
+	invoice := &models.Invoice{
+		OrderId:  order.Id,
+		SellerId: sellerID,
+		BuyerId:  order.BuyerId,
+		Subtotal: models.NewMoney(0, order.Total.Currency),
+		Tax:      models.NewMoney(0, order.Total.Currency),
+		Shipping: models.NewMoney(order.SellerShipping(sellerID), order.Total.Currency),
+	}
+	var lines []models.OrderLine
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		if line.SellerId != sellerID {
+			continue
+		}
+		lines = append(lines, *line)
+		invoice.Subtotal.Amount += order.LineNet(line).Amount
+		if tax := order.LineTax(line.Id); tax != nil {
+			invoice.Tax.Amount += tax.Tax.Amount
+		}
+	}
+	invoice.Total = models.NewMoney(invoice.Subtotal.Amount+invoice.Tax.Amount+invoice.Shipping.Amount, order.Total.Currency)
+
+	var written string
+	_, err := s.invoiceRepo.CreateInvoice(ctx, invoice, func(invoice *models.Invoice) (string, error) {
+		pdf := s.render(ctx, invoice, order, lines)
+		path, err := utils.SavePDF(s.dir, invoice.Number+".pdf", pdf)
+		written = path
+		return path, err
+	})
+	if err != nil && written != "" {
+		// The number was rolled back, so the file must not survive either
+		os.Remove(written)
+	}
+	return err
+}
+
+// GetInvoices lists an order's invoices for a viewer. The buyer and admins
+// see all of them, a seller only their own. A paid order's missing
+// invoices are issued first.
+func (s *InvoiceService) GetInvoices(ctx context.Context, orderID, viewerID string, isAdmin bool) ([]models.Invoice, error) {
+	order, err := s.authorize(ctx, orderID, viewerID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderPaid {
+		return nil, ErrOrderNotSettled
+	}
+	if err := s.IssueForOrder(ctx, orderID); err != nil {
+		return nil, err
+	}
+
+	invoices, err := s.invoiceRepo.GetInvoicesByOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	visible := []models.Invoice{}
+	for _, invoice := range invoices {
+		if isAdmin || order.BuyerId == viewerID || invoice.SellerId == viewerID {
+			invoice.URL = "/api/orders/" + orderID + "/invoice?seller_id=" + invoice.SellerId
+			visible = append(visible, invoice)
+		}
+	}
+	return visible, nil
+}
+
+// GetInvoice returns the invoice for an order from sellerID. sellerID may
+// be empty when the viewer is the order's only seller or the order has a
+// single seller.
+func (s *InvoiceService) GetInvoice(ctx context.Context, orderID, sellerID, viewerID string, isAdmin bool) (*models.Invoice, error) {
+	invoices, err := s.GetInvoices(ctx, orderID, viewerID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if sellerID == "" {
+		for i := range invoices {
+			if invoices[i].SellerId == viewerID {
+				return &invoices[i], nil
+			}
+		}
+		if len(invoices) == 1 {
+			return &invoices[0], nil
+		}
+		return nil, ErrSellerRequired
+	}
+	for i := range invoices {
+		if invoices[i].SellerId == sellerID {
+			return &invoices[i], nil
+		}
+	}
+	return nil, ErrInvoiceNotFound
+}
+
+// authorize loads an order the viewer may see invoices for: the buyer,
+// sellers on the order and admins
+func (s *InvoiceService) authorize(ctx context.Context, orderID, viewerID string, isAdmin bool) (*models.Order, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, lookupError(err, ErrOrderNotFound)
+	}
+	if isAdmin || order.BuyerId == viewerID {
+		return order, nil
+	}
+	for _, line := range order.Lines {
+		if line.SellerId == viewerID {
+			return order, nil
+		}
+	}
+	return nil, ErrOrderNotFound
+}
+
+// Invoice layout, in points
+const (
+	invoiceMargin   = 50.0
+	invoiceRowSpace = 16.0
+)
+
+// render lays out an invoice as a PDF
+func (s *InvoiceService) render(ctx context.Context, invoice *models.Invoice, order *models.Order, lines []models.OrderLine) []byte {
+	pdf := utils.NewPDF()
+	right := utils.PDFPageWidth - invoiceMargin
+	y := utils.PDFPageHeight - invoiceMargin
+
+	pdf.Text(invoiceMargin, y-10, 22, true, "Invoice")
+	pdf.TextRight(right, y-10, 11, true, invoice.Number)
+	y -= 45
+
+	issued := invoice.IssuedAt
+	if issued.IsZero() {
+		issued = time.Now()
+	}
+	details := [][2]string{
+		{"Invoice date", issued.Format("2 January 2006")},
+		{"Order", order.Id},
+		{"Order date", order.CreatedAt.Format("2 January 2006")},
+	}
+	if order.PaidAt != nil {
+		details = append(details, [2]string{"Paid", order.PaidAt.Format("2 January 2006")})
+	}
+	if order.TaxRegion != "" {
+		details = append(details, [2]string{"Tax region", order.TaxRegion})
+	}
+	for _, d := range details {
+		pdf.Text(invoiceMargin, y, 10, true, d[0])
+		pdf.Text(invoiceMargin+80, y, 10, false, d[1])
+		y -= invoiceRowSpace
+	}
+	y -= 10
+
+	pdf.Text(invoiceMargin, y, 10, true, "Seller")
+	pdf.Text(invoiceMargin+250, y, 10, true, "Bill to")
+	y -= invoiceRowSpace
+	seller, buyer := s.party(ctx, invoice.SellerId), s.party(ctx, invoice.BuyerId)
+	for i := range seller {
+		pdf.Text(invoiceMargin, y, 10, false, seller[i])
+		pdf.Text(invoiceMargin+250, y, 10, false, buyer[i])
+		y -= invoiceRowSpace
+	}
+	y -= 20
+
+	columns := func(y float64, bold bool, item, qty, unit, net, rate, tax string) {
+		pdf.Text(invoiceMargin, y, 9, bold, item)
+		pdf.TextRight(280, y, 9, bold, qty)
+		pdf.TextRight(350, y, 9, bold, unit)
+		pdf.TextRight(420, y, 9, bold, net)
+		pdf.TextRight(470, y, 9, bold, rate)
+		pdf.TextRight(right, y, 9, bold, tax)
+	}
+	header := func() {
+		columns(y, true, "Item", "Qty", "Unit price", "Net", "Tax rate", "Tax")
+		y -= 6
+		pdf.Line(invoiceMargin, y, right, y)
+		y -= invoiceRowSpace
+	}
+	header()
+	for i := range lines {
+		if y < invoiceMargin+100 {
+			pdf.AddPage()
+			y = utils.PDFPageHeight - invoiceMargin
+			header()
+		}
+		line := &lines[i]
+		rate, tax := "-", "-"
+		if t := order.LineTax(line.Id); t != nil {
+			rate = fmt.Sprintf("%d.%02d%%", t.RateBPS/100, t.RateBPS%100)
+			tax = t.Tax.String()
+			if t.Inclusive {
+				rate += " incl."
+			}
+		}
+		columns(y, false, truncate(line.ItemName, 45), fmt.Sprint(line.Quantity), line.UnitPrice.String(),
+			order.LineNet(line).String(), rate, tax)
+		y -= invoiceRowSpace
+	}
+
+	y -= 4
+	pdf.Line(320, y, right, y)
+	y -= invoiceRowSpace
+	type row struct {
+		label string
+		value models.Money
+	}
+	totals := []row{{"Subtotal", invoice.Subtotal}, {"Tax", invoice.Tax}}
+	if invoice.Shipping.Amount != 0 {
+		totals = append(totals, row{"Shipping", invoice.Shipping})
+	}
+	totals = append(totals, row{"Total " + invoice.Total.Currency, invoice.Total})
+	for _, total := range totals {
+		bold := strings.HasPrefix(total.label, "Total")
+		pdf.Text(330, y, 10, bold, total.label)
+		pdf.TextRight(right, y, 10, bold, total.value.String())
+		y -= invoiceRowSpace
+	}
+
+	if order.PaidAt != nil {
+		y -= 20
+		pdf.Text(invoiceMargin, y, 10, false, "Paid in full - this invoice is also your receipt.")
+	}
+	return pdf.Bytes()
+}
+
+// party returns the lines describing a user on an invoice
+func (s *InvoiceService) party(ctx context.Context, userID string) [2]string {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return [2]string{"(closed account)", userID}
+	}
+	return [2]string{user.Username, user.Email}
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
@@ -1,22 +1,58 @@
 package service
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"log"
 	"mime/multipart"
+	"os"
+	"path/filepath"
 	repository "primeauction/api/Repository"
+	"primeauction/api/config"
 	"primeauction/api/models"
 	"primeauction/api/utils"
+	"primeauction/api/validate"
+	"slices"
+	"time"
 )
 
 type ItemService struct {
-	itemRepo  *repository.ItemRepository
-	imageRepo *repository.ItemImageRepository
+	tx               repository.TxRunner // for units of work spanning the stores
+	itemRepo         repository.ItemStore
+	imageRepo        repository.ItemImageStore
+	inviteRepo       repository.ItemInviteStore
+	flagRepo         repository.ImageFlagStore
+	shippingRepo     repository.ShippingProfileStore
+	uploads          UploadClaimer
+	fees             ListingFees
+	signedURLTTL     time.Duration
+	phashMaxDistance int
 }
 
-func NewItemService(itemRepo *repository.ItemRepository) *ItemService {
+// ListingFees charges sellers for listing items. FeeService is the one the
+// API uses.
+type ListingFees interface {
+	ChargeListingFee(ctx context.Context, item *models.Item)
+}
+
+// UploadClaimer hands over completed resumable uploads as image files.
+// UploadService is the one the API uses.
+type UploadClaimer interface {
+	ClaimUploads(ctx context.Context, userID string, ids []string) ([]string, error)
+}
+
+func NewItemService(tx repository.TxRunner, itemRepo repository.ItemStore, imageRepo repository.ItemImageStore, inviteRepo repository.ItemInviteStore, flagRepo repository.ImageFlagStore, shippingRepo repository.ShippingProfileStore, uploads UploadClaimer, fees ListingFees) *ItemService {
 	return &ItemService{
-		itemRepo:  itemRepo,
-		imageRepo: repository.NewItemImageRepository(itemRepo.GetDB()),
+		tx:               tx,
+		itemRepo:         itemRepo,
+		imageRepo:        imageRepo,
+		inviteRepo:       inviteRepo,
+		flagRepo:         flagRepo,
+		shippingRepo:     shippingRepo,
+		uploads:          uploads,
+		fees:             fees,
+		signedURLTTL:     config.Get().Uploads.SignedURLTTL,
+		phashMaxDistance: config.Get().Uploads.PHashMaxDistance,
 	}
 }
 
@@ -27,158 +63,511 @@ func (s *ItemService) ValidateImages(fileHeaders []*multipart.FileHeader) error
 	}
 
 	if len(fileHeaders) > utils.MaxImages {
-		return errors.New("maximum 10 images allowed per item")
+		return InvalidField("images", fmt.Sprintf("maximum %d images allowed per item", utils.MaxImages))
 	}
 
+	// Every bad image is reported, numbered from 1 as the seller sees them
+	var errs validate.Errors
 	for i, fileHeader := range fileHeaders {
 		if err := utils.ValidateImageFile(fileHeader); err != nil {
-			return errors.New("image " + string(rune(i+1)) + ": " + err.Error())
+			errs.Add("images", fmt.Sprintf("image %d: %v", i+1, err))
+		}
+	}
+	return Invalid(errs)
+}
+
+// CreateItem validates and creates an item with user_id. The item and its
+// images are saved together: the image files in imagePaths belong to the
+// item from here on and are removed again if it isn't saved, and the
+// resumable uploads in uploadIDs are claimed with it, after those files.
+func (s *ItemService) CreateItem(ctx context.Context, userID string, item *models.Item, imagePaths, uploadIDs []string) error {
+	images := describeImages(imagePaths, uploadIDs)
+	return s.tx.WithTx(ctx, func(ctx context.Context) error {
+		repository.OnRollback(ctx, func() { utils.DeleteMultipleImages(imagePaths) })
+		return s.createItem(ctx, userID, item, images)
+	})
+}
+
+func (s *ItemService) createItem(ctx context.Context, userID string, item *models.Item, newImages itemImages) error {
+	// Set user_id from parameter (ensures user can only create items for themselves)
+	item.UserId = userID
+	if item.Visibility == "" {
+		item.Visibility = models.VisibilityPublic
+	}
+	if err := s.validateItem(ctx, item); err != nil {
+		return err
+	}
+	images, err := s.claimUploads(ctx, userID, newImages)
+	if err != nil {
+		return err
+	}
+
+	// Set primary image if we have images
+	if len(images) > 0 {
+		item.Image = images[0].ImagePath
+	}
+
+	// Create item first
+	if err := s.itemRepo.CreateItem(ctx, item); err != nil {
+		return err
+	}
+
+	// Save images if provided
+	if len(images) > 0 {
+		if err := s.saveImages(ctx, item, images); err != nil {
+			return fmt.Errorf("failed to save images: %w", err)
 		}
 	}
 
+	repository.OnCommit(ctx, func() { s.fees.ChargeListingFee(ctx, item) })
 	return nil
 }
 
-// CreateItem validates and creates an item with user_id
-func (s *ItemService) CreateItem(userID string, item *models.Item, imagePaths []string) error {
-	// Validate user_id is provided
-	if userID == "" {
-		return errors.New("user_id is required")
+// itemImages are the new images of an item, described before the unit of
+// work that saves them so their files aren't read while it is open
+type itemImages struct {
+	files     []models.ItemImage // already saved where they will stay
+	uploadIDs []string
+	uploads   []models.ItemImage // the uploads' staged files, in uploadIDs order
+}
+
+// describeImages reads the size and perceptual hash of the image files in
+// imagePaths and of the staged files of the uploads in uploadIDs. An
+// upload that isn't complete yet is described as far as it can be; claiming
+// it fails anyway.
+func describeImages(imagePaths, uploadIDs []string) itemImages {
+	describe := func(paths []string) []models.ItemImage {
+		images := make([]models.ItemImage, len(paths))
+		for i, path := range paths {
+			images[i].ImagePath = path
+			if info, err := os.Stat(path); err == nil {
+				images[i].SizeBytes = info.Size()
+			}
+			if hash, err := utils.ComputeImageHash(path); err == nil {
+				signed := int64(hash) // stored bit-for-bit in a BIGINT column
+				images[i].PHash = &signed
+			}
+		}
+		return images
+	}
+	staged := make([]string, len(uploadIDs))
+	for i, id := range uploadIDs {
+		// The ids aren't checked until they are claimed, so one that could
+		// name a file outside the staging directory isn't read
+		if id == filepath.Base(id) && id != "." && id != ".." {
+			staged[i] = utils.StagingPath(id)
+		}
+	}
+	return itemImages{files: describe(imagePaths), uploadIDs: uploadIDs, uploads: describe(staged)}
+}
+
+// claimUploads claims the uploads in the unit of work ctx carries, and
+// returns the image files followed by the uploads' files
+func (s *ItemService) claimUploads(ctx context.Context, userID string, images itemImages) ([]models.ItemImage, error) {
+	if len(images.uploadIDs) == 0 {
+		return images.files, nil
 	}
+	claimed, err := s.uploads.ClaimUploads(ctx, userID, images.uploadIDs)
+	if err != nil {
+		return nil, err
+	}
+	uploads := slices.Clone(images.uploads)
+	for i, path := range claimed {
+		uploads[i].ImagePath = path
+	}
+	return append(slices.Clip(images.files), uploads...), nil
+}
 
-	// Validate item data
-	if item.Name == "" {
-		return errors.New("item name is required")
+// ValidateItem returns the rules an item userID wants to save breaks,
+// without saving it. Handlers that couldn't read some fields of a request
+// use it to report the rest of the item's problems along with them.
+func (s *ItemService) ValidateItem(ctx context.Context, userID string, item *models.Item) (validate.Errors, error) {
+	c := *item
+	c.UserId = userID
+	if c.Visibility == "" {
+		c.Visibility = models.VisibilityPublic // as when creating it
 	}
+	return s.itemErrors(ctx, &c)
+}
 
-	if item.Price < 0 {
-		return errors.New("price cannot be negative")
+// validateItem checks an item against the rules on its fields and those
+// that span fields or need a lookup, reporting every problem at once
+func (s *ItemService) validateItem(ctx context.Context, item *models.Item) error {
+	errs, err := s.itemErrors(ctx, item)
+	if err != nil {
+		return err
 	}
+	return Invalid(errs)
+}
 
-	if item.SellingPrice < item.Price {
-		return errors.New("selling price must be greater than or equal to cost price")
+// itemErrors returns the rules an item breaks, or an error when a lookup
+// needed to tell failed
+func (s *ItemService) itemErrors(ctx context.Context, item *models.Item) (validate.Errors, error) {
+	item.Category = normalizeCategory(item.Category) // so fee rules match it
+	errs := validate.Struct(item)
+	errs.Merge(validatePrices(item))
+	profileErrs, err := s.validateShippingProfile(ctx, item)
+	if err != nil {
+		return nil, err
 	}
+	errs.Merge(profileErrs)
+	return errs, nil
+}
 
-	if item.Quantity < 0 {
-		return errors.New("quantity cannot be negative")
+// validateShippingProfile checks that an item's shipping profile belongs
+// to the seller and charges in the item's currency. Only a profile that
+// doesn't exist is the seller's mistake; failing to look it up is not.
+func (s *ItemService) validateShippingProfile(ctx context.Context, item *models.Item) (validate.Errors, error) {
+	var errs validate.Errors
+	if item.ShippingProfileId == nil {
+		return errs, nil
+	}
+	profile, err := s.shippingRepo.GetProfile(ctx, *item.ShippingProfileId)
+	if err != nil && !isNotFound(err) {
+		return nil, fmt.Errorf("failed to look up shipping profile: %w", err)
+	}
+	if err != nil || profile.SellerId != item.UserId {
+		errs.Add("shipping_profile_id", "shipping profile not found")
+	} else if profile.Currency != item.SellingPrice.Currency {
+		errs.Add("shipping_profile_id", fmt.Sprintf("the shipping profile charges in %s but the item is priced in %s",
+			profile.Currency, item.SellingPrice.Currency))
 	}
+	return errs, nil
+}
 
-	// Set user_id from parameter (ensures user can only create items for themselves)
-	item.UserId = userID
+// validatePrices checks the cost and selling price of an item. Both must be
+// in the same currency so they can be compared exactly.
+func validatePrices(item *models.Item) validate.Errors {
+	var errs validate.Errors
+	if item.Price.Currency == "" {
+		item.Price.Currency = models.DefaultCurrency
+	}
+	if item.SellingPrice.Currency == "" {
+		item.SellingPrice.Currency = item.Price.Currency
+	}
+	if !item.Price.SameCurrency(item.SellingPrice) {
+		errs.Add("currency", "price and selling price must use the same currency")
+		return errs
+	}
 
-	// Set primary image if we have images
-	if len(imagePaths) > 0 {
-		item.Image = imagePaths[0]
+	if item.Price.IsNegative() {
+		errs.Add("price", "price cannot be negative")
+	} else if item.SellingPrice.Amount < item.Price.Amount {
+		errs.Add("selling_price", "selling price must be greater than or equal to cost price")
 	}
+	return errs
+}
 
-	// Create item first
-	if err := s.itemRepo.CreateItem(item); err != nil {
+// saveImages stores image rows with their perceptual hashes and, once they
+// are committed, flags any that look like another seller's photos
+func (s *ItemService) saveImages(ctx context.Context, item *models.Item, images []models.ItemImage) error {
+	if err := s.imageRepo.CreateImages(ctx, item.Id, images); err != nil {
 		return err
 	}
 
-	// Save images if provided
-	if len(imagePaths) > 0 {
-		if err := s.imageRepo.CreateImages(item.Id, imagePaths); err != nil {
-			// If image save fails, we should ideally rollback item creation
-			// For now, we'll just return the error
-			return errors.New("failed to save images: " + err.Error())
+	// Detection is advisory: failures are logged, never surfaced to the seller
+	repository.OnCommit(ctx, func() {
+		if err := s.flagDuplicateImages(ctx, item, images); err != nil {
+			log.Printf("duplicate image detection for item %s: %v", item.Id, err)
 		}
-	}
+	})
+	return nil
+}
 
+// flagDuplicateImages queues a review flag for every image that closely
+// matches an image on another seller's item
+func (s *ItemService) flagDuplicateImages(ctx context.Context, item *models.Item, images []models.ItemImage) error {
+	for _, img := range images {
+		if img.PHash == nil {
+			continue
+		}
+		matches, err := s.imageRepo.FindSimilarImages(ctx, item.UserId, *img.PHash, s.phashMaxDistance)
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			flag := &models.ImageFlag{
+				ItemId:         item.Id,
+				ImageId:        img.Id,
+				MatchedItemId:  match.ItemID,
+				MatchedImageId: match.ImageID,
+				Distance:       match.Distance,
+				Status:         models.FlagPending,
+			}
+			if err := s.flagRepo.CreateFlag(ctx, flag); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
-// GetItemById retrieves an item by ID
-func (s *ItemService) GetItemById(id string) (*models.Item, error) {
+// GetItemById retrieves an item by ID without an access check. Callers
+// must only hand the result to users allowed to see it, since image URLs
+// of non-public items are signed.
+func (s *ItemService) GetItemById(ctx context.Context, id string) (*models.Item, error) {
 	if id == "" {
-		return nil, errors.New("item id is required")
+		return nil, InvalidField("id", "item id is required")
 	}
-	return s.itemRepo.GetItemById(id)
+	item, err := s.itemRepo.GetItemById(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, ErrItemNotFound)
+	}
+	s.attachImageURLs(item)
+	return item, nil
 }
 
-// UpdateItem updates an item (with authorization check)
-func (s *ItemService) UpdateItem(userID string, item *models.Item, imagePaths []string) error {
-	// Get existing item to check ownership
-	existingItem, err := s.itemRepo.GetItemById(item.Id)
+// GetItemForUpdate retrieves an item for its seller to edit. Anyone else
+// is refused before they send the changes.
+func (s *ItemService) GetItemForUpdate(ctx context.Context, id, userID string) (*models.Item, error) {
+	item, err := s.GetItemById(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if item.UserId != userID {
+		return nil, forbidden("you can only update your own items")
+	}
+	return item, nil
+}
+
+// GetItemForViewer retrieves an item, hiding non-public items from users
+// who are not allowed to see them
+func (s *ItemService) GetItemForViewer(ctx context.Context, id, viewerID string, isAdmin bool) (*models.Item, error) {
+	item, err := s.GetItemById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := s.CanView(ctx, item, viewerID, isAdmin)
+	if err != nil {
+		return nil, err
 	}
+	if !allowed {
+		// Same error as a missing item so drafts don't leak their existence
+		return nil, ErrItemNotFound
+	}
+	return item, nil
+}
 
-	// Check if user owns the item
-	if existingItem.UserId != userID {
-		return errors.New("unauthorized: you can only update your own items")
+// CanView reports whether a viewer may see an item and its images. Public
+// items are visible to everyone, drafts only to the owner and admins, and
+// private items additionally to invited users.
+func (s *ItemService) CanView(ctx context.Context, item *models.Item, viewerID string, isAdmin bool) (bool, error) {
+	if item.Visibility == models.VisibilityPublic || item.Visibility == "" {
+		return true, nil
+	}
+	if isAdmin || (viewerID != "" && viewerID == item.UserId) {
+		return true, nil
+	}
+	if item.Visibility == models.VisibilityPrivate && viewerID != "" {
+		return s.inviteRepo.IsInvited(ctx, item.Id, viewerID)
+	}
+	return false, nil
+}
+
+// ImageRequiresSignature reports whether an uploaded file belongs to a
+// non-public item and may therefore only be served through a signed URL
+func (s *ItemService) ImageRequiresSignature(ctx context.Context, imagePath string) (bool, error) {
+	itemID, err := s.imageRepo.GetItemIDByImagePath(ctx, imagePath)
+	if err != nil {
+		return true, err
+	}
+	if itemID == "" {
+		return false, nil
+	}
+	item, err := s.itemRepo.GetItemById(ctx, itemID)
+	if err != nil {
+		return true, err
 	}
+	return item.Visibility != models.VisibilityPublic && item.Visibility != "", nil
+}
 
-	// Validate updates
-	if item.Name == "" {
-		return errors.New("item name is required")
+// attachImageURLs fills in the URLs clients should load images from
+func (s *ItemService) attachImageURLs(item *models.Item) {
+	url := utils.PublicImageURL
+	if item.Visibility != models.VisibilityPublic && item.Visibility != "" {
+		expiresAt := time.Now().Add(s.signedURLTTL)
+		url = func(path string) string { return utils.SignImageURL(path, expiresAt) }
+	}
+	item.ImageURL = url(item.Image)
+	for i := range item.Images {
+		item.Images[i].URL = url(item.Images[i].ImagePath)
 	}
+}
 
-	if item.Price < 0 {
-		return errors.New("price cannot be negative")
+// UpdateItem updates an item (with authorization check). The stock is only
+// changed when quantity isn't nil: otherwise an edit would undo the sales
+// made since the seller loaded the item. New images in imagePaths and the
+// resumable uploads in uploadIDs replace the existing ones, as CreateItem
+// saves them; the replaced files are removed once the update is saved.
+func (s *ItemService) UpdateItem(ctx context.Context, userID string, item *models.Item, quantity *int, imagePaths, uploadIDs []string) error {
+	images := describeImages(imagePaths, uploadIDs)
+	return s.tx.WithTx(ctx, func(ctx context.Context) error {
+		repository.OnRollback(ctx, func() { utils.DeleteMultipleImages(imagePaths) })
+		return s.updateItem(ctx, userID, item, quantity, images)
+	})
+}
+
+func (s *ItemService) updateItem(ctx context.Context, userID string, item *models.Item, quantity *int, newImages itemImages) error {
+	// Get existing item to check ownership
+	existingItem, err := s.itemRepo.GetItemById(ctx, item.Id)
+	if err != nil {
+		return lookupError(err, ErrItemNotFound)
 	}
 
-	if item.SellingPrice < item.Price {
-		return errors.New("selling price must be greater than or equal to cost price")
+	// Check if user owns the item
+	if existingItem.UserId != userID {
+		return forbidden("you can only update your own items")
 	}
 
 	// Ensure user_id cannot be changed
 	item.UserId = userID
+	if item.Visibility == "" {
+		item.Visibility = existingItem.Visibility
+	}
+	item.Quantity = existingItem.Quantity
+	if quantity != nil {
+		item.Quantity = *quantity
+	}
+	if err := s.validateItem(ctx, item); err != nil {
+		return err
+	}
+	images, err := s.claimUploads(ctx, userID, newImages)
+	if err != nil {
+		return err
+	}
 
-	// Set primary image if we have new images
-	if len(imagePaths) > 0 {
-		item.Image = imagePaths[0]
-		// Delete old images
-		s.imageRepo.DeleteImagesByItemID(item.Id)
-		// Save new images
-		if err := s.imageRepo.CreateImages(item.Id, imagePaths); err != nil {
-			return errors.New("failed to save images: " + err.Error())
+	// New images replace the existing ones, files included once committed
+	if len(images) > 0 {
+		item.Image = images[0].ImagePath
+		if err := s.imageRepo.DeleteImagesByItemID(ctx, item.Id); err != nil {
+			return err
+		}
+		if err := s.saveImages(ctx, item, images); err != nil {
+			return fmt.Errorf("failed to save images: %w", err)
 		}
+		repository.OnCommit(ctx, func() { deleteItemFiles(existingItem) })
 	}
 
-	return s.itemRepo.UpdateItem(item)
+	if err := s.itemRepo.UpdateItem(ctx, item); err != nil {
+		return err
+	}
+	if quantity != nil {
+		if err := s.itemRepo.SetQuantity(ctx, item.Id, *quantity); err != nil {
+			return err
+		}
+	}
+	// A draft being published is listed for the first time
+	repository.OnCommit(ctx, func() { s.fees.ChargeListingFee(ctx, item) })
+	return nil
 }
 
 // DeleteItem deletes an item (with authorization check)
-func (s *ItemService) DeleteItem(itemID, userID string) error {
+func (s *ItemService) DeleteItem(ctx context.Context, itemID, userID string) error {
 	if itemID == "" {
-		return errors.New("item id is required")
+		return InvalidField("id", "item id is required")
 	}
 
 	// Get item to check ownership
-	item, err := s.itemRepo.GetItemById(itemID)
+	item, err := s.itemRepo.GetItemById(ctx, itemID)
 	if err != nil {
-		return err
+		return lookupError(err, ErrItemNotFound)
 	}
 
 	// Check if user owns the item
 	if item.UserId != userID {
-		return errors.New("unauthorized: you can only delete your own items")
+		return forbidden("you can only delete your own items")
 	}
 
-	// Delete associated images
-	s.imageRepo.DeleteImagesByItemID(itemID)
+	// The files go only once the rows are gone for good
+	return s.tx.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.imageRepo.DeleteImagesByItemID(ctx, itemID); err != nil {
+			return err
+		}
+		if err := s.itemRepo.DeleteItem(ctx, itemID); err != nil {
+			return err
+		}
+		repository.OnCommit(ctx, func() { deleteItemFiles(item) })
+		return nil
+	})
+}
 
-	// Delete image files
+// deleteItemFiles removes the image files of an item
+func deleteItemFiles(item *models.Item) {
 	for _, img := range item.Images {
 		utils.DeleteImage(img.ImagePath)
 	}
 	if item.Image != "" {
 		utils.DeleteImage(item.Image)
 	}
-
-	return s.itemRepo.DeleteItem(itemID)
 }
 
-// GetAllItems retrieves all items
-func (s *ItemService) GetAllItems() ([]*models.Item, error) {
-	return s.itemRepo.GetAllItems()
+// GetAllItems retrieves all items the viewer is allowed to list. Private
+// items are reachable by invitees through a direct link but are not listed.
+func (s *ItemService) GetAllItems(ctx context.Context, viewerID string, isAdmin bool) ([]*models.Item, error) {
+	items, err := s.itemRepo.GetAllItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	visible := []*models.Item{}
+	for _, item := range items {
+		if item.Visibility != models.VisibilityPublic && !isAdmin && item.UserId != viewerID {
+			continue
+		}
+		s.attachImageURLs(item)
+		visible = append(visible, item)
+	}
+	return visible, nil
 }
 
 // GetItemsByUserID retrieves all items for a specific user
-func (s *ItemService) GetItemsByUserID(userID string) ([]*models.Item, error) {
+func (s *ItemService) GetItemsByUserID(ctx context.Context, userID string) ([]*models.Item, error) {
 	if userID == "" {
-		return nil, errors.New("user_id is required")
+		return nil, InvalidField("user_id", "user_id is required")
+	}
+	items, err := s.itemRepo.GetItemsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		s.attachImageURLs(item)
+	}
+	return items, nil
+}
+
+// InviteUser lets a user view one of the owner's private items
+func (s *ItemService) InviteUser(ctx context.Context, itemID, ownerID, inviteeID string) error {
+	if inviteeID == "" {
+		return InvalidField("user_id", "user_id is required")
+	}
+	if err := s.checkOwner(ctx, itemID, ownerID); err != nil {
+		return err
+	}
+	return s.inviteRepo.AddInvite(ctx, itemID, inviteeID)
+}
+
+// RevokeInvite removes a previously invited user from an item
+func (s *ItemService) RevokeInvite(ctx context.Context, itemID, ownerID, inviteeID string) error {
+	if err := s.checkOwner(ctx, itemID, ownerID); err != nil {
+		return err
+	}
+	return s.inviteRepo.RemoveInvite(ctx, itemID, inviteeID)
+}
+
+// GetInvites lists the users invited to one of the owner's items
+func (s *ItemService) GetInvites(ctx context.Context, itemID, ownerID string) ([]models.ItemInvite, error) {
+	if err := s.checkOwner(ctx, itemID, ownerID); err != nil {
+		return nil, err
 	}
-	return s.itemRepo.GetItemsByUserID(userID)
+	return s.inviteRepo.GetInvitesByItemID(ctx, itemID)
+}
+
+func (s *ItemService) checkOwner(ctx context.Context, itemID, userID string) error {
+	item, err := s.itemRepo.GetItemById(ctx, itemID)
+	if err != nil {
+		return lookupError(err, ErrItemNotFound)
+	}
+	if item.UserId != userID {
+		return forbidden("you can only manage invites for your own items")
+	}
+	return nil
 }
@@ -0,0 +1,373 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	repository "primeauction/api/Repository"
+	"primeauction/api/Repository/memory"
+	"primeauction/api/models"
+)
+
+// listingFees records the items it is asked to charge for
+type listingFees struct {
+	charged []string
+}
+
+func (f *listingFees) ChargeListingFee(ctx context.Context, item *models.Item) {
+	f.charged = append(f.charged, item.Id)
+}
+
+// uploadClaimer hands over the files in paths by upload id, and fails for
+// any other id after claiming the ones before it
+type uploadClaimer struct {
+	paths    map[string]string
+	returned []string // ids whose claim was rolled back
+}
+
+func (c *uploadClaimer) ClaimUploads(ctx context.Context, userID string, ids []string) ([]string, error) {
+	var claimed []string
+	for _, id := range ids {
+		path, ok := c.paths[id]
+		if !ok {
+			return nil, ErrUploadNotFound
+		}
+		repository.OnRollback(ctx, func() { c.returned = append(c.returned, id) })
+		claimed = append(claimed, path)
+	}
+	return claimed, nil
+}
+
+type itemFixture struct {
+	s        *ItemService
+	fees     *listingFees
+	uploads  *uploadClaimer
+	flags    *memory.ImageFlagStore
+	shipping *memory.ShippingProfileStore
+}
+
+func newItemFixture() itemFixture {
+	items := memory.NewItemStore()
+	f := itemFixture{
+		fees:     &listingFees{},
+		uploads:  &uploadClaimer{paths: map[string]string{}},
+		flags:    memory.NewImageFlagStore(),
+		shipping: memory.NewShippingProfileStore(),
+	}
+	f.s = NewItemService(memory.TxRunner{}, items, memory.NewItemImageStore(items), memory.NewItemInviteStore(), f.flags, f.shipping, f.uploads, f.fees)
+	return f
+}
+
+func newItem(name string) *models.Item {
+	return &models.Item{
+		Name:         name,
+		Price:        models.NewMoney(1000, "USD"),
+		SellingPrice: models.NewMoney(1500, "USD"),
+		Quantity:     3,
+	}
+}
+
+// writeImage saves a PNG of a gradient to a temporary file and returns its
+// path. Images from the same seed look alike.
+func writeImage(t *testing.T, name string, seed int) string {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.SetGray(x, y, color.Gray{Y: uint8((x*seed + y*4) % 256)})
+		}
+	}
+	path := filepath.Join(t.TempDir(), name)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestItemServiceCreate(t *testing.T) {
+	ctx := context.Background()
+	f := newItemFixture()
+
+	item := newItem("Lamp")
+	if err := f.s.CreateItem(ctx, "seller-1", item, nil, nil); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+	if item.UserId != "seller-1" || item.Visibility != models.VisibilityPublic {
+		t.Errorf("CreateItem = %+v, want it owned by seller-1 and public", item)
+	}
+	if len(f.fees.charged) != 1 || f.fees.charged[0] != item.Id {
+		t.Errorf("listing fees charged for %v, want %s", f.fees.charged, item.Id)
+	}
+
+	got, err := f.s.GetItemById(ctx, item.Id)
+	if err != nil {
+		t.Fatalf("GetItemById: %v", err)
+	}
+	if got.Name != "Lamp" || got.SellingPrice != models.NewMoney(1500, "USD") {
+		t.Errorf("GetItemById = %+v", got)
+	}
+	if _, err := f.s.GetItemById(ctx, "missing"); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("GetItemById of an unknown id: got %v, want %v", err, ErrItemNotFound)
+	}
+}
+
+func TestItemServiceCreateReportsEveryField(t *testing.T) {
+	ctx := context.Background()
+	f := newItemFixture()
+	other := &models.ShippingProfile{SellerId: "seller-2", Currency: "USD"}
+	f.shipping.AddProfile(other)
+
+	imagePath := writeImage(t, "lamp.png", 3)
+	item := newItem("")
+	item.SellingPrice = models.NewMoney(500, "USD")
+	item.Quantity = -1
+	item.ShippingProfileId = &other.Id
+	err := f.s.CreateItem(ctx, "seller-1", item, []string{imagePath}, nil)
+
+	var e *Error
+	if !errors.As(err, &e) || e.Kind != KindInvalid {
+		t.Fatalf("CreateItem of an invalid item: got %v, want a KindInvalid error", err)
+	}
+	fields := map[string]bool{}
+	for _, fe := range e.Fields {
+		fields[fe.Field] = true
+	}
+	for _, field := range []string{"name", "quantity", "selling_price", "shipping_profile_id"} {
+		if !fields[field] {
+			t.Errorf("CreateItem reported %v, missing %s", e.Fields, field)
+		}
+	}
+	if len(f.fees.charged) != 0 {
+		t.Errorf("listing fees charged for %v, want none", f.fees.charged)
+	}
+	if _, err := os.Stat(imagePath); !os.IsNotExist(err) {
+		t.Errorf("the image of an item that wasn't saved is still there: %v", err)
+	}
+}
+
+func TestItemServiceClaimsUploadsWithTheItem(t *testing.T) {
+	ctx := context.Background()
+	f := newItemFixture()
+	embedded := writeImage(t, "embedded.png", 3)
+	f.uploads.paths["upload-1"] = writeImage(t, "uploaded.png", 5)
+	f.uploads.paths["upload-2"] = writeImage(t, "uploaded2.png", 5)
+
+	item := newItem("Lamp")
+	if err := f.s.CreateItem(ctx, "seller-1", item, []string{embedded}, []string{"upload-1"}); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+	if item.Image != embedded {
+		t.Errorf("primary image = %q, want the embedded file %q", item.Image, embedded)
+	}
+
+	// A failed claim undoes the others and removes the embedded files
+	embedded = writeImage(t, "embedded2.png", 3)
+	err := f.s.CreateItem(ctx, "seller-1", newItem("Desk"), []string{embedded}, []string{"upload-2", "missing"})
+	if !errors.Is(err, ErrUploadNotFound) {
+		t.Fatalf("CreateItem with an unknown upload: got %v, want %v", err, ErrUploadNotFound)
+	}
+	if len(f.uploads.returned) != 1 || f.uploads.returned[0] != "upload-2" {
+		t.Errorf("claims rolled back for %v, want upload-2", f.uploads.returned)
+	}
+	if _, err := os.Stat(embedded); !os.IsNotExist(err) {
+		t.Errorf("the embedded image of an item that wasn't saved is still there: %v", err)
+	}
+	if _, err := os.Stat(f.uploads.paths["upload-2"]); err != nil {
+		t.Errorf("the file of a returned upload was removed: %v", err)
+	}
+}
+
+func TestItemServiceUpdateKeepsStock(t *testing.T) {
+	ctx := context.Background()
+	f := newItemFixture()
+
+	item := newItem("Lamp")
+	if err := f.s.CreateItem(ctx, "seller-1", item, nil, nil); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+	// A sale made after the seller loaded the item
+	if err := f.s.itemRepo.SetQuantity(ctx, item.Id, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	edit := *item
+	edit.Name = "Desk lamp"
+	if err := f.s.UpdateItem(ctx, "seller-1", &edit, nil, nil, nil); err != nil {
+		t.Fatalf("UpdateItem: %v", err)
+	}
+	got, _ := f.s.GetItemById(ctx, item.Id)
+	if got.Name != "Desk lamp" || got.Quantity != 1 {
+		t.Errorf("after an edit leaving stock alone got %q with %d left, want %q with 1", got.Name, got.Quantity, "Desk lamp")
+	}
+
+	zero := 0
+	if err := f.s.UpdateItem(ctx, "seller-1", &edit, &zero, nil, nil); err != nil {
+		t.Fatalf("UpdateItem: %v", err)
+	}
+	got, _ = f.s.GetItemById(ctx, item.Id)
+	if got.Quantity != 0 || !got.IsSold {
+		t.Errorf("after setting the stock to 0 got %d left, sold %v; want 0 and sold", got.Quantity, got.IsSold)
+	}
+
+	var e *Error
+	err := f.s.UpdateItem(ctx, "seller-2", &edit, nil, nil, nil)
+	if !errors.As(err, &e) || e.Kind != KindForbidden {
+		t.Errorf("UpdateItem by another seller: got %v, want a KindForbidden error", err)
+	}
+}
+
+func TestItemServiceGetItemForUpdate(t *testing.T) {
+	ctx := context.Background()
+	f := newItemFixture()
+
+	item := newItem("Lamp")
+	if err := f.s.CreateItem(ctx, "seller-1", item, nil, nil); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+	if got, err := f.s.GetItemForUpdate(ctx, item.Id, "seller-1"); err != nil || got.Id != item.Id {
+		t.Errorf("GetItemForUpdate by the seller = %v, %v", got, err)
+	}
+	var e *Error
+	if _, err := f.s.GetItemForUpdate(ctx, item.Id, "seller-2"); !errors.As(err, &e) || e.Kind != KindForbidden {
+		t.Errorf("GetItemForUpdate by another seller: got %v, want a KindForbidden error", err)
+	}
+	if _, err := f.s.GetItemForUpdate(ctx, "missing", "seller-1"); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("GetItemForUpdate of an unknown id: got %v, want %v", err, ErrItemNotFound)
+	}
+}
+
+// brokenProfiles fails every lookup, like a database that is down
+type brokenProfiles struct{}
+
+var errProfilesDown = errors.New("connection refused")
+
+func (brokenProfiles) GetProfile(ctx context.Context, id string) (*models.ShippingProfile, error) {
+	return nil, errProfilesDown
+}
+
+func TestItemServiceShippingProfileLookup(t *testing.T) {
+	ctx := context.Background()
+	f := newItemFixture()
+	missing := "missing"
+
+	item := newItem("Lamp")
+	item.ShippingProfileId = &missing
+	err := f.s.CreateItem(ctx, "seller-1", item, nil, nil)
+	var e *Error
+	if !errors.As(err, &e) || e.Kind != KindInvalid || len(e.Fields) != 1 || e.Fields[0].Field != "shipping_profile_id" {
+		t.Errorf("CreateItem with an unknown shipping profile: got %v, want a shipping_profile_id field error", err)
+	}
+
+	// A failed lookup is not the seller's mistake
+	f.s.shippingRepo = brokenProfiles{}
+	item = newItem("Lamp")
+	item.ShippingProfileId = &missing
+	if err := f.s.CreateItem(ctx, "seller-1", item, nil, nil); !errors.Is(err, errProfilesDown) {
+		t.Errorf("CreateItem when profiles can't be looked up: got %v, want %v", err, errProfilesDown)
+	}
+	if _, err := f.s.ValidateItem(ctx, "seller-1", item); !errors.Is(err, errProfilesDown) {
+		t.Errorf("ValidateItem when profiles can't be looked up: got %v, want %v", err, errProfilesDown)
+	}
+}
+
+func TestItemServiceDelete(t *testing.T) {
+	ctx := context.Background()
+	f := newItemFixture()
+
+	imagePath := writeImage(t, "lamp.png", 3)
+	item := newItem("Lamp")
+	if err := f.s.CreateItem(ctx, "seller-1", item, []string{imagePath}, nil); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+
+	var e *Error
+	if err := f.s.DeleteItem(ctx, item.Id, "seller-2"); !errors.As(err, &e) || e.Kind != KindForbidden {
+		t.Errorf("DeleteItem by another seller: got %v, want a KindForbidden error", err)
+	}
+	if err := f.s.DeleteItem(ctx, item.Id, "seller-1"); err != nil {
+		t.Fatalf("DeleteItem: %v", err)
+	}
+	if _, err := f.s.GetItemById(ctx, item.Id); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("GetItemById after DeleteItem: got %v, want %v", err, ErrItemNotFound)
+	}
+	if _, err := os.Stat(imagePath); !os.IsNotExist(err) {
+		t.Errorf("the image of a deleted item is still there: %v", err)
+	}
+}
+
+func TestItemServicePrivateItems(t *testing.T) {
+	ctx := context.Background()
+	f := newItemFixture()
+
+	item := newItem("Lamp")
+	item.Visibility = models.VisibilityPrivate
+	if err := f.s.CreateItem(ctx, "seller-1", item, nil, nil); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+
+	if _, err := f.s.GetItemForViewer(ctx, item.Id, "buyer-1", false); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("GetItemForViewer before an invite: got %v, want %v", err, ErrItemNotFound)
+	}
+	if err := f.s.InviteUser(ctx, item.Id, "buyer-1", "buyer-1"); err == nil {
+		t.Error("InviteUser by someone other than the seller succeeded")
+	}
+	if err := f.s.InviteUser(ctx, item.Id, "seller-1", "buyer-1"); err != nil {
+		t.Fatalf("InviteUser: %v", err)
+	}
+	if _, err := f.s.GetItemForViewer(ctx, item.Id, "buyer-1", false); err != nil {
+		t.Errorf("GetItemForViewer by an invitee: %v", err)
+	}
+	if items, _ := f.s.GetAllItems(ctx, "buyer-1", false); len(items) != 0 {
+		t.Errorf("GetAllItems listed %d private items to an invitee, want none", len(items))
+	}
+
+	if err := f.s.RevokeInvite(ctx, item.Id, "seller-1", "buyer-1"); err != nil {
+		t.Fatalf("RevokeInvite: %v", err)
+	}
+	invites, err := f.s.GetInvites(ctx, item.Id, "seller-1")
+	if err != nil || len(invites) != 0 {
+		t.Errorf("GetInvites after RevokeInvite = %v, %v; want none", invites, err)
+	}
+	if _, err := f.s.GetItemForViewer(ctx, item.Id, "buyer-1", false); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("GetItemForViewer after RevokeInvite: got %v, want %v", err, ErrItemNotFound)
+	}
+}
+
+func TestItemServiceFlagsCopiedImages(t *testing.T) {
+	ctx := context.Background()
+	f := newItemFixture()
+
+	original := newItem("Lamp")
+	if err := f.s.CreateItem(ctx, "seller-1", original, []string{writeImage(t, "lamp.png", 3)}, nil); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+	copied := newItem("Lamp")
+	if err := f.s.CreateItem(ctx, "seller-2", copied, []string{writeImage(t, "copy.png", 3)}, nil); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+	own := newItem("Lamp again")
+	if err := f.s.CreateItem(ctx, "seller-2", own, []string{writeImage(t, "again.png", 3)}, nil); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+
+	flags := f.flags.Flags()
+	if len(flags) != 2 {
+		t.Fatalf("got %d flags, want one for each of seller-2's items", len(flags))
+	}
+	for i, item := range []*models.Item{copied, own} {
+		if flags[i].ItemId != item.Id || flags[i].MatchedItemId != original.Id || flags[i].Status != models.FlagPending {
+			t.Errorf("flag %d = %+v, want item %s matching %s", i, flags[i], item.Id, original.Id)
+		}
+	}
+}
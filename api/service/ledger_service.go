@@ -0,0 +1,551 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+	"sort"
+	"strings"
+)
+
+// ErrInsufficientBalance is returned when a payout exceeds a seller's balance
+var ErrInsufficientBalance = repository.ErrInsufficientBalance
+
+// Ledger account codes. The platform holds buyer money in cash, earns
+// commission into fees, and owes each seller the balance of their payable
+// account.
+func cashAccount(currency string) string { return "platform:cash:" + currency }
+func feeAccount(currency string) string  { return "platform:fees:" + currency }
+func sellerAccount(sellerID, currency string) string {
+	return "seller:" + sellerID + ":payable:" + currency
+}
+
+// taxAccount holds tax collected for a region until it is remitted
+func taxAccount(region, currency string) string { return "tax:" + region + ":payable:" + currency }
+
+// LedgerService records money movements in the double-entry ledger: buyer
+// payments are split into seller earnings and platform commission, refunds
+// reverse both in proportion, and payouts settle what sellers are owed.
+// Every entry has an idempotency key, so recording the same event twice
+// is harmless and a missed entry can be repaired by reconciliation.
+type LedgerService struct {
+	ledgerRepo  *repository.LedgerRepository
+	paymentRepo *repository.PaymentRepository
+	orderRepo   *repository.OrderRepository
+}
+
+func NewLedgerService(ledgerRepo *repository.LedgerRepository, paymentRepo *repository.PaymentRepository, orderRepo *repository.OrderRepository) *LedgerService {
+	return &LedgerService{
+		ledgerRepo:  ledgerRepo,
+		paymentRepo: paymentRepo,
+		orderRepo:   orderRepo,
+	}
+}
+
+func posting(code, accountType, ownerID, memo string, amount int64, currency string) models.LedgerPosting {
+	return models.LedgerPosting{
+		AccountCode: code,
+		AccountType: accountType,
+		OwnerId:     ownerID,
+		Memo:        memo,
+		Amount:      models.NewMoney(amount, currency),
+	}
+}
+
+// RecordSale posts a captured payment: the buyer's money into platform
+// cash, each seller's takings net of tax into their balance, the tax into
+// the region's tax account, and the commission from each seller's balance
+// into platform fees. Tax and commission are the amounts worked out when
+// the order was placed.
+func (s *LedgerService) RecordSale(ctx context.Context, p *models.Payment) error {
+	order, err := s.orderRepo.GetOrderByID(ctx, p.OrderId)
+	if err != nil {
+		return err
+	}
+	currency := p.Amount.Currency
+
+	gross := map[string]int64{}
+	fees := map[string]int64{}
+	taxes := map[string]int64{}
+	var total int64
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		net := order.LineNet(line).Amount
+		gross[line.SellerId] += net
+		total += net
+		if line.Fee != nil {
+			fees[line.SellerId] += line.Fee.Amount.Amount
+		}
+		if tax := order.LineTax(line.Id); tax != nil {
+			taxes[taxAccount(tax.Region, currency)] += tax.Tax.Amount
+			total += tax.Tax.Amount
+		}
+	}
+	// Sellers ship their own parcels, so the shipping they charged is theirs
+	for _, shipment := range order.Shipments {
+		gross[shipment.SellerId] += shipment.Cost.Amount
+		total += shipment.Cost.Amount
+	}
+	if total != p.Amount.Amount {
+		return fmt.Errorf("payment %s amount %d does not match order total %d", p.Id, p.Amount.Amount, total)
+	}
+
+	postings := []models.LedgerPosting{
+		posting(cashAccount(currency), models.AccountAsset, "", "buyer payment", p.Amount.Amount, currency),
+	}
+	var totalFee int64
+	for _, seller := range sortedKeys(gross) {
+		postings = append(postings, posting(sellerAccount(seller, currency), models.AccountLiability, seller, "sale", -gross[seller], currency))
+		if fee := fees[seller]; fee != 0 {
+			postings = append(postings, posting(sellerAccount(seller, currency), models.AccountLiability, seller, "commission", fee, currency))
+			totalFee += fee
+		}
+	}
+	if totalFee != 0 {
+		postings = append(postings, posting(feeAccount(currency), models.AccountRevenue, "", "commission", -totalFee, currency))
+	}
+	for _, account := range sortedKeys(taxes) {
+		if taxes[account] != 0 {
+			postings = append(postings, posting(account, models.AccountLiability, "", "tax", -taxes[account], currency))
+		}
+	}
+
+	_, err = s.ledgerRepo.PostEntry(ctx, &models.LedgerEntry{
+		Kind:           models.EntrySale,
+		ReferenceId:    p.Id,
+		IdempotencyKey: "sale:" + p.Id,
+		Description:    "Order " + order.Id,
+		Postings:       postings,
+	})
+	return err
+}
+
+// RecordRefund posts whatever part of a payment's refunded total the
+// ledger hasn't seen yet. A refund recorded for one seller's items, e.g.
+// when a dispute settles, is taken only from that seller and the tax
+// charged on their items. The rest is taken from each seller and tax
+// account in proportion to their share of the sale. Either way the same
+// share of the commission is handed back to the sellers.
+func (s *LedgerService) RecordRefund(ctx context.Context, p *models.Payment) error {
+	recorded, err := s.ledgerRepo.GetRefundedTotal(ctx, p.Id)
+	if err != nil {
+		return err
+	}
+	delta := p.Refunded.Amount - recorded
+	if delta <= 0 {
+		return nil
+	}
+
+	refunds, err := s.paymentRepo.GetRefunds(ctx, p.Id)
+	if err != nil {
+		return err
+	}
+	for _, refund := range refunds {
+		if refund.SellerId == nil || refund.Status != models.RefundSucceeded || refund.Amount.Amount > delta {
+			continue
+		}
+		key := "refund:" + refund.Id
+		posted, err := s.ledgerRepo.GetEntryByKey(ctx, key)
+		if err != nil {
+			return err
+		}
+		if posted != nil {
+			continue
+		}
+		if err := s.postRefund(ctx, p, refund.Amount.Amount, *refund.SellerId, key); err != nil {
+			return err
+		}
+		delta -= refund.Amount.Amount
+	}
+	if delta <= 0 {
+		return nil
+	}
+	return s.postRefund(ctx, p, delta, "", fmt.Sprintf("refund:%s:%d", p.Id, p.Refunded.Amount))
+}
+
+// postRefund posts a refund of delta under key, taken from sellerID alone
+// or, when it is empty, from every seller in proportion
+func (s *LedgerService) postRefund(ctx context.Context, p *models.Payment, delta int64, sellerID, key string) error {
+	sale, err := s.ledgerRepo.GetEntryByKey(ctx, "sale:"+p.Id)
+	if err != nil {
+		return err
+	}
+	if sale == nil {
+		if err := s.RecordSale(ctx, p); err != nil {
+			return err
+		}
+		if sale, err = s.ledgerRepo.GetEntryByKey(ctx, "sale:"+p.Id); err != nil || sale == nil {
+			return fmt.Errorf("sale entry for payment %s is missing: %v", p.Id, err)
+		}
+	}
+
+	// Each seller's takings and commission, and the tax per account, as
+	// recorded at sale time
+	gross := map[string]int64{}
+	fees := map[string]int64{}
+	taxes := map[string]int64{}
+	for _, sp := range sale.Postings {
+		switch {
+		case sp.AccountType != models.AccountLiability:
+		case sp.Memo == "tax":
+			taxes[sp.AccountCode] += -sp.Amount.Amount
+		case sp.Amount.Amount < 0:
+			gross[sp.OwnerId] += -sp.Amount.Amount
+		default:
+			fees[sp.OwnerId] += sp.Amount.Amount
+		}
+	}
+	currency := p.Amount.Currency
+	if sellerID != "" {
+		gross = map[string]int64{sellerID: gross[sellerID]}
+		fees = map[string]int64{sellerID: fees[sellerID]}
+		if taxes, err = s.sellerTaxes(ctx, p.OrderId, sellerID, currency); err != nil {
+			return err
+		}
+	}
+
+	sellers := sortedKeys(gross)
+	taxAccounts := sortedKeys(taxes)
+	weights := make([]int64, 0, len(sellers)+len(taxAccounts))
+	feeWeights := make([]int64, len(sellers))
+	var totalFee, base int64
+	for i, seller := range sellers {
+		weights = append(weights, gross[seller])
+		feeWeights[i] = fees[seller]
+		totalFee += fees[seller]
+		base += gross[seller]
+	}
+	for _, account := range taxAccounts {
+		weights = append(weights, taxes[account])
+		base += taxes[account]
+	}
+
+	refundShares := allocate(delta, weights)
+	feeBack := mulDivRound(totalFee, delta, base)
+	feeShares := allocate(feeBack, feeWeights)
+
+	postings := []models.LedgerPosting{
+		posting(cashAccount(currency), models.AccountAsset, "", "buyer refund", -delta, currency),
+	}
+	for i, seller := range sellers {
+		if refundShares[i] != 0 {
+			postings = append(postings, posting(sellerAccount(seller, currency), models.AccountLiability, seller, "refund", refundShares[i], currency))
+		}
+		if feeShares[i] != 0 {
+			postings = append(postings, posting(sellerAccount(seller, currency), models.AccountLiability, seller, "commission refund", -feeShares[i], currency))
+		}
+	}
+	if feeBack != 0 {
+		postings = append(postings, posting(feeAccount(currency), models.AccountRevenue, "", "commission refund", feeBack, currency))
+	}
+	for i, account := range taxAccounts {
+		if share := refundShares[len(sellers)+i]; share != 0 {
+			postings = append(postings, posting(account, models.AccountLiability, "", "tax refund", share, currency))
+		}
+	}
+
+	_, err = s.ledgerRepo.PostEntry(ctx, &models.LedgerEntry{
+		Kind:           models.EntryRefund,
+		ReferenceId:    p.Id,
+		IdempotencyKey: key,
+		Description:    "Refund for order " + p.OrderId,
+		Postings:       postings,
+	})
+	return err
+}
+
+// sellerTaxes totals the tax charged on a seller's items in an order, per
+// tax account
+func (s *LedgerService) sellerTaxes(ctx context.Context, orderID, sellerID, currency string) (map[string]int64, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	taxes := map[string]int64{}
+	for _, line := range order.Lines {
+		if line.SellerId != sellerID {
+			continue
+		}
+		if tax := order.LineTax(line.Id); tax != nil {
+			taxes[taxAccount(tax.Region, currency)] += tax.Tax.Amount
+		}
+	}
+	return taxes, nil
+}
+
+// RecordPaymentEffects brings the ledger up to date with a payment's
+// current state. Failures are only logged: the payment itself has already
+// been recorded and reconciliation will post anything that is missing.
+func (s *LedgerService) RecordPaymentEffects(ctx context.Context, p *models.Payment) {
+	ctx = context.WithoutCancel(ctx) // the payment is recorded whether or not the caller waits
+	switch p.Status {
+	case models.PaymentCaptured, models.PaymentPartiallyRefunded, models.PaymentRefunded:
+	default:
+		return
+	}
+	if err := s.RecordSale(ctx, p); err != nil {
+		log.Printf("ledger: failed to record sale for payment %s: %v", p.Id, err)
+		return
+	}
+	if p.Refunded.Amount > 0 {
+		if err := s.RecordRefund(ctx, p); err != nil {
+			log.Printf("ledger: failed to record refund for payment %s: %v", p.Id, err)
+		}
+	}
+}
+
+// RecordPayout moves amount out of a seller's balance. key makes retries
+// of the same payout safe; a repeated key returns the original entry.
+func (s *LedgerService) RecordPayout(ctx context.Context, sellerID string, amount models.Money, key string) (*models.LedgerEntry, error) {
+	if sellerID == "" {
+		return nil, InvalidField("seller_id", "seller_id is required")
+	}
+	if key == "" {
+		return nil, InvalidField("Idempotency-Key", "an idempotency key is required")
+	}
+	if amount.Amount <= 0 {
+		return nil, InvalidField("amount", "payout amount must be greater than zero")
+	}
+
+	currency := amount.Currency
+	account := sellerAccount(sellerID, currency)
+	entry := &models.LedgerEntry{
+		Kind:           models.EntryPayout,
+		ReferenceId:    sellerID,
+		IdempotencyKey: "payout:" + key,
+		Description:    "Payout to seller",
+		Postings: []models.LedgerPosting{
+			posting(account, models.AccountLiability, sellerID, "payout", amount.Amount, currency),
+			posting(cashAccount(currency), models.AccountAsset, "", "payout", -amount.Amount, currency),
+		},
+	}
+	created, err := s.ledgerRepo.PostEntry(ctx, entry, account)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return s.ledgerRepo.GetEntryByKey(ctx, entry.IdempotencyKey)
+	}
+	return entry, nil
+}
+
+// RecordListingFee charges a listing fee to the item's seller. An item is
+// only charged once, whatever the fee rules say when it is listed again.
+func (s *LedgerService) RecordListingFee(ctx context.Context, item *models.Item, fee models.FeeAssessment) error {
+	currency := fee.Amount.Currency
+	_, err := s.ledgerRepo.PostEntry(ctx, &models.LedgerEntry{
+		Kind:           models.EntryListingFee,
+		ReferenceId:    item.Id,
+		IdempotencyKey: "listing:" + item.Id,
+		Description:    fmt.Sprintf("Listing fee for %s (fee schedule v%d, %s)", item.Name, fee.ScheduleVersion, fee.Rule),
+		Postings: []models.LedgerPosting{
+			posting(sellerAccount(item.UserId, currency), models.AccountLiability, item.UserId, "listing fee", fee.Amount.Amount, currency),
+			posting(feeAccount(currency), models.AccountRevenue, "", "listing fee", -fee.Amount.Amount, currency),
+		},
+	})
+	return err
+}
+
+// GetBalance returns what the platform owes a user, per currency
+func (s *LedgerService) GetBalance(ctx context.Context, userID string) ([]models.LedgerBalance, error) {
+	return s.ledgerRepo.GetBalances(ctx, userID)
+}
+
+// GetStatement returns the latest lines of a user's statement in one
+// currency, newest first
+func (s *LedgerService) GetStatement(ctx context.Context, userID, currency string, limit int) ([]models.StatementLine, error) {
+	currency = strings.ToUpper(currency)
+	if !models.ValidCurrencyCode(currency) {
+		return nil, InvalidField("currency", fmt.Sprintf("invalid currency code %q", currency))
+	}
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	return s.ledgerRepo.GetStatement(ctx, userID, currency, limit)
+}
+
+// ReconcileReport is the outcome of checking the ledger
+type ReconcileReport struct {
+	Fix              bool                        `json:"fix"`
+	EntriesChecked   int                         `json:"entries_checked"`
+	BrokenChain      []string                    `json:"broken_chain"` // entries whose hash or link doesn't verify
+	Unbalanced       []string                    `json:"unbalanced"`
+	TrialBalance     map[string]int64            `json:"trial_balance"` // non-zero currency totals
+	MissingSales     []string                    `json:"missing_sales"`
+	RefundMismatches []repository.RefundMismatch `json:"refund_mismatches"`
+	Repaired         []string                    `json:"repaired"`
+	Errors           []string                    `json:"errors"`
+}
+
+// OK reports whether the ledger verified cleanly, counting anything that
+// was repaired as fine
+func (r *ReconcileReport) OK() bool {
+	return len(r.BrokenChain) == 0 && len(r.Unbalanced) == 0 && len(r.TrialBalance) == 0 &&
+		len(r.MissingSales) == 0 && len(r.RefundMismatches) == 0 && len(r.Errors) == 0
+}
+
+// Reconcile verifies the ledger: every entry's hash and link to the one
+// before it, that every entry and the ledger as a whole balance, and that
+// every captured payment and refund has been posted. With fix set, missing
+// sale and refund entries are posted; nothing recorded is ever changed.
+func (s *LedgerService) Reconcile(ctx context.Context, fix bool) (*ReconcileReport, error) {
+	report := &ReconcileReport{Fix: fix, TrialBalance: map[string]int64{}}
+
+	prevHash := models.LedgerGenesisHash
+	var afterSeq int64
+	for {
+		entries, err := s.ledgerRepo.GetEntries(ctx, afterSeq, 500)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load ledger entries: %w", err)
+		}
+		if len(entries) == 0 {
+			break
+		}
+		for _, entry := range entries {
+			report.EntriesChecked++
+			if entry.PrevHash != prevHash || entry.ComputeHash() != entry.Hash {
+				report.BrokenChain = append(report.BrokenChain, entry.Id)
+			}
+			if len(entry.Unbalanced()) > 0 {
+				report.Unbalanced = append(report.Unbalanced, entry.Id)
+			}
+			prevHash = entry.Hash
+			afterSeq = entry.Seq
+		}
+	}
+
+	sums, err := s.ledgerRepo.GetTrialBalance(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute trial balance: %w", err)
+	}
+	for currency, sum := range sums {
+		if sum != 0 {
+			report.TrialBalance[currency] = sum
+		}
+	}
+
+	missing, err := s.ledgerRepo.GetPaymentsMissingSale(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find unposted payments: %w", err)
+	}
+	for _, id := range missing {
+		if fix {
+			p, err := s.paymentRepo.GetPaymentByID(ctx, id)
+			if err == nil {
+				err = s.RecordSale(ctx, p)
+			}
+			if err == nil {
+				report.Repaired = append(report.Repaired, "sale:"+id)
+				continue
+			}
+			report.Errors = append(report.Errors, fmt.Sprintf("payment %s: %v", id, err))
+		}
+		report.MissingSales = append(report.MissingSales, id)
+	}
+
+	mismatches, err := s.ledgerRepo.GetRefundMismatches(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare refunds: %w", err)
+	}
+	for _, m := range mismatches {
+		// The ledger can only catch up; it never shows more refunded than
+		// the payment, since that would need an entry to be reversed
+		if fix && m.PaymentRefunded > m.LedgerRefunded {
+			p, err := s.paymentRepo.GetPaymentByID(ctx, m.PaymentID)
+			if err == nil {
+				err = s.RecordRefund(ctx, p)
+			}
+			if err == nil {
+				report.Repaired = append(report.Repaired, "refund:"+m.PaymentID)
+				continue
+			}
+			report.Errors = append(report.Errors, fmt.Sprintf("payment %s: %v", m.PaymentID, err))
+		}
+		report.RefundMismatches = append(report.RefundMismatches, m)
+	}
+
+	return report, nil
+}
+
+// LogReconcileReport writes a human readable summary of a reconciliation
+func LogReconcileReport(report *ReconcileReport) {
+	log.Printf("ledger reconcile: checked %d entries, %d broken hash links, %d unbalanced entries, %d unposted payments, %d refund mismatches, %d repaired, %d errors",
+		report.EntriesChecked, len(report.BrokenChain), len(report.Unbalanced), len(report.MissingSales),
+		len(report.RefundMismatches), len(report.Repaired), len(report.Errors))
+	for _, id := range report.BrokenChain {
+		log.Printf("ledger reconcile: entry %s fails hash verification", id)
+	}
+	for _, id := range report.Unbalanced {
+		log.Printf("ledger reconcile: entry %s does not balance", id)
+	}
+	for currency, sum := range report.TrialBalance {
+		log.Printf("ledger reconcile: postings in %s sum to %d, not zero", currency, sum)
+	}
+	for _, id := range report.MissingSales {
+		log.Printf("ledger reconcile: captured payment %s has no sale entry", id)
+	}
+	for _, m := range report.RefundMismatches {
+		log.Printf("ledger reconcile: payment %s refunded %d, ledger shows %d", m.PaymentID, m.PaymentRefunded, m.LedgerRefunded)
+	}
+	for _, e := range report.Errors {
+		log.Printf("ledger reconcile: error %s", e)
+	}
+}
+
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mulDivRound returns a*b/c rounded half away from zero, without overflow
+// in the intermediate product
+func mulDivRound(a, b, c int64) int64 {
+	if c == 0 {
+		return 0
+	}
+	value := new(big.Rat).SetFrac(new(big.Int).Mul(big.NewInt(a), big.NewInt(b)), big.NewInt(c))
+	rounded, err := roundHalfAwayFromZero(value)
+	if err != nil {
+		return 0
+	}
+	return rounded
+}
+
+// allocate splits total across weights in proportion, handing the units
+// lost to rounding to the largest remainders so the parts sum to total
+func allocate(total int64, weights []int64) []int64 {
+	parts := make([]int64, len(weights))
+	var sum int64
+	for _, w := range weights {
+		sum += w
+	}
+	if sum == 0 || total == 0 {
+		return parts
+	}
+
+	type remainder struct {
+		index int
+		rem   *big.Int
+	}
+	remainders := make([]remainder, len(weights))
+	var assigned int64
+	for i, w := range weights {
+		q, r := new(big.Int).QuoRem(new(big.Int).Mul(big.NewInt(total), big.NewInt(w)), big.NewInt(sum), new(big.Int))
+		parts[i] = q.Int64()
+		assigned += parts[i]
+		remainders[i] = remainder{i, r}
+	}
+	sort.SliceStable(remainders, func(a, b int) bool { return remainders[a].rem.Cmp(remainders[b].rem) > 0 })
+	for i := 0; assigned < total; i = (i + 1) % len(remainders) {
+		parts[remainders[i].index]++
+		assigned++
+	}
+	return parts
+}
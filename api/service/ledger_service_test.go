@@ -0,0 +1,51 @@
+package service
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestAllocate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		total   int64
+		weights []int64
+		want    []int64
+	}{
+		{"even", 900, []int64{1, 1, 1}, []int64{300, 300, 300}},
+		{"proportional", 1000, []int64{3000, 1000}, []int64{750, 250}},
+		// 100/3 leaves one unit over; it goes to the largest remainder
+		{"one unit over", 100, []int64{1, 1, 1}, []int64{34, 33, 33}},
+		{"largest remainder", 10, []int64{14, 30, 56}, []int64{1, 3, 6}},
+		{"ties go to the first", 10, []int64{15, 30, 55}, []int64{2, 3, 5}},
+		{"zero weight", 500, []int64{0, 2, 3}, []int64{0, 200, 300}},
+		{"no weight", 500, []int64{0, 0}, []int64{0, 0}},
+		{"nothing to split", 0, []int64{1, 2}, []int64{0, 0}},
+		{"large amounts", math.MaxInt64, []int64{math.MaxInt64 / 2, math.MaxInt64 / 2}, []int64{math.MaxInt64/2 + 1, math.MaxInt64 / 2}},
+	} {
+		got := allocate(tc.total, tc.weights)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: allocate(%d, %v) = %v, want %v", tc.name, tc.total, tc.weights, got, tc.want)
+		}
+	}
+}
+
+func TestMulDivRound(t *testing.T) {
+	for _, tc := range []struct {
+		a, b, c int64
+		want    int64
+	}{
+		{1000, 1, 4, 250},
+		{10, 1, 4, 3},   // 2.5 rounds up
+		{10, 1, 3, 3},   // 3.33 rounds down
+		{-10, 1, 4, -3}, // away from zero
+		{-10, 1, 3, -3},
+		{math.MaxInt64, 3, 3, math.MaxInt64}, // the product overflows int64
+		{5, 7, 0, 0},
+	} {
+		if got := mulDivRound(tc.a, tc.b, tc.c); got != tc.want {
+			t.Errorf("mulDivRound(%d, %d, %d) = %d, want %d", tc.a, tc.b, tc.c, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	repository "primeauction/api/Repository"
+	"primeauction/api/config"
+	"primeauction/api/models"
+	"time"
+)
+
+// Errors the order service reports to clients
+var (
+	ErrOrderNotFound     = newError(KindNotFound, "order_not_found", "order not found")
+	ErrInvalidOrder      = newError(KindInvalid, "invalid_order", "invalid order")
+	ErrItemNotFound      = repository.ErrItemNotFound
+	ErrItemUnavailable   = repository.ErrItemUnavailable
+	ErrInsufficientStock = repository.ErrInsufficientStock
+	ErrMixedCurrencies   = repository.ErrMixedCurrencies
+	ErrOrderNotPending   = repository.ErrOrderNotPending
+)
+
+// maxOrderLines bounds how many distinct items one checkout can lock
+const maxOrderLines = 50
+
+// OrderService places orders for fixed-price items. Placing an order
+// reserves stock straight away; the reservation is released if the buyer
+// cancels or doesn't pay within the reservation TTL.
+type OrderService struct {
+	orderRepo      *repository.OrderRepository
+	fees           *FeeService
+	taxes          *TaxService
+	reservationTTL time.Duration
+}
+
+func NewOrderService(orderRepo *repository.OrderRepository, fees *FeeService, taxes *TaxService) *OrderService {
+	return &OrderService{
+		orderRepo:      orderRepo,
+		fees:           fees,
+		taxes:          taxes,
+		reservationTTL: config.Get().Orders.ReservationTTL,
+	}
+}
+
+// CreateOrder validates the requested lines and reserves their stock.
+// Lines naming the same item are merged. Tax is charged for taxRegion, or
+// the buyer's saved region when it is empty, and seller fees are assessed
+// with the fee rules in effect now; both are kept on the order. Shipping
+// is quoted for shipTo, which defaults to the tax region.
+func (s *OrderService) CreateOrder(ctx context.Context, buyerID, taxRegion, shipTo string, requests []models.OrderLineRequest) (*models.Order, error) {
+	if buyerID == "" {
+		return nil, InvalidField("user_id", "user_id is required")
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("%w: at least one line is required", ErrInvalidOrder)
+	}
+
+	merged := make([]models.OrderLineRequest, 0, len(requests))
+	index := make(map[string]int, len(requests))
+	for _, req := range requests {
+		if req.ItemId == "" {
+			return nil, fmt.Errorf("%w: item_id is required", ErrInvalidOrder)
+		}
+		if req.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantity must be greater than zero", ErrInvalidOrder)
+		}
+		if i, ok := index[req.ItemId]; ok {
+			merged[i].Quantity += req.Quantity
+			continue
+		}
+		index[req.ItemId] = len(merged)
+		merged = append(merged, req)
+	}
+	if len(merged) > maxOrderLines {
+		return nil, fmt.Errorf("%w: at most %d items per order", ErrInvalidOrder, maxOrderLines)
+	}
+
+	region, err := s.taxes.ResolveRegion(ctx, buyerID, taxRegion)
+	if err != nil {
+		return nil, err
+	}
+	if shipTo == "" {
+		shipTo = region
+	} else if shipTo, err = models.NormalizeTaxRegion(shipTo); err != nil {
+		return nil, InvalidField("ship_to_region", err.Error())
+	}
+	taxes, err := s.taxes.TableFor(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	fees, err := s.fees.CurrentSchedule(ctx)
+	if err != nil {
+		return nil, err
+	}
+	order := &models.Order{
+		BuyerId:      buyerID,
+		TaxRegion:    region,
+		ShipToRegion: shipTo,
+		ExpiresAt:    time.Now().Add(s.reservationTTL),
+	}
+	if err := s.orderRepo.CreateReservedOrder(ctx, order, merged, fees, taxes); err != nil {
+		return nil, err
+	}
+	hideFees(order, buyerID, false)
+	return order, nil
+}
+
+// hideFees removes the fee breakdown from lines the viewer didn't sell;
+// what a seller pays the platform is between them and the platform
+func hideFees(order *models.Order, viewerID string, isAdmin bool) {
+	if isAdmin {
+		return
+	}
+	for i := range order.Lines {
+		if order.Lines[i].SellerId != viewerID {
+			order.Lines[i].Fee = nil
+		}
+	}
+}
+
+// GetOrder retrieves an order for a viewer. Buyers, sellers with a line on
+// the order and admins may see it; anyone else gets ErrOrderNotFound.
+func (s *OrderService) GetOrder(ctx context.Context, id, viewerID string, isAdmin bool) (*models.Order, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, ErrOrderNotFound)
+	}
+	hideFees(order, viewerID, isAdmin)
+	if isAdmin || order.BuyerId == viewerID {
+		return order, nil
+	}
+	for _, line := range order.Lines {
+		if line.SellerId == viewerID {
+			return order, nil
+		}
+	}
+	return nil, ErrOrderNotFound
+}
+
+// GetOrdersByBuyer lists the orders a user has placed
+func (s *OrderService) GetOrdersByBuyer(ctx context.Context, buyerID string) ([]*models.Order, error) {
+	if buyerID == "" {
+		return nil, InvalidField("user_id", "user_id is required")
+	}
+	orders, err := s.orderRepo.GetOrdersByBuyer(ctx, buyerID)
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		hideFees(order, buyerID, false)
+	}
+	return orders, nil
+}
+
+// GetSales lists the orders a seller has items on, with only the seller's
+// own lines and the fees charged on them
+func (s *OrderService) GetSales(ctx context.Context, sellerID string) ([]*models.Order, error) {
+	if sellerID == "" {
+		return nil, InvalidField("user_id", "user_id is required")
+	}
+	orders, err := s.orderRepo.GetOrdersBySeller(ctx, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		lines := order.Lines[:0]
+		for _, line := range order.Lines {
+			if line.SellerId == sellerID {
+				lines = append(lines, line)
+			}
+		}
+		order.Lines = lines
+
+		taxLines := order.TaxLines[:0]
+		for _, t := range order.TaxLines {
+			for _, line := range lines {
+				if t.OrderLineId == line.Id {
+					taxLines = append(taxLines, t)
+				}
+			}
+		}
+		order.TaxLines = taxLines
+
+		shipments := order.Shipments[:0]
+		for _, shipment := range order.Shipments {
+			if shipment.SellerId == sellerID {
+				shipments = append(shipments, shipment)
+			}
+		}
+		order.Shipments = shipments
+	}
+	return orders, nil
+}
+
+// CancelOrder cancels a buyer's pending order and releases its stock
+func (s *OrderService) CancelOrder(ctx context.Context, id, buyerID string) (*models.Order, error) {
+	if err := s.orderRepo.CancelOrder(ctx, id, buyerID); err != nil {
+		if errors.Is(err, ErrOrderNotPending) {
+			return nil, err
+		}
+		return nil, ErrOrderNotFound
+	}
+	order, err := s.orderRepo.GetOrderByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	hideFees(order, buyerID, false)
+	return order, nil
+}
+
+// ExpireReservations releases stock held by unpaid orders past their
+// reservation expiry and returns the expired order IDs
+func (s *OrderService) ExpireReservations(ctx context.Context) ([]string, error) {
+	return s.orderRepo.ExpireReservations(ctx, time.Now())
+}
+
+// StartReservationExpiry expires lapsed reservations every interval until
+// ctx is cancelled
+func (s *OrderService) StartReservationExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := s.ExpireReservations(ctx)
+			if err != nil {
+				log.Printf("order reservations: %v", err)
+				continue
+			}
+			if len(expired) > 0 {
+				log.Printf("order reservations: expired %d unpaid orders", len(expired))
+			}
+		}
+	}
+}
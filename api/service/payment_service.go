@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+	"primeauction/api/payment"
+	"time"
+)
+
+// Errors the payment service reports to clients
+var (
+	ErrPaymentNotFound   = newError(KindNotFound, "payment_not_found", "payment not found")
+	ErrInvalidWebhook    = newError(KindInvalid, "invalid_webhook", "invalid webhook")
+	ErrPaymentNotAllowed = newError(KindConflict, "payment_not_allowed", "payment cannot be changed in its current state")
+	ErrInvalidRefund     = newError(KindInvalid, "invalid_refund", "invalid refund")
+	ErrPaymentChanged    = newError(KindConflict, "payment_changed", "the payment changed while the request was handled; try again")
+)
+
+// eventAttempts is how many times a webhook is worked out again when its
+// payment or order changes while the provider is being called
+const eventAttempts = 3
+
+// PaymentService takes payment for orders through a payment.Provider.
+// Intents are authorised first and only captured once the webhook shows
+// the order is still waiting for payment, so a buyer whose reservation
+// lapsed is never charged.
+type PaymentService struct {
+	paymentRepo *repository.PaymentRepository
+	orderRepo   *repository.OrderRepository
+	provider    payment.Provider
+	ledger      *LedgerService
+	invoices    *InvoiceService
+}
+
+func NewPaymentService(paymentRepo *repository.PaymentRepository, orderRepo *repository.OrderRepository, provider payment.Provider, ledger *LedgerService, invoices *InvoiceService) *PaymentService {
+	return &PaymentService{paymentRepo: paymentRepo, orderRepo: orderRepo, provider: provider, ledger: ledger, invoices: invoices}
+}
+
+// StartPayment returns the payment attempt in progress for a buyer's order,
+// creating a provider intent for the order total if there is none
+func (s *PaymentService) StartPayment(ctx context.Context, orderID, buyerID string) (*models.Payment, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, lookupError(err, ErrOrderNotFound)
+	}
+	if order.BuyerId != buyerID {
+		return nil, ErrOrderNotFound
+	}
+	if order.Status != models.OrderPending || time.Now().After(order.ExpiresAt) {
+		return nil, ErrOrderNotPending
+	}
+
+	active, err := s.paymentRepo.GetActivePayment(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		return active, nil
+	}
+
+	p := &models.Payment{
+		OrderId:  order.Id,
+		Provider: s.provider.Name(),
+		Status:   models.PaymentPending,
+		Amount:   order.Total,
+		Refunded: models.NewMoney(0, order.Total.Currency),
+	}
+	if err := s.paymentRepo.CreatePayment(ctx, p); err != nil {
+		return nil, err
+	}
+	intent, err := s.provider.CreateIntent(ctx, payment.IntentRequest{
+		Amount:         order.Total,
+		Reference:      order.Id,
+		IdempotencyKey: p.Id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment intent: %w", err)
+	}
+	if err := s.paymentRepo.SetIntent(ctx, p.Id, intent.ID, intent.ClientSecret); err != nil {
+		return nil, err
+	}
+	p.ProviderRef = intent.ID
+	p.ClientSecret = intent.ClientSecret
+	return p, nil
+}
+
+// GetOrderPayments lists the payment attempts for an order the viewer
+// bought, or any order for admins
+func (s *PaymentService) GetOrderPayments(ctx context.Context, orderID, viewerID string, isAdmin bool) ([]models.Payment, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, lookupError(err, ErrOrderNotFound)
+	}
+	if !isAdmin && order.BuyerId != viewerID {
+		return nil, ErrOrderNotFound
+	}
+	return s.paymentRepo.GetPaymentsByOrder(ctx, orderID)
+}
+
+// GetPayment returns a payment and the webhook events applied to it
+func (s *PaymentService) GetPayment(ctx context.Context, id string) (*models.Payment, []models.PaymentEvent, error) {
+	p, err := s.paymentRepo.GetPaymentByID(ctx, id)
+	if err != nil {
+		return nil, nil, lookupError(err, ErrPaymentNotFound)
+	}
+	events, err := s.paymentRepo.GetEvents(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.ClientSecret = ""
+	return p, events, nil
+}
+
+// HandleWebhook verifies and applies a provider webhook. Redelivered
+// events are ignored, and an error leaves the event unrecorded so the
+// provider's retry is processed again. The provider is called before the
+// event is recorded, with the payment and order unlocked; if either has
+// changed by the time they are locked, the event is worked out again.
+func (s *PaymentService) HandleWebhook(ctx context.Context, payload []byte, header http.Header) error {
+	event, err := s.provider.VerifyWebhook(payload, header)
+	if errors.Is(err, payment.ErrInvalidSignature) {
+		return fmt.Errorf("%w: the signature can't be verified", ErrInvalidWebhook)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: the payload can't be read", ErrInvalidWebhook)
+	}
+	if event.ID == "" {
+		return fmt.Errorf("%w: event has no ID", ErrInvalidWebhook)
+	}
+	if event.Kind == "" || event.IntentID == "" {
+		return nil // not an event we act on
+	}
+
+	for attempt := 1; ; attempt++ {
+		p, changed, err := s.handleEvent(ctx, event)
+		if errors.Is(err, ErrPaymentChanged) && attempt < eventAttempts {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if changed {
+			s.ledger.RecordPaymentEffects(ctx, p)
+			if p.Status == models.PaymentCaptured {
+				s.invoices.IssueInvoices(ctx, p.OrderId)
+			}
+		}
+		return nil
+	}
+}
+
+// handleEvent works out what event does to its payment, calling the
+// provider as needed, then records the event and the result, provided the
+// payment and its order are still as they were read
+func (s *PaymentService) handleEvent(ctx context.Context, event *payment.Event) (*models.Payment, bool, error) {
+	current, err := s.paymentRepo.GetPaymentByRef(ctx, s.provider.Name(), event.IntentID)
+	if err != nil && !errors.Is(err, repository.ErrPaymentNotFound) {
+		return nil, false, err
+	}
+	var next *models.Payment
+	var orderStatus string
+	if current != nil {
+		order, err := s.orderRepo.GetOrderByID(ctx, current.OrderId)
+		if err != nil {
+			return nil, false, err
+		}
+		orderStatus = order.Status
+		copied := *current
+		next = &copied
+		if err := s.applyEvent(ctx, next, orderStatus, event); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return s.paymentRepo.ApplyEvent(ctx, s.provider.Name(), event.ID, event.Type, event.IntentID,
+		func(p *models.Payment, status string) error {
+			if next == nil || p.Status != current.Status || p.Refunded.Amount != current.Refunded.Amount || status != orderStatus {
+				return ErrPaymentChanged
+			}
+			p.Status, p.Refunded = next.Status, next.Refunded
+			return nil
+		})
+}
+
+// applyEvent moves a payment along for an event. Events that would move
+// it backwards, e.g. a late "authorized" after capture, change nothing.
+// A capture that takes the money turns event into the "succeeded" it now
+// is, so that working it out again for an order that has since stopped
+// waiting refunds the payment rather than trying to cancel it.
+func (s *PaymentService) applyEvent(ctx context.Context, p *models.Payment, orderStatus string, event *payment.Event) error {
+	switch event.Kind {
+	case payment.EventAuthorized:
+		if !models.CanTransitionPayment(p.Status, models.PaymentAuthorized) {
+			return nil
+		}
+		if orderStatus != models.OrderPending {
+			// The reservation lapsed or another attempt paid; release the hold
+			if _, err := s.provider.Cancel(ctx, p.ProviderRef, p.Id+"-cancel"); err != nil {
+				return fmt.Errorf("failed to cancel payment intent: %w", err)
+			}
+			p.Status = models.PaymentCancelled
+			return nil
+		}
+		intent, err := s.provider.Capture(ctx, p.ProviderRef, p.Id+"-capture")
+		if err != nil {
+			return fmt.Errorf("failed to capture payment: %w", err)
+		}
+		p.Status = models.PaymentAuthorized
+		if intent.Status == payment.IntentSucceeded {
+			p.Status = models.PaymentCaptured
+			event.Kind = payment.EventSucceeded
+		}
+
+	case payment.EventSucceeded:
+		if !models.CanTransitionPayment(p.Status, models.PaymentCaptured) {
+			return nil
+		}
+		p.Status = models.PaymentCaptured
+		if orderStatus != models.OrderPending {
+			// Captured without our say-so for an order that can't be
+			// fulfilled any more; give the money back
+			if _, err := s.provider.Refund(ctx, p.ProviderRef, p.Amount, p.Id+"-refund-unfulfillable"); err != nil {
+				return fmt.Errorf("failed to refund payment for unfulfillable order: %w", err)
+			}
+			p.Refunded = p.Amount
+			p.Status = models.PaymentRefunded
+		}
+
+	case payment.EventFailed:
+		if models.CanTransitionPayment(p.Status, models.PaymentFailed) {
+			p.Status = models.PaymentFailed
+		}
+
+	case payment.EventCanceled:
+		if models.CanTransitionPayment(p.Status, models.PaymentCancelled) {
+			p.Status = models.PaymentCancelled
+		}
+
+	case payment.EventRefunded:
+		// The event carries the total refunded so far, so applying it twice
+		// or out of order never double counts
+		if event.Amount.Amount <= p.Refunded.Amount {
+			return nil
+		}
+		status := refundStatus(p, event.Amount.Amount)
+		if !models.CanTransitionPayment(p.Status, status) {
+			return nil
+		}
+		p.Refunded.Amount = min(event.Amount.Amount, p.Amount.Amount)
+		p.Status = status
+	}
+	return nil
+}
+
+// RefundPayment refunds part or, when amount is empty, all of what is left
+// of a captured payment. amount is a decimal in the payment's currency.
+func (s *PaymentService) RefundPayment(ctx context.Context, id, amount string) (*models.Payment, error) {
+	p, err := s.paymentRepo.GetPaymentByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, ErrPaymentNotFound)
+	}
+	remaining := p.Amount.Amount - p.Refunded.Amount
+
+	refund := models.NewMoney(remaining, p.Amount.Currency)
+	if amount != "" {
+		if refund, err = models.ParseMoney(amount, p.Amount.Currency); err != nil {
+			return nil, fmt.Errorf("%w: amount must be a decimal amount of %s such as 12.50", ErrInvalidRefund, p.Amount.Currency)
+		}
+	}
+	if refund.Amount <= 0 || refund.Amount > remaining {
+		return nil, fmt.Errorf("%w: amount must be between 0 and %s", ErrInvalidRefund, models.NewMoney(remaining, p.Amount.Currency))
+	}
+
+	// Keyed on the total refunded before this call, so retrying the same
+	// request never refunds twice but a later refund gets a fresh key. The
+	// refund is recorded first, so a retry after a failure finds the key.
+	before := p.Refunded.Amount
+	record := &models.PaymentRefund{
+		PaymentId:      p.Id,
+		IdempotencyKey: fmt.Sprintf("%s-refund-%d", p.Id, before),
+		Amount:         refund,
+	}
+	if err := s.paymentRepo.CreateRefund(ctx, record); err != nil {
+		return nil, err
+	}
+	if record.Status != models.RefundPending || record.Amount.Amount != refund.Amount {
+		// Another refund from the same total got there first
+		return nil, ErrPaymentChanged
+	}
+	if err := s.refund(ctx, p, record); err != nil {
+		return nil, err
+	}
+	if err := s.paymentRepo.CompleteRefund(ctx, record, before, p.Refunded.Amount, p.Status); err != nil {
+		if errors.Is(err, repository.ErrPaymentChanged) {
+			return nil, ErrPaymentChanged
+		}
+		return nil, err
+	}
+	p.ClientSecret = ""
+	s.ledger.RecordPaymentEffects(ctx, p)
+	return p, nil
+}
+
+// refund asks the provider for a recorded refund of a captured payment,
+// under the refund's idempotency key, and moves p's refunded total and
+// status on; storing them is up to the caller
+func (s *PaymentService) refund(ctx context.Context, p *models.Payment, refund *models.PaymentRefund) error {
+	total := p.Refunded.Amount + refund.Amount.Amount
+	status := refundStatus(p, total)
+	if !models.CanTransitionPayment(p.Status, status) {
+		return ErrPaymentNotAllowed
+	}
+	made, err := s.provider.Refund(ctx, p.ProviderRef, refund.Amount, refund.IdempotencyKey)
+	if err != nil {
+		return fmt.Errorf("refund failed: %w", err)
+	}
+	refund.ProviderRef = made.ID
+	p.Refunded.Amount = total
+	p.Status = status
+	return nil
+}
+
+func refundStatus(p *models.Payment, refunded int64) string {
+	if refunded >= p.Amount.Amount {
+		return models.PaymentRefunded
+	}
+	return models.PaymentPartiallyRefunded
+}
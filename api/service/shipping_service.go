@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	repository "primeauction/api/Repository"
+	"primeauction/api/models"
+	"strings"
+	"time"
+)
+
+// Errors the shipping service reports to clients
+var (
+	ErrProfileNotFound    = newError(KindNotFound, "shipping_profile_not_found", "shipping profile not found")
+	ErrInvalidProfile     = newError(KindInvalid, "invalid_shipping_profile", "invalid shipping profile")
+	ErrProfileInUse       = repository.ErrProfileInUse
+	ErrShipmentNotFound   = repository.ErrShipmentNotFound
+	ErrInvalidShipment    = newError(KindInvalid, "invalid_shipment", "invalid shipment update")
+	ErrShipmentNotAllowed = newError(KindConflict, "shipment_not_allowed", "shipment cannot be changed in its current state")
+	ErrNotShippable       = models.ErrNotShippable
+)
+
+// maxProfilesPerSeller bounds how many shipping profiles one seller keeps
+const maxProfilesPerSeller = 50
+
+// ShippingService manages sellers' shipping profiles, quotes shipping
+// before checkout and lets sellers track the shipments of paid orders.
+// The cost charged on an order is worked out when it is placed and never
+// changes with the profile afterwards.
+type ShippingService struct {
+	shippingRepo *repository.ShippingRepository
+	itemRepo     repository.ItemStore
+	orderRepo    *repository.OrderRepository
+	taxes        *TaxService
+}
+
+func NewShippingService(shippingRepo *repository.ShippingRepository, itemRepo repository.ItemStore, orderRepo *repository.OrderRepository, taxes *TaxService) *ShippingService {
+	return &ShippingService{
+		shippingRepo: shippingRepo,
+		itemRepo:     itemRepo,
+		orderRepo:    orderRepo,
+		taxes:        taxes,
+	}
+}
+
+// GetProfile retrieves a shipping profile. Profiles are public so buyers
+// can see what an item will cost to ship.
+func (s *ShippingService) GetProfile(ctx context.Context, id string) (*models.ShippingProfile, error) {
+	profile, err := s.shippingRepo.GetProfile(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, ErrProfileNotFound)
+	}
+	return profile, nil
+}
+
+// GetProfiles lists a seller's shipping profiles
+func (s *ShippingService) GetProfiles(ctx context.Context, sellerID string) ([]models.ShippingProfile, error) {
+	return s.shippingRepo.GetProfilesBySeller(ctx, sellerID)
+}
+
+// CreateProfile validates and stores a new profile for a seller
+func (s *ShippingService) CreateProfile(ctx context.Context, sellerID string, profile *models.ShippingProfile) error {
+	if err := profile.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+	}
+	existing, err := s.shippingRepo.GetProfilesBySeller(ctx, sellerID)
+	if err != nil {
+		return err
+	}
+	if len(existing) >= maxProfilesPerSeller {
+		return fmt.Errorf("%w: at most %d shipping profiles per seller", ErrInvalidProfile, maxProfilesPerSeller)
+	}
+	profile.SellerId = sellerID
+	return s.shippingRepo.CreateProfile(ctx, profile)
+}
+
+// UpdateProfile replaces one of a seller's profiles. The currency is fixed
+// once set, since the items using the profile are priced in it.
+func (s *ShippingService) UpdateProfile(ctx context.Context, sellerID string, profile *models.ShippingProfile) error {
+	existing, err := s.shippingRepo.GetProfile(ctx, profile.Id)
+	if err != nil {
+		return lookupError(err, ErrProfileNotFound)
+	}
+	if existing.SellerId != sellerID {
+		return ErrProfileNotFound
+	}
+	if profile.Currency == "" {
+		profile.Currency = existing.Currency
+	}
+	if err := profile.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+	}
+	if profile.Currency != existing.Currency {
+		return fmt.Errorf("%w: the currency of a profile cannot be changed", ErrInvalidProfile)
+	}
+	profile.SellerId = sellerID
+	return s.shippingRepo.UpdateProfile(ctx, profile)
+}
+
+// DeleteProfile removes one of a seller's profiles once no item uses it
+func (s *ShippingService) DeleteProfile(ctx context.Context, sellerID, id string) error {
+	existing, err := s.shippingRepo.GetProfile(ctx, id)
+	if err != nil {
+		return lookupError(err, ErrProfileNotFound)
+	}
+	if existing.SellerId != sellerID {
+		return ErrProfileNotFound
+	}
+	return s.shippingRepo.DeleteProfile(ctx, id)
+}
+
+// QuoteOrder works out what shipping the requested lines would cost to
+// region, or the buyer's saved region when it is empty. It reserves
+// nothing; the cost is worked out again when the order is placed.
+func (s *ShippingService) QuoteOrder(ctx context.Context, buyerID, region string, requests []models.OrderLineRequest) ([]models.ShippingQuote, error) {
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("%w: at least one line is required", ErrInvalidOrder)
+	}
+	if len(requests) > maxOrderLines {
+		return nil, fmt.Errorf("%w: at most %d items per order", ErrInvalidOrder, maxOrderLines)
+	}
+	region, err := s.taxes.ResolveRegion(ctx, buyerID, region)
+	if err != nil {
+		return nil, err
+	}
+
+	parcels := make([]models.ShippingItem, 0, len(requests))
+	profiles := map[string]*models.ShippingProfile{}
+	for _, req := range requests {
+		if req.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantity must be greater than zero", ErrInvalidOrder)
+		}
+		item, err := s.itemRepo.GetItemById(ctx, req.ItemId)
+		if err != nil {
+			return nil, lookupError(err, ErrItemNotFound)
+		}
+		if item.Visibility != models.VisibilityPublic && item.Visibility != "" && item.UserId != buyerID {
+			return nil, ErrItemNotFound
+		}
+		value, err := item.SellingPrice.Mul(int64(req.Quantity))
+		if err != nil {
+			return nil, fmt.Errorf("%w: quantity is too large", ErrInvalidOrder)
+		}
+		if id := item.ShippingProfileId; id != nil && profiles[*id] == nil {
+			profile, err := s.shippingRepo.GetProfile(ctx, *id)
+			if err != nil {
+				return nil, err
+			}
+			profiles[*id] = profile
+		}
+		parcels = append(parcels, models.ShippingItem{
+			ItemId:    item.Id,
+			SellerId:  item.UserId,
+			ProfileId: item.ShippingProfileId,
+			Grams:     item.ChargeableGrams() * int64(req.Quantity),
+			Value:     value,
+		})
+	}
+	return models.QuoteShipments(parcels, profiles, region)
+}
+
+// UpdateShipment lets the seller, or an admin, add tracking details to a
+// shipment of a paid order and move it along. Each status change is kept
+// in the shipment's history.
+func (s *ShippingService) UpdateShipment(ctx context.Context, orderID, shipmentID, userID string, isAdmin bool, update models.ShipmentUpdate) (*models.Shipment, error) {
+	update.Carrier = strings.TrimSpace(update.Carrier)
+	update.TrackingNumber = strings.TrimSpace(update.TrackingNumber)
+	update.Note = strings.TrimSpace(update.Note)
+	if len(update.Carrier) > 100 || len(update.TrackingNumber) > 100 || len(update.Note) > 1000 {
+		return nil, fmt.Errorf("%w: carrier and tracking_number are at most 100 characters, note at most 1000",
+			ErrInvalidShipment)
+	}
+	if update.Status == "" && update.Carrier == "" && update.TrackingNumber == "" {
+		return nil, fmt.Errorf("%w: nothing to update", ErrInvalidShipment)
+	}
+
+	err := s.shippingRepo.UpdateShipment(ctx, orderID, shipmentID, func(shipment *models.Shipment, orderStatus string) (*models.ShipmentEvent, error) {
+		if !isAdmin && shipment.SellerId != userID {
+			return nil, ErrShipmentNotFound
+		}
+		if orderStatus != models.OrderPaid {
+			return nil, fmt.Errorf("%w: only paid orders are shipped", ErrShipmentNotAllowed)
+		}
+		if update.Carrier != "" {
+			shipment.Carrier = update.Carrier
+		}
+		if update.TrackingNumber != "" {
+			shipment.TrackingNumber = update.TrackingNumber
+		}
+		if update.Status == "" || update.Status == shipment.Status && update.Status != models.ShipmentInTransit {
+			return nil, nil
+		}
+		if !models.CanTransitionShipment(shipment.Status, update.Status) {
+			return nil, fmt.Errorf("%w: a %s shipment cannot become %s", ErrShipmentNotAllowed, shipment.Status, update.Status)
+		}
+
+		now := time.Now()
+		switch update.Status {
+		case models.ShipmentShipped:
+			shipment.ShippedAt = &now
+		case models.ShipmentDelivered:
+			if shipment.ShippedAt == nil {
+				shipment.ShippedAt = &now
+			}
+			shipment.DeliveredAt = &now
+		}
+		shipment.Status = update.Status
+		return &models.ShipmentEvent{Status: update.Status, Note: update.Note}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range order.Shipments {
+		if order.Shipments[i].Id == shipmentID {
+			return &order.Shipments[i], nil
+		}
+	}
+	return nil, ErrShipmentNotFound
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	repository "primeauction/api/Repository"
+	"primeauction/api/config"
+	"primeauction/api/models"
+)
+
+// ErrQuotaExceeded is returned when an upload would take a user over
+// their storage quota
+var ErrQuotaExceeded = newError(KindTooLarge, "quota_exceeded", "storage quota exceeded")
+
+// StorageService tracks per-user upload storage against role-based quotas
+type StorageService struct {
+	storageRepo *repository.StorageRepository
+	userQuota   int64
+	adminQuota  int64
+}
+
+func NewStorageService(storageRepo *repository.StorageRepository) *StorageService {
+	return &StorageService{
+		storageRepo: storageRepo,
+		userQuota:   config.Get().Uploads.QuotaBytes,
+		adminQuota:  config.Get().Uploads.AdminQuotaBytes,
+	}
+}
+
+// QuotaFor returns the quota that applies to a role
+func (s *StorageService) QuotaFor(isAdmin bool) int64 {
+	if isAdmin {
+		return s.adminQuota
+	}
+	return s.userQuota
+}
+
+// GetUsage reports a user's current usage and remaining quota
+func (s *StorageService) GetUsage(ctx context.Context, userID string, isAdmin bool) (*models.StorageUsage, error) {
+	if userID == "" {
+		return nil, InvalidField("user_id", "user_id is required")
+	}
+	usage, err := s.storageRepo.GetStorageUsage(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	usage.QuotaBytes = s.QuotaFor(isAdmin)
+	usage.RemainingBytes = max(0, usage.QuotaBytes-usage.UsedBytes)
+	return usage, nil
+}
+
+// CheckQuota returns ErrQuotaExceeded when adding delta bytes would take the
+// user over quota. delta may be negative when images are being replaced.
+func (s *StorageService) CheckQuota(ctx context.Context, userID string, isAdmin bool, delta int64) error {
+	if delta <= 0 {
+		return nil
+	}
+	usage, err := s.GetUsage(ctx, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+	if usage.UsedBytes+delta > usage.QuotaBytes {
+		return fmt.Errorf("%w: %d bytes requested, %d of %d bytes remaining",
+			ErrQuotaExceeded, delta, usage.RemainingBytes, usage.QuotaBytes)
+	}
+	return nil
+}
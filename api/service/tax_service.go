@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	repository "primeauction/api/Repository"
+	"primeauction/api/config"
+	"primeauction/api/models"
+	"strings"
+)
+
+// TaxService looks up the tax rates for a buyer's region. Tax never changes
+// an item's stored price: it is worked out at checkout, on top of the price
+// or backed out of it depending on the seller's setting, and the result is
+// stored with the order.
+type TaxService struct {
+	taxRepo  *repository.TaxRepository
+	rounding string
+}
+
+func NewTaxService(taxRepo *repository.TaxRepository) *TaxService {
+	return &TaxService{taxRepo: taxRepo, rounding: config.Get().Tax.Rounding}
+}
+
+// ResolveRegion returns the region to tax an order for: the one given at
+// checkout, else the buyer's saved region. An empty result means the order
+// is not taxed.
+func (s *TaxService) ResolveRegion(ctx context.Context, buyerID, requested string) (string, error) {
+	if requested != "" {
+		region, err := models.NormalizeTaxRegion(requested)
+		if err != nil {
+			return "", InvalidField("tax_region", err.Error())
+		}
+		return region, nil
+	}
+	settings, err := s.taxRepo.GetTaxSettings(ctx, buyerID)
+	if err != nil {
+		return "", err
+	}
+	return settings.TaxRegion, nil
+}
+
+// TableFor loads the rates that can apply in region
+func (s *TaxService) TableFor(ctx context.Context, region string) (*models.TaxTable, error) {
+	table := &models.TaxTable{Region: region, Rounding: s.rounding}
+	if region == "" {
+		return table, nil
+	}
+	country, _, _ := strings.Cut(region, "-")
+	rates, err := s.taxRepo.GetRatesForRegion(ctx, region, country)
+	if err != nil {
+		return nil, err
+	}
+	table.Rates = rates
+	return table, nil
+}
+
+// GetRates lists every configured tax rate
+func (s *TaxService) GetRates(ctx context.Context) ([]models.TaxRate, error) {
+	return s.taxRepo.GetRates(ctx)
+}
+
+// SaveRate validates and stores a rate. Orders already placed keep the
+// rate they were taxed at.
+func (s *TaxService) SaveRate(ctx context.Context, rate *models.TaxRate) error {
+	region, err := models.NormalizeTaxRegion(rate.Region)
+	if err != nil {
+		return InvalidField("region", err.Error())
+	}
+	rate.Region = region
+	rate.Category = normalizeCategory(rate.Category)
+	rate.Name = strings.TrimSpace(rate.Name)
+	if rate.Name == "" || len(rate.Name) > 50 {
+		return InvalidField("name", "name is required and at most 50 characters")
+	}
+	if rate.RateBPS < 0 || rate.RateBPS > 10000 {
+		return InvalidField("rate_bps", "rate_bps must be between 0 and 10000")
+	}
+	return s.taxRepo.SaveRate(ctx, rate)
+}
+
+// DeleteRate removes the rate for a region and category
+func (s *TaxService) DeleteRate(ctx context.Context, region, category string) error {
+	region, err := models.NormalizeTaxRegion(region)
+	if err != nil {
+		return InvalidField("region", err.Error())
+	}
+	return s.taxRepo.DeleteRate(ctx, region, normalizeCategory(category))
+}
+
+// GetSettings returns a user's tax region and price setting
+func (s *TaxService) GetSettings(ctx context.Context, userID string) (*models.TaxSettings, error) {
+	return s.taxRepo.GetTaxSettings(ctx, userID)
+}
+
+// UpdateSettings stores a user's tax region (empty to clear it) and whether
+// their prices include tax. The price setting applies to orders placed
+// from now on.
+func (s *TaxService) UpdateSettings(ctx context.Context, userID string, settings *models.TaxSettings) error {
+	if settings.TaxRegion != "" {
+		region, err := models.NormalizeTaxRegion(settings.TaxRegion)
+		if err != nil {
+			return InvalidField("tax_region", err.Error())
+		}
+		settings.TaxRegion = region
+	}
+	return s.taxRepo.SetTaxSettings(ctx, userID, settings)
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	repository "primeauction/api/Repository"
+	"primeauction/api/utils"
+	"time"
+)
+
+// UploadGC reconciles image files on disk against the item_images and
+// items.image columns, removing files nothing points at
+type UploadGC struct {
+	imageRepo     *repository.ItemImageRepository
+	uploadService *UploadService
+	uploadDir     string
+	gracePeriod   time.Duration
+	dryRun        bool
+}
+
+// GCReport summarises a single garbage collection pass
+type GCReport struct {
+	DryRun         bool                        `json:"dry_run"`
+	ScannedFiles   int                         `json:"scanned_files"`
+	OrphanFiles    []string                    `json:"orphan_files"`
+	DeletedFiles   []string                    `json:"deleted_files"`
+	SkippedYoung   int                         `json:"skipped_young"`
+	DanglingRefs   []repository.ImageReference `json:"dangling_refs"`
+	ExpiredUploads []string                    `json:"expired_uploads"`
+	SizesFilled    int                         `json:"sizes_filled"` // image rows given their file's size
+	Errors         []string                    `json:"errors"`
+}
+
+func NewUploadGC(imageRepo *repository.ItemImageRepository, uploadService *UploadService, gracePeriod time.Duration, dryRun bool) *UploadGC {
+	return &UploadGC{
+		imageRepo:     imageRepo,
+		uploadService: uploadService,
+		uploadDir:     utils.UploadDir,
+		gracePeriod:   gracePeriod,
+		dryRun:        dryRun,
+	}
+}
+
+// Run performs one reconciliation pass. Files younger than the grace period
+// are left alone so uploads that are still being attached to an item are
+// never collected.
+func (g *UploadGC) Run(ctx context.Context) (*GCReport, error) {
+	report := &GCReport{DryRun: g.dryRun}
+
+	refs, err := g.imageRepo.GetAllImageReferences(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load image references: %w", err)
+	}
+
+	referenced := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		referenced[filepath.Clean(ref.ImagePath)] = true
+	}
+
+	entries, err := os.ReadDir(g.uploadDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read upload directory: %w", err)
+	}
+
+	onDisk := make(map[string]bool, len(entries))
+	cutoff := time.Now().Add(-g.gracePeriod)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(g.uploadDir, entry.Name())
+		onDisk[path] = true
+		report.ScannedFiles++
+
+		if referenced[path] {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		if info.ModTime().After(cutoff) {
+			report.SkippedYoung++
+			continue
+		}
+
+		report.OrphanFiles = append(report.OrphanFiles, path)
+		if g.dryRun {
+			continue
+		}
+		if err := utils.DeleteImage(path); err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		report.DeletedFiles = append(report.DeletedFiles, path)
+	}
+
+	// Rows whose file is gone cannot be fixed automatically, only reported
+	for _, ref := range refs {
+		if !onDisk[filepath.Clean(ref.ImagePath)] {
+			report.DanglingRefs = append(report.DanglingRefs, ref)
+		}
+	}
+
+	filled, err := g.BackfillImageSizes(ctx)
+	report.SizesFilled = filled
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+
+	// Resumable uploads that were abandoned or never attached to an item
+	expired, err := g.uploadService.ExpireUploads(ctx, g.dryRun)
+	report.ExpiredUploads = expired
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+
+	return report, nil
+}
+
+// BackfillImageSizes fills in the size of image rows created before sizes
+// were tracked, which count as zero bytes against storage quotas until
+// then, from their files on disk. It returns how many rows it filled in, or
+// in dry-run mode would have. Rows whose file is gone are left alone; the
+// collector reports them as dangling references.
+func (g *UploadGC) BackfillImageSizes(ctx context.Context) (int, error) {
+	images, err := g.imageRepo.GetImagesMissingSize(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load images missing a size: %w", err)
+	}
+	filled := 0
+	var errs []error
+	for _, img := range images {
+		info, err := os.Stat(img.ImagePath)
+		if err != nil || info.Size() == 0 {
+			continue
+		}
+		if !g.dryRun {
+			if err := g.imageRepo.UpdateImageSize(ctx, img.Id, info.Size()); err != nil {
+				errs = append(errs, fmt.Errorf("failed to record size of %s: %w", img.ImagePath, err))
+				continue
+			}
+		}
+		filled++
+	}
+	return filled, errors.Join(errs...)
+}
+
+// Start runs the collector every interval until ctx is cancelled
+func (g *UploadGC) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := g.Run(ctx)
+			if err != nil {
+				log.Printf("upload gc: %v", err)
+				continue
+			}
+			LogGCReport(report)
+		}
+	}
+}
+
+// LogGCReport writes a human readable summary of a pass to the log
+func LogGCReport(report *GCReport) {
+	action := "deleted"
+	count := len(report.DeletedFiles)
+	if report.DryRun {
+		action = "would delete"
+		count = len(report.OrphanFiles)
+	}
+	log.Printf("upload gc: scanned %d files, %s %d orphans, skipped %d within grace period, %d dangling references, %d expired uploads, %d image sizes filled in, %d errors",
+		report.ScannedFiles, action, count, report.SkippedYoung, len(report.DanglingRefs), len(report.ExpiredUploads), report.SizesFilled, len(report.Errors))
+
+	for _, path := range report.OrphanFiles {
+		log.Printf("upload gc: orphan file %s", path)
+	}
+	for _, ref := range report.DanglingRefs {
+		log.Printf("upload gc: dangling reference %s/%s (item %s) -> %s", ref.Source, ref.ID, ref.ItemID, ref.ImagePath)
+	}
+	for _, e := range report.Errors {
+		log.Printf("upload gc: error %s", e)
+	}
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	repository "primeauction/api/Repository"
+	"primeauction/api/config"
+	"primeauction/api/models"
+	"primeauction/api/utils"
+	"sync"
+	"time"
+)
+
+// Errors the upload service reports to clients
+var (
+	ErrUploadOffsetMismatch = newError(KindConflict, "upload_offset_mismatch", "upload offset does not match")
+	ErrUploadNotFound       = newError(KindNotFound, "upload_not_found", "upload not found")
+	ErrUploadComplete       = newError(KindConflict, "upload_complete", "upload is already complete")
+	ErrInvalidUpload        = newError(KindInvalid, "invalid_upload", "uploaded file is not a valid image")
+)
+
+// UploadService implements resumable uploads: a client declares the size
+// up front, sends the bytes in as many chunks as it needs, and can ask for
+// the current offset after a dropped connection to continue from there.
+type UploadService struct {
+	uploadRepo *repository.UploadRepository
+	expiry     time.Duration
+	locks      sync.Map // upload ID -> *sync.Mutex, serialises chunks per upload
+}
+
+func NewUploadService(uploadRepo *repository.UploadRepository) *UploadService {
+	return &UploadService{
+		uploadRepo: uploadRepo,
+		expiry:     config.Get().Uploads.Expiry,
+	}
+}
+
+// CreateUpload starts a new upload of size bytes
+func (s *UploadService) CreateUpload(ctx context.Context, userID, filename string, size int64) (*models.Upload, error) {
+	if userID == "" {
+		return nil, InvalidField("user_id", "user_id is required")
+	}
+	if size <= 0 {
+		return nil, InvalidField("Upload-Length", "upload length must be greater than zero")
+	}
+	if size > utils.MaxFileSize {
+		return nil, InvalidField("Upload-Length", "file size exceeds maximum allowed size of 5MB")
+	}
+
+	upload := &models.Upload{
+		UserId:    userID,
+		Filename:  filename,
+		Size:      size,
+		Status:    models.UploadInProgress,
+		ExpiresAt: time.Now().Add(s.expiry),
+	}
+	if err := s.uploadRepo.CreateUpload(ctx, upload); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(utils.StagingDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	file, err := os.Create(utils.StagingPath(upload.Id))
+	if err != nil {
+		s.uploadRepo.DeleteUpload(ctx, upload.Id)
+		return nil, fmt.Errorf("failed to create staging file: %w", err)
+	}
+	file.Close()
+
+	return upload, nil
+}
+
+// GetUpload returns an upload owned by userID
+func (s *UploadService) GetUpload(ctx context.Context, id, userID string) (*models.Upload, error) {
+	upload, err := s.uploadRepo.GetUploadByID(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, ErrUploadNotFound)
+	}
+	if upload.UserId != userID {
+		// Don't reveal other users' upload IDs
+		return nil, ErrUploadNotFound
+	}
+	return upload, nil
+}
+
+// AppendChunk writes the bytes from r at offset. The offset must equal the
+// current server-side offset; whatever arrives before the body is cut off
+// is kept so the client can resume from the returned upload's offset.
+func (s *UploadService) AppendChunk(ctx context.Context, id, userID string, offset int64, r io.Reader) (*models.Upload, error) {
+	lock, _ := s.locks.LoadOrStore(id, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	upload, err := s.GetUpload(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Status != models.UploadInProgress {
+		return upload, ErrUploadComplete
+	}
+	if offset != upload.Offset {
+		return upload, ErrUploadOffsetMismatch
+	}
+
+	stagedPath := utils.StagingPath(id)
+	file, err := os.OpenFile(stagedPath, os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open staging file: %w", err)
+	}
+	defer file.Close()
+
+	// Drop anything past the recorded offset left by an interrupted write
+	if err := file.Truncate(upload.Offset); err != nil {
+		return nil, fmt.Errorf("failed to truncate staging file: %w", err)
+	}
+	if _, err := file.Seek(upload.Offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek staging file: %w", err)
+	}
+
+	written, copyErr := io.Copy(file, io.LimitReader(r, upload.Size-upload.Offset))
+	if err := file.Sync(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+
+	newOffset := upload.Offset + written
+	status := models.UploadInProgress
+	if newOffset == upload.Size {
+		if err := utils.ValidateStagedImage(stagedPath); err != nil {
+			var pathErr *fs.PathError
+			if errors.As(err, &pathErr) {
+				return nil, err // the staged file couldn't be read, not a bad image
+			}
+			// A complete file that isn't a valid image can never be attached
+			s.discard(ctx, id)
+			return nil, fmt.Errorf("%w: %v", ErrInvalidUpload, err)
+		}
+		status = models.UploadCompleted
+	}
+
+	if err := s.uploadRepo.UpdateProgress(ctx, id, upload.Offset, newOffset, status); err != nil {
+		return nil, err
+	}
+	upload.Offset = newOffset
+	upload.Status = status
+
+	if copyErr != nil {
+		return upload, fmt.Errorf("upload interrupted: %w", copyErr)
+	}
+	return upload, nil
+}
+
+// CancelUpload abandons an upload and removes its staged bytes
+func (s *UploadService) CancelUpload(ctx context.Context, id, userID string) error {
+	if _, err := s.GetUpload(ctx, id, userID); err != nil {
+		return err
+	}
+	return s.discard(ctx, id)
+}
+
+// ClaimUploads moves completed uploads into the image directory so they
+// can be attached to an item and returns the new image paths in the order
+// given. Nothing is moved unless every upload is complete and owned by
+// userID. Claim uploads in the unit of work that saves the item: their
+// records are deleted in it, and if it rolls back the files go back to
+// staging, where the uploads can be claimed again.
+func (s *UploadService) ClaimUploads(ctx context.Context, userID string, ids []string) ([]string, error) {
+	uploads := make([]*models.Upload, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return nil, InvalidField("upload_ids", fmt.Sprintf("upload %s listed more than once", id))
+		}
+		seen[id] = true
+
+		upload, err := s.GetUpload(ctx, id, userID)
+		if err != nil {
+			return nil, fmt.Errorf("upload %s: %w", id, err)
+		}
+		if upload.Status != models.UploadCompleted {
+			return nil, InvalidField("upload_ids", fmt.Sprintf("upload %s is not complete (%d of %d bytes)", id, upload.Offset, upload.Size))
+		}
+		uploads = append(uploads, upload)
+	}
+
+	var paths []string
+	for _, upload := range uploads {
+		stagedPath := utils.StagingPath(upload.Id)
+		path, err := utils.PromoteStagedImage(stagedPath, upload.Filename, userID)
+		if err != nil {
+			return nil, err
+		}
+		repository.OnRollback(ctx, func() {
+			if err := os.Rename(path, stagedPath); err != nil {
+				log.Printf("returning upload %s to staging: %v", upload.Id, err)
+			}
+		})
+		paths = append(paths, path)
+
+		if err := s.uploadRepo.DeleteUpload(ctx, upload.Id); err != nil {
+			return nil, err
+		}
+		repository.OnCommit(ctx, func() { s.locks.Delete(upload.Id) })
+	}
+	return paths, nil
+}
+
+// ExpireUploads removes uploads past their expiry together with their
+// staged bytes and returns the IDs that were (or in dry-run mode, would
+// be) removed
+func (s *UploadService) ExpireUploads(ctx context.Context, dryRun bool) ([]string, error) {
+	uploads, err := s.uploadRepo.GetExpiredUploads(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	var expired []string
+	for _, upload := range uploads {
+		expired = append(expired, upload.Id)
+		if dryRun {
+			continue
+		}
+		if err := s.discard(ctx, upload.Id); err != nil {
+			return expired, err
+		}
+	}
+	return expired, nil
+}
+
+func (s *UploadService) discard(ctx context.Context, id string) error {
+	if err := os.Remove(utils.StagingPath(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete staging file: %w", err)
+	}
+	s.locks.Delete(id)
+	return s.uploadRepo.DeleteUpload(ctx, id)
+}
@@ -1,92 +1,139 @@
 package service
 
 import (
+	"context"
 	"errors"
 	repository "primeauction/api/Repository"
 	"primeauction/api/models"
+	"primeauction/api/validate"
 
 	"golang.org/x/crypto/bcrypt"
 	)
 
+// ErrInvalidCredentials is returned when a login doesn't match a user. It
+// doesn't say which part was wrong.
+var ErrInvalidCredentials = newError(KindUnauthenticated, "invalid_credentials", "invalid email or password")
+
 type UserService struct {
-	userRepo *repository.UserRepository
+	userRepo repository.UserStore
 }
 
-func NewUserService(userRepo *repository.UserRepository) *UserService {
+func NewUserService(userRepo repository.UserStore) *UserService {
 	return &UserService{userRepo: userRepo}
 }
-func (s *UserService) GetAllUsers() ([]models.User, error) {
-	users, err := s.userRepo.GetAllUsers()
+func (s *UserService) GetAllUsers(ctx context.Context) ([]models.User, error) {
+	users, err := s.userRepo.GetAllUsers(ctx)
 	if err != nil {
 		return nil, err
 	}
 	return users, nil
 
 }
-func (s *UserService) CreateUser(user *models.User) error {
-	if user.Username == "" {
-		return errors.New("name is required")
-	}
-	if user.Email == "" {
-		return errors.New("email is required")
-	}
-	if user.Password == "" {
-		return errors.New("password is required")
+// CreateUser registers a user. Admins can't be created this way; see
+// CreateAdmin.
+func (s *UserService) CreateUser(ctx context.Context, user *models.User) error {
+	user.IsAdmin = false
+	return s.createUser(ctx, user)
+}
+
+// CreateAdmin creates a user with admin rights. Only the command line
+// offers this, so the first admin can be made without touching the database.
+func (s *UserService) CreateAdmin(ctx context.Context, user *models.User) error {
+	user.IsAdmin = true
+	return s.createUser(ctx, user)
+}
+
+func (s *UserService) createUser(ctx context.Context, user *models.User) error {
+	if err := Invalid(validate.Struct(user)); err != nil {
+		return err
 	}
 	hashpassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 	user.Password = string(hashpassword)
-	if err := s.userRepo.CreateUser(user); err != nil {
+	if err := s.userRepo.CreateUser(ctx, user); err != nil {
 		return err
 	}
 	return nil
 
 	
 }
-func (s *UserService) LoginUser(email, password string) (*models.User, error) {
-	user, err := s.userRepo.GetUserByEmail(email)
+func (s *UserService) LoginUser(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
 	if err != nil {
-		return nil, err
+		return nil, lookupError(err, ErrInvalidCredentials)
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, errors.New("invalid password")
+		return nil, ErrInvalidCredentials
 	}
 	user.Password = ""
 	return user, nil
 }
-func (s *UserService) GetUserById(id string)(*models.User,error){
-	user,err:=s.userRepo.GetUserByID(id)
+func (s *UserService) GetUserById(ctx context.Context, id string)(*models.User,error){
+	user,err:=s.userRepo.GetUserByID(ctx, id)
 	if err!=nil{
 		return nil,err
 	}
 	return user,nil
 }
-func (s *UserService) UpdateUser(id string,user *models.User) error {
-	if user.Username == "" {
-		return errors.New("name is required")
-	}
-	if user.Email == "" {
-		return errors.New("email is required")
-	}
-	if user.Password == "" {
-		return errors.New("password is required")
-
+func (s *UserService) UpdateUser(ctx context.Context, id string,user *models.User) error {
+	if err := Invalid(validate.Struct(user)); err != nil {
+		return err
 	}
 	hashpassword,err :=bcrypt.GenerateFromPassword([]byte(user.Password),bcrypt.DefaultCost)
 	if err!=nil{
 		return  errors.New("failed to hash password")
 	}
 	user.Password=string(hashpassword)
-	if err:=s.userRepo.UpdateUser(id,user);err!=nil{
+	if err:=s.userRepo.UpdateUser(ctx, id,user);err!=nil{
 		return err
 	}
 	return nil
 
 }
-func (s *UserService)DeleteUser(id string) error{
-	if err:=s.userRepo.DeleteUser(id);err!=nil{
+// GetUserByEmail looks a user up by email, without their password
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = ""
+	return user, nil
+}
+
+// SetAdmin grants or revokes the admin rights of the user with an email
+func (s *UserService) SetAdmin(ctx context.Context, email string, isAdmin bool) (*models.User, error) {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.SetAdmin(ctx, user.Id, isAdmin); err != nil {
+		return nil, err
+	}
+	user.IsAdmin = isAdmin
+	user.Password = ""
+	return user, nil
+}
+
+// ResetPassword sets a new password for the user with an email
+func (s *UserService) ResetPassword(ctx context.Context, email, password string) error {
+	if err := Invalid(validate.Value("password", password, "required,password")); err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+	return s.userRepo.UpdatePassword(ctx, user.Id, string(hash))
+}
+
+func (s *UserService)DeleteUser(ctx context.Context, id string) error{
+	if err:=s.userRepo.DeleteUser(ctx, id);err!=nil{
 		return err
 	}
 	return  nil
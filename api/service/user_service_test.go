@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	repository "primeauction/api/Repository"
+	"primeauction/api/Repository/memory"
+	"primeauction/api/models"
+)
+
+func TestUserServiceCreateAndLogin(t *testing.T) {
+	ctx := context.Background()
+	s := NewUserService(memory.NewUserStore())
+
+	user := &models.User{Username: "ada", Email: "ada@example.com", Password: "s3cret-pass", IsAdmin: true}
+	if err := s.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if user.IsAdmin {
+		t.Error("CreateUser made an admin")
+	}
+	if user.Password == "s3cret-pass" {
+		t.Error("CreateUser stored the password unhashed")
+	}
+
+	got, err := s.LoginUser(ctx, "ada@example.com", "s3cret-pass")
+	if err != nil {
+		t.Fatalf("LoginUser: %v", err)
+	}
+	if got.Id != user.Id || got.Password != "" {
+		t.Errorf("LoginUser = %+v, want user %s without a password", got, user.Id)
+	}
+	if _, err := s.LoginUser(ctx, "ada@example.com", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("LoginUser with a wrong password: got %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, err := s.LoginUser(ctx, "bob@example.com", "s3cret-pass"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("LoginUser for an unknown email: got %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	taken := &models.User{Username: "ada2", Email: "ada@example.com", Password: "an0ther-pass"}
+	if err := s.CreateUser(ctx, taken); !errors.Is(err, repository.ErrUserExists) {
+		t.Errorf("CreateUser with a taken email: got %v, want %v", err, repository.ErrUserExists)
+	}
+}
+
+func TestUserServiceAdminAndPassword(t *testing.T) {
+	ctx := context.Background()
+	s := NewUserService(memory.NewUserStore())
+
+	user := &models.User{Username: "grace", Email: "grace@example.com", Password: "first-pass1"}
+	if err := s.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	promoted, err := s.SetAdmin(ctx, "grace@example.com", true)
+	if err != nil {
+		t.Fatalf("SetAdmin: %v", err)
+	}
+	if !promoted.IsAdmin || promoted.Password != "" {
+		t.Errorf("SetAdmin = %+v, want an admin without a password", promoted)
+	}
+
+	if err := s.ResetPassword(ctx, "grace@example.com", "second-pass2"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if _, err := s.LoginUser(ctx, "grace@example.com", "first-pass1"); err == nil {
+		t.Error("the old password still works after ResetPassword")
+	}
+	if _, err := s.LoginUser(ctx, "grace@example.com", "second-pass2"); err != nil {
+		t.Errorf("LoginUser with the new password: %v", err)
+	}
+}
+
+func TestUserServiceValidation(t *testing.T) {
+	ctx := context.Background()
+	s := NewUserService(memory.NewUserStore())
+
+	err := s.CreateUser(ctx, &models.User{Username: "al", Email: "not-an-email", Password: "short"})
+	var e *Error
+	if !errors.As(err, &e) || e.Kind != KindInvalid {
+		t.Fatalf("CreateUser with bad fields: got %v, want a validation error", err)
+	}
+	var fields []string
+	for _, f := range e.Fields {
+		fields = append(fields, f.Field)
+	}
+	if len(fields) != 3 || fields[0] != "username" || fields[1] != "email" || fields[2] != "password" {
+		t.Errorf("CreateUser reported %v, want username, email and password at once", fields)
+	}
+
+	if err := s.ResetPassword(ctx, "ada@example.com", "aaaaaaaa"); !errors.As(err, &e) || e.Fields[0].Field != "password" {
+		t.Errorf("ResetPassword with a weak password: got %v, want a password error", err)
+	}
+}
@@ -12,6 +12,8 @@ import (
 
 const (
 	UploadDir   = "uploads/images"
+	StagingDir  = "uploads/staging" // resumable uploads in progress
+	InvoiceDir  = "uploads/invoices" // generated PDFs, only served through the API
 	MaxFileSize = 5 * 1024 * 1024 // 5MB
 	MaxImages   = 10               // Maximum images per item
 )
@@ -43,15 +45,18 @@ func ValidateImageFile(fileHeader *multipart.FileHeader) error {
 	}
 	defer file.Close()
 
+	return validateImageContent(file)
+}
+
+// validateImageContent sniffs the first 512 bytes of r and checks the
+// detected MIME type against the allowed image types
+func validateImageContent(r io.Reader) error {
 	buffer := make([]byte, 512)
-	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
+	n, err := io.ReadFull(r, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
-
-	if n < 512 {
-		buffer = buffer[:n]
-	}
+	buffer = buffer[:n]
 
 	mimeType := http.DetectContentType(buffer)
 	if !allowedMimeTypes[mimeType] {
@@ -61,6 +66,54 @@ func ValidateImageFile(fileHeader *multipart.FileHeader) error {
 	return nil
 }
 
+// StagingPath returns where the bytes of a resumable upload are kept
+func StagingPath(uploadID string) string {
+	return filepath.Join(StagingDir, uploadID)
+}
+
+// ValidateStagedImage applies the same size and type checks as
+// ValidateImageFile to a completed resumable upload
+func ValidateStagedImage(stagedPath string) error {
+	info, err := os.Stat(stagedPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	if info.Size() > MaxFileSize {
+		return fmt.Errorf("file size exceeds maximum allowed size of 5MB")
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("file is empty")
+	}
+
+	file, err := os.Open(stagedPath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	return validateImageContent(file)
+}
+
+// PromoteStagedImage moves a completed upload into the image directory
+// under the same naming scheme as SaveUploadedImage and returns its path
+func PromoteStagedImage(stagedPath, originalName, userID string) (string, error) {
+	if err := os.MkdirAll(UploadDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	ext := filepath.Ext(originalName)
+	if ext == "" {
+		ext = ".jpg"
+	}
+	filename := fmt.Sprintf("%s_%d%s", userID, time.Now().UnixNano(), ext)
+	filePath := filepath.Join(UploadDir, filename)
+
+	if err := os.Rename(stagedPath, filePath); err != nil {
+		return "", fmt.Errorf("failed to move file: %w", err)
+	}
+	return filePath, nil
+}
+
 // SaveUploadedImage saves an uploaded image file and returns the file path
 func SaveUploadedImage(fileHeader *multipart.FileHeader, userID string) (string, error) {
 	// Validate first
@@ -7,13 +7,9 @@ import(
 )
 
 
-var jwtSecret = []byte(getJWTSecret())
-func getJWTSecret()string{
-	secret:= config.GetJWTSecret()
-	if secret == ""{
-		return "your-secret-key"
-	}
-	return secret
+// jwtSecret is read when used, after the configuration has been loaded
+func jwtSecret() []byte {
+	return []byte(config.Get().Auth.JWTSecret)
 }
 type Claims struct{
 	UserID string `json:"user_id"`
@@ -33,12 +29,12 @@ func GenerateToken(userID,email string,isAdmin bool)(string,error){
 		},
 	}
 	token:=jwt.NewWithClaims(jwt.SigningMethodHS256,claims)
-	return token.SignedString(jwtSecret)
+	return token.SignedString(jwtSecret())
 }
 func ValidateToken(tokenstring string )(*Claims ,error){
 	claims := &Claims{}
     token, err := jwt.ParseWithClaims(tokenstring, claims, func(token *jwt.Token) (interface{}, error) {
-        return jwtSecret, nil
+        return jwtSecret(), nil
     })
 
     if err != nil {
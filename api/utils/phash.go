@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"fmt"
+	"image"
+	"math/bits"
+	"os"
+
+	// Register decoders for the formats image.Decode should understand
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// ComputeImageHash returns a 64-bit difference hash (dHash) of the image at
+// path. The image is reduced to a 9x8 grayscale grid and each bit records
+// whether a cell is brighter than its right-hand neighbour, so re-encoded,
+// resized or lightly edited copies of a photo hash to nearby values.
+// Formats the standard library cannot decode (such as webp) return an error.
+func ComputeImageHash(path string) (uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open image: %w", err)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return DHash(img), nil
+}
+
+// DHash computes the difference hash of an already decoded image
+func DHash(img image.Image) uint64 {
+	const width, height = 9, 8
+	var grid [height][width]float64
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return 0
+	}
+
+	// Box-filter each cell so small shifts and noise average out
+	for gy := 0; gy < height; gy++ {
+		y0 := bounds.Min.Y + gy*h/height
+		y1 := bounds.Min.Y + (gy+1)*h/height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for gx := 0; gx < width; gx++ {
+			x0 := bounds.Min.X + gx*w/width
+			x1 := bounds.Min.X + (gx+1)*w/width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			// Sample at most 16x16 pixels per cell to keep large photos cheap
+			stepX, stepY := max(1, (x1-x0)/16), max(1, (y1-y0)/16)
+			var sum float64
+			var n int
+			for y := y0; y < y1; y += stepY {
+				for x := x0; x < x1; x += stepX {
+					r, g, b, _ := img.At(x, y).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+					n++
+				}
+			}
+			grid[gy][gx] = sum / float64(n)
+		}
+	}
+
+	var hash uint64
+	for gy := 0; gy < height; gy++ {
+		for gx := 0; gx < width-1; gx++ {
+			hash <<= 1
+			if grid[gy][gx] > grid[gy][gx+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// HammingDistance counts the bits that differ between two hashes
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
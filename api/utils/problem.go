@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"primeauction/api/models"
+)
+
+// Codes for problems found before a request reaches a service
+const (
+	CodeBadRequest       = "bad_request"
+	CodeInvalidBody      = "invalid_body"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeUnsupportedMedia = "unsupported_media_type"
+	CodeRateLimited      = "rate_limited"
+	CodeInternal         = "internal_error"
+
+	// Signed image URLs
+	CodeSignatureRequired = "signature_required"
+	CodeSignatureExpired  = "signature_expired"
+	CodeSignatureInvalid  = "signature_invalid"
+
+	// The fake payment provider's confirmation endpoint
+	CodeIntentNotFound   = "payment_intent_not_found"
+	CodeIntentNotPending = "payment_intent_not_pending"
+)
+
+// WriteProblem writes an application/problem+json response. The problem
+// type is about:blank, so the title is the standard text for status and
+// code is what clients should branch on.
+func WriteProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string, fields ...models.FieldError) {
+	title := http.StatusText(status)
+	if title == "" {
+		title = code // a non-standard status such as 499
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.Problem{
+		Type:     "about:blank",
+		Title:    title,
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     code,
+		Errors:   fields,
+	})
+}
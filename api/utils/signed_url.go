@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"primeauction/api/config"
+)
+
+// PublicImageURL returns the plain URL an image path is served from
+func PublicImageURL(imagePath string) string {
+	if imagePath == "" {
+		return ""
+	}
+	return "/" + strings.TrimPrefix(imagePath, "/")
+}
+
+// SignImageURL returns a URL for imagePath that the upload file handler
+// accepts until expiresAt
+func SignImageURL(imagePath string, expiresAt time.Time) string {
+	if imagePath == "" {
+		return ""
+	}
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("sig", imageSignature(imagePath, expires))
+	return PublicImageURL(imagePath) + "?" + query.Encode()
+}
+
+// Reasons VerifyImageSignature refuses a URL
+var (
+	ErrSignatureMissing = errors.New("missing signature")
+	ErrSignatureExpired = errors.New("signature expired")
+	ErrSignatureInvalid = errors.New("invalid signature")
+)
+
+// VerifyImageSignature checks the expires and sig query parameters of a
+// signed image URL against imagePath
+func VerifyImageSignature(imagePath, expires, sig string) error {
+	if expires == "" || sig == "" {
+		return ErrSignatureMissing
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad expiry: %v", ErrSignatureInvalid, err)
+	}
+	if time.Now().Unix() > unix {
+		return ErrSignatureExpired
+	}
+	expected := imageSignature(imagePath, expires)
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+func imageSignature(imagePath, expires string) string {
+	mac := hmac.New(sha256.New, []byte(config.Get().Auth.URLSigningKey))
+	mac.Write([]byte(strings.TrimPrefix(imagePath, "/")))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,245 @@
+// Package validate checks request values against rules declared in
+// `validate` struct tags, and reports every broken rule at once. Fields are
+// named by their json tag, the name clients send them under in JSON bodies
+// and forms alike.
+//
+// The rules, separated by commas:
+//
+//	required     the field must not be its zero value (or nil, or empty)
+//	min=N max=N  a number's value, a string's length in characters or a
+//	             list's length must be at least or at most N
+//	oneof=a b c  the value must be one of those listed
+//	email        a bare email address, such as ada@example.com
+//	password     8 to 72 bytes mixing at least two of lower-case letters,
+//	             upper-case letters, digits and symbols
+//
+// A field left empty is only checked by required, so an optional field is
+// free to be missing but must follow its rules when given.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"primeauction/api/models"
+)
+
+// Errors collects the field errors found in one request
+type Errors []models.FieldError
+
+// Add records that field is invalid
+func (e *Errors) Add(field, message string) {
+	*e = append(*e, models.FieldError{Field: field, Message: message})
+}
+
+// Has reports whether field already has an error
+func (e Errors) Has(field string) bool {
+	for _, f := range e {
+		if f.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge adds the errors in more for fields that have none yet, so a field
+// is reported once, with the first problem found
+func (e *Errors) Merge(more Errors) {
+	for _, f := range more {
+		if !e.Has(f.Field) {
+			*e = append(*e, f)
+		}
+	}
+}
+
+// Struct checks the fields of the struct v, or the struct v points to,
+// against their rules
+func Struct(v any) Errors {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validate: %T is not a struct", v))
+	}
+	var errs Errors
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		rules, ok := sf.Tag.Lookup("validate")
+		if !ok || !sf.IsExported() {
+			continue
+		}
+		if msg := check(rv.Field(i), rules); msg != "" {
+			name := fieldName(sf)
+			errs.Add(name, name+" "+msg)
+		}
+	}
+	return errs
+}
+
+// Value checks one value that isn't part of a struct against rules
+func Value(field string, v any, rules string) Errors {
+	var errs Errors
+	if msg := check(reflect.ValueOf(v), rules); msg != "" {
+		errs.Add(field, field+" "+msg)
+	}
+	return errs
+}
+
+// fieldName is the name a struct field has in JSON
+func fieldName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+	return name
+}
+
+// check returns what is wrong with v under rules, to follow the field's
+// name, or "" when it follows them all. Only the first broken rule is
+// reported.
+func check(v reflect.Value, rules string) string {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			break
+		}
+		v = v.Elem()
+	}
+	empty := !v.IsValid() || v.IsZero() || (isList(v) && v.Len() == 0)
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		if name == "required" {
+			if empty {
+				return "is required"
+			}
+			continue
+		}
+		if empty {
+			return ""
+		}
+		var msg string
+		switch name {
+		case "min", "max":
+			msg = checkBound(v, name, arg)
+		case "oneof":
+			msg = checkOneOf(v, strings.Fields(arg))
+		case "email":
+			msg = checkEmail(v.String())
+		case "password":
+			msg = checkPassword(v.String())
+		default:
+			panic("validate: unknown rule " + name)
+		}
+		if msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+func isList(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// checkBound applies min or max to a number's value or a length
+func checkBound(v reflect.Value, rule, arg string) string {
+	bound, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		panic("validate: bad bound " + arg)
+	}
+	var n float64
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	case reflect.String:
+		n = float64(utf8.RuneCountInString(v.String()))
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n = float64(v.Len())
+	default:
+		panic("validate: " + rule + " on a " + v.Kind().String())
+	}
+	if rule == "min" && n >= bound || rule == "max" && n <= bound {
+		return ""
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if rule == "min" {
+			return "must be at least " + arg + " characters"
+		}
+		return "cannot be longer than " + arg + " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if rule == "min" {
+			return "must have at least " + arg + " entries"
+		}
+		return "cannot have more than " + arg + " entries"
+	}
+	switch {
+	case rule == "max":
+		return "cannot be more than " + arg
+	case bound == 0:
+		return "cannot be negative"
+	}
+	return "must be at least " + arg
+}
+
+func checkOneOf(v reflect.Value, allowed []string) string {
+	s := fmt.Sprint(v.Interface())
+	for _, a := range allowed {
+		if s == a {
+			return ""
+		}
+	}
+	return "must be one of " + strings.Join(allowed, ", ")
+}
+
+func checkEmail(s string) string {
+	// ParseAddress also takes "Ada <ada@example.com>"; only the bare
+	// address is wanted
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return "must be a valid email address"
+	}
+	return ""
+}
+
+// Longest password bcrypt can hash; it refuses anything longer
+const maxPasswordBytes = 72
+
+func checkPassword(s string) string {
+	if utf8.RuneCountInString(s) < 8 {
+		return "must be at least 8 characters"
+	}
+	if len(s) > maxPasswordBytes {
+		return fmt.Sprintf("cannot be longer than %d bytes", maxPasswordBytes)
+	}
+	var lower, upper, digit, other int
+	for _, r := range s {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+	if lower+upper+digit+other < 2 {
+		return "must mix at least two of lower-case letters, upper-case letters, digits and symbols"
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package validate
+
+import (
+	"reflect"
+	"testing"
+)
+
+type signup struct {
+	Name     string   `json:"name" validate:"required,min=3,max=10"`
+	Email    string   `json:"email" validate:"required,email"`
+	Password string   `json:"password" validate:"required,password"`
+	Role     string   `json:"role" validate:"oneof=buyer seller"`
+	Age      int      `json:"age" validate:"min=0,max=150"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Nickname *string  `json:"nickname" validate:"min=2"`
+	Note     string   // no rules
+}
+
+func TestStructReportsEveryField(t *testing.T) {
+	short := "x"
+	errs := Struct(&signup{
+		Name:     "al",
+		Email:    "Al <al@example.com>",
+		Password: "password",
+		Role:     "admin",
+		Age:      -1,
+		Tags:     []string{"a", "b", "c"},
+		Nickname: &short,
+	})
+	want := Errors{
+		{Field: "name", Message: "name must be at least 3 characters"},
+		{Field: "email", Message: "email must be a valid email address"},
+		{Field: "password", Message: "password must mix at least two of lower-case letters, upper-case letters, digits and symbols"},
+		{Field: "role", Message: "role must be one of buyer, seller"},
+		{Field: "age", Message: "age cannot be negative"},
+		{Field: "tags", Message: "tags cannot have more than 2 entries"},
+		{Field: "nickname", Message: "nickname must be at least 2 characters"},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("Struct =\n%v\nwant\n%v", errs, want)
+	}
+}
+
+func TestStructSkipsEmptyOptionalFields(t *testing.T) {
+	errs := Struct(signup{Name: "ada", Email: "ada@example.com", Password: "s3cret-pass"})
+	if len(errs) != 0 {
+		t.Errorf("Struct = %v, want no errors", errs)
+	}
+
+	errs = Struct(signup{})
+	for _, field := range []string{"name", "email", "password"} {
+		if !errs.Has(field) {
+			t.Errorf("Struct of an empty signup has no error for %s", field)
+		}
+	}
+	if len(errs) != 3 {
+		t.Errorf("Struct of an empty signup = %v, want only the required fields", errs)
+	}
+}
+
+func TestValue(t *testing.T) {
+	for _, tc := range []struct {
+		value any
+		rules string
+		want  string
+	}{
+		{"", "required", "x is required"},
+		{"Passw0rd", "password", ""},
+		{"abcdefg", "password", "x must be at least 8 characters"},
+		{11, "max=10", "x cannot be more than 10"},
+		{4, "min=5", "x must be at least 5"},
+		{"a@b.example", "email", ""},
+		{"not an email", "email", "x must be a valid email address"},
+	} {
+		errs := Value("x", tc.value, tc.rules)
+		got := ""
+		if len(errs) > 0 {
+			got = errs[0].Message
+		}
+		if got != tc.want {
+			t.Errorf("Value(%v, %q) = %q, want %q", tc.value, tc.rules, got, tc.want)
+		}
+	}
+}
+
+func TestMergeKeepsFirstErrorPerField(t *testing.T) {
+	var errs Errors
+	errs.Add("price", "invalid price")
+	errs.Merge(Errors{{Field: "price", Message: "price cannot be negative"}, {Field: "name", Message: "name is required"}})
+	want := Errors{{Field: "price", Message: "invalid price"}, {Field: "name", Message: "name is required"}}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("Merge = %v, want %v", errs, want)
+	}
+}
@@ -71,7 +71,7 @@ export default function DashboardPage() {
       <div className="grid grid-cols-1 md:grid-cols-3 gap-6 mb-16">
          <div className="bg-white border border-gray-100 p-8 rounded-[2rem] premium-shadow">
             <span className="block text-[10px] font-black text-gray-400 uppercase tracking-widest mb-2">Total Value</span>
-            <span className="text-3xl font-black text-gray-900 tracking-tighter">${items.reduce((acc, item) => acc + (item.selling_price || 0), 0).toLocaleString()}</span>
+            <span className="text-3xl font-black text-gray-900 tracking-tighter">${(items.reduce((acc, item) => acc + (item.selling_price?.amount || 0), 0) / 100).toLocaleString()}</span>
          </div>
          <div className="bg-white border border-gray-100 p-8 rounded-[2rem] premium-shadow">
             <span className="block text-[10px] font-black text-gray-400 uppercase tracking-widest mb-2">My Listings</span>
@@ -2,7 +2,7 @@
 
 import { useEffect, useState } from 'react';
 import { useParams, useRouter } from 'next/navigation';
-import api from '@/lib/api';
+import api, { getErrorMessage } from '@/lib/api';
 import { isAuthenticated } from '@/lib/auth';
 import { Item } from '@/types';
 
@@ -16,6 +16,9 @@ export default function EditItemPage() {
     selling_price: '',
     quantity: '1',
   });
+  // Stock as loaded; it is only sent when the seller changes it, so sales
+  // made while the form is open aren't undone
+  const [loadedQuantity, setLoadedQuantity] = useState('');
   const [imageFiles, setImageFiles] = useState<File[]>([]);
   const [error, setError] = useState('');
   const [loading, setLoading] = useState(true);
@@ -37,10 +40,11 @@ export default function EditItemPage() {
       setFormData({
         name: item.name,
         description: item.description,
-        price: item.price?.toString() || '0',
-        selling_price: item.selling_price?.toString() || '0',
+        price: item.price?.decimal || '0',
+        selling_price: item.selling_price?.decimal || '0',
         quantity: item.quantity?.toString() || '1',
       });
+      setLoadedQuantity(item.quantity?.toString() || '1');
     } catch (err: any) {
       setError('Failed to load item');
     } finally {
@@ -60,7 +64,9 @@ export default function EditItemPage() {
       formDataToSend.append('description', formData.description);
       formDataToSend.append('price', formData.price);
       formDataToSend.append('selling_price', formData.selling_price);
-      formDataToSend.append('quantity', formData.quantity);
+      if (formData.quantity !== loadedQuantity) {
+        formDataToSend.append('quantity', formData.quantity);
+      }
 
       // Append image files if new ones are selected
       if (imageFiles.length > 0) {
@@ -76,8 +82,7 @@ export default function EditItemPage() {
       });
       router.push(`/items/${params.id}`);
     } catch (err: any) {
-      const errorMessage = err.response?.data || err.message || 'Failed to update item';
-      setError(typeof errorMessage === 'string' ? errorMessage : 'Failed to update item');
+      setError(getErrorMessage(err, 'Failed to update item'));
     } finally {
       setSaving(false);
     }
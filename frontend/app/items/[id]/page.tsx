@@ -2,7 +2,7 @@
 
 import { useEffect, useState } from 'react';
 import { useParams, useRouter } from 'next/navigation';
-import api, { getImageUrl } from '@/lib/api';
+import api, { formatMoney, getImageUrl } from '@/lib/api';
 import { Item } from '@/types';
 import { isAuthenticated, getUser } from '@/lib/auth';
 
@@ -45,8 +45,8 @@ export default function ItemDetailPage() {
   if (error || !item) return <div className="text-center py-20 text-gray-500 font-bold text-2xl tracking-widest uppercase">{error || 'Asset not found'}</div>;
 
   const images = item.images && item.images.length > 0 
-    ? item.images.map(img => img.image_path)
-    : [item.image];
+    ? item.images.map(img => img.url || img.image_path)
+    : [item.image_url || item.image];
 
   return (
     <div className="max-w-7xl mx-auto px-4 sm:px-6 lg:px-8 py-12">
@@ -96,8 +96,8 @@ export default function ItemDetailPage() {
           <div className="mb-12">
             <span className="text-gray-400 text-[10px] uppercase font-black tracking-widest block mb-2">Sale Price</span>
             <div className="flex items-baseline gap-3">
-               <p className="text-6xl font-black text-gray-900 tracking-tighter">${item.selling_price?.toLocaleString()}</p>
-               <span className="text-blue-600 font-bold text-sm">USD</span>
+               <p className="text-6xl font-black text-gray-900 tracking-tighter">{formatMoney(item.selling_price)}</p>
+               <span className="text-blue-600 font-bold text-sm">{item.selling_price?.currency}</span>
             </div>
           </div>
 
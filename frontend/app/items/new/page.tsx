@@ -2,7 +2,7 @@
 
 import { useState } from 'react';
 import { useRouter } from 'next/navigation';
-import api from '@/lib/api';
+import api, { getErrorMessage } from '@/lib/api';
 import { isAuthenticated } from '@/lib/auth';
 
 export default function CreateItemPage() {
@@ -52,8 +52,7 @@ export default function CreateItemPage() {
       if (err.response?.status === 403) {
         setError('Access denied: Only administrators can create items. Please contact an admin to add items to the market.');
       } else {
-        const errorMessage = err.response?.data || err.message || 'Failed to create item';
-        setError(typeof errorMessage === 'string' ? errorMessage : 'Failed to create item');
+        setError(getErrorMessage(err, 'Failed to create item'));
       }
     } finally {
       setLoading(false);
@@ -3,7 +3,7 @@
 import { useState } from 'react';
 import { useRouter } from 'next/navigation';
 import Link from 'next/link';
-import api from '@/lib/api';
+import api, { getErrorMessage } from '@/lib/api';
 import { setToken, setUser } from '@/lib/auth';
 import { LoginCredentials } from '@/types';
 
@@ -27,7 +27,7 @@ export default function LoginPage() {
       setUser(response.data.user);
       router.push('/dashboard');
     } catch (err: any) {
-      setError(err.response?.data || 'Login failed. Please check your credentials.');
+      setError(getErrorMessage(err, 'Login failed. Please check your credentials.'));
     } finally {
       setLoading(false);
     }
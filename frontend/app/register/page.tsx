@@ -3,7 +3,7 @@
 import { useState } from 'react';
 import { useRouter } from 'next/navigation';
 import Link from 'next/link';
-import api from '@/lib/api';
+import api, { getErrorMessage } from '@/lib/api';
 import { setToken, setUser } from '@/lib/auth';
 import { RegisterData } from '@/types';
 
@@ -28,7 +28,7 @@ export default function RegisterPage() {
       setUser(response.data.user);
       router.push('/dashboard');
     } catch (err: any) {
-      setError(err.response?.data || 'Registration failed. Please try again.');
+      setError(getErrorMessage(err, 'Registration failed. Please try again.'));
     } finally {
       setLoading(false);
     }
@@ -95,6 +95,9 @@ export default function RegisterPage() {
                 id="password"
                 type="password"
                 required
+                minLength={8}
+                maxLength={72}
+                title="At least 8 characters, mixing two of lower-case letters, upper-case letters, digits and symbols"
                 value={formData.password}
                 onChange={(e) => setFormData({ ...formData, password: e.target.value })}
                 className="w-full px-6 py-4 bg-gray-50 border border-gray-100 rounded-2xl focus:outline-none focus:ring-4 focus:ring-blue-50 focus:bg-white focus:border-blue-200 transition-all font-bold text-gray-900"
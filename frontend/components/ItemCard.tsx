@@ -2,7 +2,7 @@
 
 import Link from 'next/link';
 import { Item } from '@/types';
-import { getImageUrl } from '@/lib/api';
+import { formatMoney, getImageUrl } from '@/lib/api';
 
 interface ItemCardProps {
   item: Item;
@@ -14,7 +14,7 @@ export default function ItemCard({ item }: ItemCardProps) {
       <div className="bg-white rounded-[2.5rem] border border-gray-100 premium-shadow hover:shadow-2xl hover:-translate-y-2 transition-all duration-500 overflow-hidden h-full flex flex-col p-3">
         <div className="relative aspect-[1/1] rounded-[2rem] bg-gray-50 overflow-hidden">
           <img
-            src={getImageUrl(item.image)}
+            src={getImageUrl(item.image_url || item.image)}
             alt={item.name}
             className="w-full h-full object-cover group-hover:scale-110 transition-transform duration-700"
           />
@@ -45,7 +45,7 @@ export default function ItemCard({ item }: ItemCardProps) {
             <div>
               <span className="text-gray-400 text-[10px] uppercase font-black tracking-widest block mb-1">Price</span>
               <p className="text-2xl font-black text-gray-900 tracking-tighter">
-                ${item.selling_price?.toLocaleString() || '0.00'}
+                {item.selling_price?.currency} {formatMoney(item.selling_price)}
               </p>
             </div>
             <div className="w-12 h-12 bg-gray-50 rounded-2xl flex items-center justify-center group-hover:bg-blue-600 group-hover:text-white transition-all duration-300 shadow-inner">
@@ -1,5 +1,6 @@
 import axios, { AxiosError } from 'axios';
 import { getToken, removeToken } from './auth';
+import { Money, Problem } from '@/types';
 
 export const BACKEND_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
 
@@ -23,6 +24,28 @@ export const getImageUrl = (path: string | null | undefined): string => {
   return `${BACKEND_URL}/${cleanPath}`;
 };
 
+/**
+ * Formats a Money value for display, e.g. "19.99" -> "19.99" with grouping.
+ */
+export const formatMoney = (money: Money | null | undefined): string => {
+  if (!money) return '0.00';
+  const [whole, frac] = money.decimal.split('.');
+  const grouped = Number(whole).toLocaleString();
+  return frac !== undefined ? `${grouped}.${frac}` : grouped;
+};
+
+/**
+ * Extracts a message to show from a failed request: the problem detail the
+ * API sent, else the given fallback.
+ */
+export const getErrorMessage = (err: unknown, fallback: string): string => {
+  const problem = (err as AxiosError<Problem>)?.response?.data;
+  if (problem && typeof problem === 'object' && problem.detail) {
+    return problem.detail;
+  }
+  return fallback;
+};
+
 // Add token to requests
 api.interceptors.request.use((config) => {
   const token = getToken();
@@ -6,12 +6,20 @@ export interface User {
   created_at: string;
   updated_at: string;
   is_admin: boolean;
+  preferred_currency?: string;
+}
+
+export interface Money {
+  amount: number; // integer minor units, e.g. cents
+  currency: string;
+  decimal: string; // exact decimal string, e.g. "19.99"
 }
 
 export interface ItemImage {
   id: string;
   item_id: string;
   image_path: string;
+  url?: string;
   display_order: number;
   created_at: string;
 }
@@ -21,14 +29,266 @@ export interface Item {
   user_id: string;
   name: string;
   description: string;
-  price: number;
-  selling_price: number;
+  category?: string;
+  price: Money;
+  selling_price: Money;
   image: string;
+  image_url?: string;
   images?: ItemImage[];
   quantity: number;
   created_at: string;
   updated_at: string;
   is_sold: boolean;
+  visibility?: 'public' | 'draft' | 'private';
+  weight_grams: number;
+  length_cm: number;
+  width_cm: number;
+  height_cm: number;
+  shipping_profile_id: string | null;
+  converted?: {
+    price: Money;
+    selling_price: Money;
+    rate: string;
+    rate_as_of: string;
+    rate_source: string;
+  };
+}
+
+export interface OrderLine {
+  id: string;
+  order_id: string;
+  item_id: string | null;
+  seller_id: string;
+  item_name: string;
+  category: string;
+  quantity: number;
+  unit_price: Money;
+  line_total: Money;
+  created_at: string;
+  fee?: FeeAssessment; // only for the line's seller and admins
+  shipment_id?: string;
+}
+
+export interface Order {
+  id: string;
+  buyer_id: string;
+  status: 'pending' | 'paid' | 'cancelled' | 'expired';
+  subtotal: Money; // net of tax
+  tax: Money;
+  shipping: Money;
+  total: Money; // what the buyer pays
+  expires_at: string;
+  paid_at?: string;
+  created_at: string;
+  updated_at: string;
+  lines: OrderLine[];
+  tax_region?: string;
+  tax_lines: TaxLine[];
+  fee_schedule_version?: number;
+  ship_to_region?: string;
+  shipments: Shipment[];
+}
+
+export interface TaxLine {
+  id: string;
+  order_id: string;
+  order_line_id: string;
+  region: string;
+  name: string;
+  rate_bps: number;
+  inclusive: boolean; // the seller's price already included the tax
+  taxable: Money;
+  tax: Money;
+}
+
+export interface TaxRate {
+  region: string; // e.g. "DE" or "US-CA"
+  category: string;
+  name: string;
+  rate_bps: number;
+  updated_at: string;
+}
+
+export interface TaxSettings {
+  tax_region: string;
+  prices_include_tax: boolean;
+}
+
+export interface FeeTier {
+  up_to?: Money;
+  bps: number;
+}
+
+export interface FeeRule {
+  name: string;
+  event: 'sale' | 'listing';
+  category?: string;
+  seller_tier?: string;
+  type: 'percentage' | 'fixed' | 'tiered';
+  bps?: number;
+  fixed?: Money;
+  tiers?: FeeTier[];
+  min?: Money;
+  cap?: Money;
+}
+
+export interface FeeSchedule {
+  version: number;
+  rules: FeeRule[];
+  note: string;
+  created_by?: string;
+  created_at: string;
+}
+
+export interface FeeAssessment {
+  schedule_version: number;
+  rule?: string;
+  type?: string;
+  base: Money;
+  calculated: Money;
+  capped?: boolean;
+  amount: Money;
+}
+
+export interface Payment {
+  id: string;
+  order_id: string;
+  provider: string;
+  provider_ref: string;
+  status: 'pending' | 'authorized' | 'captured' | 'failed' | 'cancelled' | 'partially_refunded' | 'refunded';
+  amount: Money;
+  refunded: Money;
+  client_secret?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface LedgerBalance {
+  currency: string;
+  available: Money;
+}
+
+export interface StatementLine {
+  entry_id: string;
+  kind: 'sale' | 'refund' | 'payout' | 'listing_fee';
+  reference_id: string;
+  description: string;
+  memo: string;
+  amount: Money;
+  balance: Money;
+  created_at: string;
+}
+
+export interface Invoice {
+  id: string;
+  number: string;
+  sequence: number;
+  order_id: string;
+  seller_id: string;
+  buyer_id: string;
+  subtotal: Money;
+  tax: Money;
+  shipping: Money;
+  total: Money;
+  issued_at: string;
+  url: string; // the PDF, served to authenticated viewers
+}
+
+export interface Dispute {
+  id: string;
+  order_id: string;
+  buyer_id: string;
+  seller_id: string;
+  reason: 'not_received' | 'not_as_described' | 'return' | 'other';
+  status: 'open' | 'seller_responded' | 'escalated' | 'resolved';
+  resolution?: 'refund' | 'partial_refund' | 'no_refund';
+  refund: Money;
+  restocked: boolean;
+  resolved_by?: string;
+  created_at: string;
+  updated_at: string;
+  resolved_at?: string;
+  messages?: DisputeMessage[];
+}
+
+export interface DisputeMessage {
+  id: string;
+  dispute_id: string;
+  author_id: string;
+  role: 'buyer' | 'seller' | 'admin';
+  body: string;
+  created_at: string;
+}
+
+export interface ShippingProfile {
+  id: string;
+  seller_id: string;
+  name: string;
+  type: 'flat' | 'by_weight' | 'pickup';
+  currency: string;
+  flat?: Money;
+  rates?: ShippingRate[]; // lightest first
+  free_over?: Money;
+  excluded_regions?: string[];
+  pickup_location?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface ShippingRate {
+  up_to_grams: number | null; // null covers any weight
+  cost: Money;
+}
+
+export interface Shipment {
+  id: string;
+  order_id: string;
+  seller_id: string;
+  profile_id: string | null;
+  method: '' | 'flat' | 'by_weight' | 'pickup';
+  weight_grams: number;
+  cost: Money;
+  status: 'pending' | 'shipped' | 'in_transit' | 'delivered' | 'returned';
+  carrier?: string;
+  tracking_number?: string;
+  pickup_location?: string;
+  shipped_at?: string;
+  delivered_at?: string;
+  created_at: string;
+  updated_at: string;
+  events?: ShipmentEvent[];
+}
+
+export interface ShipmentEvent {
+  status: Shipment['status'];
+  note?: string;
+  created_at: string;
+}
+
+export interface ShippingQuote {
+  seller_id: string;
+  profile_id: string | null;
+  method: Shipment['method'];
+  weight_grams: number;
+  cost: Money;
+  pickup_location?: string;
+  item_ids: string[];
+}
+
+export interface FieldError {
+  field: string;
+  message: string;
+}
+
+// Problem is the application/problem+json body every API error comes back as
+export interface Problem {
+  type: string;
+  title: string;
+  status: number;
+  detail?: string;
+  instance?: string;
+  code: string;
+  errors?: FieldError[];
 }
 
 export interface AuthResponse {